@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrAaExec          = fmt.Errorf("%w: aa-exec", Err)
+	ErrAaExecNoProfile = fmt.Errorf("%w: profile must be set", ErrAaExec)
+)
+
+// AaExec is a Runner that wraps another Runner and runs commands confined by
+// an AppArmor profile via aa-exec.
+//
+// As aa-exec passes its own environment through to the command it executes,
+// calls to Env are passed directly to the underlying Runner.
+type AaExec struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with aa-exec. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Profile is the name of the AppArmor profile to confine commands with,
+	// passed via the -p flag.
+	Profile string
+
+	// Namespace is the AppArmor policy namespace the profile belongs to,
+	// passed via the -n flag. When empty, no -n flag will be used.
+	Namespace string
+
+	// Args is a string slice of extra arguments to pass to aa-exec.
+	Args []string
+}
+
+var _ Runner = &AaExec{}
+
+var _ Validator = &AaExec{}
+
+// Validate checks the AaExec is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner and Profile are set,
+// and that values passed to aa-exec do not contain illegal characters, or
+// begin with "-" where they could be mistaken for flags. The underlying Runner
+// is validated too.
+func (r *AaExec) Validate() error {
+	if r.Profile == "" {
+		return &ValidationError{
+			Field: "AaExec.Profile",
+			Msg:   "must be set",
+			Err:   ErrAaExecNoProfile,
+		}
+	}
+
+	return firstError(
+		validateRunner("AaExec.Runner", r.Runner),
+		validateWord("AaExec.Profile", r.Profile),
+		validateWord("AaExec.Namespace", r.Namespace),
+		Validate(r.Runner),
+	)
+}
+
+// Run executes the command confined by the AppArmor profile by calling Run on
+// the underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Profile field is empty.
+func (r *AaExec) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	aaArgs, err := r.args(command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.Run(stdin, stdout, stderr, "aa-exec", aaArgs...)
+}
+
+// RunContext executes the command confined by the AppArmor profile by calling
+// RunContext on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Profile field is empty.
+func (r *AaExec) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	aaArgs, err := r.args(command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(
+		ctx, stdin, stdout, stderr, "aa-exec", aaArgs...,
+	)
+}
+
+func (r *AaExec) args(command string, args []string) ([]string, error) {
+	if r.Profile == "" {
+		return nil, ErrAaExecNoProfile
+	}
+
+	aaArgs := []string{"-p", r.Profile}
+	if r.Namespace != "" {
+		aaArgs = append(aaArgs, "-n", r.Namespace)
+	}
+	aaArgs = append(aaArgs, r.Args...)
+	aaArgs = append(aaArgs, "--", command)
+	aaArgs = append(aaArgs, args...)
+
+	return aaArgs, nil
+}
+
+// Env sets the environment by calling Env on the underlying Runner. Will panic
+// if Runner field is nil.
+func (r *AaExec) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var aaExecTestCases = []struct {
+	name     string
+	runner   AaExec
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	command  string
+	args     []string
+	err      error
+	wantArgs []string
+	wantErr  string
+}{
+	{
+		name:     "profile",
+		runner:   AaExec{Profile: "nginx"},
+		stdout:   &bytes.Buffer{},
+		stderr:   &bytes.Buffer{},
+		command:  "nginx",
+		args:     []string{"-t"},
+		wantArgs: []string{"-p", "nginx", "--", "nginx", "-t"},
+	},
+	{
+		name:     "stdin",
+		runner:   AaExec{Profile: "restricted"},
+		stdin:    bytes.NewBufferString("foo\nbar"),
+		command:  "cat",
+		wantArgs: []string{"-p", "restricted", "--", "cat"},
+	},
+	{
+		name: "with Namespace and Args",
+		runner: AaExec{
+			Profile:   "nginx",
+			Namespace: "web",
+			Args:      []string{"-d"},
+		},
+		command: "nginx",
+		wantArgs: []string{
+			"-p", "nginx", "-n", "web", "-d", "--", "nginx",
+		},
+	},
+	{
+		name:     "error",
+		runner:   AaExec{Profile: "nginx"},
+		command:  "nginx",
+		err:      errors.New("exit status 1"),
+		wantArgs: []string{"-p", "nginx", "--", "nginx"},
+		wantErr:  "exit status 1",
+	},
+	{
+		name:    "no profile",
+		runner:  AaExec{},
+		command: "nginx",
+		wantErr: "runner: aa-exec: profile must be set",
+	},
+}
+
+func TestAaExec_Run(t *testing.T) {
+	for _, tt := range aaExecTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgs != nil {
+				r.EXPECT().Run(
+					tt.stdin, tt.stdout, tt.stderr, "aa-exec", tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			a := tt.runner
+			a.Runner = r
+
+			err := a.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAaExec_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range aaExecTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgs != nil {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					tt.stdin, tt.stdout, tt.stderr, "aa-exec", tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			a := tt.runner
+			a.Runner = r
+
+			err := a.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAaExec_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env("FOO=BAR", "PORT=8080")
+
+	a := &AaExec{Runner: r, Profile: "nginx"}
+	a.Env("FOO=BAR", "PORT=8080")
+}
+
+func TestAaExec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *AaExec
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "valid",
+			runner: &AaExec{Runner: &Local{}, Profile: "myapp"},
+		},
+		{
+			name:    "no profile",
+			runner:  &AaExec{Runner: &Local{}},
+			wantErr: "runner: invalid: AaExec.Profile: must be set",
+			wantIs:  ErrAaExecNoProfile,
+		},
+		{
+			name:    "no runner",
+			runner:  &AaExec{Profile: "myapp"},
+			wantErr: "runner: invalid: AaExec.Runner: must be set",
+		},
+		{
+			name: "namespace is a flag",
+			runner: &AaExec{
+				Runner: &Local{}, Profile: "myapp", Namespace: "-d",
+			},
+			wantErr: "runner: invalid: AaExec.Namespace: " +
+				"must not begin with -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ANSIStripWriter is an io.Writer which removes ANSI escape sequences, like
+// colours and cursor movements, from data written to it before forwarding it
+// to the underlying writer. Sequences split across writes are handled.
+//
+// CSI sequences like "\x1b[31m", OSC sequences like terminal title changes,
+// and other escape sequences are removed. Other control characters, like
+// carriage returns and tabs, are retained. ANSIStripWriter is safe for
+// concurrent use.
+type ANSIStripWriter struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	state ansiState
+	out   []byte
+}
+
+type ansiState int
+
+const (
+	ansiText ansiState = iota
+	ansiEsc
+	ansiEscIntermediate
+	ansiCSI
+	ansiString
+	ansiStringEsc
+)
+
+var _ io.Writer = &ANSIStripWriter{}
+
+// NewANSIStripWriter returns an ANSIStripWriter which writes to w.
+func NewANSIStripWriter(w io.Writer) *ANSIStripWriter {
+	return &ANSIStripWriter{w: w}
+}
+
+// Write removes any escape sequences from p, and writes the remaining data to
+// the underlying writer.
+func (sw *ANSIStripWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.out = sw.out[:0]
+	for _, c := range p {
+		switch sw.state {
+		case ansiText:
+			if c == 0x1b {
+				sw.state = ansiEsc
+			} else {
+				sw.out = append(sw.out, c)
+			}
+		case ansiEsc:
+			switch {
+			case c == '[':
+				sw.state = ansiCSI
+			case c == ']' || c == 'P' || c == 'X' || c == '^' || c == '_':
+				// OSC, DCS, SOS, PM, and APC strings, terminated by ST or,
+				// for OSC, BEL.
+				sw.state = ansiString
+			case c >= 0x20 && c <= 0x2f:
+				sw.state = ansiEscIntermediate
+			default:
+				sw.state = ansiText
+			}
+		case ansiEscIntermediate:
+			if c < 0x20 || c > 0x2f {
+				sw.state = ansiText
+			}
+		case ansiCSI:
+			if c >= 0x40 && c <= 0x7e {
+				sw.state = ansiText
+			}
+		case ansiString:
+			switch c {
+			case 0x07:
+				sw.state = ansiText
+			case 0x1b:
+				sw.state = ansiStringEsc
+			}
+		case ansiStringEsc:
+			if c == '\\' {
+				sw.state = ansiText
+			} else {
+				sw.state = ansiString
+			}
+		}
+	}
+
+	if len(sw.out) > 0 {
+		_, err := sw.w.Write(sw.out)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// StripANSI is a Runner that wraps another Runner, and removes ANSI escape
+// sequences from the stdout and stderr output of commands with
+// ANSIStripWriters.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type StripANSI struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+}
+
+var _ Runner = &StripANSI{}
+
+// Run calls Run on the underlying Runner, with escape sequences removed from
+// its output.
+//
+// Will panic if Runner field is nil.
+func (r *StripANSI) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	stdout, stderr = stripANSIWriters(stdout, stderr)
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext calls RunContext on the underlying Runner, with escape sequences
+// removed from its output.
+//
+// Will panic if Runner field is nil.
+func (r *StripANSI) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	stdout, stderr = stripANSIWriters(stdout, stderr)
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *StripANSI) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+// stripANSIWriters wraps non-nil stdout and stderr writers with
+// ANSIStripWriters. Each stream gets its own ANSIStripWriter, so sequences
+// are tracked separately.
+func stripANSIWriters(
+	stdout io.Writer,
+	stderr io.Writer,
+) (io.Writer, io.Writer) {
+	stdout, stderr = syncIfSame(stdout, stderr)
+
+	if stdout != nil {
+		stdout = NewANSIStripWriter(stdout)
+	}
+	if stderr != nil {
+		stderr = NewANSIStripWriter(stderr)
+	}
+
+	return stdout, stderr
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestANSIStripWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "plain text",
+			writes: []string{"hello\r\n\tworld\n"},
+			want:   "hello\r\n\tworld\n",
+		},
+		{
+			name:   "colours",
+			writes: []string{"\x1b[1;31mERROR\x1b[0m: failed\n"},
+			want:   "ERROR: failed\n",
+		},
+		{
+			name:   "cursor movement",
+			writes: []string{"50%\x1b[2K\x1b[1G100%\x1b[?25h\n"},
+			want:   "50%100%\n",
+		},
+		{
+			name: "OSC",
+			writes: []string{
+				"\x1b]0;title\x07a\x1b]8;;http://x\x1b\\link\x1b]8;;\x1b\\\n",
+			},
+			want: "alink\n",
+		},
+		{
+			name:   "charset and keypad",
+			writes: []string{"\x1b(Ba\x1b=b\x1b>c\n"},
+			want:   "abc\n",
+		},
+		{
+			name:   "split across writes",
+			writes: []string{"a\x1b", "[3", "2mb\x1b]0;t", "itle\x1b", "\\c"},
+			want:   "abc",
+		},
+		{
+			name:   "UTF-8",
+			writes: []string{"\x1b[32m✓\x1b[0m déjà vu\n"},
+			want:   "✓ déjà vu\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewANSIStripWriter(&buf)
+
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestANSIStripWriter_error(t *testing.T) {
+	errBoom := errors.New("boom")
+	w := NewANSIStripWriter(&errWriter{err: errBoom})
+
+	n, err := w.Write([]byte("\x1b[0m"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	n, err = w.Write([]byte("a"))
+	assert.ErrorIs(t, err, errBoom)
+	assert.Zero(t, n)
+}
+
+func TestStripANSI_Run(t *testing.T) {
+	r := &StripANSI{Runner: &Local{}}
+	script := `printf '\033[32mok\033[0m\n'; ` +
+		`printf '\033[31merr\033[0m\n' >&2`
+
+	var stdout, stderr bytes.Buffer
+	err := r.Run(nil, &stdout, &stderr, "sh", "-c", script)
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+
+	var combined bytes.Buffer
+	err = r.RunContext(
+		context.Background(), nil, &combined, &combined, "sh", "-c", script,
+	)
+	require.NoError(t, err)
+	assert.ElementsMatch(
+		t, []string{"ok", "err"}, strings.Fields(combined.String()),
+	)
+
+	err = r.Run(nil, nil, nil, "sh", "-c", script)
+	require.NoError(t, err)
+}
+
+func TestStripANSI_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &StripANSI{Runner: m}
+	r.Env("FOO=bar")
+}
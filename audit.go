@@ -0,0 +1,265 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/user"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAudit       = fmt.Errorf("%w: audit", Err)
+	ErrAuditTamper = fmt.Errorf("%w: chain broken", ErrAudit)
+)
+
+// AuditRecord is a record of a single command run via an Audit runner.
+type AuditRecord struct {
+	// Time is the time the command was started.
+	Time time.Time `json:"time"`
+
+	// User identifies who ran the command.
+	User string `json:"user,omitempty"`
+
+	// Command is the command which was run.
+	Command string `json:"command"`
+
+	// ArgsHash is the AuditArgsHash of the arguments the command was run
+	// with. Arguments are hashed rather than recorded as they may contain
+	// secrets.
+	ArgsHash string `json:"args_hash"`
+
+	// ExitCode is the exit code of the command. It is -1 when the command
+	// failed without an exit code.
+	ExitCode int `json:"exit_code"`
+
+	// PrevHash is the Hash of the previous record, when hash-chaining is
+	// enabled.
+	PrevHash string `json:"prev_hash,omitempty"`
+
+	// Hash is the SHA-256 hash of the record with Hash itself omitted, when
+	// hash-chaining is enabled. As it covers PrevHash, modifying, removing,
+	// or reordering records breaks the chain.
+	Hash string `json:"hash,omitempty"`
+}
+
+// AuditArgsHash returns the hex encoded SHA-256 hash of args, each terminated
+// by a NUL byte, as used for AuditRecord.ArgsHash.
+func AuditArgsHash(args []string) string {
+	h := sha256.New()
+	for _, arg := range args {
+		_, _ = io.WriteString(h, arg)
+		_, _ = h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hash returns the hex encoded SHA-256 hash of the JSON encoding of rec, with
+// its Hash field omitted.
+func (rec AuditRecord) hash() (string, error) {
+	rec.Hash = ""
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Audit is a Runner that wraps another Runner, and appends an AuditRecord of
+// each command run to Writer as a single line of JSON, once the command has
+// completed.
+//
+// If Writer has a Sync method, like *os.File, it is called after each record
+// is written. If writing the record fails, an error wrapping ErrAudit is
+// returned, even if the command succeeded.
+//
+// Audit is safe for concurrent use. Calls to Env are passed directly to the
+// underlying Runner.
+type Audit struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Writer is where records are written. If not set, running commands will
+	// cause a panic.
+	Writer io.Writer
+
+	// User identifies who runs commands. When empty, the name of the current
+	// OS user is used.
+	User string
+
+	// Chain enables hash-chaining of records, making tampering detectable with
+	// VerifyAuditLog.
+	Chain bool
+
+	// PrevHash is the Hash of the last record previously written to Writer,
+	// allowing a chain to be continued across restarts. It is updated as
+	// records are written.
+	PrevHash string
+
+	mu       sync.Mutex
+	userOnce sync.Once
+	user     string
+}
+
+var _ Runner = &Audit{}
+
+// Run executes the command with the underlying Runner, and writes an audit
+// record.
+func (r *Audit) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.Run(stdin, stdout, stderr, command, args...)
+
+	return r.write(start, command, args, err)
+}
+
+// RunContext executes the command with the underlying Runner, and writes an
+// audit record.
+func (r *Audit) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+
+	return r.write(start, command, args, err)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Audit) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Audit) write(
+	start time.Time,
+	command string,
+	args []string,
+	runErr error,
+) error {
+	rec := AuditRecord{
+		Time:     start.UTC(),
+		User:     r.userName(),
+		Command:  command,
+		ArgsHash: AuditArgsHash(args),
+		ExitCode: ExitCode(runErr),
+	}
+
+	err := r.append(rec)
+	if err != nil && runErr == nil {
+		return fmt.Errorf("%w: %w", ErrAudit, err)
+	}
+
+	return runErr
+}
+
+func (r *Audit) append(rec AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Chain {
+		rec.PrevHash = r.PrevHash
+
+		var err error
+		rec.Hash, err = rec.hash()
+		if err != nil {
+			return err
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Writer.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+
+	if r.Chain {
+		r.PrevHash = rec.Hash
+	}
+
+	if s, ok := r.Writer.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+func (r *Audit) userName() string {
+	if r.User != "" {
+		return r.User
+	}
+
+	r.userOnce.Do(func() {
+		if u, err := user.Current(); err == nil {
+			r.user = u.Username
+		}
+	})
+
+	return r.user
+}
+
+// VerifyAuditLog reads hash-chained AuditRecords written by an Audit runner
+// from rd, and verifies the chain is intact. It returns the Hash of the last
+// record, for use as Audit.PrevHash, or an error wrapping ErrAuditTamper
+// identifying the first line where the chain is broken.
+//
+// The chain is expected to start with a record with an empty PrevHash.
+func VerifyAuditLog(rd io.Reader) (string, error) {
+	var prev string
+
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+		err := json.Unmarshal(scanner.Bytes(), &rec)
+		if err != nil {
+			return "", fmt.Errorf("%w: line %d: %w", ErrAudit, line, err)
+		}
+
+		if rec.PrevHash != prev {
+			return "", fmt.Errorf(
+				"%w: line %d: previous hash mismatch", ErrAuditTamper, line,
+			)
+		}
+
+		hash, err := rec.hash()
+		if err != nil {
+			return "", fmt.Errorf("%w: line %d: %w", ErrAudit, line, err)
+		}
+		if rec.Hash != hash {
+			return "", fmt.Errorf(
+				"%w: line %d: hash mismatch", ErrAuditTamper, line,
+			)
+		}
+
+		prev = rec.Hash
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrAudit, err)
+	}
+
+	return prev, nil
+}
@@ -0,0 +1,206 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func readAuditRecords(t *testing.T, data string) []AuditRecord {
+	t.Helper()
+
+	var recs []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		var rec AuditRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		recs = append(recs, rec)
+	}
+
+	return recs
+}
+
+func TestAudit_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{ExitCode: 2})
+
+	var buf bytes.Buffer
+	r := &Audit{Runner: f, Writer: &buf, User: "deploy"}
+
+	before := time.Now().Add(-time.Second)
+	require.NoError(t, r.Run(nil, nil, nil, "true", "-v", "TOKEN=secret"))
+	err := r.RunContext(context.Background(), nil, nil, nil, "false")
+	assert.EqualError(t, err, "false: exit status 2")
+
+	recs := readAuditRecords(t, buf.String())
+	require.Len(t, recs, 2)
+
+	for _, rec := range recs {
+		assert.True(t, rec.Time.After(before))
+		assert.Equal(t, time.UTC, rec.Time.Location())
+	}
+	assert.Equal(t, AuditRecord{
+		Time:     recs[0].Time,
+		User:     "deploy",
+		Command:  "true",
+		ArgsHash: AuditArgsHash([]string{"-v", "TOKEN=secret"}),
+	}, recs[0])
+	assert.Equal(t, AuditRecord{
+		Time:     recs[1].Time,
+		User:     "deploy",
+		Command:  "false",
+		ArgsHash: AuditArgsHash(nil),
+		ExitCode: 2,
+	}, recs[1])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestAudit_currentUser(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+
+	var buf bytes.Buffer
+	r := &Audit{Runner: f, Writer: &buf}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+
+	recs := readAuditRecords(t, buf.String())
+	require.Len(t, recs, 1)
+	assert.NotEmpty(t, recs[0].User)
+}
+
+type errWriter struct{ err error }
+
+func (w *errWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestAudit_writeError(t *testing.T) {
+	errDisk := errors.New("disk full")
+
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	r := &Audit{Runner: f, Writer: &errWriter{err: errDisk}, User: "deploy"}
+
+	err := r.Run(nil, nil, nil, "true")
+	assert.EqualError(t, err, "runner: audit: disk full")
+	assert.ErrorIs(t, err, ErrAudit)
+	assert.ErrorIs(t, err, errDisk)
+
+	err = r.Run(nil, nil, nil, "false")
+	assert.EqualError(t, err, "false: exit status 1")
+}
+
+func TestAudit_Chain(t *testing.T) {
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{})
+
+	var buf bytes.Buffer
+	r := &Audit{Runner: f, Writer: &buf, User: "deploy", Chain: true}
+	for _, cmd := range []string{"one", "two", "three"} {
+		require.NoError(t, r.Run(nil, nil, nil, cmd))
+	}
+
+	recs := readAuditRecords(t, buf.String())
+	require.Len(t, recs, 3)
+	assert.Empty(t, recs[0].PrevHash)
+	assert.Equal(t, recs[0].Hash, recs[1].PrevHash)
+	assert.Equal(t, recs[1].Hash, recs[2].PrevHash)
+	assert.Equal(t, recs[2].Hash, r.PrevHash)
+
+	last, err := VerifyAuditLog(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	assert.Equal(t, r.PrevHash, last)
+
+	// Continuing the chain with a new runner.
+	r2 := &Audit{
+		Runner: f, Writer: &buf, User: "deploy", Chain: true, PrevHash: last,
+	}
+	require.NoError(t, r2.Run(nil, nil, nil, "four"))
+
+	_, err = VerifyAuditLog(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{})
+
+	var buf bytes.Buffer
+	r := &Audit{Runner: f, Writer: &buf, User: "deploy", Chain: true}
+	for _, cmd := range []string{"one", "two", "three"} {
+		require.NoError(t, r.Run(nil, nil, nil, cmd))
+	}
+	lines := strings.SplitAfter(strings.TrimSpace(buf.String()), "\n")
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "empty",
+			data: "",
+		},
+		{
+			name: "intact",
+			data: buf.String(),
+		},
+		{
+			name: "modified record",
+			data: lines[0] +
+				strings.Replace(lines[1], `"two"`, `"t3o"`, 1) +
+				lines[2],
+			wantErr: "runner: audit: chain broken: line 2: hash mismatch",
+		},
+		{
+			name: "removed record",
+			data: lines[0] + lines[2],
+			wantErr: "runner: audit: chain broken: line 2: " +
+				"previous hash mismatch",
+		},
+		{
+			name: "reordered records",
+			data: lines[1] + lines[0] + lines[2],
+			wantErr: "runner: audit: chain broken: line 1: " +
+				"previous hash mismatch",
+		},
+		{
+			name: "invalid JSON",
+			data: lines[0] + "{\n",
+			wantErr: "runner: audit: line 2: " +
+				"unexpected end of JSON input",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyAuditLog(strings.NewReader(tt.data))
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrAudit)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAudit_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=bar"})
+
+	a := &Audit{Runner: r}
+	a.Env("FOO=bar")
+}
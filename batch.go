@@ -0,0 +1,216 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBatch is the error matched by errors.Is for errors returned by Batch
+// when one or more commands fail.
+var ErrBatch = fmt.Errorf("%w: batch", Err)
+
+// BatchCommand is a command to be run by Batch.
+type BatchCommand struct {
+	// Command is the command to run.
+	Command string
+
+	// Args are the arguments to run the command with.
+	Args []string
+
+	// Stdin, when not nil, is given to the command as its stdin.
+	Stdin io.Reader
+
+	// Options configure the command as with RunWith. Options which set stdout
+	// or stderr replace the capture of output into the BatchResult.
+	Options []RunOption
+}
+
+// BatchResult is the outcome of a command run by Batch.
+type BatchResult struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Stdout is the output the command wrote to stdout.
+	Stdout []byte
+
+	// Stderr is the output the command wrote to stderr.
+	Stderr []byte
+
+	// ExitCode is the exit code of the command, or -1 if it failed without an
+	// exit code, or was not run.
+	ExitCode int
+
+	// Duration is the wall-clock time taken to run the command.
+	Duration time.Duration
+
+	// Err is the error returned by the command, if any.
+	Err error
+
+	// Skipped indicates the command was not run, as StopOnError is set and an
+	// earlier command failed, or the context was done.
+	Skipped bool
+}
+
+// BatchError is returned by Batch when one or more commands fail. It matches
+// ErrBatch with errors.Is, and the errors of each failed command with
+// errors.Is and errors.As.
+type BatchError struct {
+	// Failed are the results of the commands which failed, in the order they
+	// were given to Batch. Skipped commands are not included.
+	Failed []*BatchResult
+
+	// Total is the number of commands given to Batch.
+	Total int
+}
+
+var _ error = &BatchError{}
+
+func (e *BatchError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, res := range e.Failed {
+		cmdline := strings.Join(append([]string{res.Command}, res.Args...), " ")
+		msgs = append(msgs, fmt.Sprintf("%s: %s", cmdline, res.Err))
+	}
+
+	return fmt.Sprintf(
+		"%s: %d of %d commands failed: %s",
+		ErrBatch.Error(), len(e.Failed), e.Total, strings.Join(msgs, "; "),
+	)
+}
+
+// Unwrap returns ErrBatch, and the errors of the failed commands.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	errs = append(errs, ErrBatch)
+	for _, res := range e.Failed {
+		errs = append(errs, res.Err)
+	}
+
+	return errs
+}
+
+// Batch runs sets of commands concurrently on a Runner, using a pool of
+// workers, and collects the result of each command.
+type Batch struct {
+	// Runner is the Runner to run commands with. If not set, running commands
+	// will cause a panic.
+	Runner Runner
+
+	// Concurrency is the maximum number of commands run at once. When zero or
+	// negative, all commands are run at once.
+	Concurrency int
+
+	// StopOnError causes commands which have not started to be skipped once
+	// any command fails.
+	StopOnError bool
+}
+
+// Run runs the given commands via RunContext on the Runner, and returns their
+// results in the same order as the commands. If any command fails, a
+// *BatchError is returned alongside the results.
+//
+// Commands which have not started when ctx is done are skipped, and ctx's
+// error is returned if no command failed.
+func (b *Batch) Run(
+	ctx context.Context,
+	cmds ...BatchCommand,
+) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(cmds))
+	for i, cmd := range cmds {
+		results[i] = &BatchResult{
+			Command:  cmd.Command,
+			Args:     cmd.Args,
+			ExitCode: -1,
+			Skipped:  true,
+		}
+	}
+
+	workers := b.Concurrency
+	if workers <= 0 || workers > len(cmds) {
+		workers = len(cmds)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				runBatchCommand(ctx, b.Runner, cmds[i], results[i])
+				if results[i].Err != nil && b.StopOnError {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	func() {
+		defer close(queue)
+
+		for i := range cmds {
+			select {
+			case queue <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	berr := &BatchError{Total: len(cmds)}
+	for _, res := range results {
+		if res.Err != nil {
+			berr.Failed = append(berr.Failed, res)
+		}
+	}
+	if len(berr.Failed) > 0 {
+		return results, berr
+	}
+
+	return results, ctx.Err()
+}
+
+// runBatchCommand runs cmd via RunWith on r, and records its outcome in res.
+func runBatchCommand(
+	ctx context.Context,
+	r Runner,
+	cmd BatchCommand,
+	res *BatchResult,
+) {
+	// Check for the context being done by StopOnError, as the select in
+	// Batch.Run may still pick a queued command.
+	if ctx.Err() != nil {
+		return
+	}
+
+	var stdout, stderr bytes.Buffer
+	opts := make([]RunOption, 0, len(cmd.Options)+3)
+	opts = append(opts, WithStdout(&stdout), WithStderr(&stderr))
+	if cmd.Stdin != nil {
+		opts = append(opts, WithStdin(cmd.Stdin))
+	}
+	opts = append(opts, cmd.Options...)
+
+	start := time.Now()
+	err := RunWith(ctx, r, opts, cmd.Command, cmd.Args...)
+
+	res.Duration = time.Since(start)
+	res.Stdout = stdout.Bytes()
+	res.Stderr = stderr.Bytes()
+	res.ExitCode = ExitCode(err)
+	res.Err = err
+	res.Skipped = false
+}
@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("echo", nil, FakeResponse{Stdout: "hi\n", Stderr: "warn\n"})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+	f.Respond("broken", nil, FakeResponse{Err: errors.New("broken")})
+
+	tests := []struct {
+		name        string
+		stopOnError bool
+		cmds        []BatchCommand
+		wantCodes   []int
+		wantSkipped []bool
+		wantErr     string
+	}{
+		{
+			name:    "no commands",
+			cmds:    nil,
+			wantErr: "",
+		},
+		{
+			name: "all succeed",
+			cmds: []BatchCommand{
+				{Command: "echo", Args: []string{"a"}},
+				{Command: "echo", Args: []string{"b"}},
+			},
+			wantCodes:   []int{0, 0},
+			wantSkipped: []bool{false, false},
+		},
+		{
+			name: "some fail",
+			cmds: []BatchCommand{
+				{Command: "echo"},
+				{Command: "false", Args: []string{"x"}},
+				{Command: "broken"},
+			},
+			wantCodes:   []int{0, 1, -1},
+			wantSkipped: []bool{false, false, false},
+			wantErr: "runner: batch: 2 of 3 commands failed: " +
+				"false x: false: exit status 1; broken: broken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Batch{
+				Runner:      f,
+				Concurrency: 2,
+				StopOnError: tt.stopOnError,
+			}
+
+			results, err := b.Run(context.Background(), tt.cmds...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrBatch)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			require.Len(t, results, len(tt.cmds))
+			for i, res := range results {
+				assert.Equal(t, tt.cmds[i].Command, res.Command)
+				assert.Equal(t, tt.cmds[i].Args, res.Args)
+				assert.Equal(t, tt.wantCodes[i], res.ExitCode)
+				assert.Equal(t, tt.wantSkipped[i], res.Skipped)
+			}
+		})
+	}
+}
+
+func TestBatch_Run_output(t *testing.T) {
+	s := &Stub{Fallback: func(
+		ctx context.Context,
+		stdin io.Reader,
+		stdout, stderr io.Writer,
+		command string,
+		args ...string,
+	) error {
+		in, _ := io.ReadAll(stdin)
+		_, _ = io.WriteString(stdout, command+":"+string(in))
+		_, _ = io.WriteString(stderr, strings.Join(callEnv(ctx), ","))
+
+		return nil
+	}}
+
+	b := &Batch{Runner: s}
+	results, err := b.Run(
+		context.Background(),
+		BatchCommand{Command: "cat", Stdin: strings.NewReader("in")},
+		BatchCommand{Command: "env", Options: []RunOption{WithEnv("A=1")}},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "cat:in", string(results[0].Stdout))
+	assert.Equal(t, "", string(results[0].Stderr))
+	assert.Equal(t, "env:", string(results[1].Stdout))
+	assert.Equal(t, "A=1", string(results[1].Stderr))
+	assert.Greater(t, results[0].Duration, time.Duration(0))
+}
+
+func TestBatch_Run_concurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	s := &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+
+		return nil
+	}}
+
+	cmds := make([]BatchCommand, 10)
+	for i := range cmds {
+		cmds[i] = BatchCommand{Command: "sleep"}
+	}
+
+	b := &Batch{Runner: s, Concurrency: 3}
+	_, err := b.Run(context.Background(), cmds...)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(3), maxRunning.Load())
+}
+
+func TestBatch_Run_stopOnError(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	b := &Batch{Runner: f, Concurrency: 1, StopOnError: true}
+	results, err := b.Run(
+		context.Background(),
+		BatchCommand{Command: "true"},
+		BatchCommand{Command: "false"},
+		BatchCommand{Command: "true"},
+		BatchCommand{Command: "true"},
+	)
+
+	var berr *BatchError
+	require.ErrorAs(t, err, &berr)
+	assert.Len(t, berr.Failed, 1)
+	assert.Equal(t, 4, berr.Total)
+
+	var exitErr *FakeExitError
+	assert.ErrorAs(t, err, &exitErr)
+
+	assert.False(t, results[0].Skipped)
+	assert.False(t, results[1].Skipped)
+	assert.True(t, results[2].Skipped)
+	assert.True(t, results[3].Skipped)
+	assert.Len(t, f.Calls(), 2)
+}
+
+func TestBatch_Run_contextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b := &Batch{Runner: &Fake{}}
+	results, err := b.Run(ctx, BatchCommand{Command: "true"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, results[0].Skipped)
+	assert.Equal(t, -1, results[0].ExitCode)
+}
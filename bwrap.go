@@ -0,0 +1,263 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// BwrapNamespace is the name of a Linux namespace which bubblewrap can
+// unshare.
+type BwrapNamespace string
+
+const (
+	BwrapNamespaceUser   BwrapNamespace = "user"
+	BwrapNamespaceIPC    BwrapNamespace = "ipc"
+	BwrapNamespacePID    BwrapNamespace = "pid"
+	BwrapNamespaceNet    BwrapNamespace = "net"
+	BwrapNamespaceUTS    BwrapNamespace = "uts"
+	BwrapNamespaceCgroup BwrapNamespace = "cgroup"
+)
+
+// BwrapBind describes a bind mount of a path on the host into the sandbox.
+type BwrapBind struct {
+	// Source is the path on the host to bind mount.
+	Source string
+
+	// Dest is the path inside the sandbox to mount Source on. When empty,
+	// Source is used.
+	Dest string
+
+	// ReadOnly causes the bind mount to be read-only (--ro-bind).
+	ReadOnly bool
+
+	// Try causes the bind mount to be skipped if Source does not exist
+	// (--bind-try / --ro-bind-try).
+	Try bool
+}
+
+func (b BwrapBind) args() []string {
+	flag := "--bind"
+	if b.ReadOnly {
+		flag = "--ro-bind"
+	}
+	if b.Try {
+		flag += "-try"
+	}
+
+	dest := b.Dest
+	if dest == "" {
+		dest = b.Source
+	}
+
+	return []string{flag, b.Source, dest}
+}
+
+// Bwrap is a Runner that wraps another Runner and runs commands inside a
+// bubblewrap (bwrap) sandbox.
+//
+// Mount related arguments are passed to bwrap in the order of ReadOnlyRoot,
+// Binds, Dev, Proc, and Tmpfs, as later mounts are layered on top of earlier
+// ones.
+//
+// Environment variables and working directories given for a single command
+// with RunWith are applied within the sandbox, via --setenv and --chdir.
+type Bwrap struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with bwrap. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// ReadOnlyRoot bind mounts the host's root filesystem read-only as the
+	// root of the sandbox (--ro-bind / /).
+	ReadOnlyRoot bool
+
+	// Binds is a list of bind mounts to set up within the sandbox.
+	Binds []BwrapBind
+
+	// Dev is the path to mount a new devtmpfs on (--dev). When empty, no
+	// --dev flag will be used.
+	Dev string
+
+	// Proc is the path to mount a new procfs on (--proc). When empty, no
+	// --proc flag will be used.
+	Proc string
+
+	// Tmpfs is a list of paths to mount new tmpfs filesystems on (--tmpfs).
+	Tmpfs []string
+
+	// UnshareAll unshares all namespaces supported by bwrap (--unshare-all).
+	UnshareAll bool
+
+	// Unshare is a list of individual namespaces to unshare.
+	Unshare []BwrapNamespace
+
+	// ShareNet retains the network namespace of the host, even when UnshareAll
+	// is set (--share-net).
+	ShareNet bool
+
+	// DieWithParent kills the sandboxed command when the bwrap process dies
+	// (--die-with-parent).
+	DieWithParent bool
+
+	// Chdir is the working directory within the sandbox (--chdir). When empty,
+	// no --chdir flag will be used.
+	Chdir string
+
+	// Args is a string slice of extra arguments to pass to bwrap.
+	Args []string
+
+	env   []string
+	envMu sync.RWMutex
+}
+
+var _ Runner = &Bwrap{}
+
+var _ Validator = &Bwrap{}
+
+// Validate checks the Bwrap is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner is set, that paths do
+// not contain control characters, that each of Binds has a Source, and that
+// Unshare only lists known namespaces. The underlying Runner is validated too.
+func (r *Bwrap) Validate() error {
+	errs := []error{
+		validateRunner("Bwrap.Runner", r.Runner),
+		validateChars("Bwrap.Dev", r.Dev, false),
+		validateChars("Bwrap.Proc", r.Proc, false),
+		validateChars("Bwrap.Chdir", r.Chdir, false),
+		validateEnv("Bwrap.Env", r.environ()),
+	}
+	for i, b := range r.Binds {
+		f := fmt.Sprintf("Bwrap.Binds[%d]", i)
+		if b.Source == "" {
+			errs = append(errs, &ValidationError{
+				Field: f + ".Source",
+				Msg:   "must be set",
+			})
+		}
+		errs = append(errs,
+			validateChars(f+".Source", b.Source, false),
+			validateChars(f+".Dest", b.Dest, false),
+		)
+	}
+	for i, path := range r.Tmpfs {
+		f := fmt.Sprintf("Bwrap.Tmpfs[%d]", i)
+		errs = append(errs, validateChars(f, path, false))
+	}
+	for i, ns := range r.Unshare {
+		switch ns {
+		case BwrapNamespaceUser, BwrapNamespaceIPC, BwrapNamespacePID,
+			BwrapNamespaceNet, BwrapNamespaceUTS, BwrapNamespaceCgroup:
+		default:
+			errs = append(errs, &ValidationError{
+				Field: fmt.Sprintf("Bwrap.Unshare[%d]", i),
+				Msg:   fmt.Sprintf("unknown namespace %q", ns),
+			})
+		}
+	}
+
+	return firstError(append(errs, Validate(r.Runner))...)
+}
+
+// Run executes the command inside a bwrap sandbox by calling Run on the
+// underlying Runner. Will panic if Runner field is nil.
+func (r *Bwrap) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.Run(
+		stdin, stdout, stderr, "bwrap",
+		r.args(context.Background(), command, args)...,
+	)
+}
+
+// RunContext executes the command inside a bwrap sandbox by calling RunContext
+// on the underlying Runner. Will panic if Runner field is nil.
+func (r *Bwrap) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.RunContext(
+		withoutCallOptions(ctx), stdin, stdout, stderr, "bwrap",
+		r.args(ctx, command, args)...,
+	)
+}
+
+func (r *Bwrap) args(
+	ctx context.Context,
+	command string,
+	args []string,
+) []string {
+	bwrapArgs := []string{}
+
+	if r.ReadOnlyRoot {
+		bwrapArgs = append(bwrapArgs, "--ro-bind", "/", "/")
+	}
+	for _, b := range r.Binds {
+		bwrapArgs = append(bwrapArgs, b.args()...)
+	}
+	if r.Dev != "" {
+		bwrapArgs = append(bwrapArgs, "--dev", r.Dev)
+	}
+	if r.Proc != "" {
+		bwrapArgs = append(bwrapArgs, "--proc", r.Proc)
+	}
+	for _, path := range r.Tmpfs {
+		bwrapArgs = append(bwrapArgs, "--tmpfs", path)
+	}
+
+	if r.UnshareAll {
+		bwrapArgs = append(bwrapArgs, "--unshare-all")
+	}
+	for _, ns := range r.Unshare {
+		bwrapArgs = append(bwrapArgs, "--unshare-"+string(ns))
+	}
+	if r.ShareNet {
+		bwrapArgs = append(bwrapArgs, "--share-net")
+	}
+	if r.DieWithParent {
+		bwrapArgs = append(bwrapArgs, "--die-with-parent")
+	}
+	dir := r.Chdir
+	if d := callDir(ctx); d != "" {
+		dir = d
+	}
+	if dir != "" {
+		bwrapArgs = append(bwrapArgs, "--chdir", dir)
+	}
+
+	for _, v := range DedupEnv(mergeEnv(r.environ(), callEnv(ctx))...) {
+		key, value, _ := strings.Cut(v, "=")
+		bwrapArgs = append(bwrapArgs, "--setenv", key, value)
+	}
+
+	bwrapArgs = append(bwrapArgs, r.Args...)
+	bwrapArgs = append(bwrapArgs, "--", command)
+	bwrapArgs = append(bwrapArgs, args...)
+
+	return bwrapArgs
+}
+
+// Env sets the environment variables which will be set within the sandbox via
+// --setenv flags.
+func (r *Bwrap) Env(env ...string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+func (r *Bwrap) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
+}
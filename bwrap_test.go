@@ -0,0 +1,279 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var bwrapTestCases = []struct {
+	name     string
+	env      []string
+	runner   *Bwrap
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	command  string
+	args     []string
+	err      error
+	wantArgs []string
+	wantErr  string
+}{
+	{
+		name:     "bwrap",
+		runner:   &Bwrap{},
+		stdout:   &bytes.Buffer{},
+		stderr:   &bytes.Buffer{},
+		command:  "make",
+		args:     []string{"build"},
+		wantArgs: []string{"--", "make", "build"},
+	},
+	{
+		name:     "with ReadOnlyRoot",
+		runner:   &Bwrap{ReadOnlyRoot: true},
+		stdin:    bytes.NewBufferString("foo\nbar"),
+		command:  "cat",
+		wantArgs: []string{"--ro-bind", "/", "/", "--", "cat"},
+	},
+	{
+		name: "with Binds",
+		runner: &Bwrap{
+			Binds: []BwrapBind{
+				{Source: "/src"},
+				{Source: "/home/app/out", Dest: "/out"},
+				{Source: "/etc/resolv.conf", ReadOnly: true},
+				{Source: "/opt/cache", Try: true},
+				{Source: "/nix", Dest: "/nix", ReadOnly: true, Try: true},
+			},
+		},
+		command: "make",
+		wantArgs: []string{
+			"--bind", "/src", "/src",
+			"--bind", "/home/app/out", "/out",
+			"--ro-bind", "/etc/resolv.conf", "/etc/resolv.conf",
+			"--bind-try", "/opt/cache", "/opt/cache",
+			"--ro-bind-try", "/nix", "/nix",
+			"--", "make",
+		},
+	},
+	{
+		name: "with Dev, Proc and Tmpfs",
+		runner: &Bwrap{
+			Dev:   "/dev",
+			Proc:  "/proc",
+			Tmpfs: []string{"/tmp", "/run"},
+		},
+		command: "make",
+		wantArgs: []string{
+			"--dev", "/dev", "--proc", "/proc",
+			"--tmpfs", "/tmp", "--tmpfs", "/run",
+			"--", "make",
+		},
+	},
+	{
+		name: "with UnshareAll and ShareNet",
+		runner: &Bwrap{
+			UnshareAll: true,
+			ShareNet:   true,
+		},
+		command:  "make",
+		wantArgs: []string{"--unshare-all", "--share-net", "--", "make"},
+	},
+	{
+		name: "with Unshare",
+		runner: &Bwrap{
+			Unshare: []BwrapNamespace{
+				BwrapNamespacePID, BwrapNamespaceNet, BwrapNamespaceIPC,
+			},
+		},
+		command: "make",
+		wantArgs: []string{
+			"--unshare-pid", "--unshare-net", "--unshare-ipc", "--", "make",
+		},
+	},
+	{
+		name:    "with Env",
+		env:     []string{"FOO=BAZ", "EMPTY=", "EQ=a=b", "FOO=BAR"},
+		runner:  &Bwrap{},
+		command: "make",
+		wantArgs: []string{
+			"--setenv", "EMPTY", "",
+			"--setenv", "EQ", "a=b",
+			"--setenv", "FOO", "BAR",
+			"--", "make",
+		},
+	},
+	{
+		name: "with everything",
+		env:  []string{"FOO=BAR"},
+		runner: &Bwrap{
+			ReadOnlyRoot:  true,
+			Binds:         []BwrapBind{{Source: "/src"}},
+			Dev:           "/dev",
+			Proc:          "/proc",
+			Tmpfs:         []string{"/tmp"},
+			UnshareAll:    true,
+			DieWithParent: true,
+			Chdir:         "/src",
+			Args:          []string{"--hostname", "sandbox"},
+		},
+		command: "make",
+		args:    []string{"-j4"},
+		wantArgs: []string{
+			"--ro-bind", "/", "/",
+			"--bind", "/src", "/src",
+			"--dev", "/dev",
+			"--proc", "/proc",
+			"--tmpfs", "/tmp",
+			"--unshare-all",
+			"--die-with-parent",
+			"--chdir", "/src",
+			"--setenv", "FOO", "BAR",
+			"--hostname", "sandbox",
+			"--", "make", "-j4",
+		},
+	},
+	{
+		name:     "error",
+		runner:   &Bwrap{},
+		command:  "zfs",
+		args:     []string{"list"},
+		err:      errors.New("zfs: command not found"),
+		wantArgs: []string{"--", "zfs", "list"},
+		wantErr:  "zfs: command not found",
+	},
+}
+
+func TestBwrap_Run(t *testing.T) {
+	for _, tt := range bwrapTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().Run(
+				tt.stdin, tt.stdout, tt.stderr, "bwrap", tt.wantArgs,
+			).Return(tt.err)
+
+			b := tt.runner
+			b.Runner = r
+			if len(tt.env) > 0 {
+				b.Env(tt.env...)
+			}
+
+			err := b.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBwrap_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range bwrapTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				tt.stdin, tt.stdout, tt.stderr, "bwrap", tt.wantArgs,
+			).Return(tt.err)
+
+			b := tt.runner
+			b.Runner = r
+			if len(tt.env) > 0 {
+				b.Env(tt.env...)
+			}
+
+			err := b.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBwrap_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+
+	b := &Bwrap{Runner: r}
+	b.Env("FOO=BAR", "PORT=8080")
+
+	assert.Equal(t, []string{"FOO=BAR", "PORT=8080"}, b.env)
+}
+
+func TestBwrap_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Bwrap
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name: "valid",
+			runner: &Bwrap{
+				Runner:  &Local{},
+				Binds:   []BwrapBind{{Source: "/srv/my app", Dest: "/app"}},
+				Unshare: []BwrapNamespace{BwrapNamespaceNet},
+			},
+		},
+		{
+			name:    "no runner",
+			runner:  &Bwrap{},
+			wantErr: "runner: invalid: Bwrap.Runner: must be set",
+		},
+		{
+			name: "bind without source",
+			runner: &Bwrap{
+				Runner: &Local{}, Binds: []BwrapBind{{Dest: "/app"}},
+			},
+			wantErr: "runner: invalid: Bwrap.Binds[0].Source: must be set",
+		},
+		{
+			name:   "chdir with control character",
+			runner: &Bwrap{Runner: &Local{}, Chdir: "/srv\n"},
+			wantErr: `runner: invalid: Bwrap.Chdir: ` +
+				`contains illegal character '\n'`,
+		},
+		{
+			name: "unknown namespace",
+			runner: &Bwrap{
+				Runner: &Local{}, Unshare: []BwrapNamespace{"mnt"},
+			},
+			wantErr: `runner: invalid: Bwrap.Unshare[0]: ` +
+				`unknown namespace "mnt"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// CacheRule sets how long a Cache runner caches the results of commands
+// matching its PolicyRule.
+type CacheRule struct {
+	PolicyRule
+
+	// TTL is how long the result of a matching command is cached for. When
+	// zero, matching commands are not cached.
+	TTL time.Duration
+}
+
+// Cache is a Runner that wraps another Runner, and caches the output and exit
+// status of commands matching its Rules for a period of time. Only read-only
+// commands, whose results are safe to reuse, should be matched by the Rules.
+//
+// Commands are cached by their name, arguments, environment variables, and
+// working directory given with WithDir. Commands which exit with an exit
+// code, including zero, are cached, while commands which fail without one,
+// such as when the context is canceled, are not. Cached stdout and stderr
+// output is written to the writers of later callers in the order it was
+// written by the command, and the same error is returned.
+//
+// Commands with a non-nil stdin, or which do not match any of the Rules, are
+// never cached. Calls to Env are passed to the underlying Runner, and also
+// retained to identify cached commands.
+type Cache struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Rules sets which commands are cached, and for how long. The first
+	// matching rule is used.
+	Rules []CacheRule
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	env     []string
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	command string
+	args    []string
+	expires time.Time
+	chunks  []TranscriptChunk
+	err     error
+}
+
+var _ Runner = &Cache{}
+
+// Run calls Run on the underlying Runner, unless a cached result for the
+// command exists.
+//
+// Will panic if Runner field is nil.
+func (r *Cache) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if stdin != nil {
+		return r.Runner.Run(stdin, stdout, stderr, command, args...)
+	}
+
+	return r.cache(
+		context.Background(), stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.Run(nil, stdout, stderr, command, args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, unless a cached
+// result for the command exists.
+//
+// Will panic if Runner field is nil.
+func (r *Cache) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if stdin != nil {
+		return r.Runner.RunContext(
+			ctx, stdin, stdout, stderr, command, args...,
+		)
+	}
+
+	return r.cache(
+		ctx, stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.RunContext(
+				ctx, nil, stdout, stderr, command, args...,
+			)
+		},
+	)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Cache) Env(env ...string) {
+	r.mu.Lock()
+	r.env = copyStrings(env)
+	r.mu.Unlock()
+
+	r.Runner.Env(env...)
+}
+
+// Invalidate removes cached results of commands matching the given command
+// and leading arguments, which are glob patterns as with PolicyRule. For
+// example, Invalidate("zpool") removes the results of all zpool commands.
+// It should be called after running commands which change the data read by
+// cached commands.
+func (r *Cache) Invalidate(command string, args ...string) {
+	rule := PolicyRule{Command: command, Args: args}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, e := range r.entries {
+		if rule.Match(e.command, e.args) {
+			delete(r.entries, key)
+		}
+	}
+}
+
+// Purge removes all cached results.
+func (r *Cache) Purge() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+func (r *Cache) cache(
+	ctx context.Context,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args []string,
+	run func(stdout, stderr io.Writer) error,
+) error {
+	ttl := r.ttl(command, args)
+	if ttl <= 0 {
+		return run(stdout, stderr)
+	}
+
+	r.mu.Lock()
+	now := r.time()
+	key := commandKey(ctx, r.env, command, args)
+	e, ok := r.entries[key]
+	if ok && now.After(e.expires) {
+		delete(r.entries, key)
+		ok = false
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		t := &Transcript{}
+		err := run(t.Stdout(), t.Stderr())
+		e = &cacheEntry{
+			command: command,
+			args:    copyStrings(args),
+			expires: now.Add(ttl),
+			chunks:  t.Chunks(),
+			err:     err,
+		}
+
+		if err == nil || ExitCode(err) >= 0 {
+			r.mu.Lock()
+			if r.entries == nil {
+				r.entries = map[string]*cacheEntry{}
+			}
+			r.entries[key] = e
+			r.mu.Unlock()
+		}
+	}
+
+	if err := replayChunks(e.chunks, stdout, stderr); err != nil {
+		return err
+	}
+
+	return e.err
+}
+
+func (r *Cache) ttl(command string, args []string) time.Duration {
+	for _, rule := range r.Rules {
+		if rule.Match(command, args) {
+			return rule.TTL
+		}
+	}
+
+	return 0
+}
+
+func (r *Cache) time() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+
+	return time.Now()
+}
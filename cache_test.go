@@ -0,0 +1,232 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCache_RunContext(t *testing.T) {
+	type run struct {
+		command string
+		args    []string
+		opts    []RunOption
+		advance time.Duration
+	}
+	tests := []struct {
+		name       string
+		rules      []CacheRule
+		runs       []run
+		wantCalls  int
+		wantStdout string
+		wantErr    string
+	}{
+		{
+			name:  "cached within TTL",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+			runs: []run{
+				{command: "lsblk", args: []string{"-J"}},
+				{
+					command: "lsblk", args: []string{"-J"},
+					advance: 30 * time.Second,
+				},
+			},
+			wantCalls:  1,
+			wantStdout: "out\nout\n",
+		},
+		{
+			name:  "expired after TTL",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+			runs: []run{
+				{command: "lsblk", args: []string{"-J"}},
+				{
+					command: "lsblk", args: []string{"-J"},
+					advance: 2 * time.Minute,
+				},
+			},
+			wantCalls:  2,
+			wantStdout: "out\nout\n",
+		},
+		{
+			name:  "not matching rules",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+			runs: []run{
+				{command: "zpool", args: []string{"status"}},
+				{command: "zpool", args: []string{"status"}},
+			},
+			wantCalls:  2,
+			wantStdout: "out\nout\n",
+		},
+		{
+			name:  "zero TTL",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, 0}},
+			runs: []run{
+				{command: "lsblk"},
+				{command: "lsblk"},
+			},
+			wantCalls:  2,
+			wantStdout: "out\nout\n",
+		},
+		{
+			name:  "different args",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+			runs: []run{
+				{command: "lsblk", args: []string{"-J"}},
+				{command: "lsblk", args: []string{"-b"}},
+			},
+			wantCalls:  2,
+			wantStdout: "out\nout\n",
+		},
+		{
+			name:  "different call env and dir",
+			rules: []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+			runs: []run{
+				{command: "lsblk"},
+				{command: "lsblk", opts: []RunOption{WithEnv("A=1")}},
+				{command: "lsblk", opts: []RunOption{WithDir("/tmp")}},
+			},
+			wantCalls:  3,
+			wantStdout: "out\nout\nout\n",
+		},
+		{
+			name: "exit status cached",
+			rules: []CacheRule{
+				{PolicyRule{Command: "zpool"}, time.Minute},
+			},
+			runs: []run{
+				{command: "zpool", args: []string{"fail"}},
+				{command: "zpool", args: []string{"fail"}},
+			},
+			wantCalls:  1,
+			wantStdout: "bad\nbad\n",
+			wantErr:    "zpool: exit status 1",
+		},
+		{
+			name: "errors without exit code not cached",
+			rules: []CacheRule{
+				{PolicyRule{Command: "zpool"}, time.Minute},
+			},
+			runs: []run{
+				{command: "zpool", args: []string{"broken"}},
+				{command: "zpool", args: []string{"broken"}},
+			},
+			wantCalls: 2,
+			wantErr:   "broken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("*", nil, FakeResponse{Stdout: "out\n"})
+			f.Respond("zpool", []string{"fail"}, FakeResponse{
+				Stdout: "bad\n", ExitCode: 1,
+			})
+			f.Respond("zpool", []string{"broken"}, FakeResponse{
+				Err: errors.New("broken"),
+			})
+
+			now := time.Now()
+			r := &Cache{
+				Runner: f,
+				Rules:  tt.rules,
+				now:    func() time.Time { return now },
+			}
+
+			var stdout bytes.Buffer
+			var err error
+			for _, run := range tt.runs {
+				now = now.Add(run.advance)
+				opts := append([]RunOption{WithStdout(&stdout)}, run.opts...)
+				err = RunWith(
+					context.Background(), r, opts, run.command, run.args...,
+				)
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Len(t, f.Calls(), tt.wantCalls)
+		})
+	}
+}
+
+func TestCache_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("lsblk", nil, FakeResponse{Stdout: "out\n", Stderr: "err\n"})
+
+	r := &Cache{
+		Runner: f,
+		Rules:  []CacheRule{{PolicyRule{Command: "lsblk"}, time.Minute}},
+	}
+
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		require.NoError(t, r.Run(nil, &buf, &buf, "lsblk", "-J"))
+		assert.Equal(t, "out\nerr\n", buf.String())
+	}
+	assert.Len(t, f.Calls(), 1)
+
+	// Commands with stdin are never cached.
+	for i := 0; i < 2; i++ {
+		err := r.Run(strings.NewReader("x"), nil, nil, "lsblk", "-J")
+		require.NoError(t, err)
+	}
+	assert.Len(t, f.Calls(), 3)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{Stdout: "out\n"})
+
+	r := &Cache{
+		Runner: f,
+		Rules:  []CacheRule{{PolicyRule{Command: "*"}, time.Minute}},
+	}
+	ctx := context.Background()
+	runAll := func() {
+		for _, args := range [][]string{
+			{"zpool", "status"},
+			{"zpool", "list"},
+			{"lsblk", "-J"},
+		} {
+			require.NoError(t, RunWith(ctx, r, nil, args[0], args[1:]...))
+		}
+	}
+
+	runAll()
+	assert.Len(t, f.Calls(), 3)
+
+	r.Invalidate("zpool", "status")
+	runAll()
+	assert.Len(t, f.Calls(), 4)
+
+	r.Invalidate("zpool")
+	runAll()
+	assert.Len(t, f.Calls(), 6)
+
+	r.Purge()
+	runAll()
+	assert.Len(t, f.Calls(), 9)
+}
+
+func TestCache_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Cache{Runner: m}
+	r.Env("FOO=bar")
+
+	assert.Equal(t, []string{"FOO=bar"}, r.env)
+}
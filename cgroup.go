@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	ErrCgroup            = fmt.Errorf("%w: cgroup", Err)
+	ErrCgroupUnsupported = fmt.Errorf(
+		"%w: not supported on this platform", ErrCgroup,
+	)
+)
+
+// Cgroup describes a cgroup v2 which commands executed by Local are placed in,
+// enforcing resource limits on the command and all of its child processes.
+//
+// A new cgroup is created as a child of Parent for each executed command, and
+// it is removed once the command exits, killing any processes which remain in
+// it.
+//
+// The memory, cpu, and pids controllers must be enabled in the
+// cgroup.subtree_control file of Parent for their respective limits to be
+// set. Cgroups are only supported on Linux.
+type Cgroup struct {
+	// Parent is the path to an existing cgroup v2 directory which new cgroups
+	// are created within, e.g. "/sys/fs/cgroup/myapp".
+	Parent string
+
+	// MemoryMax is the memory usage hard limit in bytes, written to
+	// memory.max. When 0, no limit is set.
+	MemoryMax int64
+
+	// CPUMax is the maximum CPU bandwidth limit written verbatim to cpu.max,
+	// in the form "$MAX $PERIOD", e.g. "50000 100000" to limit commands to
+	// half a CPU. When empty, no limit is set.
+	CPUMax string
+
+	// PidsMax is the maximum number of processes, written to pids.max. When 0,
+	// no limit is set.
+	PidsMax int64
+}
+
+func (c *Cgroup) writeLimits(dir string) error {
+	limits := [][2]string{}
+	if c.MemoryMax != 0 {
+		limits = append(limits, [2]string{
+			"memory.max", strconv.FormatInt(c.MemoryMax, 10),
+		})
+	}
+	if c.CPUMax != "" {
+		limits = append(limits, [2]string{"cpu.max", c.CPUMax})
+	}
+	if c.PidsMax != 0 {
+		limits = append(limits, [2]string{
+			"pids.max", strconv.FormatInt(c.PidsMax, 10),
+		})
+	}
+
+	for _, l := range limits {
+		err := writeCgroupFile(filepath.Join(dir, l[0]), l[1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeCgroupFile(path string, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCgroup, err)
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(value)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCgroup, err)
+	}
+
+	return nil
+}
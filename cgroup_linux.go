@@ -0,0 +1,72 @@
+//go:build linux
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// apply creates a new cgroup within Parent and configures cmd to be started
+// within it. The returned function must be called once cmd has exited to kill
+// any remaining processes and remove the cgroup.
+func (c *Cgroup) apply(cmd *exec.Cmd) (func() error, error) {
+	dir, err := os.MkdirTemp(c.Parent, "runner-")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCgroup, err)
+	}
+
+	err = c.writeLimits(dir)
+	if err != nil {
+		_ = os.Remove(dir)
+
+		return nil, err
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		_ = os.Remove(dir)
+
+		return nil, fmt.Errorf("%w: %w", ErrCgroup, err)
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+
+	return func() error {
+		f.Close()
+
+		return removeCgroup(dir)
+	}, nil
+}
+
+// removeCgroup kills all processes within the cgroup at dir, and removes it
+// once it is empty.
+func removeCgroup(dir string) error {
+	// The cgroup.kill file is only available on Linux 5.14 and later. Any
+	// failure to write it is ignored, as the command itself has already
+	// exited, and removal will fail below if processes remain.
+	_ = writeCgroupFile(filepath.Join(dir, "cgroup.kill"), "1")
+
+	var err error
+	for i := 0; i < 100; i++ {
+		err = os.Remove(dir)
+		if err == nil || !errors.Is(err, syscall.EBUSY) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCgroup, err)
+	}
+
+	return nil
+}
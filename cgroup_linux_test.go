@@ -0,0 +1,53 @@
+//go:build linux
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_cgroup(t *testing.T) {
+	// Creating cgroups requires a delegated cgroup v2 hierarchy, so this test
+	// only runs when one has been provided.
+	parent := os.Getenv("RUNNER_TEST_CGROUP_PARENT")
+	if parent == "" {
+		t.Skip("RUNNER_TEST_CGROUP_PARENT not set")
+	}
+
+	var stdout bytes.Buffer
+	r := &Local{Cgroup: &Cgroup{Parent: parent}}
+
+	err := r.Run(nil, &stdout, nil, "cat", "/proc/self/cgroup")
+	require.NoError(t, err)
+
+	var cgroupPath string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			cgroupPath = strings.TrimPrefix(line, "0::")
+		}
+	}
+	assert.Contains(t, filepath.Base(cgroupPath), "runner-")
+
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), "runner-", "cgroup not removed")
+	}
+}
+
+func TestLocal_Run_cgroupMissingParent(t *testing.T) {
+	r := &Local{
+		Cgroup: &Cgroup{Parent: filepath.Join(t.TempDir(), "nope")},
+	}
+
+	err := r.Run(nil, nil, nil, "true")
+
+	assert.ErrorIs(t, err, ErrCgroup)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package runner
+
+import "os/exec"
+
+func (c *Cgroup) apply(*exec.Cmd) (func() error, error) {
+	return nil, ErrCgroupUnsupported
+}
@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroup_writeLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		cgroup    Cgroup
+		wantFiles map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "no limits",
+			cgroup:    Cgroup{},
+			wantFiles: map[string]string{},
+		},
+		{
+			name:   "MemoryMax",
+			cgroup: Cgroup{MemoryMax: 512 * 1024 * 1024},
+			wantFiles: map[string]string{
+				"memory.max": "536870912",
+			},
+		},
+		{
+			name:   "CPUMax",
+			cgroup: Cgroup{CPUMax: "50000 100000"},
+			wantFiles: map[string]string{
+				"cpu.max": "50000 100000",
+			},
+		},
+		{
+			name:   "PidsMax",
+			cgroup: Cgroup{PidsMax: 64},
+			wantFiles: map[string]string{
+				"pids.max": "64",
+			},
+		},
+		{
+			name: "all limits",
+			cgroup: Cgroup{
+				MemoryMax: 1024,
+				CPUMax:    "max 100000",
+				PidsMax:   10,
+			},
+			wantFiles: map[string]string{
+				"memory.max": "1024",
+				"cpu.max":    "max 100000",
+				"pids.max":   "10",
+			},
+		},
+		{
+			name:    "missing controller",
+			cgroup:  Cgroup{MemoryMax: 1024},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Cgroup interface files always exist, so mimic that for all
+			// expected files.
+			for file := range tt.wantFiles {
+				err := os.WriteFile(filepath.Join(dir, file), nil, 0o600)
+				require.NoError(t, err)
+			}
+
+			err := tt.cgroup.writeLimits(dir)
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrCgroup)
+
+				return
+			}
+			require.NoError(t, err)
+			for file, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dir, file))
+				require.NoError(t, err)
+				assert.Equal(t, want, string(got))
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package runner
+
+// Middleware wraps a Runner, returning a Runner which typically modifies
+// commands before running them with the wrapped Runner.
+type Middleware func(r Runner) Runner
+
+// Chain composes a stack of runners by wrapping base with each of the given
+// middlewares. The first middleware is the outermost, and hence the first to
+// receive commands, so Chain(base, a, b) is equivalent to a(b(base)).
+//
+// Nil middlewares are skipped, allowing layers to be included conditionally.
+// Wrap composes runners in the same way, but returns a Stack which also
+// provides access to each layer.
+func Chain(base Runner, middlewares ...Middleware) Runner {
+	r := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			r = middlewares[i](r)
+		}
+	}
+
+	return r
+}
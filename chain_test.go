@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	base := mock_runner.NewMockRunner(ctrl)
+	base.EXPECT().Run(
+		nil, nil, nil, "ssh",
+		[]string{"narnia.local", "--", "sudo", "-n", "--", "podman", "ps"},
+	).Return(nil)
+
+	r := Chain(
+		base,
+		func(r Runner) Runner {
+			return &Rewrite{
+				Runner:   r,
+				Commands: map[string]string{"docker": "podman"},
+			}
+		},
+		nil,
+		func(r Runner) Runner {
+			return &Sudo{Runner: r}
+		},
+		func(r Runner) Runner {
+			return &SSHCLI{Runner: r, Destination: "narnia.local"}
+		},
+	)
+
+	rw, ok := r.(*Rewrite)
+	require.True(t, ok)
+	s, ok := rw.Runner.(*Sudo)
+	require.True(t, ok)
+	ssh, ok := s.Runner.(*SSHCLI)
+	require.True(t, ok)
+	assert.Equal(t, base, ssh.Runner)
+
+	err := r.Run(nil, nil, nil, "docker", "ps")
+	assert.NoError(t, err)
+}
+
+func TestChain_noMiddlewares(t *testing.T) {
+	base := &Local{}
+
+	assert.Same(t, base, Chain(base))
+}
@@ -0,0 +1,226 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	ErrChaos          = fmt.Errorf("%w: chaos", Err)
+	ErrChaosTransport = fmt.Errorf("%w: injected transport error", ErrChaos)
+)
+
+// DefaultChaosTruncateMax is the default value for Chaos.TruncateMax.
+const DefaultChaosTruncateMax = 1024
+
+// ChaosExitError is returned by Chaos when it injects a failure in place of
+// running a command.
+type ChaosExitError struct {
+	// Command is the command which was not run.
+	Command string
+
+	// Code is the injected exit code.
+	Code int
+}
+
+var _ error = &ChaosExitError{}
+
+func (e *ChaosExitError) Error() string {
+	return fmt.Sprintf(
+		"%s: %s: injected exit status %d", ErrChaos, e.Command, e.Code,
+	)
+}
+
+// Unwrap returns ErrChaos.
+func (e *ChaosExitError) Unwrap() error {
+	return ErrChaos
+}
+
+// ExitCode returns the injected exit code.
+func (e *ChaosExitError) ExitCode() int {
+	return e.Code
+}
+
+// Chaos is a Runner that wraps another Runner, and randomly injects failures,
+// output truncation, and delays, for testing how code copes with unreliable
+// commands. Each rate is a probability between 0 and 1, and is evaluated
+// independently for each command.
+//
+// For each command, Chaos first injects any delay, then either returns a
+// transport error, returns a failed exit status, or runs the command with the
+// underlying Runner, possibly truncating its stdout output. Commands which are
+// failed by Chaos are not run.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Chaos struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Seed seeds the random number generator, making the injected chaos
+	// reproducible for a given sequence of commands. When zero, a random seed
+	// is used.
+	Seed int64
+
+	// TransportErrorRate is the probability of returning an error wrapping
+	// ErrChaosTransport, simulating failure to reach a remote host.
+	TransportErrorRate float64
+
+	// FailureRate is the probability of returning a *ChaosExitError.
+	FailureRate float64
+
+	// ExitCode is the exit code of injected failures. Defaults to 1.
+	ExitCode int
+
+	// TruncateRate is the probability of truncating stdout output. The
+	// command still runs to completion, but only up to a random number of
+	// bytes below TruncateMax are written to stdout.
+	TruncateRate float64
+
+	// TruncateMax is the exclusive upper bound on the number of bytes written
+	// to stdout when truncating. Defaults to DefaultChaosTruncateMax.
+	TruncateMax int
+
+	// DelayRate is the probability of delaying a command before running it.
+	DelayRate float64
+
+	// MaxDelay is the exclusive upper bound of injected delays.
+	MaxDelay time.Duration
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+var _ Runner = &Chaos{}
+
+// Run calls Run on the underlying Runner, unless a failure is injected.
+//
+// Will panic if Runner field is nil.
+func (r *Chaos) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, unless a failure is
+// injected. If ctx is done during an injected delay, its error is returned.
+//
+// Will panic if Runner field is nil.
+func (r *Chaos) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	p := r.plan()
+
+	err := sleepContext(ctx, p.delay)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.transportErr:
+		return fmt.Errorf(
+			"%w: %s", ErrChaosTransport, formatCmdline(command, args),
+		)
+	case p.fail:
+		code := r.ExitCode
+		if code == 0 {
+			code = 1
+		}
+
+		return &ChaosExitError{Command: command, Code: code}
+	}
+
+	if p.truncate >= 0 && stdout != nil {
+		stdout = &truncateWriter{w: stdout, n: p.truncate}
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// chaosPlan is the chaos to inject for a single command.
+type chaosPlan struct {
+	delay        time.Duration
+	transportErr bool
+	fail         bool
+	truncate     int
+}
+
+func (r *Chaos) plan() chaosPlan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rnd == nil {
+		seed := r.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r.rnd = rand.New(rand.NewSource(seed)) //nolint:gosec
+	}
+
+	// Always draw the same number of values, so the outcome for each command
+	// only depends on the seed and its position in the sequence.
+	p := chaosPlan{truncate: -1}
+	delay, delayLen := r.rnd.Float64(), r.rnd.Int63()
+	transportErr, fail := r.rnd.Float64(), r.rnd.Float64()
+	truncate, truncateLen := r.rnd.Float64(), r.rnd.Int()
+
+	if delay < r.DelayRate && r.MaxDelay > 0 {
+		p.delay = time.Duration(delayLen % int64(r.MaxDelay))
+	}
+	p.transportErr = transportErr < r.TransportErrorRate
+	p.fail = fail < r.FailureRate
+	if truncate < r.TruncateRate {
+		limit := r.TruncateMax
+		if limit <= 0 {
+			limit = DefaultChaosTruncateMax
+		}
+		p.truncate = truncateLen % limit
+	}
+
+	return p
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Chaos) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+// truncateWriter is an io.Writer which writes at most n bytes to w, silently
+// discarding the rest.
+type truncateWriter struct {
+	w io.Writer
+	n int
+}
+
+func (t *truncateWriter) Write(p []byte) (int, error) {
+	if t.n <= 0 {
+		return len(p), nil
+	}
+
+	b := p
+	if len(b) > t.n {
+		b = b[:t.n]
+	}
+	n, err := t.w.Write(b)
+	t.n -= n
+	if err != nil {
+		return n, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChaos_noChaos(t *testing.T) {
+	f := &Fake{}
+	f.Respond("echo", nil, FakeResponse{Stdout: "hello\n"})
+
+	r := &Chaos{Runner: f, Seed: 1}
+	for i := 0; i < 20; i++ {
+		var stdout bytes.Buffer
+		err := r.Run(nil, &stdout, nil, "echo", "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "hello\n", stdout.String())
+	}
+	assert.Len(t, f.Calls(), 20)
+}
+
+func TestChaos_transportError(t *testing.T) {
+	f := &Fake{}
+	r := &Chaos{Runner: f, TransportErrorRate: 1}
+
+	err := r.Run(nil, nil, nil, "zfs", "list", "my pool")
+	assert.EqualError(
+		t, err,
+		"runner: chaos: injected transport error: zfs list 'my pool'",
+	)
+	assert.ErrorIs(t, err, ErrChaosTransport)
+	assert.Empty(t, f.Calls())
+}
+
+func TestChaos_failure(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		wantCode int
+	}{
+		{name: "default exit code", wantCode: 1},
+		{name: "custom exit code", exitCode: 255, wantCode: 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			r := &Chaos{Runner: f, FailureRate: 1, ExitCode: tt.exitCode}
+
+			err := r.Run(nil, nil, nil, "zfs", "list")
+
+			var exitErr *ChaosExitError
+			require.ErrorAs(t, err, &exitErr)
+			assert.ErrorIs(t, err, ErrChaos)
+			assert.Equal(t, tt.wantCode, ExitCode(err))
+			assert.Equal(t, "zfs", exitErr.Command)
+			assert.Empty(t, f.Calls())
+		})
+	}
+}
+
+func TestChaos_truncate(t *testing.T) {
+	f := &Fake{}
+	f.Respond("cat", nil, FakeResponse{Stdout: strings.Repeat("x", 100)})
+
+	r := &Chaos{Runner: f, Seed: 42, TruncateRate: 1, TruncateMax: 50}
+	for i := 0; i < 20; i++ {
+		var stdout bytes.Buffer
+		err := r.Run(nil, &stdout, nil, "cat")
+		require.NoError(t, err)
+		assert.Less(t, stdout.Len(), 50)
+	}
+}
+
+func TestChaos_delay(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+
+	r := &Chaos{
+		Runner:    f,
+		DelayRate: 1,
+		MaxDelay:  50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.Run(nil, nil, nil, "true"))
+	}
+	assert.Less(t, time.Since(start), 5*50*time.Millisecond+time.Second)
+
+	r = &Chaos{Runner: f, DelayRate: 1, MaxDelay: time.Hour, Seed: 3}
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Millisecond,
+	)
+	defer cancel()
+
+	err := r.RunContext(ctx, nil, nil, nil, "true")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, f.Calls(), 5)
+}
+
+func TestChaos_seed(t *testing.T) {
+	outcomes := func(seed int64) []string {
+		f := &Fake{}
+		f.Respond("true", nil, FakeResponse{})
+		r := &Chaos{
+			Runner:             f,
+			Seed:               seed,
+			TransportErrorRate: 0.3,
+			FailureRate:        0.3,
+		}
+
+		var got []string
+		for i := 0; i < 50; i++ {
+			err := r.Run(nil, nil, nil, "true")
+			switch {
+			case errors.Is(err, ErrChaosTransport):
+				got = append(got, "transport")
+			case err != nil:
+				got = append(got, "fail")
+			default:
+				got = append(got, "ok")
+			}
+		}
+
+		return got
+	}
+
+	a := outcomes(7)
+	assert.Equal(t, a, outcomes(7))
+	assert.NotEqual(t, a, outcomes(8))
+	assert.Contains(t, a, "ok")
+	assert.Contains(t, a, "fail")
+	assert.Contains(t, a, "transport")
+}
+
+func TestChaos_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	c := &Chaos{Runner: r}
+	c.Env("FOO=BAR", "PORT=8080")
+}
+
+func Test_truncateWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &truncateWriter{w: &buf, n: 5}
+
+	n, err := w.Write([]byte("abc"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	n, err = w.Write([]byte("defgh"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	n, err = w.Write([]byte("ijk"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	assert.Equal(t, "abcde", buf.String())
+}
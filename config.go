@@ -0,0 +1,358 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrConfig = fmt.Errorf("%w: config", Err)
+
+// ConfigError is returned when a Config is invalid. It matches ErrConfig with
+// errors.Is.
+type ConfigError struct {
+	// Field is the path of the offending field, like "stack[1].user".
+	Field string
+
+	// Msg describes the problem with the field.
+	Msg string
+}
+
+var _ error = &ConfigError{}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrConfig.Error(), e.Field, e.Msg)
+}
+
+// Unwrap returns ErrConfig.
+func (e *ConfigError) Unwrap() error {
+	return ErrConfig
+}
+
+// ConfigLayer describes one runner in the stack of a Config. Type selects the
+// runner, and only the fields which apply to it may be set:
+//
+//   - "local": a Local runner, via New. It may only be the first layer.
+//   - "sudo": a Sudo runner, using User, Dir, and Args.
+//   - "ssh": a SSHCLI runner, using Destination, Port, Login, IdentityFile,
+//     Dir, and Args. Destination is required.
+//   - "docker": a Docker runner, using Container, User, Dir, and Args.
+//     Container is required.
+//   - "jexec": a Jexec runner, using Jail, User, JailUser, Clean, and Args.
+//     Jail is required.
+//
+// Env applies to all types, and is passed to the runner's Env method.
+type ConfigLayer struct {
+	// Type is the type of runner, one of "local", "sudo", "ssh", "docker",
+	// or "jexec".
+	Type string `json:"type" yaml:"type"`
+
+	// Destination is the SSH destination, as with SSHCLI.Destination.
+	Destination string `json:"destination" yaml:"destination"`
+
+	// Port is the SSH port, as with SSHCLI.Port.
+	Port int `json:"port" yaml:"port"`
+
+	// Login is the SSH login, as with SSHCLI.Login.
+	Login string `json:"login" yaml:"login"`
+
+	// IdentityFile is the SSH identity file, as with SSHCLI.IdentityFile.
+	IdentityFile string `json:"identity_file" yaml:"identity_file"`
+
+	// Container is the Docker container, as with Docker.Container.
+	Container string `json:"container" yaml:"container"`
+
+	// Jail is the jail, as with Jexec.Jail.
+	Jail string `json:"jail" yaml:"jail"`
+
+	// JailUser is the user inside the jail, as with Jexec.JailUser.
+	JailUser string `json:"jail_user" yaml:"jail_user"`
+
+	// Clean runs commands in a clean environment, as with Jexec.Clean.
+	Clean bool `json:"clean" yaml:"clean"`
+
+	// User is the user to run commands as, for sudo, docker, and jexec.
+	User string `json:"user" yaml:"user"`
+
+	// Dir is the working directory, for sudo, ssh, and docker.
+	Dir string `json:"dir" yaml:"dir"`
+
+	// Args are extra arguments for the wrapping command.
+	Args []string `json:"args" yaml:"args"`
+
+	// Env are environment variables passed to the runner's Env method.
+	Env []string `json:"env" yaml:"env"`
+}
+
+// configLayerFields are the fields which may be set for each layer type,
+// besides type and env.
+var configLayerFields = map[string][]string{
+	"local":  {},
+	"sudo":   {"user", "dir", "args"},
+	"ssh":    {"destination", "port", "login", "identity_file", "dir", "args"},
+	"docker": {"container", "user", "dir", "args"},
+	"jexec":  {"jail", "user", "jail_user", "clean", "args"},
+}
+
+// Config describes a stack of runners, each wrapping the one before it. For
+// example, this YAML runs commands on a remote host via ssh, as the local
+// deploy user, and then as root on the remote host via sudo:
+//
+//	stack:
+//	  - type: local
+//	  - type: sudo
+//	    user: deploy
+//	  - type: ssh
+//	    destination: web1.example.com
+//	    identity_file: /home/deploy/.ssh/id_ed25519
+//	  - type: sudo
+//
+// When the first layer is not "local", a Local runner is used beneath it. An
+// empty stack builds a Local runner.
+//
+// Config has both JSON and YAML field tags, so it may be embedded in the
+// configuration files of services.
+type Config struct {
+	Stack []ConfigLayer `json:"stack" yaml:"stack"`
+}
+
+// LoadConfig reads the YAML or JSON config file at path, and builds the
+// Runner it describes.
+func LoadConfig(path string) (Runner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Build()
+}
+
+// ParseConfig parses a YAML or JSON config, as JSON is valid YAML. Unknown
+// fields are rejected. The config is not validated until it is built.
+func ParseConfig(data []byte) (*Config, error) {
+	c := &Config{}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	return c, nil
+}
+
+// Validate checks the config is valid, returning a *ConfigError for the first
+// offending field.
+func (c *Config) Validate() error {
+	for i := range c.Stack {
+		err := c.Stack[i].validate(fmt.Sprintf("stack[%d]", i), i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Build checks the config with Validate, and returns the Runner it
+// describes. Each layer is also validated as it is built, and a *ConfigError
+// is returned for the first invalid field of any of them.
+func (c *Config) Build() (Runner, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := New()
+	for i := range c.Stack {
+		r = c.Stack[i].build(r)
+
+		if err := Validate(r); err != nil {
+			return nil, layerError(fmt.Sprintf("stack[%d]", i), err)
+		}
+	}
+
+	return r, nil
+}
+
+// layerError returns a *ConfigError for the error returned by validating the
+// runner built for the layer at path. The field of a *ValidationError, like
+// "SSHCLI.IdentityFile", is mapped to the layer's, like "identity_file".
+func layerError(path string, err error) error {
+	var vErr *ValidationError
+	if !errors.As(err, &vErr) {
+		return &ConfigError{Field: path, Msg: err.Error()}
+	}
+
+	field := vErr.Field
+	if _, after, ok := strings.Cut(field, "."); ok {
+		field = after
+	}
+
+	var b strings.Builder
+	for i, c := range field {
+		if unicode.IsUpper(c) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+
+	return &ConfigError{Field: path + "." + b.String(), Msg: vErr.Msg}
+}
+
+func (l *ConfigLayer) validate(path string, index int) error {
+	allowed, ok := configLayerFields[l.Type]
+	switch {
+	case l.Type == "":
+		return &ConfigError{Field: path + ".type", Msg: "required"}
+	case !ok:
+		return &ConfigError{
+			Field: path + ".type",
+			Msg:   fmt.Sprintf("unknown type %q", l.Type),
+		}
+	case l.Type == "local" && index > 0:
+		return &ConfigError{
+			Field: path + ".type",
+			Msg:   "local may only be the first layer",
+		}
+	}
+
+	for _, field := range l.setFields() {
+		if !containsString(allowed, field) {
+			return &ConfigError{
+				Field: path + "." + field,
+				Msg:   fmt.Sprintf("not supported by type %q", l.Type),
+			}
+		}
+	}
+
+	var required string
+	switch {
+	case l.Type == "ssh" && l.Destination == "":
+		required = "destination"
+	case l.Type == "docker" && l.Container == "":
+		required = "container"
+	case l.Type == "jexec" && l.Jail == "":
+		required = "jail"
+	}
+	if required != "" {
+		return &ConfigError{Field: path + "." + required, Msg: "required"}
+	}
+
+	if l.Port < 0 || l.Port > 65535 {
+		return &ConfigError{
+			Field: path + ".port",
+			Msg:   fmt.Sprintf("invalid port %d", l.Port),
+		}
+	}
+
+	for j, kv := range l.Env {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return &ConfigError{
+				Field: fmt.Sprintf("%s.env[%d]", path, j),
+				Msg:   fmt.Sprintf("%q is not of the form key=value", kv),
+			}
+		}
+	}
+
+	return nil
+}
+
+// setFields returns the names of the type-specific fields which are set.
+func (l *ConfigLayer) setFields() []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"destination", l.Destination != ""},
+		{"port", l.Port != 0},
+		{"login", l.Login != ""},
+		{"identity_file", l.IdentityFile != ""},
+		{"container", l.Container != ""},
+		{"jail", l.Jail != ""},
+		{"jail_user", l.JailUser != ""},
+		{"clean", l.Clean},
+		{"user", l.User != ""},
+		{"dir", l.Dir != ""},
+		{"args", len(l.Args) > 0},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+
+	return fields
+}
+
+func (l *ConfigLayer) build(underlying Runner) Runner {
+	var r Runner
+	switch l.Type {
+	case "local":
+		r = underlying
+	case "sudo":
+		r = &Sudo{
+			Runner: underlying,
+			User:   l.User,
+			Dir:    l.Dir,
+			Args:   copyStrings(l.Args),
+		}
+	case "ssh":
+		r = &SSHCLI{
+			Runner:       underlying,
+			Destination:  l.Destination,
+			Port:         l.Port,
+			Login:        l.Login,
+			IdentityFile: l.IdentityFile,
+			Dir:          l.Dir,
+			Args:         copyStrings(l.Args),
+		}
+	case "docker":
+		r = &Docker{
+			Runner:    underlying,
+			Container: l.Container,
+			User:      l.User,
+			Dir:       l.Dir,
+			Args:      copyStrings(l.Args),
+		}
+	case "jexec":
+		r = &Jexec{
+			Runner:   underlying,
+			Jail:     l.Jail,
+			User:     l.User,
+			JailUser: l.JailUser,
+			Clean:    l.Clean,
+			Args:     copyStrings(l.Args),
+		}
+	}
+
+	if len(l.Env) > 0 {
+		r.Env(l.Env...)
+	}
+
+	return r
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,223 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    Runner
+		wantEnv []string
+		wantErr string
+	}{
+		{
+			name:   "empty",
+			config: "",
+			want:   &Local{},
+		},
+		{
+			name:   "local",
+			config: "stack:\n  - type: local\n",
+			want:   &Local{},
+		},
+		{
+			name: "yaml stack",
+			config: `
+stack:
+  - type: local
+  - type: sudo
+    user: deploy
+  - type: ssh
+    destination: web1.example.com
+    port: 2222
+    login: deploy
+    identity_file: /keys/deploy
+    dir: /srv/app
+    args: ["-o", "BatchMode=yes"]
+  - type: sudo
+`,
+			want: &Sudo{
+				Runner: &SSHCLI{
+					Runner: &Sudo{
+						Runner: &Local{},
+						User:   "deploy",
+					},
+					Destination:  "web1.example.com",
+					Port:         2222,
+					Login:        "deploy",
+					IdentityFile: "/keys/deploy",
+					Dir:          "/srv/app",
+					Args:         []string{"-o", "BatchMode=yes"},
+				},
+			},
+		},
+		{
+			name: "json stack",
+			config: `{"stack": [
+				{"type": "docker", "container": "app", "user": "www"},
+				{"type": "sudo", "args": ["-E"]}
+			]}`,
+			want: &Sudo{
+				Runner: &Docker{
+					Runner:    &Local{},
+					Container: "app",
+					User:      "www",
+				},
+				Args: []string{"-E"},
+			},
+		},
+		{
+			name: "jexec",
+			config: "stack:\n" +
+				"  - {type: jexec, jail: www, jail_user: web, clean: true}\n",
+			want: &Jexec{
+				Runner:   &Local{},
+				Jail:     "www",
+				JailUser: "web",
+				Clean:    true,
+			},
+		},
+		{
+			name:    "env",
+			config:  "stack:\n  - {type: ssh, destination: web1, env: [A=1]}\n",
+			wantEnv: []string{"A=1"},
+		},
+		{
+			name:   "unknown field",
+			config: "stack:\n  - type: ssh\n    host: web1\n",
+			wantErr: "runner: config: yaml: unmarshal errors:\n" +
+				"  line 3: field host not found in type runner.ConfigLayer",
+		},
+		{
+			name:   "invalid syntax",
+			config: "stack: [",
+			wantErr: "runner: config: yaml: line 1: did not find " +
+				"expected node content",
+		},
+		{
+			name:    "missing type",
+			config:  "stack:\n  - user: deploy\n",
+			wantErr: "runner: config: stack[0].type: required",
+		},
+		{
+			name:    "unknown type",
+			config:  "stack:\n  - type: telnet\n",
+			wantErr: `runner: config: stack[0].type: unknown type "telnet"`,
+		},
+		{
+			name:   "local not first",
+			config: "stack:\n  - type: sudo\n  - type: local\n",
+			wantErr: "runner: config: stack[1].type: " +
+				"local may only be the first layer",
+		},
+		{
+			name: "field not supported by type",
+			config: "stack:\n  - type: sudo\n" +
+				"  - {type: sudo, user: deploy, destination: web1}\n",
+			wantErr: "runner: config: stack[1].destination: " +
+				`not supported by type "sudo"`,
+		},
+		{
+			name:    "missing destination",
+			config:  "stack:\n  - {type: ssh, port: 22}\n",
+			wantErr: "runner: config: stack[0].destination: required",
+		},
+		{
+			name:    "missing container",
+			config:  "stack:\n  - {type: docker}\n",
+			wantErr: "runner: config: stack[0].container: required",
+		},
+		{
+			name:    "missing jail",
+			config:  "stack:\n  - {type: jexec}\n",
+			wantErr: "runner: config: stack[0].jail: required",
+		},
+		{
+			name: "invalid port",
+			config: "stack:\n" +
+				"  - {type: ssh, destination: web1, port: 99999}\n",
+			wantErr: "runner: config: stack[0].port: invalid port 99999",
+		},
+		{
+			name: "invalid layer",
+			config: "stack:\n" +
+				"  - {type: ssh, destination: web1}\n" +
+				"  - {type: ssh, destination: web 2}\n",
+			wantErr: "runner: config: stack[1].destination: " +
+				`contains illegal character ' '`,
+		},
+		{
+			name: "invalid layer field with multiple words",
+			config: "stack:\n" +
+				`  - {type: ssh, destination: web1, identity_file: "a\tb"}` +
+				"\n",
+			wantErr: "runner: config: stack[0].identity_file: " +
+				`contains illegal character '\t'`,
+		},
+		{
+			name:   "invalid env",
+			config: "stack:\n  - {type: sudo, env: [A=1, B]}\n",
+			wantErr: `runner: config: stack[0].env[1]: ` +
+				`"B" is not of the form key=value`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseConfig([]byte(tt.config))
+			var got Runner
+			if err == nil {
+				got, err = c.Build()
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrConfig)
+
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.wantEnv != nil {
+				ssh, ok := got.(*SSHCLI)
+				require.True(t, ok)
+				assert.Equal(t, tt.wantEnv, ssh.environ())
+
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigError(t *testing.T) {
+	err := (&Config{Stack: []ConfigLayer{{Type: "ssh"}}}).Validate()
+
+	var cfgErr *ConfigError
+	require.ErrorAs(t, err, &cfgErr)
+	assert.Equal(t, "stack[0].destination", cfgErr.Field)
+	assert.Equal(t, "required", cfgErr.Msg)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "runner.yaml")
+	err := os.WriteFile(
+		path, []byte("stack:\n  - {type: docker, container: app}\n"), 0o600,
+	)
+	require.NoError(t, err)
+
+	got, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Docker{Runner: &Local{}, Container: "app"}, got)
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.yaml"))
+	assert.ErrorIs(t, err, ErrConfig)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+// ContextEnv is a Runner that wraps another Runner, and adds environment
+// variables derived from the context to each command, like a correlation or
+// trace ID, so commands can log the same ID as the code which ran them.
+//
+// The env vars are added as with WithEnv, so they are applied by Local, and by
+// Sudo and SSHCLI to the command they wrap, regardless of how many runners
+// are between them and ContextEnv. Env vars given with WithEnv take
+// precedence.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type ContextEnv struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// FromContext returns "key=value" env vars to add to commands run with
+	// ctx, like "TRACEPARENT=00-...". If not set, or if it returns no env
+	// vars, commands are run as is.
+	FromContext func(ctx context.Context) []string
+}
+
+var _ Runner = &ContextEnv{}
+
+// Run calls RunContext on the underlying Runner with a background context,
+// adding any env vars FromContext returns for it.
+//
+// Will panic if Runner field is nil.
+func (r *ContextEnv) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext adds any env vars FromContext returns for ctx, and calls
+// RunContext on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+func (r *ContextEnv) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if r.FromContext != nil {
+		if env := r.FromContext(ctx); len(env) > 0 {
+			// Prepend so env given with WithEnv takes precedence.
+			ctx = context.WithValue(
+				ctx, callEnvKey{}, mergeEnv(env, callEnv(ctx)),
+			)
+		}
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *ContextEnv) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id, which is returned by
+// CorrelationID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or an empty string.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+
+	return id
+}
+
+// CorrelationIDEnv returns a function for use as ContextEnv.FromContext, which
+// sets the env var key to the correlation ID carried by ctx, if any.
+func CorrelationIDEnv(key string) func(ctx context.Context) []string {
+	return func(ctx context.Context) []string {
+		id := CorrelationID(ctx)
+		if id == "" {
+			return nil
+		}
+
+		return []string{key + "=" + id}
+	}
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestContextEnv_wrappers(t *testing.T) {
+	ctx := WithCorrelationID(context.Background(), "req-123")
+
+	tests := []struct {
+		name     string
+		wrap     func(r Runner) Runner
+		opts     []RunOption
+		wantCmd  string
+		wantArgs []string
+		wantEnv  []string
+	}{
+		{
+			name:    "no wrapper",
+			wrap:    func(r Runner) Runner { return r },
+			wantCmd: "whoami",
+			wantEnv: []string{"REQUEST_ID=req-123"},
+		},
+		{
+			name: "Sudo",
+			wrap: func(r Runner) Runner {
+				return &Sudo{Runner: r}
+			},
+			wantCmd: "sudo",
+			wantArgs: []string{
+				"-n", "REQUEST_ID=req-123", "--", "whoami",
+			},
+		},
+		{
+			name: "SSHCLI",
+			wrap: func(r Runner) Runner {
+				return &SSHCLI{Runner: r, Destination: "host"}
+			},
+			wantCmd: "ssh",
+			wantArgs: []string{
+				"host", "--", "env", "REQUEST_ID=req-123", "whoami",
+			},
+		},
+		{
+			name: "Sudo with call env",
+			wrap: func(r Runner) Runner {
+				return &Sudo{Runner: r}
+			},
+			opts:    []RunOption{WithEnv("REQUEST_ID=override", "FOO=bar")},
+			wantCmd: "sudo",
+			wantArgs: []string{
+				"-n", "FOO=bar", "REQUEST_ID=override", "--", "whoami",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("*", nil, FakeResponse{})
+
+			r := &ContextEnv{
+				Runner:      tt.wrap(f),
+				FromContext: CorrelationIDEnv("REQUEST_ID"),
+			}
+
+			err := RunWith(ctx, r, tt.opts, "whoami")
+			require.NoError(t, err)
+
+			calls := f.Calls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.wantCmd, calls[0].Command)
+			assert.Equal(t, tt.wantArgs, calls[0].Args)
+			assert.Equal(t, tt.wantEnv, calls[0].Env)
+		})
+	}
+}
+
+func TestContextEnv_Local(t *testing.T) {
+	r := &ContextEnv{
+		Runner:      &Local{},
+		FromContext: CorrelationIDEnv("REQUEST_ID"),
+	}
+
+	var stdout bytes.Buffer
+	ctx := WithCorrelationID(context.Background(), "req-123")
+	err := r.RunContext(
+		ctx, nil, &stdout, nil, "sh", "-c", `echo "id=$REQUEST_ID"`,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "id=req-123\n", stdout.String())
+
+	stdout.Reset()
+	err = r.Run(nil, &stdout, nil, "sh", "-c", `echo "id=$REQUEST_ID"`)
+	require.NoError(t, err)
+	assert.Equal(t, "id=\n", stdout.String())
+}
+
+func TestContextEnv_noFromContext(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+
+	r := &ContextEnv{Runner: f}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+
+	assert.Equal(t, []FakeCall{{Command: "true"}}, f.Calls())
+}
+
+func TestContextEnv_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=bar"})
+
+	c := &ContextEnv{Runner: r}
+	c.Env("FOO=bar")
+}
+
+func TestCorrelationID(t *testing.T) {
+	assert.Empty(t, CorrelationID(context.Background()))
+
+	ctx := WithCorrelationID(context.Background(), "abc")
+	assert.Equal(t, "abc", CorrelationID(ctx))
+
+	fn := CorrelationIDEnv("X_ID")
+	assert.Nil(t, fn(context.Background()))
+	assert.Equal(t, []string{"X_ID=abc"}, fn(ctx))
+}
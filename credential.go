@@ -0,0 +1,7 @@
+package runner
+
+import "fmt"
+
+var ErrCredentialUnsupported = fmt.Errorf(
+	"%w: credential: not supported on this platform", Err,
+)
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+func setCredential(*exec.Cmd, *uint32, *uint32, []uint32) error {
+	return ErrCredentialUnsupported
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCredential configures cmd to run as the given user and group IDs. A nil
+// uid or gid uses the user or group ID of the current process.
+func setCredential(
+	cmd *exec.Cmd,
+	uid *uint32,
+	gid *uint32,
+	groups []uint32,
+) error {
+	cred := &syscall.Credential{
+		Uid:    uint32(syscall.Getuid()),
+		Gid:    uint32(syscall.Getgid()),
+		Groups: groups,
+	}
+	if uid != nil {
+		cred.Uid = *uid
+	}
+	if gid != nil {
+		cred.Gid = *gid
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+
+	return nil
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_credential(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	uint32Ptr := func(v uint32) *uint32 { return &v }
+
+	tests := []struct {
+		name       string
+		uid        *uint32
+		gid        *uint32
+		groups     []uint32
+		wantStdout string
+	}{
+		{
+			name:       "uid",
+			uid:        uint32Ptr(65534),
+			wantStdout: "65534 0 0\n",
+		},
+		{
+			name:       "uid and gid",
+			uid:        uint32Ptr(65534),
+			gid:        uint32Ptr(65534),
+			wantStdout: "65534 65534 65534\n",
+		},
+		{
+			name:       "uid, gid and groups",
+			uid:        uint32Ptr(65534),
+			gid:        uint32Ptr(65534),
+			groups:     []uint32{100, 200},
+			wantStdout: "65534 65534 65534 100 200\n",
+		},
+		{
+			name:       "gid",
+			gid:        uint32Ptr(1234),
+			wantStdout: "0 1234 1234\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{Uid: tt.uid, Gid: tt.gid, Groups: tt.groups}
+
+			err := r.Run(
+				nil, &stdout, nil,
+				"sh", "-c", `echo "$(id -u) $(id -g) $(id -G)"`,
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStdout, stdout.String())
+		})
+	}
+}
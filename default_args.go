@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+// DefaultArgs is a Runner that wraps another Runner, and inserts default
+// arguments for specific commands before the arguments they are run with. For
+// example, "--no-color" can be added to all git commands.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type DefaultArgs struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Commands maps command names to the arguments to insert before the
+	// arguments of each matching command. Commands are matched exactly as
+	// given, so "git" does not match "/usr/bin/git".
+	Commands map[string][]string
+}
+
+var _ Runner = &DefaultArgs{}
+
+// Run inserts any default arguments and calls Run on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+func (r *DefaultArgs) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.Run(
+		stdin, stdout, stderr, command, r.args(command, args)...,
+	)
+}
+
+// RunContext inserts any default arguments and calls RunContext on the
+// underlying Runner.
+//
+// Will panic if Runner field is nil.
+func (r *DefaultArgs) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.RunContext(
+		ctx, stdin, stdout, stderr, command, r.args(command, args)...,
+	)
+}
+
+func (r *DefaultArgs) args(command string, args []string) []string {
+	defaults, ok := r.Commands[command]
+	if !ok || len(defaults) == 0 {
+		return args
+	}
+
+	newArgs := make([]string, 0, len(defaults)+len(args))
+	newArgs = append(newArgs, defaults...)
+
+	return append(newArgs, args...)
+}
+
+// Env calls Env on the underlying Runner.
+func (r *DefaultArgs) Env(env ...string) {
+	r.Runner.Env(env...)
+}
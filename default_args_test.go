@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var defaultArgsTestCases = []struct {
+	name     string
+	commands map[string][]string
+	command  string
+	args     []string
+	err      error
+	wantArgs []string
+	wantErr  string
+}{
+	{
+		name:     "no defaults",
+		command:  "git",
+		args:     []string{"log"},
+		wantArgs: []string{"log"},
+	},
+	{
+		name: "defaults",
+		commands: map[string][]string{
+			"git": {"--no-pager", "-c", "color.ui=never"},
+			"ssh": {"-o", "LogLevel=ERROR"},
+		},
+		command:  "git",
+		args:     []string{"log", "-1"},
+		wantArgs: []string{"--no-pager", "-c", "color.ui=never", "log", "-1"},
+	},
+	{
+		name: "defaults without args",
+		commands: map[string][]string{
+			"ssh": {"-o", "LogLevel=ERROR"},
+		},
+		command:  "ssh",
+		wantArgs: []string{"-o", "LogLevel=ERROR"},
+	},
+	{
+		name: "other command",
+		commands: map[string][]string{
+			"git": {"--no-pager"},
+		},
+		command:  "/usr/bin/git",
+		args:     []string{"log"},
+		wantArgs: []string{"log"},
+	},
+	{
+		name: "error",
+		commands: map[string][]string{
+			"zfs": {"-H"},
+		},
+		command:  "zfs",
+		args:     []string{"list"},
+		err:      errors.New("zfs: command not found"),
+		wantArgs: []string{"-H", "list"},
+		wantErr:  "zfs: command not found",
+	},
+}
+
+func TestDefaultArgs_Run(t *testing.T) {
+	for _, tt := range defaultArgsTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().Run(nil, nil, nil, tt.command, tt.wantArgs).
+				Return(tt.err)
+
+			d := &DefaultArgs{Runner: r, Commands: tt.commands}
+			err := d.Run(nil, nil, nil, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDefaultArgs_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range defaultArgsTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx), nil, nil, nil, tt.command, tt.wantArgs,
+			).Return(tt.err)
+
+			d := &DefaultArgs{Runner: r, Commands: tt.commands}
+			err := d.RunContext(ctx, nil, nil, nil, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDefaultArgs_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	d := &DefaultArgs{Runner: r}
+	d.Env("FOO=BAR", "PORT=8080")
+}
@@ -0,0 +1,123 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// DelayRule sets the latency added by a Delay runner to commands matching
+// its PolicyRule.
+type DelayRule struct {
+	PolicyRule
+
+	// Delay is a fixed delay added before running matching commands.
+	Delay time.Duration
+
+	// Jitter is the exclusive upper bound of a random delay added on top of
+	// Delay.
+	Jitter time.Duration
+}
+
+// Delay is a Runner that wraps another Runner, and adds latency before running
+// each command. This is useful for reproducing timeout behavior, and testing
+// progress reporting.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Delay struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Delay is a fixed delay added before running commands which do not match
+	// any of the Rules.
+	Delay time.Duration
+
+	// Jitter is the exclusive upper bound of a random delay added on top of
+	// Delay, for commands which do not match any of the Rules.
+	Jitter time.Duration
+
+	// Rules sets the latency of specific commands. The first matching rule is
+	// used instead of Delay and Jitter.
+	Rules []DelayRule
+}
+
+var _ Runner = &Delay{}
+
+// Run sleeps for the configured delay, and then calls Run on the underlying
+// Runner.
+//
+// Will panic if Runner field is nil.
+func (r *Delay) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	time.Sleep(r.delay(command, args))
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext sleeps for the configured delay, and then calls RunContext on
+// the underlying Runner. If ctx is done before the delay has elapsed, its
+// error is returned without running the command.
+//
+// Will panic if Runner field is nil.
+func (r *Delay) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := sleepContext(ctx, r.delay(command, args))
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+func (r *Delay) delay(command string, args []string) time.Duration {
+	delay, jitter := r.Delay, r.Jitter
+	for _, rule := range r.Rules {
+		if rule.Match(command, args) {
+			delay, jitter = rule.Delay, rule.Jitter
+
+			break
+		}
+	}
+
+	if jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitter))) //nolint:gosec
+	}
+
+	return delay
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Delay) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+// sleepContext sleeps for d, returning early with the error of ctx if it is
+// done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
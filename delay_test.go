@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDelay_delay(t *testing.T) {
+	r := &Delay{
+		Delay:  time.Second,
+		Jitter: 500 * time.Millisecond,
+		Rules: []DelayRule{
+			{
+				PolicyRule: PolicyRule{Command: "zfs", Args: []string{"send"}},
+				Delay:      time.Minute,
+			},
+			{
+				PolicyRule: PolicyRule{Command: "zfs"},
+				Delay:      10 * time.Second,
+				Jitter:     time.Second,
+			},
+			{PolicyRule: PolicyRule{Command: "true"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		min     time.Duration
+		max     time.Duration
+	}{
+		{
+			name:    "default",
+			command: "ls",
+			min:     time.Second,
+			max:     1500 * time.Millisecond,
+		},
+		{
+			name:    "first matching rule",
+			command: "zfs",
+			args:    []string{"send", "tank@snap"},
+			min:     time.Minute,
+			max:     time.Minute + 1,
+		},
+		{
+			name:    "rule with jitter",
+			command: "zfs",
+			args:    []string{"list"},
+			min:     10 * time.Second,
+			max:     11 * time.Second,
+		},
+		{
+			name:    "rule without delay",
+			command: "true",
+			min:     0,
+			max:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := r.delay(tt.command, tt.args)
+
+				assert.GreaterOrEqual(t, got, tt.min)
+				assert.Less(t, got, tt.max)
+			}
+		})
+	}
+}
+
+func TestDelay_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Run(nil, nil, nil, "uptime", []string{"-p"}).Return(nil)
+
+	d := &Delay{Runner: r, Delay: 20 * time.Millisecond}
+
+	start := time.Now()
+	err := d.Run(nil, nil, nil, "uptime", "-p")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestDelay_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, nil, nil, "uptime", []string{"-p"},
+	).Return(nil)
+
+	d := &Delay{Runner: r, Delay: 20 * time.Millisecond}
+
+	start := time.Now()
+	err := d.RunContext(ctx, nil, nil, nil, "uptime", "-p")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// The command is not run if the context is done during the delay.
+	d.Delay = time.Hour
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	err = d.RunContext(tctx, nil, nil, nil, "uptime", "-p")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestDelay_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	d := &Delay{Runner: r}
+	d.Env("FOO=BAR", "PORT=8080")
+}
@@ -0,0 +1,107 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrDetach = fmt.Errorf("%w: detach", Err)
+
+// detachScript starts the command given as its arguments detached from the
+// shell, and prints its PID. The first two arguments are the files to append
+// the command's stdout and stderr to.
+const detachScript = `out=$1 err=$2; shift 2
+if command -v setsid >/dev/null 2>&1; then set -- setsid "$@"; fi
+if [ "$out" = "$err" ]; then
+  nohup "$@" >>"$out" 2>&1 </dev/null &
+else
+  nohup "$@" >>"$out" 2>>"$err" </dev/null &
+fi
+echo $!`
+
+// Detach starts commands fully detached from the process which started them,
+// so they keep running after it exits, or after the SSH connection they were
+// started over is closed.
+//
+// Commands are started via "sh -c" on Runner, with nohup semantics, in a new
+// session via setsid(1) where available, with stdin read from /dev/null, and
+// stdout and stderr appended to files. As the command runs on the host
+// Runner runs commands on, this works both locally, and on remote hosts via
+// SSHCLI.
+type Detach struct {
+	// Runner is the Runner to start commands with. If not set, starting
+	// commands will cause a panic.
+	Runner Runner
+
+	// Stdout is the path of the file the command's stdout is appended to, on
+	// the host the command runs on. Defaults to "/dev/null".
+	Stdout string
+
+	// Stderr is the path of the file the command's stderr is appended to.
+	// Defaults to Stdout.
+	Stderr string
+
+	// QuoteArgs causes the arguments passed to Runner to be shell-quoted. It
+	// must be set when Runner runs commands via a shell, like SSHCLI does on
+	// the remote host, and is implied when Runner is a *SSHCLI.
+	QuoteArgs bool
+}
+
+// Start starts the given command detached, and returns its PID on the host it
+// runs on. It returns once the command has been started, without waiting for
+// it to exit. Any error running the command itself is only reported to its
+// stderr file.
+func (d *Detach) Start(
+	ctx context.Context,
+	command string,
+	args ...string,
+) (int, error) {
+	stdoutPath := d.Stdout
+	if stdoutPath == "" {
+		stdoutPath = "/dev/null"
+	}
+	stderrPath := d.Stderr
+	if stderrPath == "" {
+		stderrPath = stdoutPath
+	}
+
+	shArgs := append(
+		[]string{"-c", detachScript, "sh", stdoutPath, stderrPath, command},
+		args...,
+	)
+
+	shArgs = quoteArgs(d.Runner, d.QuoteArgs, shArgs)
+
+	var stdout, stderr bytes.Buffer
+	err := d.Runner.RunContext(ctx, nil, &stdout, &stderr, "sh", shArgs...)
+	if err != nil {
+		return 0, err
+	}
+
+	out := strings.TrimSpace(stdout.String())
+	pid, err := strconv.Atoi(out)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf(
+			"%w: invalid pid %q: %s", ErrDetach, out,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return pid, nil
+}
+
+// quoteArgs shell-quotes args in place when quote is true, or r is a *SSHCLI,
+// which passes arguments through the remote user's shell, and returns them.
+func quoteArgs(r Runner, quote bool, args []string) []string {
+	if _, isSSH := r.(*SSHCLI); !quote && !isSSH {
+		return args
+	}
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+
+	return args
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetach_Start(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available on Windows")
+	}
+
+	tests := []struct {
+		name       string
+		sameFile   bool
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "separate files",
+			wantStdout: "out 1\nout 2\n",
+			wantStderr: "err\n",
+		},
+		{
+			name:       "same file",
+			sameFile:   true,
+			wantStdout: "out 1\nerr\nout 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			d := &Detach{
+				Runner: New(),
+				Stdout: filepath.Join(dir, "stdout.log"),
+				Stderr: filepath.Join(dir, "stderr.log"),
+			}
+			if tt.sameFile {
+				d.Stderr = ""
+			}
+			done := filepath.Join(dir, "done")
+
+			pid, err := d.Start(
+				context.Background(), "sh", "-c",
+				`echo "out 1"; echo err >&2; sleep 0.1; echo "out 2"; `+
+					`touch "$0"`,
+				done,
+			)
+			require.NoError(t, err)
+			assert.Greater(t, pid, 0)
+
+			if runtime.GOOS == "linux" {
+				// The detached command leads its own session, once it has
+				// been started by nohup and setsid.
+				assert.Eventually(t, func() bool {
+					return processSession(pid) == strconv.Itoa(pid)
+				}, time.Second, time.Millisecond)
+			}
+
+			require.Eventually(t, func() bool {
+				_, err := os.Stat(done)
+
+				return err == nil
+			}, 5*time.Second, 10*time.Millisecond)
+
+			b, err := os.ReadFile(d.Stdout)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStdout, string(b))
+			if !tt.sameFile {
+				b, err = os.ReadFile(d.Stderr)
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantStderr, string(b))
+			}
+		})
+	}
+}
+
+// processSession returns the session ID of the process with the given pid,
+// as read from /proc.
+func processSession(pid int) string {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ""
+	}
+
+	// Fields following the command name start at field 3 (state), and the
+	// session ID is field 6.
+	fields := strings.Fields(string(b[bytes.LastIndexByte(b, ')')+1:]))
+	if len(fields) < 4 {
+		return ""
+	}
+
+	return fields[3]
+}
+
+func TestDetach_Start_args(t *testing.T) {
+	tests := []struct {
+		name     string
+		detach   Detach
+		wantArgs []string
+	}{
+		{
+			name:   "defaults",
+			detach: Detach{},
+			wantArgs: []string{
+				"-c", detachScript, "sh", "/dev/null", "/dev/null",
+				"migrate", "--all",
+			},
+		},
+		{
+			name:   "paths",
+			detach: Detach{Stdout: "/var/log/m.log", Stderr: "/var/log/e.log"},
+			wantArgs: []string{
+				"-c", detachScript, "sh", "/var/log/m.log", "/var/log/e.log",
+				"migrate", "--all",
+			},
+		},
+		{
+			name:   "quoted",
+			detach: Detach{Stdout: "/var/log/m.log", QuoteArgs: true},
+			wantArgs: []string{
+				"'-c'", shellQuote(detachScript), "'sh'",
+				"'/var/log/m.log'", "'/var/log/m.log'",
+				"'migrate'", "'--all'",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("sh", nil, FakeResponse{Stdout: "4242\n"})
+
+			d := tt.detach
+			d.Runner = f
+
+			pid, err := d.Start(context.Background(), "migrate", "--all")
+			require.NoError(t, err)
+
+			assert.Equal(t, 4242, pid)
+			require.Len(t, f.Calls(), 1)
+			assert.Equal(t, tt.wantArgs, f.Calls()[0].Args)
+		})
+	}
+}
+
+func TestDetach_Start_sshcli(t *testing.T) {
+	f := &Fake{}
+	f.Respond("ssh", nil, FakeResponse{Stdout: "17\n"})
+
+	d := &Detach{Runner: &SSHCLI{Runner: f, Destination: "db1"}}
+	pid, err := d.Start(context.Background(), "migrate")
+	require.NoError(t, err)
+
+	assert.Equal(t, 17, pid)
+	assert.Equal(t, []string{
+		"db1", "--", "sh", "'-c'", shellQuote(detachScript), "'sh'",
+		"'/dev/null'", "'/dev/null'", "'migrate'",
+	}, f.Calls()[0].Args)
+}
+
+func TestDetach_Start_errors(t *testing.T) {
+	f := &Fake{}
+	f.Respond("sh", nil, FakeResponse{Stdout: "\n", Stderr: "boom\n"})
+
+	d := &Detach{Runner: f}
+	_, err := d.Start(context.Background(), "migrate")
+	assert.EqualError(t, err, `runner: detach: invalid pid "": boom`)
+	assert.ErrorIs(t, err, ErrDetach)
+
+	f.Respond("sh", nil, FakeResponse{ExitCode: 2})
+	_, err = d.Start(context.Background(), "migrate")
+	assert.EqualError(t, err, "sh: exit status 2")
+}
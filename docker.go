@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	ErrDocker            = fmt.Errorf("%w: docker", Err)
+	ErrDockerNoContainer = fmt.Errorf(
+		"%w: container must be set", ErrDocker,
+	)
+)
+
+// Docker is a Runner that wraps another Runner and runs commands inside a
+// running container via "docker exec".
+//
+// When stdin is not nil, the -i flag is passed so it is attached to the
+// command. A TTY is never allocated.
+type Docker struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with docker exec. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Container is the name or ID of the container to execute commands in.
+	Container string
+
+	// User is the user to run commands as inside the container, passed via
+	// the -u flag. When empty, no -u flag will be used.
+	User string
+
+	// Dir is the working directory inside the container to run commands in,
+	// passed via the -w flag. When empty, no -w flag will be used.
+	Dir string
+
+	// Args is a string slice of extra arguments to pass to docker exec.
+	Args []string
+
+	env   []string
+	envMu sync.RWMutex
+}
+
+var _ Runner = &Docker{}
+
+var _ Validator = &Docker{}
+
+// Validate checks the Docker is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner and Container are set,
+// and that values passed to docker do not contain illegal characters, or
+// begin with "-" where they could be mistaken for flags. The underlying Runner
+// is validated too.
+func (r *Docker) Validate() error {
+	if r.Container == "" {
+		return &ValidationError{
+			Field: "Docker.Container",
+			Msg:   "must be set",
+			Err:   ErrDockerNoContainer,
+		}
+	}
+
+	return firstError(
+		validateRunner("Docker.Runner", r.Runner),
+		validateWord("Docker.Container", r.Container),
+		validateWord("Docker.User", r.User),
+		validateChars("Docker.Dir", r.Dir, false),
+		validateEnv("Docker.Env", r.environ()),
+		Validate(r.Runner),
+	)
+}
+
+// Run executes the command inside the container by calling Run on the
+// underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Container field is empty.
+func (r *Docker) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	dockerArgs, err := r.args(context.Background(), stdin, command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.Run(stdin, stdout, stderr, "docker", dockerArgs...)
+}
+
+// RunContext executes the command inside the container by calling RunContext
+// on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Container field is empty.
+func (r *Docker) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	dockerArgs, err := r.args(ctx, stdin, command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(
+		withoutCallOptions(ctx), stdin, stdout, stderr,
+		"docker", dockerArgs...,
+	)
+}
+
+func (r *Docker) args(
+	ctx context.Context,
+	stdin io.Reader,
+	command string,
+	args []string,
+) ([]string, error) {
+	if r.Container == "" {
+		return nil, ErrDockerNoContainer
+	}
+
+	dockerArgs := []string{"exec"}
+
+	if stdin != nil {
+		dockerArgs = append(dockerArgs, "-i")
+	}
+	if r.User != "" {
+		dockerArgs = append(dockerArgs, "-u", r.User)
+	}
+
+	dir := r.Dir
+	if d := callDir(ctx); d != "" {
+		dir = d
+	}
+	if dir != "" {
+		dockerArgs = append(dockerArgs, "-w", dir)
+	}
+
+	for _, kv := range DedupEnv(mergeEnv(r.environ(), callEnv(ctx))...) {
+		dockerArgs = append(dockerArgs, "-e", kv)
+	}
+	dockerArgs = append(dockerArgs, r.Args...)
+	dockerArgs = append(dockerArgs, r.Container, command)
+	dockerArgs = append(dockerArgs, args...)
+
+	return dockerArgs, nil
+}
+
+// Env sets the environment variables which will be provided to commands run
+// inside the container, via the -e flag.
+func (r *Docker) Env(env ...string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+func (r *Docker) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
+}
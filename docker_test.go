@@ -0,0 +1,229 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var dockerTestCases = []struct {
+	name        string
+	env         []string
+	runner      *Docker
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	command     string
+	args        []string
+	err         error
+	wantCommand string
+	wantArgs    []string
+	wantErr     string
+}{
+	{
+		name:        "container",
+		runner:      &Docker{Container: "web"},
+		stdout:      &bytes.Buffer{},
+		stderr:      &bytes.Buffer{},
+		command:     "nginx",
+		args:        []string{"-t"},
+		wantCommand: "docker",
+		wantArgs:    []string{"exec", "web", "nginx", "-t"},
+	},
+	{
+		name:        "with stdin",
+		runner:      &Docker{Container: "web"},
+		stdin:       bytes.NewBufferString("foo\nbar"),
+		command:     "cat",
+		wantCommand: "docker",
+		wantArgs:    []string{"exec", "-i", "web", "cat"},
+	},
+	{
+		name:        "with User and Dir",
+		runner:      &Docker{Container: "web", User: "www", Dir: "/srv"},
+		command:     "ls",
+		wantCommand: "docker",
+		wantArgs: []string{
+			"exec", "-u", "www", "-w", "/srv", "web", "ls",
+		},
+	},
+	{
+		name:        "with Env",
+		env:         []string{"FOO=BAR", "PORT=8080", "FOO=BAZ"},
+		runner:      &Docker{Container: "web"},
+		command:     "myapp",
+		args:        []string{"run"},
+		wantCommand: "docker",
+		wantArgs: []string{
+			"exec", "-e", "FOO=BAZ", "-e", "PORT=8080", "web", "myapp", "run",
+		},
+	},
+	{
+		name:        "with Args",
+		runner:      &Docker{Container: "web", Args: []string{"--privileged"}},
+		command:     "id",
+		wantCommand: "docker",
+		wantArgs:    []string{"exec", "--privileged", "web", "id"},
+	},
+	{
+		name:        "error",
+		runner:      &Docker{Container: "web"},
+		command:     "zfs",
+		args:        []string{"list"},
+		err:         errors.New("zfs: command not found"),
+		wantCommand: "docker",
+		wantArgs:    []string{"exec", "web", "zfs", "list"},
+		wantErr:     "zfs: command not found",
+	},
+	{
+		name:    "no container",
+		runner:  &Docker{},
+		command: "whoami",
+		wantErr: "runner: docker: container must be set",
+	},
+}
+
+func TestDocker_Run(t *testing.T) {
+	for _, tt := range dockerTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantCommand != "" {
+				r.EXPECT().Run(
+					tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			d := tt.runner
+			d.Runner = r
+			if len(tt.env) > 0 {
+				d.Env(tt.env...)
+			}
+
+			err := d.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDocker_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range dockerTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantCommand != "" {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			d := tt.runner
+			d.Runner = r
+			if len(tt.env) > 0 {
+				d.Env(tt.env...)
+			}
+
+			err := d.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDocker_RunWith(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomock.Any(), nil, nil, nil, "docker", []string{
+			"exec", "-w", "/tmp", "-e", "BAR=1", "-e", "FOO=call",
+			"web", "env",
+		},
+	).Return(nil)
+
+	d := &Docker{Runner: r, Container: "web", Dir: "/srv"}
+	d.Env("FOO=BAR", "BAR=1")
+
+	err := RunWith(
+		context.Background(), d,
+		[]RunOption{WithEnv("FOO=call"), WithDir("/tmp")}, "env",
+	)
+
+	assert.NoError(t, err)
+}
+
+func TestDocker_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+
+	d := &Docker{Runner: r, Container: "web"}
+	d.Env("FOO=BAR", "PORT=8080")
+
+	assert.Equal(t, []string{"FOO=BAR", "PORT=8080"}, d.env)
+}
+
+func TestDocker_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Docker
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "valid",
+			runner: &Docker{Runner: &Local{}, Container: "app", User: "www"},
+		},
+		{
+			name:    "no container",
+			runner:  &Docker{Runner: &Local{}},
+			wantErr: "runner: invalid: Docker.Container: must be set",
+			wantIs:  ErrDockerNoContainer,
+		},
+		{
+			name:    "no runner",
+			runner:  &Docker{Container: "app"},
+			wantErr: "runner: invalid: Docker.Runner: must be set",
+		},
+		{
+			name:    "container is a flag",
+			runner:  &Docker{Runner: &Local{}, Container: "--privileged"},
+			wantErr: "runner: invalid: Docker.Container: must not begin with -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"sort"
+	"strings"
+)
+
+// EnvSet is an ordered set of environment variables, for incrementally
+// building an environment to pass to the Env method of any Runner.
+//
+// Each key appears at most once. Setting an existing key replaces its value in
+// place, while new keys are added to the end.
+//
+// The zero value is an empty EnvSet ready to use.
+type EnvSet struct {
+	entries []string
+}
+
+// NewEnvSet returns a new EnvSet containing the given "key=value" entries.
+func NewEnvSet(env ...string) *EnvSet {
+	e := &EnvSet{}
+	e.AppendEnv(env...)
+
+	return e
+}
+
+// Setenv sets the value of the environment variable named by key.
+func (e *EnvSet) Setenv(key, value string) {
+	e.set(key, key+"="+value)
+}
+
+// Unsetenv removes the environment variable named by key, if present.
+func (e *EnvSet) Unsetenv(key string) {
+	if i := e.index(key); i >= 0 {
+		e.entries = append(e.entries[:i], e.entries[i+1:]...)
+	}
+}
+
+// AppendEnv adds the given "key=value" entries, replacing the value of any
+// existing entries with the same key. Entries without a "=" are treated as a
+// key with an empty value.
+func (e *EnvSet) AppendEnv(env ...string) {
+	for _, entry := range env {
+		key, _, ok := strings.Cut(entry, "=")
+		if !ok {
+			entry += "="
+		}
+		e.set(key, entry)
+	}
+}
+
+// LookupEnv returns the value of the environment variable named by key, and
+// reports if it is present.
+func (e *EnvSet) LookupEnv(key string) (string, bool) {
+	i := e.index(key)
+	if i < 0 {
+		return "", false
+	}
+
+	return e.entries[i][len(key)+1:], true
+}
+
+// Environ returns a copy of the entries in the form "key=value", suitable for
+// passing to the Env method of a Runner.
+func (e *EnvSet) Environ() []string {
+	return append([]string{}, e.entries...)
+}
+
+func (e *EnvSet) set(key, entry string) {
+	if i := e.index(key); i >= 0 {
+		e.entries[i] = entry
+
+		return
+	}
+
+	e.entries = append(e.entries, entry)
+}
+
+func (e *EnvSet) index(key string) int {
+	for i, entry := range e.entries {
+		if k, _, _ := strings.Cut(entry, "="); k == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// copyStrings returns a copy of s, preserving whether it is nil. It is used to
+// ensure slices given by callers cannot be modified after they are stored.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append(make([]string, 0, len(s)), s...)
+}
+
+// DedupEnv returns a normalized copy of env, with entries of duplicate keys
+// removed, keeping the last, and sorted by key. Entries without a "=" are
+// treated as a key with an empty value.
+//
+// Wrapper runners which pass the environment as command arguments use it so
+// the arguments are minimal and deterministic.
+func DedupEnv(env ...string) []string {
+	entries := NewEnvSet(env...).entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		ki, _, _ := strings.Cut(entries[i], "=")
+		kj, _, _ := strings.Cut(entries[j], "=")
+
+		return ki < kj
+	})
+
+	return entries
+}
@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrEnvFile = fmt.Errorf("%w: env file", Err)
+
+// LoadEnvFile reads the dotenv-style file at path, and returns its variables
+// as "key=value" entries suitable for passing to the Env method of a Runner.
+// See ParseEnvFile for details of the supported syntax.
+func LoadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseEnvFile(path, f)
+}
+
+// ParseEnvFile parses dotenv-style content from r, and returns its variables
+// as "key=value" entries suitable for passing to the Env method of a Runner.
+//
+// Each line is of the form "KEY=value", optionally prefixed with "export ".
+// Blank lines, and lines starting with "#" are ignored. Values may be:
+//
+//   - Unquoted, in which case surrounding whitespace is trimmed, and anything
+//     following a "#" preceded by whitespace is treated as a comment.
+//   - Single-quoted, in which case the value is used literally.
+//   - Double-quoted, in which case the escape sequences \n, \r, \t, \", \\
+//     and \$ are supported.
+//
+// Quoted values may span multiple lines. Variable references like ${FOO} are
+// not expanded.
+func ParseEnvFile(r io.Reader) ([]string, error) {
+	return parseEnvFile("", r)
+}
+
+func parseEnvFile(name string, r io.Reader) ([]string, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &envFileParser{name: name, src: string(b), line: 1}
+
+	return p.parse()
+}
+
+type envFileParser struct {
+	name string
+	src  string
+	pos  int
+	line int
+}
+
+func (p *envFileParser) parse() ([]string, error) {
+	env := []string{}
+
+	for {
+		p.skip(" \t\r\n")
+		if p.eof() {
+			return env, nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
+
+			continue
+		}
+
+		entry, err := p.entry()
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, entry)
+	}
+}
+
+func (p *envFileParser) entry() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], "export") {
+		rest := p.src[p.pos+len("export"):]
+		if rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			p.pos += len("export")
+			p.skip(" \t")
+		}
+	}
+
+	start := p.pos
+	for !p.eof() && isEnvKeyByte(p.peek()) {
+		p.pos++
+	}
+	key := p.src[start:p.pos]
+	if key == "" {
+		return "", p.errorf("invalid character %q in key", p.peek())
+	}
+
+	p.skip(" \t")
+	if p.eof() || p.peek() != '=' {
+		return "", p.errorf("missing \"=\" after key %q", key)
+	}
+	p.pos++
+	p.skip(" \t")
+
+	var value string
+	var err error
+	switch {
+	case p.eof():
+	case p.peek() == '\'':
+		value, err = p.singleQuoted()
+	case p.peek() == '"':
+		value, err = p.doubleQuoted()
+	default:
+		value = p.unquoted()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p.skip(" \t\r")
+	if !p.eof() && p.peek() == '#' {
+		p.skipLine()
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return "", p.errorf("unexpected %q after value of %q", p.peek(), key)
+	}
+
+	return key + "=" + value, nil
+}
+
+func (p *envFileParser) unquoted() string {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		if p.peek() == '#' && p.pos > start &&
+			(p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+			break
+		}
+		p.pos++
+	}
+
+	return strings.TrimRight(p.src[start:p.pos], " \t\r")
+}
+
+func (p *envFileParser) singleQuoted() (string, error) {
+	p.pos++
+
+	end := strings.IndexByte(p.src[p.pos:], '\'')
+	if end < 0 {
+		return "", p.errorf("unterminated single-quoted value")
+	}
+
+	value := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+
+	return value, nil
+}
+
+func (p *envFileParser) doubleQuoted() (string, error) {
+	line := p.line
+	p.pos++
+
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		p.pos++
+
+		switch c {
+		case '"':
+			return sb.String(), nil
+		case '\n':
+			p.line++
+		case '\\':
+			if p.eof() {
+				continue
+			}
+			c = p.peek()
+			p.pos++
+
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case '"', '\\', '$':
+			default:
+				sb.WriteByte('\\')
+				if c == '\n' {
+					p.line++
+				}
+			}
+		}
+		sb.WriteByte(c)
+	}
+
+	// Report the line the value started on.
+	p.line = line
+
+	return "", p.errorf("unterminated double-quoted value")
+}
+
+func (p *envFileParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *envFileParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *envFileParser) skip(chars string) {
+	for !p.eof() && strings.IndexByte(chars, p.peek()) >= 0 {
+		if p.peek() == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+func (p *envFileParser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+func (p *envFileParser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.name != "" {
+		return fmt.Errorf("%w: %s:%d: %s", ErrEnvFile, p.name, p.line, msg)
+	}
+
+	return fmt.Errorf("%w: line %d: %s", ErrEnvFile, p.line, msg)
+}
+
+func isEnvKeyByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "empty",
+			src:  "",
+			want: []string{},
+		},
+		{
+			name: "comments and blank lines",
+			src:  "# comment\n\n   # indented comment\n\r\n",
+			want: []string{},
+		},
+		{
+			name: "unquoted",
+			src:  "FOO=bar\nHELLO = world  \nEMPTY=\nEQ=a=b\n",
+			want: []string{"FOO=bar", "HELLO=world", "EMPTY=", "EQ=a=b"},
+		},
+		{
+			name: "unquoted with comments",
+			src:  "FOO=bar # comment\nURL=http://host/#anchor\nTAB=x\t#c",
+			want: []string{"FOO=bar", "URL=http://host/#anchor", "TAB=x"},
+		},
+		{
+			name: "export prefix",
+			src:  "export FOO=bar\nexport\tBAZ=qux\nexport=yes\n",
+			want: []string{"FOO=bar", "BAZ=qux", "export=yes"},
+		},
+		{
+			name: "CRLF line endings",
+			src:  "FOO=bar\r\nBAZ='qux'\r\n",
+			want: []string{"FOO=bar", "BAZ=qux"},
+		},
+		{
+			name: "single-quoted",
+			src:  `FOO='bar # not a comment \n ${X}' # comment`,
+			want: []string{`FOO=bar # not a comment \n ${X}`},
+		},
+		{
+			name: "double-quoted",
+			src:  `FOO="a\nb\tc \"d\" \\ \$HOME \x" # comment`,
+			want: []string{"FOO=a\nb\tc \"d\" \\ $HOME \\x"},
+		},
+		{
+			name: "multi-line quoted",
+			src:  "A=\"line 1\nline 2\"\nB='line 3\nline 4'\nC=5\n",
+			want: []string{
+				"A=line 1\nline 2", "B=line 3\nline 4", "C=5",
+			},
+		},
+		{
+			name: "key characters",
+			src:  "a.b-c_D9=1\n",
+			want: []string{"a.b-c_D9=1"},
+		},
+		{
+			name:    "missing equals",
+			src:     "FOO=bar\nBAZ\n",
+			wantErr: `runner: env file: line 2: missing "=" after key "BAZ"`,
+		},
+		{
+			name:    "invalid key",
+			src:     "FOO=bar\n\n$FOO=baz\n",
+			wantErr: `runner: env file: line 3: invalid character '$' in key`,
+		},
+		{
+			name: "text after quoted value",
+			src:  `FOO="bar" baz`,
+			wantErr: `runner: env file: line 1: ` +
+				`unexpected 'b' after value of "FOO"`,
+		},
+		{
+			name: "unterminated single quote",
+			src:  "A=1\nFOO='bar\nBAZ=qux\n",
+			wantErr: "runner: env file: line 2: " +
+				"unterminated single-quoted value",
+		},
+		{
+			name: "unterminated double quote",
+			src:  "A=1\n\nFOO=\"bar\nBAZ=qux\n",
+			wantErr: "runner: env file: line 3: " +
+				"unterminated double-quoted value",
+		},
+		{
+			name:    "line numbers after multi-line value",
+			src:     "A=\"1\n2\n3\"\nB\n",
+			wantErr: `runner: env file: line 4: missing "=" after key "B"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnvFile(strings.NewReader(tt.src))
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrEnvFile)
+				assert.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, ".env")
+	err := os.WriteFile(path, []byte("FOO=bar\nBAZ='qux'\n"), 0o600)
+	require.NoError(t, err)
+
+	got, err := LoadEnvFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FOO=bar", "BAZ=qux"}, got)
+
+	invalid := filepath.Join(dir, "invalid.env")
+	err = os.WriteFile(invalid, []byte("FOO=bar\nBAZ\n"), 0o600)
+	require.NoError(t, err)
+
+	_, err = LoadEnvFile(invalid)
+	assert.EqualError(
+		t, err,
+		`runner: env file: `+invalid+`:2: missing "=" after key "BAZ"`,
+	)
+
+	_, err = LoadEnvFile(filepath.Join(dir, "missing.env"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+var ErrEnvProvider = fmt.Errorf("%w: env provider", Err)
+
+// EnvProvider provides environment variables which are resolved each time a
+// command is run, allowing values like secrets to be fetched lazily from an
+// external source, and to change over time without reconfiguring the runner.
+type EnvProvider interface {
+	// Resolve returns the environment variables to run a command with, each
+	// of the form "key=value". The context is the one the command is run
+	// with.
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// EnvProviderFunc is an adapter to allow the use of an ordinary function as an
+// EnvProvider.
+type EnvProviderFunc func(ctx context.Context) ([]string, error)
+
+var _ EnvProvider = EnvProviderFunc(nil)
+
+// Resolve calls f(ctx).
+func (f EnvProviderFunc) Resolve(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// ProvidedEnv is a Runner that wraps another Runner, and resolves Provider
+// each time a command is run, adding the variables it returns to the command.
+//
+// Unlike Local.EnvProvider, which sets the variables on the process Local
+// starts, like ssh or sudo, the variables are added as with WithEnv. They are
+// hence applied by Local, and by wrapper runners like Sudo and SSHCLI to the
+// command they wrap, regardless of how many runners are between them and
+// ProvidedEnv. Note that such wrappers pass env vars as arguments, which may
+// be visible to other users of the host via the process list. Env vars given
+// with WithEnv take precedence.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type ProvidedEnv struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Provider is resolved with the context of each command. If it returns
+	// an error, the command is not run. If not set, commands are run as is.
+	Provider EnvProvider
+}
+
+var _ Runner = &ProvidedEnv{}
+
+// Run calls RunContext with a background context.
+//
+// Will panic if Runner field is nil.
+func (r *ProvidedEnv) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext resolves Provider, and calls RunContext on the underlying Runner
+// with the variables it returns added to the command.
+//
+// Will panic if Runner field is nil.
+func (r *ProvidedEnv) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if r.Provider != nil {
+		env, err := r.Provider.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrEnvProvider, err)
+		}
+		if len(env) > 0 {
+			// Prepend so env given with WithEnv takes precedence.
+			ctx = context.WithValue(
+				ctx, callEnvKey{}, mergeEnv(env, callEnv(ctx)),
+			)
+		}
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *ProvidedEnv) Env(env ...string) {
+	r.Runner.Env(env...)
+}
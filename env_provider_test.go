@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type envProviderCtxKey struct{}
+
+func TestLocal_EnvProvider(t *testing.T) {
+	t.Setenv("RUNNER_TEST_INHERITED", "inherited")
+
+	tests := []struct {
+		name     string
+		env      []string
+		provided []string
+		opts     []RunOption
+		want     string
+	}{
+		{
+			name:     "provided only",
+			provided: []string{"SECRET=s3cr3t"},
+			want:     "s3cr3t inherited\n",
+		},
+		{
+			name:     "provided on top of env",
+			env:      []string{"SECRET=old", "OTHER=other"},
+			provided: []string{"SECRET=s3cr3t"},
+			want:     "s3cr3t other\n",
+		},
+		{
+			name:     "per-call env on top of provided",
+			provided: []string{"SECRET=s3cr3t"},
+			opts:     []RunOption{WithEnv("SECRET=call")},
+			want:     "call inherited\n",
+		},
+		{
+			name: "nothing provided",
+			env:  []string{"SECRET=env"},
+			want: "env\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			ctx := context.WithValue(
+				context.Background(), envProviderCtxKey{}, tt.name,
+			)
+
+			r := &Local{
+				EnvProvider: EnvProviderFunc(
+					func(ctx context.Context) ([]string, error) {
+						assert.Equal(t, tt.name, ctx.Value(envProviderCtxKey{}))
+
+						return tt.provided, nil
+					},
+				),
+			}
+			if tt.env != nil {
+				r.Env(tt.env...)
+			}
+
+			opts := append([]RunOption{WithStdout(&stdout)}, tt.opts...)
+			err := RunWith(
+				ctx, r, opts, "/bin/sh", "-c",
+				"echo $SECRET $OTHER $RUNNER_TEST_INHERITED",
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestLocal_EnvProvider_resolvedPerRun(t *testing.T) {
+	n := 0
+	r := &Local{
+		EnvProvider: EnvProviderFunc(
+			func(context.Context) ([]string, error) {
+				n++
+
+				return []string{"TOKEN=" + strconv.Itoa(n)}, nil
+			},
+		),
+	}
+
+	for _, want := range []string{"1\n", "2\n"} {
+		var stdout bytes.Buffer
+		err := r.Run(nil, &stdout, nil, "/bin/sh", "-c", "echo $TOKEN")
+		require.NoError(t, err)
+		assert.Equal(t, want, stdout.String())
+	}
+}
+
+func TestLocal_EnvProvider_error(t *testing.T) {
+	providerErr := errors.New("vault sealed")
+	var stdout bytes.Buffer
+	r := &Local{
+		EnvProvider: EnvProviderFunc(
+			func(context.Context) ([]string, error) {
+				return nil, providerErr
+			},
+		),
+	}
+
+	err := r.RunContext(
+		context.Background(), nil, &stdout, nil, "echo", "hello",
+	)
+
+	assert.EqualError(t, err, "runner: env provider: vault sealed")
+	assert.ErrorIs(t, err, ErrEnvProvider)
+	assert.ErrorIs(t, err, providerErr)
+	assert.Empty(t, stdout.String())
+}
+
+func TestProvidedEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		provided []string
+		err      error
+		opts     []RunOption
+		wantArgs []string
+		wantErr  error
+	}{
+		{
+			name:     "provided",
+			provided: []string{"SECRET=s3cr3t"},
+			wantArgs: []string{"-n", "SECRET=s3cr3t", "--", "deploy"},
+		},
+		{
+			name:     "per-call env on top of provided",
+			provided: []string{"SECRET=s3cr3t"},
+			opts:     []RunOption{WithEnv("SECRET=call")},
+			wantArgs: []string{"-n", "SECRET=call", "--", "deploy"},
+		},
+		{
+			name:     "nothing provided",
+			wantArgs: []string{"-n", "--", "deploy"},
+		},
+		{
+			name:    "error",
+			err:     errors.New("vault sealed"),
+			wantErr: ErrEnvProvider,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("sudo", nil, FakeResponse{})
+
+			r := &ProvidedEnv{
+				Runner: &Sudo{Runner: f},
+				Provider: EnvProviderFunc(
+					func(context.Context) ([]string, error) {
+						return tt.provided, tt.err
+					},
+				),
+			}
+			err := RunWith(context.Background(), r, tt.opts, "deploy")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, f.Calls())
+
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, f.Calls(), 1)
+			assert.Equal(t, tt.wantArgs, f.Calls()[0].Args)
+			assert.Empty(t, f.Calls()[0].Env)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEnvSet(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{
+			name: "empty",
+			env:  nil,
+			want: []string{},
+		},
+		{
+			name: "entries",
+			env:  []string{"foo=bar", "HELLO=WORLD"},
+			want: []string{"foo=bar", "HELLO=WORLD"},
+		},
+		{
+			name: "duplicate keys",
+			env:  []string{"foo=bar", "HELLO=WORLD", "foo=baz"},
+			want: []string{"foo=baz", "HELLO=WORLD"},
+		},
+		{
+			name: "entry without value",
+			env:  []string{"foo", "bar=a=b"},
+			want: []string{"foo=", "bar=a=b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnvSet(tt.env...)
+
+			assert.Equal(t, tt.want, e.Environ())
+		})
+	}
+}
+
+func TestEnvSet(t *testing.T) {
+	var e EnvSet
+
+	e.Setenv("foo", "bar")
+	e.Setenv("HELLO", "WORLD")
+	e.AppendEnv("API_KEY=12345", "foo=baz")
+	assert.Equal(
+		t, []string{"foo=baz", "HELLO=WORLD", "API_KEY=12345"}, e.Environ(),
+	)
+
+	v, ok := e.LookupEnv("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "baz", v)
+
+	e.Unsetenv("foo")
+	e.Unsetenv("nope")
+	assert.Equal(t, []string{"HELLO=WORLD", "API_KEY=12345"}, e.Environ())
+
+	v, ok = e.LookupEnv("foo")
+	assert.False(t, ok)
+	assert.Empty(t, v)
+
+	e.Setenv("EMPTY", "")
+	v, ok = e.LookupEnv("EMPTY")
+	assert.True(t, ok)
+	assert.Empty(t, v)
+}
+
+func TestEnvSet_Environ(t *testing.T) {
+	e := NewEnvSet("foo=bar")
+
+	env := e.Environ()
+	env[0] = "foo=modified"
+
+	assert.Equal(t, []string{"foo=bar"}, e.Environ())
+}
+
+func TestDedupEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{
+			name: "empty",
+			env:  nil,
+			want: nil,
+		},
+		{
+			name: "sorted by key",
+			env:  []string{"foo=bar", "HELLO=WORLD", "bar=baz"},
+			want: []string{"HELLO=WORLD", "bar=baz", "foo=bar"},
+		},
+		{
+			name: "last duplicate wins",
+			env:  []string{"foo=bar", "foo=baz", "HELLO=WORLD", "foo=qux"},
+			want: []string{"HELLO=WORLD", "foo=qux"},
+		},
+		{
+			name: "keys sharing a prefix",
+			env:  []string{"FOO_BAR=1", "FOO=2"},
+			want: []string{"FOO=2", "FOO_BAR=1"},
+		},
+		{
+			name: "entry without value",
+			env:  []string{"foo", "bar=a=b"},
+			want: []string{"bar=a=b", "foo="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := append([]string(nil), tt.env...)
+
+			got := DedupEnv(env...)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.env, env)
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package runner
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var Err = errors.New("runner")
+
+// ErrCommandNotFound is returned by Local when the command's executable could
+// not be found, allowing callers to distinguish a missing executable from a
+// command which ran and failed. It is combined with the underlying error from
+// os/exec, so errors.Is also matches exec.ErrNotFound where applicable.
+var ErrCommandNotFound = fmt.Errorf("%w: command not found", Err)
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// Event is a command lifecycle event emitted by RunEvents. It is one of
+// Started, StdoutLine, StderrLine, or Exited.
+type Event interface {
+	event()
+}
+
+// Started is emitted when the command process has started.
+type Started struct {
+	// PID is the process ID of the started process. When the command is run
+	// via a wrapper Runner, it is the process ID of the wrapping command, like
+	// sudo or ssh.
+	PID int
+}
+
+// StdoutLine is emitted for each line the command writes to stdout.
+type StdoutLine struct {
+	// Line is the line of output, excluding the trailing newline.
+	Line string
+}
+
+// StderrLine is emitted for each line the command writes to stderr.
+type StderrLine struct {
+	// Line is the line of output, excluding the trailing newline.
+	Line string
+}
+
+// Exited is always the last event emitted, once the command has completed.
+type Exited struct {
+	// Code is the exit code of the command. It is -1 if the command did not
+	// run, or was terminated by a signal.
+	Code int
+
+	// Duration is the time taken to run the command.
+	Duration time.Duration
+
+	// Err is the error returned by the Runner, if any.
+	Err error
+}
+
+func (Started) event()    {}
+func (StdoutLine) event() {}
+func (StderrLine) event() {}
+func (Exited) event()     {}
+
+// RunEvents runs the given command in the background via RunContext on r, and
+// returns a channel of events describing the command's lifecycle and output.
+// The channel is closed after the Exited event has been emitted.
+//
+// Events must be received until the channel is closed, as the command blocks
+// while writing output until the corresponding events have been received.
+//
+// The Started event is only emitted by runners which report when processes
+// start, like Local and any wrapper Runners around it.
+func RunEvents(
+	ctx context.Context,
+	r Runner,
+	stdin io.Reader,
+	command string,
+	args ...string,
+) <-chan Event {
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		ctx = withStartedFunc(ctx, func(pid int) {
+			events <- Started{PID: pid}
+		})
+
+		start := time.Now()
+		err := runLines(
+			ctx, r, stdin,
+			func(line string) { events <- StdoutLine{Line: line} },
+			func(line string) { events <- StderrLine{Line: line} },
+			command, args,
+		)
+		duration := time.Since(start)
+
+		events <- Exited{Code: ExitCode(err), Duration: duration, Err: err}
+	}()
+
+	return events
+}
+
+// ExitCode returns the exit code of a command based on the error returned when
+// running it. It returns 0 if err is nil, and -1 if err does not carry an exit
+// code, like when the command could not be started.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
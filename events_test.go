@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunEvents(t *testing.T) {
+	events := RunEvents(
+		context.Background(), New(), nil,
+		"sh", "-c", `echo out1; echo err1 >&2; printf out2; exit 3`,
+	)
+
+	var got []Event
+	for e := range events {
+		got = append(got, e)
+	}
+
+	require.Len(t, got, 5)
+
+	started, ok := got[0].(Started)
+	require.True(t, ok, "first event is not Started")
+	assert.Greater(t, started.PID, 0)
+
+	var stdout, stderr []string
+	for _, e := range got[1:4] {
+		switch e := e.(type) {
+		case StdoutLine:
+			stdout = append(stdout, e.Line)
+		case StderrLine:
+			stderr = append(stderr, e.Line)
+		default:
+			t.Fatalf("unexpected event: %#v", e)
+		}
+	}
+	assert.Equal(t, []string{"out1", "out2"}, stdout)
+	assert.Equal(t, []string{"err1"}, stderr)
+
+	exited, ok := got[4].(Exited)
+	require.True(t, ok, "last event is not Exited")
+	assert.Equal(t, 3, exited.Code)
+	assert.Greater(t, exited.Duration, time.Duration(0))
+	assert.EqualError(t, exited.Err, "sh: exit status 3")
+}
+
+func TestRunEvents_mock(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, gomock.Any(), gomock.Any(),
+		"zfs", []string{"list"},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = io.WriteString(stdout, "tank\ntank/data\n")
+
+		return errors.New("zfs: command not found")
+	})
+
+	var got []Event
+	for e := range RunEvents(ctx, r, nil, "zfs", "list") {
+		if exited, ok := e.(Exited); ok {
+			exited.Duration = 0
+			e = exited
+		}
+		got = append(got, e)
+	}
+
+	assert.Equal(t, []Event{
+		StdoutLine{Line: "tank"},
+		StdoutLine{Line: "tank/data"},
+		Exited{Code: -1, Err: errors.New("zfs: command not found")},
+	}, got)
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// StderrTailSize is the maximum number of bytes from the end of a command's
+// stderr output which are retained by ExitError.
+const StderrTailSize = 4 * 1024
+
+// ExitError is returned by Local when a command runs but does not complete
+// successfully. It unwraps to the underlying *exec.ExitError.
+type ExitError struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Stderr holds up to the last StderrTailSize bytes written to stderr by
+	// the command. It is populated when stderr was nil, or a writer other
+	// than an *os.File or the stdout writer.
+	Stderr []byte
+
+	// Err is the underlying error returned by os/exec.
+	Err *exec.ExitError
+
+	// stderrCaptured is true when Stderr was captured in place of a nil
+	// stderr writer, in which case it is included in the error message.
+	stderrCaptured bool
+}
+
+var _ error = &ExitError{}
+
+// Error returns the command and its exit status, e.g. "sh: exit status 1".
+//
+// When the command was run with a nil stderr, the captured tail of its stderr
+// output is appended, e.g. "sh: exit status 1: no such file", as the caller
+// otherwise has no way of seeing it.
+func (e *ExitError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Command, e.Err.Error())
+
+	if e.stderrCaptured {
+		if s := strings.TrimSpace(string(e.Stderr)); s != "" {
+			msg += ": " + s
+		}
+	}
+
+	return msg
+}
+
+// Unwrap returns the underlying *exec.ExitError.
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the command, or -1 if it was terminated
+// by a signal.
+func (e *ExitError) ExitCode() int {
+	return e.Err.ExitCode()
+}
+
+// tailBuffer is an io.Writer which retains only the last max bytes written to
+// it.
+type tailBuffer struct {
+	max int
+	buf []byte
+
+	// captured is true when the buffer is used in place of a nil writer.
+	captured bool
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if n >= b.max {
+		b.buf = append(b.buf[:0], p[n-b.max:]...)
+
+		return n, nil
+	}
+
+	if over := len(b.buf) + n - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	b.buf = append(b.buf, p...)
+
+	return n, nil
+}
+
+// Bytes returns a copy of the retained bytes.
+func (b *tailBuffer) Bytes() []byte {
+	return append([]byte(nil), b.buf...)
+}
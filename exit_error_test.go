@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailBuffer_Write(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		writes []string
+		want   string
+	}{
+		{
+			name:   "empty",
+			max:    8,
+			writes: []string{},
+			want:   "",
+		},
+		{
+			name:   "under max",
+			max:    8,
+			writes: []string{"foo", "bar"},
+			want:   "foobar",
+		},
+		{
+			name:   "exactly max",
+			max:    6,
+			writes: []string{"foo", "bar"},
+			want:   "foobar",
+		},
+		{
+			name:   "over max across writes",
+			max:    5,
+			writes: []string{"foo", "bar", "baz"},
+			want:   "arbaz",
+		},
+		{
+			name:   "single write over max",
+			max:    4,
+			writes: []string{"foo", "hello world"},
+			want:   "orld",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTailBuffer(tt.max)
+
+			for _, s := range tt.writes {
+				n, err := b.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+
+			assert.Equal(t, tt.want, string(b.Bytes()))
+		})
+	}
+}
+
+func TestLocal_Run_exitError(t *testing.T) {
+	tests := []struct {
+		name       string
+		stderr     bool
+		args       []string
+		wantErr    string
+		wantCode   int
+		wantArgs   []string
+		wantStderr string
+	}{
+		{
+			name:       "with stderr",
+			stderr:     true,
+			args:       []string{"-c", "echo 'Oh noes!' >&2; exit 3"},
+			wantErr:    "sh: exit status 3",
+			wantCode:   3,
+			wantArgs:   []string{"-c", "echo 'Oh noes!' >&2; exit 3"},
+			wantStderr: "Oh noes!\n",
+		},
+		{
+			name:       "nil stderr",
+			args:       []string{"-c", "echo 'Oh noes!' >&2; exit 4"},
+			wantErr:    "sh: exit status 4: Oh noes!",
+			wantCode:   4,
+			wantArgs:   []string{"-c", "echo 'Oh noes!' >&2; exit 4"},
+			wantStderr: "Oh noes!\n",
+		},
+		{
+			name:     "nil stderr without output",
+			args:     []string{"-c", "exit 5"},
+			wantErr:  "sh: exit status 5",
+			wantCode: 5,
+			wantArgs: []string{"-c", "exit 5"},
+		},
+		{
+			name:   "long stderr",
+			stderr: true,
+			args: []string{
+				"-c", "head -c 5000 /dev/zero | tr '\\0' x >&2; exit 1",
+			},
+			wantErr:  "sh: exit status 1",
+			wantCode: 1,
+			wantArgs: []string{
+				"-c", "head -c 5000 /dev/zero | tr '\\0' x >&2; exit 1",
+			},
+			wantStderr: strings.Repeat("x", StderrTailSize),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			r := &Local{}
+
+			var err error
+			if tt.stderr {
+				err = r.Run(nil, nil, &stderr, "sh", tt.args...)
+			} else {
+				err = r.Run(nil, nil, nil, "sh", tt.args...)
+			}
+
+			require.EqualError(t, err, tt.wantErr)
+
+			var exitErr *ExitError
+			require.True(t, errors.As(err, &exitErr))
+			assert.Equal(t, "sh", exitErr.Command)
+			assert.Equal(t, tt.wantArgs, exitErr.Args)
+			assert.Equal(t, tt.wantCode, exitErr.ExitCode())
+			assert.Equal(t, tt.wantStderr, string(exitErr.Stderr))
+
+			var execErr *exec.ExitError
+			require.True(t, errors.As(err, &execErr))
+			assert.Equal(t, tt.wantCode, execErr.ExitCode())
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var (
+	ErrExpect        = fmt.Errorf("%w: expect", Err)
+	ErrExpectTimeout = fmt.Errorf("%w: timed out", ErrExpect)
+	ErrExpectExited  = fmt.Errorf("%w: command exited", ErrExpect)
+)
+
+// DefaultExpectTimeout is the default value for Expect.Timeout.
+const DefaultExpectTimeout = 30 * time.Second
+
+// ExpectStep is a step of an Expect dialog, which waits for output matching
+// Pattern, and then sends a response.
+type ExpectStep struct {
+	// Pattern is matched against output written since the previous step's
+	// match. Use regexp.QuoteMeta to match literal text, like a prompt.
+	Pattern *regexp.Regexp
+
+	// Send is written to the command's stdin once Pattern matches. Include a
+	// trailing "\n" to submit a line.
+	Send string
+
+	// Timeout is the maximum time to wait for Pattern to match. Defaults to
+	// the Timeout of the Expect.
+	Timeout time.Duration
+}
+
+// ExpectError is returned by Expect when a step's pattern is not matched. It
+// matches ErrExpectTimeout or ErrExpectExited with errors.Is, and any error
+// returned by the command with errors.Is and errors.As.
+type ExpectError struct {
+	// Step is the index of the step whose pattern was not matched.
+	Step int
+
+	// Pattern is the pattern which was not matched.
+	Pattern string
+
+	// Output is the output written since the previous step's match, which
+	// the pattern did not match.
+	Output []byte
+
+	// Err is ErrExpectTimeout if the step timed out, or ErrExpectExited if
+	// the command exited first.
+	Err error
+
+	// RunErr is the error returned by the command, if any.
+	RunErr error
+}
+
+var _ error = &ExpectError{}
+
+func (e *ExpectError) Error() string {
+	msg := fmt.Sprintf(
+		"%s waiting for step %d pattern %q", e.Err, e.Step, e.Pattern,
+	)
+	if e.RunErr != nil {
+		msg += ": " + e.RunErr.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns Err, and RunErr.
+func (e *ExpectError) Unwrap() []error {
+	return []error{e.Err, e.RunErr}
+}
+
+// Expect automates interactive commands, by running them via RunContext on
+// Runner, and writing responses to their stdin as their output matches each
+// step's pattern, like the expect(1) tool.
+//
+// Interactive commands typically require a terminal, so Runner is usually a
+// *Local with PTY set, or a *SSHCLI allocating a remote terminal with "-tt".
+// With a PTY, stderr is merged into stdout, so prompts written to either are
+// matched.
+type Expect struct {
+	// Runner is the Runner to run commands with. If not set, running commands
+	// will cause a panic.
+	Runner Runner
+
+	// Steps are the steps of the dialog, in order.
+	Steps []ExpectStep
+
+	// Timeout is the maximum time to wait for each step's pattern to match,
+	// unless the step sets its own. Defaults to DefaultExpectTimeout.
+	Timeout time.Duration
+}
+
+// Run runs the given command, and responds to its output as described by
+// Steps. All output is also written to stdout, which may be nil.
+//
+// Once all steps have been completed, the command's stdin is closed, and Run
+// waits for the command to exit, returning its error. If a step's pattern is
+// not matched in time, the command is canceled, and a *ExpectError is
+// returned.
+func (e *Expect) Run(
+	ctx context.Context,
+	stdout io.Writer,
+	command string,
+	args ...string,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	out := &expectOutput{w: stdout, changed: make(chan struct{}, 1)}
+
+	// An *os.File is used for stdin, as Local passes it to the command
+	// directly. With an io.Pipe, Local would instead copy it to the command
+	// in a goroutine, which it waits for before returning, so the command
+	// exiting would go unnoticed until stdin is closed.
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	var runErr error
+	go func() {
+		defer close(done)
+
+		runErr = e.Runner.RunContext(ctx, pr, out, out, command, args...)
+
+		// Unblock any write of a response which will never be read.
+		_ = pr.Close()
+	}()
+
+	expectErr := e.dialog(out, pw, done)
+	if expectErr != nil {
+		cancel()
+	}
+	_ = pw.Close()
+	<-done
+
+	if expectErr != nil {
+		expectErr.RunErr = runErr
+
+		return expectErr
+	}
+
+	return runErr
+}
+
+func (e *Expect) dialog(
+	out *expectOutput,
+	stdin io.Writer,
+	done <-chan struct{},
+) *ExpectError {
+	for i, step := range e.Steps {
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = e.Timeout
+		}
+		if timeout <= 0 {
+			timeout = DefaultExpectTimeout
+		}
+
+		err := out.wait(step.Pattern, timeout, done)
+		if err != nil {
+			return &ExpectError{
+				Step:    i,
+				Pattern: step.Pattern.String(),
+				Output:  out.unmatched(),
+				Err:     err,
+			}
+		}
+
+		if step.Send != "" {
+			_, err = io.WriteString(stdin, step.Send)
+			if err != nil {
+				return &ExpectError{
+					Step:    i,
+					Pattern: step.Pattern.String(),
+					Err:     ErrExpectExited,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// expectOutput records the output of a command, so it can be matched against
+// patterns.
+type expectOutput struct {
+	w       io.Writer
+	changed chan struct{}
+
+	mu  sync.Mutex
+	buf []byte
+	pos int
+}
+
+func (o *expectOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf, p...)
+	select {
+	case o.changed <- struct{}{}:
+	default:
+	}
+
+	if o.w != nil {
+		return o.w.Write(p)
+	}
+
+	return len(p), nil
+}
+
+// wait waits for output after the previous match to match re, or returns an
+// error if timeout elapses or done is closed first.
+func (o *expectOutput) wait(
+	re *regexp.Regexp,
+	timeout time.Duration,
+	done <-chan struct{},
+) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		if o.match(re) {
+			return nil
+		}
+
+		select {
+		case <-o.changed:
+		case <-timer.C:
+			return ErrExpectTimeout
+		case <-done:
+			// Check output written just before the command exited.
+			if o.match(re) {
+				return nil
+			}
+
+			return ErrExpectExited
+		}
+	}
+}
+
+func (o *expectOutput) match(re *regexp.Regexp) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	loc := re.FindIndex(o.buf[o.pos:])
+	if loc == nil {
+		return false
+	}
+	o.pos += loc[1]
+
+	return true
+}
+
+func (o *expectOutput) unmatched() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]byte(nil), o.buf[o.pos:]...)
+}
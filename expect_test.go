@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// promptStub returns a Stub which prompts for a username and password, and
+// prints them once both are given.
+func promptStub() *Stub {
+	return &Stub{Fallback: func(
+		_ context.Context,
+		stdin io.Reader,
+		stdout, stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		in := bufio.NewReader(stdin)
+
+		_, _ = io.WriteString(stdout, "Username: ")
+		user, err := in.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		_, _ = io.WriteString(stderr, "Password: ")
+		pass, err := in.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		_, _ = io.WriteString(stdout, "welcome "+user+"pass "+pass)
+
+		return nil
+	}}
+}
+
+func TestExpect_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		expect     Expect
+		wantStdout string
+		wantErr    string
+		wantStep   int
+		wantOutput string
+	}{
+		{
+			name: "all steps",
+			expect: Expect{Steps: []ExpectStep{
+				{
+					Pattern: regexp.MustCompile(`Username: $`),
+					Send:    "admin\n",
+				},
+				{
+					Pattern: regexp.MustCompile(`(?i)password:`),
+					Send:    "secret\n",
+				},
+				{Pattern: regexp.MustCompile(`welcome \w+`)},
+			}},
+			wantStdout: "Username: Password: welcome admin\npass secret\n",
+		},
+		{
+			name: "timeout",
+			expect: Expect{
+				Steps: []ExpectStep{
+					{
+						Pattern: regexp.MustCompile(`Username:`),
+						Send:    "admin\n",
+					},
+					{
+						Pattern: regexp.MustCompile(`Token:`),
+						Timeout: 50 * time.Millisecond,
+					},
+				},
+				Timeout: time.Minute,
+			},
+			wantStdout: "Username: Password: ",
+			wantErr: `runner: expect: timed out waiting for step 1 ` +
+				`pattern "Token:": EOF`,
+			wantStep:   1,
+			wantOutput: " Password: ",
+		},
+		{
+			name: "exited",
+			expect: Expect{Steps: []ExpectStep{
+				{Pattern: regexp.MustCompile(`Username:`), Send: "a\n"},
+				{Pattern: regexp.MustCompile(`Password:`), Send: "b\n"},
+				{Pattern: regexp.MustCompile(`Token:`)},
+			}},
+			wantStdout: "Username: Password: welcome a\npass b\n",
+			wantErr: `runner: expect: command exited waiting for step 2 ` +
+				`pattern "Token:"`,
+			wantStep:   2,
+			wantOutput: " welcome a\npass b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := tt.expect
+			e.Runner = promptStub()
+
+			var stdout bytes.Buffer
+			err := e.Run(context.Background(), &stdout, "login")
+
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrExpect)
+
+			var expectErr *ExpectError
+			require.ErrorAs(t, err, &expectErr)
+			assert.Equal(t, tt.wantStep, expectErr.Step)
+			assert.Equal(t, tt.wantOutput, string(expectErr.Output))
+		})
+	}
+}
+
+func TestExpect_Run_pty(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("PTYs are not supported on Windows")
+	}
+
+	e := &Expect{
+		Runner: &Local{PTY: &PTY{}},
+		Steps: []ExpectStep{
+			{
+				Pattern: regexp.MustCompile(`Continue\? \[y/N\] `),
+				Send:    "y\n",
+			},
+			{Pattern: regexp.MustCompile(`done`)},
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	var stdout bytes.Buffer
+	err := e.Run(
+		context.Background(), &stdout, "sh", "-c",
+		`test -t 0 || exit 9; printf 'Continue? [y/N] '; read -r a; `+
+			`[ "$a" = y ] && echo done`,
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Continue? [y/N] y\r\ndone\r\n", stdout.String())
+}
+
+func TestExpect_Run_exitedWithoutPTY(t *testing.T) {
+	e := &Expect{
+		Runner: &Local{},
+		Steps: []ExpectStep{
+			{Pattern: regexp.MustCompile(`never`), Timeout: 5 * time.Second},
+		},
+	}
+
+	start := time.Now()
+	err := e.Run(context.Background(), nil, "sh", "-c", "echo hi; exit 3")
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.ErrorIs(t, err, ErrExpectExited)
+	assert.Equal(t, 3, ExitCode(err))
+}
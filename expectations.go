@@ -0,0 +1,350 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrExpectations      = fmt.Errorf("%w: expectations", Err)
+	ErrUnexpectedCommand = fmt.Errorf(
+		"%w: unexpected command", ErrExpectations,
+	)
+)
+
+// ExpectT is an interface that describes the *testing.T methods needed by the
+// Expectations runner implementation.
+type ExpectT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Matcher matches a single string value, like a command argument.
+type Matcher interface {
+	// Match reports if s is matched.
+	Match(s string) bool
+
+	// String describes what is matched, for use in failure messages.
+	String() string
+}
+
+type matcher struct {
+	match func(s string) bool
+	desc  string
+}
+
+func (m *matcher) Match(s string) bool {
+	return m.match(s)
+}
+
+func (m *matcher) String() string {
+	return m.desc
+}
+
+// MatchEq returns a Matcher which matches strings equal to want.
+func MatchEq(want string) Matcher {
+	return &matcher{
+		match: func(s string) bool { return s == want },
+		desc:  quoteWord(want),
+	}
+}
+
+// MatchAny returns a Matcher which matches any string.
+func MatchAny() Matcher {
+	return &matcher{
+		match: func(string) bool { return true },
+		desc:  "<any>",
+	}
+}
+
+// MatchGlob returns a Matcher which matches strings against a glob pattern,
+// as supported by path.Match.
+func MatchGlob(pattern string) Matcher {
+	return &matcher{
+		match: func(s string) bool {
+			ok, _ := path.Match(pattern, s)
+
+			return ok
+		},
+		desc: "<glob " + pattern + ">",
+	}
+}
+
+// MatchRegexp returns a Matcher which matches strings against the regular
+// expression re.
+func MatchRegexp(re *regexp.Regexp) Matcher {
+	return &matcher{
+		match: re.MatchString,
+		desc:  "<regexp " + re.String() + ">",
+	}
+}
+
+// Expectation is a command expected to be run via an Expectations runner. It
+// is created by Expectations.Expect and Expectations.ExpectMatch, and further
+// configured with its methods.
+type Expectation struct {
+	command string
+	args    []Matcher
+	env     []string
+	stdin   Matcher
+	resp    FakeResponse
+}
+
+// WithEnv requires the given "key=value" entries to be present in the
+// environment set on the Expectations runner via Env, or given with WithEnv.
+func (exp *Expectation) WithEnv(env ...string) *Expectation {
+	exp.env = append(exp.env, env...)
+
+	return exp
+}
+
+// WithStdin requires the stdin data to match m.
+func (exp *Expectation) WithStdin(m Matcher) *Expectation {
+	exp.stdin = m
+
+	return exp
+}
+
+// Return sets the response written and returned when the expected command is
+// run. By default the command succeeds with no output.
+func (exp *Expectation) Return(resp FakeResponse) *Expectation {
+	exp.resp = resp
+
+	return exp
+}
+
+func (exp *Expectation) match(call *FakeCall) bool {
+	if call.Command != exp.command || len(call.Args) != len(exp.args) {
+		return false
+	}
+	for i, m := range exp.args {
+		if !m.Match(call.Args[i]) {
+			return false
+		}
+	}
+
+	for _, want := range exp.env {
+		found := false
+		for _, entry := range call.Env {
+			if entry == want {
+				found = true
+
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if exp.stdin != nil && !exp.stdin.Match(string(call.Stdin)) {
+		return false
+	}
+
+	return true
+}
+
+// String describes the expectation, like "zfs list <any> [env FOO=bar]".
+func (exp *Expectation) String() string {
+	words := []string{quoteWord(exp.command)}
+	for _, m := range exp.args {
+		words = append(words, m.String())
+	}
+
+	var extra []string
+	if len(exp.env) > 0 {
+		extra = append(extra, "env "+quoteWords(exp.env))
+	}
+	if exp.stdin != nil {
+		extra = append(extra, "stdin "+exp.stdin.String())
+	}
+	if len(extra) > 0 {
+		words = append(words, "["+strings.Join(extra, ", ")+"]")
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Expectations is a Runner for tests which does not run commands, but
+// verifies that the commands run match those declared with Expect and
+// ExpectMatch, in order. Each expected command returns the response given to
+// its Return method.
+//
+// Unexpected commands are reported via the Errorf method of T, and return an
+// error wrapping ErrUnexpectedCommand. Expected commands which were not run
+// are reported by Verify, which is called automatically at the end of the
+// test if T has a Cleanup method, as *testing.T does.
+//
+// Stdin is always read to completion. Expectations is safe for concurrent
+// use, though commands are still expected in the declared order.
+type Expectations struct {
+	t ExpectT
+
+	mu    sync.Mutex
+	exps  []*Expectation
+	next  int
+	env   []string
+	calls []FakeCall
+}
+
+var _ Runner = &Expectations{}
+
+// NewExpectations returns a new Expectations runner which reports failures to
+// t.
+func NewExpectations(t ExpectT) *Expectations {
+	e := &Expectations{t: t}
+
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(e.Verify)
+	}
+
+	return e
+}
+
+// Expect declares that command is expected to be run next with exactly the
+// given arguments.
+func (e *Expectations) Expect(command string, args ...string) *Expectation {
+	matchers := make([]Matcher, 0, len(args))
+	for _, arg := range args {
+		matchers = append(matchers, MatchEq(arg))
+	}
+
+	return e.ExpectMatch(command, matchers...)
+}
+
+// ExpectMatch declares that command is expected to be run next with arguments
+// matching the given matchers, one per argument.
+func (e *Expectations) ExpectMatch(
+	command string,
+	args ...Matcher,
+) *Expectation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exp := &Expectation{command: command, args: args}
+	e.exps = append(e.exps, exp)
+
+	return exp
+}
+
+// Verify reports any expected commands which have not been run via the
+// Errorf method of T.
+func (e *Expectations) Verify() {
+	e.t.Helper()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, exp := range e.exps[e.next:] {
+		e.t.Errorf("%s: missing command: %s", ErrExpectations, exp)
+	}
+}
+
+// Calls returns the commands which have been run, in order, including any
+// unexpected ones.
+func (e *Expectations) Calls() []FakeCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	calls := make([]FakeCall, len(e.calls))
+	copy(calls, e.calls)
+
+	return calls
+}
+
+// Run verifies the command is the next one expected, and writes the expected
+// response.
+func (e *Expectations) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	e.t.Helper()
+
+	return e.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext verifies the command is the next one expected, and writes the
+// expected response.
+func (e *Expectations) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	e.t.Helper()
+
+	call := FakeCall{Command: command, Args: copyStrings(args)}
+	if stdin != nil {
+		var err error
+		call.Stdin, err = io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	exp, err := e.call(ctx, &call)
+	if err != nil {
+		return err
+	}
+
+	return writeFakeResponse(stdout, stderr, command, exp.resp)
+}
+
+func (e *Expectations) call(
+	ctx context.Context,
+	call *FakeCall,
+) (*Expectation, error) {
+	e.t.Helper()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	call.Env = mergeEnv(copyStrings(e.env), callEnv(ctx))
+	e.calls = append(e.calls, *call)
+
+	cmdline := formatCmdline(call.Command, call.Args)
+
+	if e.next >= len(e.exps) {
+		e.t.Errorf(
+			"%s: %s; no more commands expected",
+			ErrUnexpectedCommand, cmdline,
+		)
+
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedCommand, cmdline)
+	}
+
+	exp := e.exps[e.next]
+	if !exp.match(call) {
+		e.t.Errorf(
+			"%s: %s; expected command %d: %s\n\tenv: %s\n\tstdin: %s",
+			ErrUnexpectedCommand, cmdline, e.next+1, exp,
+			strings.Join(call.Env, " "), strconv.Quote(string(call.Stdin)),
+		)
+
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedCommand, cmdline)
+	}
+	e.next++
+
+	return exp, nil
+}
+
+// Env sets the environment variables which commands are matched against.
+func (e *Expectations) Env(env ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.env = copyStrings(env)
+}
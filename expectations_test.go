@@ -0,0 +1,186 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeExpectT struct {
+	Errors   []string
+	cleanups []func()
+}
+
+func (f *fakeExpectT) Helper() {}
+
+func (f *fakeExpectT) Errorf(format string, args ...interface{}) {
+	f.Errors = append(f.Errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeExpectT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func (f *fakeExpectT) runCleanups() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestExpectations(t *testing.T) {
+	ft := &fakeExpectT{}
+	e := NewExpectations(ft)
+	e.Expect("zfs", "list", "-H").Return(FakeResponse{Stdout: "tank\n"})
+	e.ExpectMatch("zfs", MatchEq("snapshot"), MatchGlob("tank@*")).
+		WithEnv("TZ=UTC")
+	e.ExpectMatch("zfs", MatchEq("receive"), MatchAny()).
+		WithStdin(MatchRegexp(regexp.MustCompile(`^stream`))).
+		Return(FakeResponse{Stderr: "failed\n", ExitCode: 2})
+	e.Env("TZ=UTC")
+
+	var stdout, stderr bytes.Buffer
+	err := e.Run(nil, &stdout, nil, "zfs", "list", "-H")
+	require.NoError(t, err)
+	assert.Equal(t, "tank\n", stdout.String())
+
+	err = e.RunContext(
+		context.Background(), nil, nil, nil,
+		"zfs", "snapshot", "tank@2023",
+	)
+	require.NoError(t, err)
+
+	err = e.Run(
+		strings.NewReader("stream data"), nil, &stderr,
+		"zfs", "receive", "tank/copy",
+	)
+	assert.EqualError(t, err, "zfs: exit status 2")
+	assert.Equal(t, "failed\n", stderr.String())
+
+	ft.runCleanups()
+	assert.Empty(t, ft.Errors)
+	assert.Len(t, e.Calls(), 3)
+}
+
+func TestExpectations_unexpected(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(e *Expectations)
+		run        func(e *Expectations) error
+		wantErr    string
+		wantErrors []string
+	}{
+		{
+			name: "wrong args",
+			setup: func(e *Expectations) {
+				e.Expect("zfs", "list")
+			},
+			run: func(e *Expectations) error {
+				return e.Run(nil, nil, nil, "zfs", "destroy", "tank")
+			},
+			wantErr: "runner: expectations: unexpected command: " +
+				"zfs destroy tank",
+			wantErrors: []string{
+				"runner: expectations: unexpected command: " +
+					"zfs destroy tank; expected command 1: zfs list\n" +
+					"\tenv: \n\tstdin: \"\"",
+				"runner: expectations: missing command: zfs list",
+			},
+		},
+		{
+			name: "env mismatch",
+			setup: func(e *Expectations) {
+				e.Expect("date").WithEnv("TZ=UTC")
+			},
+			run: func(e *Expectations) error {
+				ctx := withCallEnv(
+					context.Background(), []string{"TZ=Europe/London"},
+				)
+
+				return e.RunContext(ctx, nil, nil, nil, "date")
+			},
+			wantErr: "runner: expectations: unexpected command: date",
+			wantErrors: []string{
+				"runner: expectations: unexpected command: date; " +
+					"expected command 1: date [env TZ=UTC]\n" +
+					"\tenv: TZ=Europe/London\n\tstdin: \"\"",
+				"runner: expectations: missing command: date [env TZ=UTC]",
+			},
+		},
+		{
+			name: "stdin mismatch",
+			setup: func(e *Expectations) {
+				e.Expect("cat").WithStdin(MatchEq("hello world"))
+			},
+			run: func(e *Expectations) error {
+				return e.Run(strings.NewReader("bye"), nil, nil, "cat")
+			},
+			wantErr: "runner: expectations: unexpected command: cat",
+			wantErrors: []string{
+				"runner: expectations: unexpected command: cat; " +
+					"expected command 1: cat [stdin 'hello world']\n" +
+					"\tenv: \n\tstdin: \"bye\"",
+				"runner: expectations: missing command: " +
+					"cat [stdin 'hello world']",
+			},
+		},
+		{
+			name:  "no more commands",
+			setup: func(e *Expectations) {},
+			run: func(e *Expectations) error {
+				return e.Run(nil, nil, nil, "rm", "-rf", "/srv/my data")
+			},
+			wantErr: "runner: expectations: unexpected command: " +
+				"rm -rf '/srv/my data'",
+			wantErrors: []string{
+				"runner: expectations: unexpected command: " +
+					"rm -rf '/srv/my data'; no more commands expected",
+			},
+		},
+		{
+			name: "missing",
+			setup: func(e *Expectations) {
+				e.Expect("uptime")
+				e.ExpectMatch("ls", MatchAny())
+			},
+			run: func(e *Expectations) error {
+				return e.Run(nil, nil, nil, "uptime")
+			},
+			wantErrors: []string{
+				"runner: expectations: missing command: ls <any>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeExpectT{}
+			e := NewExpectations(ft)
+			tt.setup(e)
+
+			err := tt.run(e)
+			ft.runCleanups()
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrUnexpectedCommand)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantErrors, ft.Errors)
+		})
+	}
+}
+
+func TestExpectations_testingT(t *testing.T) {
+	e := NewExpectations(t)
+	e.Expect("echo", "hello").Return(FakeResponse{Stdout: "hello\n"})
+
+	out, err := Output(context.Background(), e, "echo", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", out)
+}
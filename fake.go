@@ -0,0 +1,219 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrFake           = fmt.Errorf("%w: fake", Err)
+	ErrNoFakeResponse = fmt.Errorf("%w: no response", ErrFake)
+)
+
+// FakeResponse is a canned response returned by a Fake runner.
+type FakeResponse struct {
+	// Stdout is written to stdout.
+	Stdout string
+
+	// Stderr is written to stderr.
+	Stderr string
+
+	// ExitCode, when non-zero, causes a *FakeExitError with the exit code to
+	// be returned.
+	ExitCode int
+
+	// Err, when set, is returned in place of any *FakeExitError.
+	Err error
+}
+
+// FakeCall is a command run via a Fake runner.
+type FakeCall struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Env is the environment variables set on the Fake runner via Env,
+	// followed by any given with WithEnv.
+	Env []string
+
+	// Stdin holds the data read from stdin.
+	Stdin []byte
+}
+
+// FakeExitError is returned by Fake for responses with a non-zero ExitCode.
+type FakeExitError struct {
+	// Command is the command which was run.
+	Command string
+
+	// Code is the exit code of the response.
+	Code int
+}
+
+var _ error = &FakeExitError{}
+
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("%s: exit status %d", e.Command, e.Code)
+}
+
+// ExitCode returns the exit code of the response.
+func (e *FakeExitError) ExitCode() int {
+	return e.Code
+}
+
+// Fake is a Runner which does not run commands, but instead returns canned
+// responses registered with Respond, and records each call so it can be
+// inspected with Calls. It is intended as a lighter weight alternative to the
+// gomock based MockRunner for tests.
+//
+// Commands which do not match any registered response return an error
+// wrapping ErrNoFakeResponse.
+//
+// Stdin is always read to completion. Fake is safe for concurrent use, and its
+// zero value is ready to use.
+type Fake struct {
+	mu        sync.Mutex
+	responses []fakeResponse
+	calls     []FakeCall
+	env       []string
+}
+
+type fakeResponse struct {
+	rule PolicyRule
+	resp FakeResponse
+}
+
+var _ Runner = &Fake{}
+
+// Respond registers resp to be returned for commands matching command and
+// args. As with PolicyRule, command and args are glob patterns as supported by
+// path.Match, with args matched against the leading arguments of commands. So
+// Respond("zfs", nil, resp) matches all zfs commands.
+//
+// When multiple responses match a command, the most recently registered one
+// is used, allowing tests to override earlier defaults.
+func (f *Fake) Respond(command string, args []string, resp FakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, fakeResponse{
+		rule: PolicyRule{Command: command, Args: copyStrings(args)},
+		resp: resp,
+	})
+}
+
+// Calls returns the commands which have been run, in order.
+func (f *Fake) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	calls := make([]FakeCall, len(f.calls))
+	copy(calls, f.calls)
+
+	return calls
+}
+
+// Run records the call, and writes the matching response.
+func (f *Fake) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return f.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext records the call, and writes the matching response. If ctx is
+// done, its error is returned without recording the call.
+func (f *Fake) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	call := FakeCall{Command: command, Args: copyStrings(args)}
+	if stdin != nil {
+		call.Stdin, err = io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	resp, ok := f.call(ctx, call)
+	if !ok {
+		cmdline := strings.Join(append([]string{command}, args...), " ")
+
+		return fmt.Errorf("%w: %s", ErrNoFakeResponse, cmdline)
+	}
+
+	return writeFakeResponse(stdout, stderr, command, resp)
+}
+
+// writeFakeResponse writes the output of resp to stdout and stderr, and
+// returns its error, if any.
+func writeFakeResponse(
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	resp FakeResponse,
+) error {
+	if stdout != nil && resp.Stdout != "" {
+		_, err := io.WriteString(stdout, resp.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	if stderr != nil && resp.Stderr != "" {
+		_, err := io.WriteString(stderr, resp.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case resp.Err != nil:
+		return resp.Err
+	case resp.ExitCode != 0:
+		return &FakeExitError{Command: command, Code: resp.ExitCode}
+	}
+
+	return nil
+}
+
+func (f *Fake) call(ctx context.Context, call FakeCall) (FakeResponse, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	call.Env = mergeEnv(copyStrings(f.env), callEnv(ctx))
+	f.calls = append(f.calls, call)
+
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		if f.responses[i].rule.Match(call.Command, call.Args) {
+			return f.responses[i].resp, true
+		}
+	}
+
+	return FakeResponse{}, false
+}
+
+// Env sets the environment variables recorded in subsequent calls.
+func (f *Fake) Env(env ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.env = copyStrings(env)
+}
@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFake_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	f := &Fake{}
+	f.Respond("zfs", nil, FakeResponse{Stdout: "default\n"})
+	f.Respond("zfs", []string{"list"}, FakeResponse{Stdout: "tank\n"})
+	f.Respond("zfs", []string{"destroy", "*"}, FakeResponse{
+		Stderr:   "dataset is busy\n",
+		ExitCode: 1,
+	})
+	f.Respond("rm", []string{"-rf"}, FakeResponse{Err: errBoom})
+
+	tests := []struct {
+		name       string
+		command    string
+		args       []string
+		wantStdout string
+		wantStderr string
+		wantCode   int
+		wantErr    string
+	}{
+		{
+			name:       "exact args",
+			command:    "zfs",
+			args:       []string{"list"},
+			wantStdout: "tank\n",
+		},
+		{
+			name:       "leading args",
+			command:    "zfs",
+			args:       []string{"list", "-H"},
+			wantStdout: "tank\n",
+		},
+		{
+			name:       "fallback",
+			command:    "zfs",
+			args:       []string{"get", "all"},
+			wantStdout: "default\n",
+		},
+		{
+			name:       "exit code",
+			command:    "zfs",
+			args:       []string{"destroy", "tank"},
+			wantStderr: "dataset is busy\n",
+			wantCode:   1,
+			wantErr:    "zfs: exit status 1",
+		},
+		{
+			name:     "error",
+			command:  "rm",
+			args:     []string{"-rf", "/srv/data"},
+			wantCode: -1,
+			wantErr:  "boom",
+		},
+		{
+			name:     "no response",
+			command:  "zpool",
+			args:     []string{"list"},
+			wantCode: -1,
+			wantErr:  "runner: fake: no response: zpool list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := f.Run(nil, &stdout, &stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCode, ExitCode(err))
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+
+	assert.Len(t, f.Calls(), len(tests))
+}
+
+func TestFake_RunContext(t *testing.T) {
+	f := &Fake{}
+	f.Respond("cat", nil, FakeResponse{Stdout: "hello"})
+	f.Env("FOO=bar")
+
+	ctx := withCallEnv(context.Background(), []string{"BAR=baz"})
+	var stdout bytes.Buffer
+	err := f.RunContext(ctx, strings.NewReader("hi"), &stdout, nil, "cat")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", stdout.String())
+
+	err = f.RunContext(ctx, nil, nil, nil, "cat", "-n")
+	require.NoError(t, err)
+
+	assert.Equal(t, []FakeCall{
+		{
+			Command: "cat",
+			Env:     []string{"FOO=bar", "BAR=baz"},
+			Stdin:   []byte("hi"),
+		},
+		{
+			Command: "cat",
+			Args:    []string{"-n"},
+			Env:     []string{"FOO=bar", "BAR=baz"},
+		},
+	}, f.Calls())
+
+	cctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = f.RunContext(cctx, nil, nil, nil, "cat")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, f.Calls(), 2)
+}
+
+func TestFake_override(t *testing.T) {
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	assert.NoError(t, f.Run(nil, nil, nil, "true"))
+	assert.EqualError(t, f.Run(nil, nil, nil, "false"), "false: exit status 1")
+
+	f.Respond("false", nil, FakeResponse{})
+	assert.NoError(t, f.Run(nil, nil, nil, "false"))
+}
+
+func TestFake_Calls(t *testing.T) {
+	f := &Fake{}
+	f.Respond("echo", nil, FakeResponse{})
+
+	args := []string{"hello"}
+	require.NoError(t, f.Run(nil, nil, nil, "echo", args...))
+	args[0] = "changed"
+
+	calls := f.Calls()
+	calls[0].Command = "changed"
+
+	assert.Equal(
+		t, []FakeCall{{Command: "echo", Args: []string{"hello"}}}, f.Calls(),
+	)
+}
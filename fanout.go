@@ -0,0 +1,295 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFanOut is the error matched by errors.Is for errors returned by FanOut
+// when the command fails on one or more hosts.
+var ErrFanOut = fmt.Errorf("%w: fan-out", Err)
+
+// FanOutHost is a host FanOut runs commands on.
+type FanOutHost struct {
+	// Name identifies the host in prefixed output, results, and errors.
+	Name string
+
+	// Runner is the Runner to run commands on the host with, typically a
+	// *SSHCLI.
+	Runner Runner
+}
+
+// FanOutResult is the outcome of a command run on a single host by FanOut.
+type FanOutResult struct {
+	// Host is the name of the host.
+	Host string
+
+	// Transcript is the output of the command on the host, without prefixes.
+	// It is only set when FanOut.Collect is true.
+	Transcript *Transcript
+
+	// ExitCode is the exit code of the command, or -1 if it failed without an
+	// exit code.
+	ExitCode int
+
+	// Duration is the wall-clock time taken to run the command.
+	Duration time.Duration
+
+	// Err is the error returned by the command on the host, if any.
+	Err error
+}
+
+// FanOutError is returned by FanOut when the command fails on one or more
+// hosts. It matches ErrFanOut with errors.Is, and the errors of each failed
+// host with errors.Is and errors.As.
+type FanOutError struct {
+	// Failed are the results of the hosts on which the command failed, in the
+	// order of FanOut.Hosts.
+	Failed []*FanOutResult
+
+	// Total is the number of hosts the command was run on.
+	Total int
+}
+
+var _ error = &FanOutError{}
+
+func (e *FanOutError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, res := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", res.Host, res.Err))
+	}
+
+	return fmt.Sprintf(
+		"%s: failed on %d of %d hosts: %s",
+		ErrFanOut.Error(), len(e.Failed), e.Total, strings.Join(msgs, "; "),
+	)
+}
+
+// Unwrap returns ErrFanOut, and the errors of the failed hosts.
+func (e *FanOutError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	errs = append(errs, ErrFanOut)
+	for _, res := range e.Failed {
+		errs = append(errs, res.Err)
+	}
+
+	return errs
+}
+
+// FanOut is a Runner which runs each command on several hosts concurrently,
+// each with its own Runner.
+//
+// Output from all hosts is written to the given stdout and stderr writers,
+// with each line prefixed with the name of the host it came from. Lines from
+// different hosts are never interleaved mid-line, and a final line lacking a
+// trailing newline has one added. When Merge is set, or the same writer is
+// given for both, stdout and stderr lines are merged into a single stream.
+// When Collect is set, the unprefixed output of each host is also recorded,
+// and returned by RunAll.
+//
+// If stdin is not nil, it is read into memory in full, and given to the
+// command on every host. Calls to Env are passed to the Runner of every host.
+type FanOut struct {
+	// Hosts are the hosts to run commands on.
+	Hosts []FanOutHost
+
+	// Concurrency is the maximum number of hosts a command is run on at once.
+	// When zero or negative, commands are run on all hosts at once.
+	Concurrency int
+
+	// Prefix returns the prefix for lines of output from the named host.
+	// Defaults to DefaultFanOutPrefix.
+	Prefix func(host string) string
+
+	// Merge causes lines written to stderr to be written to the stdout writer,
+	// giving a single merged view of all output. When the stdout writer is
+	// nil, all output is merged into the stderr writer instead.
+	Merge bool
+
+	// Collect causes the output of each host to be recorded into the
+	// Transcript of its FanOutResult.
+	Collect bool
+}
+
+var _ Runner = &FanOut{}
+
+// DefaultFanOutPrefix returns "[host] ", and is the default prefix for lines
+// of output from a host.
+func DefaultFanOutPrefix(host string) string {
+	return "[" + host + "] "
+}
+
+// Run calls RunAll with a background context, running the command on all
+// hosts.
+//
+// Will panic if the Runner of any host is nil.
+func (r *FanOut) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	_, err := r.RunAll(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+
+	return err
+}
+
+// RunContext calls RunAll, running the command on all hosts.
+//
+// Will panic if the Runner of any host is nil.
+func (r *FanOut) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	_, err := r.RunAll(ctx, stdin, stdout, stderr, command, args...)
+
+	return err
+}
+
+// RunAll runs the given command via RunContext on the Runner of every host,
+// and returns a result for each host, in the order of Hosts. If the command
+// fails on any host, a *FanOutError is returned alongside the results.
+//
+// Will panic if the Runner of any host is nil.
+func (r *FanOut) RunAll(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) ([]*FanOutResult, error) {
+	var input []byte
+	if stdin != nil {
+		var err error
+		input, err = io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Merge && stdout == nil {
+		stdout = stderr
+	}
+
+	var outSync, errSync *SyncWriter
+	if stdout != nil {
+		outSync = NewSyncWriter(stdout)
+	}
+	switch {
+	case r.Merge || sameWriter(stdout, stderr):
+		errSync = outSync
+	case stderr != nil:
+		errSync = NewSyncWriter(stderr)
+	}
+
+	workers := r.Concurrency
+	if workers <= 0 {
+		workers = len(r.Hosts)
+	}
+	sem := make(chan struct{}, workers)
+
+	results := make([]*FanOutResult, len(r.Hosts))
+	var wg sync.WaitGroup
+	for i, host := range r.Hosts {
+		results[i] = &FanOutResult{Host: host.Name}
+		if r.Collect {
+			results[i].Transcript = &Transcript{}
+		}
+
+		var in io.Reader
+		if stdin != nil {
+			in = bytes.NewReader(input)
+		}
+
+		wg.Add(1)
+		go func(host FanOutHost, res *FanOutResult) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			r.run(ctx, host, res, in, outSync, errSync, command, args)
+		}(host, results[i])
+	}
+	wg.Wait()
+
+	ferr := &FanOutError{Total: len(r.Hosts)}
+	for _, res := range results {
+		if res.Err != nil {
+			ferr.Failed = append(ferr.Failed, res)
+		}
+	}
+	if len(ferr.Failed) > 0 {
+		return results, ferr
+	}
+
+	return results, nil
+}
+
+// Env sets the environment variables for the Runner of every host.
+func (r *FanOut) Env(env ...string) {
+	for _, host := range r.Hosts {
+		host.Runner.Env(env...)
+	}
+}
+
+func (r *FanOut) run(
+	ctx context.Context,
+	host FanOutHost,
+	res *FanOutResult,
+	stdin io.Reader,
+	outSync *SyncWriter,
+	errSync *SyncWriter,
+	command string,
+	args []string,
+) {
+	prefix := r.Prefix
+	if prefix == nil {
+		prefix = DefaultFanOutPrefix
+	}
+
+	var stdout, stderr io.Writer
+	var lineWriters []*PrefixWriter
+	if outSync != nil {
+		pw := outSync.LineWriter(prefix(host.Name))
+		lineWriters = append(lineWriters, pw)
+		stdout = pw
+	}
+	if errSync != nil {
+		pw := errSync.LineWriter(prefix(host.Name))
+		lineWriters = append(lineWriters, pw)
+		stderr = pw
+	}
+	if res.Transcript != nil {
+		stdout = teeWriter(stdout, res.Transcript.Stdout())
+		stderr = teeWriter(stderr, res.Transcript.Stderr())
+	}
+
+	start := time.Now()
+	err := host.Runner.RunContext(
+		ctx, stdin, stdout, stderr, command, args...,
+	)
+	res.Duration = time.Since(start)
+
+	for _, pw := range lineWriters {
+		if flushErr := pw.flush(true); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+
+	res.ExitCode = ExitCode(err)
+	res.Err = err
+}
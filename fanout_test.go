@@ -0,0 +1,264 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// hostStub returns a Stub which writes its stdin and output identifying host
+// to stdout and stderr, a byte at a time to exercise line buffering.
+func hostStub(host string, err error) *Stub {
+	return &Stub{Fallback: func(
+		_ context.Context,
+		stdin io.Reader,
+		stdout, stderr io.Writer,
+		command string,
+		args ...string,
+	) error {
+		var in []byte
+		if stdin != nil {
+			in, _ = io.ReadAll(stdin)
+		}
+
+		out := host + " " + command + " " + strings.Join(args, " ") +
+			"\n" + string(in)
+		for i := 0; i < len(out); i++ {
+			_, _ = stdout.Write([]byte{out[i]})
+		}
+		_, _ = io.WriteString(stderr, host+" warning\n")
+
+		return err
+	}}
+}
+
+func sortedLines(s string) []string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	sort.Strings(lines)
+
+	return lines
+}
+
+func TestFanOut_RunAll(t *testing.T) {
+	tests := []struct {
+		name       string
+		fanOut     FanOut
+		stdin      io.Reader
+		sameWriter bool
+		wantStdout []string
+		wantStderr []string
+		wantErr    string
+	}{
+		{
+			name: "prefixed",
+			fanOut: FanOut{Hosts: []FanOutHost{
+				{Name: "a", Runner: hostStub("a", nil)},
+				{Name: "b", Runner: hostStub("b", nil)},
+			}},
+			wantStdout: []string{"[a] a zfs list", "[b] b zfs list"},
+			wantStderr: []string{"[a] a warning", "[b] b warning"},
+		},
+		{
+			name: "stdin without trailing newline",
+			fanOut: FanOut{Hosts: []FanOutHost{
+				{Name: "a", Runner: hostStub("a", nil)},
+				{Name: "b", Runner: hostStub("b", nil)},
+			}},
+			stdin: strings.NewReader("input"),
+			wantStdout: []string{
+				"[a] a zfs list", "[a] input",
+				"[b] b zfs list", "[b] input",
+			},
+			wantStderr: []string{"[a] a warning", "[b] b warning"},
+		},
+		{
+			name: "custom prefix and merge",
+			fanOut: FanOut{
+				Hosts: []FanOutHost{
+					{Name: "a", Runner: hostStub("a", nil)},
+					{Name: "b", Runner: hostStub("b", nil)},
+				},
+				Prefix: func(host string) string { return host + ": " },
+				Merge:  true,
+			},
+			wantStdout: []string{
+				"a: a warning", "a: a zfs list",
+				"b: b warning", "b: b zfs list",
+			},
+		},
+		{
+			name: "same writer",
+			fanOut: FanOut{Hosts: []FanOutHost{
+				{Name: "a", Runner: hostStub("a", nil)},
+			}},
+			sameWriter: true,
+			wantStdout: []string{"[a] a warning", "[a] a zfs list"},
+		},
+		{
+			name: "failures",
+			fanOut: FanOut{
+				Hosts: []FanOutHost{
+					{Name: "a", Runner: hostStub("a", nil)},
+					{
+						Name:   "b",
+						Runner: hostStub("b", &FakeExitError{Code: 2}),
+					},
+					{Name: "c", Runner: hostStub("c", errors.New("boom"))},
+				},
+				Concurrency: 1,
+			},
+			wantStdout: []string{
+				"[a] a zfs list", "[b] b zfs list", "[c] c zfs list",
+			},
+			wantStderr: []string{
+				"[a] a warning", "[b] b warning", "[c] c warning",
+			},
+			wantErr: "runner: fan-out: failed on 2 of 3 hosts: " +
+				"b: : exit status 2; c: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			errW := io.Writer(&stderr)
+			if tt.sameWriter {
+				errW = &stdout
+			}
+
+			results, err := tt.fanOut.RunAll(
+				context.Background(), tt.stdin, &stdout, errW,
+				"zfs", "list",
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrFanOut)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantStdout, sortedLines(stdout.String()))
+			if tt.wantStderr != nil {
+				assert.Equal(
+					t, tt.wantStderr, sortedLines(stderr.String()),
+				)
+			}
+
+			require.Len(t, results, len(tt.fanOut.Hosts))
+			for i, res := range results {
+				assert.Equal(t, tt.fanOut.Hosts[i].Name, res.Host)
+				assert.Nil(t, res.Transcript)
+				assert.Equal(t, ExitCode(res.Err), res.ExitCode)
+			}
+		})
+	}
+}
+
+func TestFanOut_RunAll_mergeWithoutStdout(t *testing.T) {
+	r := &FanOut{
+		Hosts: []FanOutHost{
+			{Name: "a", Runner: hostStub("a", nil)},
+			{Name: "b", Runner: hostStub("b", nil)},
+		},
+		Merge: true,
+	}
+
+	var stderr bytes.Buffer
+	_, err := r.RunAll(
+		context.Background(), nil, nil, &stderr, "zfs", "list",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		[]string{
+			"[a] a warning", "[a] a zfs list",
+			"[b] b warning", "[b] b zfs list",
+		},
+		sortedLines(stderr.String()),
+	)
+}
+
+func TestFanOut_RunAll_collect(t *testing.T) {
+	r := &FanOut{
+		Hosts: []FanOutHost{
+			{Name: "a", Runner: hostStub("a", nil)},
+			{Name: "b", Runner: hostStub("b", nil)},
+		},
+		Collect: true,
+	}
+
+	results, err := r.RunAll(
+		context.Background(), nil, nil, nil, "zfs", "list",
+	)
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	for _, res := range results {
+		require.NotNil(t, res.Transcript)
+		assert.Equal(
+			t, res.Host+" zfs list\n"+res.Host+" warning\n",
+			res.Transcript.String(),
+		)
+		assert.Equal(
+			t, res.Host+" warning\n",
+			string(res.Transcript.StreamBytes("stderr")),
+		)
+	}
+}
+
+func TestFanOut_RunAll_concurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	s := &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+
+		return nil
+	}}
+
+	r := &FanOut{Concurrency: 2}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		r.Hosts = append(r.Hosts, FanOutHost{Name: name, Runner: s})
+	}
+
+	require.NoError(t, r.Run(nil, nil, nil, "uptime"))
+	assert.Equal(t, int32(2), maxRunning.Load())
+}
+
+func TestFanOut_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := mock_runner.NewMockRunner(ctrl)
+	b := mock_runner.NewMockRunner(ctrl)
+	a.EXPECT().Env([]string{"FOO=bar"})
+	b.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &FanOut{Hosts: []FanOutHost{
+		{Name: "a", Runner: a},
+		{Name: "b", Runner: b},
+	}}
+	r.Env("FOO=bar")
+}
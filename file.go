@@ -0,0 +1,69 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// FileOutput describes a file which RunToFile writes a command's stdout to.
+type FileOutput struct {
+	// Path is the path of the file to write to.
+	Path string
+
+	// Flag is the set of flags used to open the file, like os.O_CREATE,
+	// os.O_TRUNC, os.O_APPEND, and os.O_EXCL. The file is always opened
+	// write-only. When 0, os.O_CREATE|os.O_TRUNC is used.
+	Flag int
+
+	// Perm is the permission bits used if the file is created, before the
+	// umask is applied. When 0, 0666 is used.
+	Perm os.FileMode
+
+	// Sync causes the file to be synced to stable storage via fsync once the
+	// command has completed successfully.
+	Sync bool
+}
+
+// RunToFile runs the given command via RunContext on r, streaming its stdout
+// directly into the file described by out, rather than buffering it in
+// memory. If the command fails, the file is left in place with any output
+// which was written before the failure.
+func RunToFile(
+	ctx context.Context,
+	r Runner,
+	out FileOutput,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) (err error) {
+	flag := out.Flag
+	if flag == 0 {
+		flag = os.O_CREATE | os.O_TRUNC
+	}
+	perm := out.Perm
+	if perm == 0 {
+		perm = 0o666
+	}
+
+	f, err := os.OpenFile(out.Path, flag|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	err = r.RunContext(ctx, nil, f, stderr, command, args...)
+	if err != nil {
+		return err
+	}
+
+	if out.Sync {
+		return f.Sync()
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build unix
+
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunToFile(t *testing.T) {
+	// The file is created by the current process, so its umask applies.
+	defer syscall.Umask(syscall.Umask(0o022))
+
+	tests := []struct {
+		name     string
+		existing string
+		out      FileOutput
+		script   string
+		want     string
+		wantPerm os.FileMode
+		wantErr  bool
+	}{
+		{
+			name:     "new file",
+			script:   "echo hello; echo world",
+			want:     "hello\nworld\n",
+			wantPerm: 0o644,
+		},
+		{
+			name:     "truncates existing file",
+			existing: "old content which is long\n",
+			script:   "echo new",
+			want:     "new\n",
+			wantPerm: 0o644,
+		},
+		{
+			name:     "append",
+			existing: "old\n",
+			out:      FileOutput{Flag: os.O_CREATE | os.O_APPEND},
+			script:   "echo new",
+			want:     "old\nnew\n",
+			wantPerm: 0o644,
+		},
+		{
+			name:     "exclusive with existing file",
+			existing: "old\n",
+			out:      FileOutput{Flag: os.O_CREATE | os.O_EXCL},
+			script:   "echo new",
+			want:     "old\n",
+			wantPerm: 0o644,
+			wantErr:  true,
+		},
+		{
+			name:     "perm and sync",
+			out:      FileOutput{Perm: 0o600, Sync: true},
+			script:   "echo secret",
+			want:     "secret\n",
+			wantPerm: 0o600,
+		},
+		{
+			name:     "command failure keeps partial output",
+			script:   "echo partial; exit 1",
+			want:     "partial\n",
+			wantPerm: 0o644,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			if tt.existing != "" {
+				err := os.WriteFile(path, []byte(tt.existing), 0o600)
+				require.NoError(t, err)
+				require.NoError(t, os.Chmod(path, tt.wantPerm))
+			}
+			out := tt.out
+			out.Path = path
+			err := RunToFile(
+				context.Background(), New(), out, nil, "sh", "-c", tt.script,
+			)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+			fi, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPerm, fi.Mode().Perm())
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrFlock         = fmt.Errorf("%w: flock", Err)
+	ErrFlockNoPath   = fmt.Errorf("%w: path must be set", ErrFlock)
+	ErrFlockConflict = fmt.Errorf("%w: lock is held", ErrFlock)
+)
+
+// DefaultFlockConflictExitCode is the default value for
+// Flock.ConflictExitCode, EX_TEMPFAIL from sysexits.h.
+const DefaultFlockConflictExitCode = 75
+
+// FlockError is returned by Flock when the lock could not be acquired as it
+// is held by another process. It matches ErrFlockConflict with errors.Is, and
+// the error returned by the underlying Runner with errors.Is and errors.As.
+type FlockError struct {
+	// Path is the path of the lock file.
+	Path string
+
+	// Err is the error returned by the underlying Runner.
+	Err error
+}
+
+var _ error = &FlockError{}
+
+func (e *FlockError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrFlockConflict.Error(), e.Path)
+}
+
+// Unwrap returns ErrFlockConflict, and the error returned by the underlying
+// Runner.
+func (e *FlockError) Unwrap() []error {
+	return []error{ErrFlockConflict, e.Err}
+}
+
+// Flock is a Runner that wraps another Runner, and runs commands while
+// holding a lock on a file via flock(1), preventing commands which use the
+// same lock file from running concurrently on the host. The lock is released
+// when the command exits.
+//
+// As the lock is taken by flock on the host the underlying Runner runs
+// commands on, it also guards commands run by other processes, or on remote
+// hosts via SSHCLI. If the lock cannot be acquired within Timeout, the command
+// is not run and a *FlockError is returned.
+//
+// As flock passes its own environment through to the command it executes,
+// calls to Env are passed directly to the underlying Runner.
+type Flock struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with flock. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Path is the path of the lock file, which is created if it does not
+	// exist.
+	Path string
+
+	// Timeout is the maximum time to wait for the lock, passed via the -w flag.
+	// When zero, the -n flag is used, and commands fail immediately if the
+	// lock is held.
+	Timeout time.Duration
+
+	// Shared takes a shared lock rather than an exclusive one, via the -s
+	// flag, allowing other commands taking a shared lock to run concurrently.
+	Shared bool
+
+	// ConflictExitCode is the exit code flock exits with when the lock cannot
+	// be acquired, passed via the -E flag, which identifies the failure as a
+	// conflict. Commands which themselves exit with this code are also
+	// treated as conflicting. Defaults to DefaultFlockConflictExitCode.
+	ConflictExitCode int
+}
+
+var _ Runner = &Flock{}
+
+var _ Validator = &Flock{}
+
+// Validate checks the Flock is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner and Path are set, that
+// Path does not contain control characters, and that ConflictExitCode is a
+// valid exit code. The underlying Runner is validated too.
+func (r *Flock) Validate() error {
+	if r.Path == "" {
+		return &ValidationError{
+			Field: "Flock.Path",
+			Msg:   "must be set",
+			Err:   ErrFlockNoPath,
+		}
+	}
+
+	var err error
+	if r.ConflictExitCode < 0 || r.ConflictExitCode > 255 {
+		err = &ValidationError{
+			Field: "Flock.ConflictExitCode",
+			Msg:   fmt.Sprintf("invalid exit code %d", r.ConflictExitCode),
+		}
+	}
+
+	return firstError(
+		validateRunner("Flock.Runner", r.Runner),
+		validateChars("Flock.Path", r.Path, false),
+		err,
+		Validate(r.Runner),
+	)
+}
+
+// Run executes the command while holding the lock by calling Run on the
+// underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Path field is empty.
+func (r *Flock) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	flockArgs, err := r.args(command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.wrapError(
+		r.Runner.Run(stdin, stdout, stderr, "flock", flockArgs...),
+	)
+}
+
+// RunContext executes the command while holding the lock by calling
+// RunContext on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Path field is empty.
+func (r *Flock) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	flockArgs, err := r.args(command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.wrapError(r.Runner.RunContext(
+		ctx, stdin, stdout, stderr, "flock", flockArgs...,
+	))
+}
+
+// Env sets the environment by calling Env on the underlying Runner. Will panic
+// if Runner field is nil.
+func (r *Flock) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Flock) args(command string, args []string) ([]string, error) {
+	if r.Path == "" {
+		return nil, ErrFlockNoPath
+	}
+
+	flockArgs := []string{}
+	if r.Shared {
+		flockArgs = append(flockArgs, "-s")
+	}
+	if r.Timeout > 0 {
+		flockArgs = append(flockArgs,
+			"-w", strconv.FormatFloat(r.Timeout.Seconds(), 'f', -1, 64),
+		)
+	} else {
+		flockArgs = append(flockArgs, "-n")
+	}
+	flockArgs = append(flockArgs,
+		"-E", strconv.Itoa(r.conflictExitCode()),
+		"--", r.Path, command,
+	)
+	flockArgs = append(flockArgs, args...)
+
+	return flockArgs, nil
+}
+
+func (r *Flock) conflictExitCode() int {
+	if r.ConflictExitCode != 0 {
+		return r.ConflictExitCode
+	}
+
+	return DefaultFlockConflictExitCode
+}
+
+func (r *Flock) wrapError(err error) error {
+	if err != nil && ExitCode(err) == r.conflictExitCode() {
+		return &FlockError{Path: r.Path, Err: err}
+	}
+
+	return err
+}
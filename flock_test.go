@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var flockTestCases = []struct {
+	name     string
+	runner   Flock
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	command  string
+	args     []string
+	err      error
+	wantArgs []string
+	wantErr  string
+}{
+	{
+		name:    "no wait",
+		runner:  Flock{Path: "/run/lock/apt.lock"},
+		stdout:  &bytes.Buffer{},
+		stderr:  &bytes.Buffer{},
+		command: "apt-get",
+		args:    []string{"install", "-y", "zfsutils-linux"},
+		wantArgs: []string{
+			"-n", "-E", "75", "--", "/run/lock/apt.lock",
+			"apt-get", "install", "-y", "zfsutils-linux",
+		},
+	},
+	{
+		name:    "stdin",
+		runner:  Flock{Path: "/run/lock/cat.lock"},
+		stdin:   bytes.NewBufferString("foo\nbar"),
+		command: "cat",
+		wantArgs: []string{
+			"-n", "-E", "75", "--", "/run/lock/cat.lock", "cat",
+		},
+	},
+	{
+		name: "timeout, shared, and conflict exit code",
+		runner: Flock{
+			Path:             "/run/lock/zpool.lock",
+			Timeout:          1500 * time.Millisecond,
+			Shared:           true,
+			ConflictExitCode: 99,
+		},
+		command: "zpool",
+		args:    []string{"status"},
+		wantArgs: []string{
+			"-s", "-w", "1.5", "-E", "99", "--", "/run/lock/zpool.lock",
+			"zpool", "status",
+		},
+	},
+	{
+		name:    "error",
+		runner:  Flock{Path: "/run/lock/zpool.lock"},
+		command: "zpool",
+		err:     errors.New("exit status 1"),
+		wantArgs: []string{
+			"-n", "-E", "75", "--", "/run/lock/zpool.lock", "zpool",
+		},
+		wantErr: "exit status 1",
+	},
+	{
+		name:    "conflict",
+		runner:  Flock{Path: "/run/lock/zpool.lock"},
+		command: "zpool",
+		err:     &FakeExitError{Command: "flock", Code: 75},
+		wantArgs: []string{
+			"-n", "-E", "75", "--", "/run/lock/zpool.lock", "zpool",
+		},
+		wantErr: "runner: flock: lock is held: /run/lock/zpool.lock",
+	},
+	{
+		name:    "no path",
+		runner:  Flock{},
+		command: "zpool",
+		wantErr: "runner: flock: path must be set",
+	},
+}
+
+func TestFlock_Run(t *testing.T) {
+	for _, tt := range flockTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgs != nil {
+				r.EXPECT().Run(
+					tt.stdin, tt.stdout, tt.stderr, "flock", tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			f := tt.runner
+			f.Runner = r
+
+			err := f.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFlock_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range flockTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgs != nil {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					tt.stdin, tt.stdout, tt.stderr, "flock", tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			f := tt.runner
+			f.Runner = r
+
+			err := f.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFlock_RunContext_conflict(t *testing.T) {
+	if _, err := exec.LookPath("flock"); err != nil {
+		t.Skip("flock not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.lock")
+	holder := &Flock{Runner: New(), Path: path}
+	waiter := &Flock{
+		Runner:  New(),
+		Path:    path,
+		Timeout: 50 * time.Millisecond,
+	}
+
+	held := make(chan error)
+	go func() {
+		held <- holder.Run(nil, nil, nil, "sleep", "0.5")
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	err := waiter.RunContext(context.Background(), nil, nil, nil, "true")
+
+	var flockErr *FlockError
+	require.ErrorAs(t, err, &flockErr)
+	assert.ErrorIs(t, err, ErrFlockConflict)
+	assert.Equal(t, path, flockErr.Path)
+	assert.Equal(t, 75, ExitCode(err))
+
+	require.NoError(t, <-held)
+
+	var stdout bytes.Buffer
+	err = waiter.RunContext(
+		context.Background(), nil, &stdout, nil, "echo", "acquired",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "acquired\n", stdout.String())
+}
+
+func TestFlock_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env("FOO=BAR", "PORT=8080")
+
+	f := &Flock{Runner: r, Path: "/run/lock/test.lock"}
+	f.Env("FOO=BAR", "PORT=8080")
+}
+
+func TestFlock_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Flock
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "valid",
+			runner: &Flock{Runner: &Local{}, Path: "/tmp/my app.lock"},
+		},
+		{
+			name:    "no path",
+			runner:  &Flock{Runner: &Local{}},
+			wantErr: "runner: invalid: Flock.Path: must be set",
+			wantIs:  ErrFlockNoPath,
+		},
+		{
+			name:    "no runner",
+			runner:  &Flock{Path: "/tmp/app.lock"},
+			wantErr: "runner: invalid: Flock.Runner: must be set",
+		},
+		{
+			name: "invalid conflict exit code",
+			runner: &Flock{
+				Runner: &Local{}, Path: "/tmp/app.lock", ConflictExitCode: 256,
+			},
+			wantErr: "runner: invalid: Flock.ConflictExitCode: " +
+				"invalid exit code 256",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
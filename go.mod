@@ -1,15 +1,16 @@
 module github.com/krystal/go-runner
 
-go 1.18
+go 1.20
 
 require (
+	github.com/creack/pty v1.1.21
 	github.com/romdo/gomockctx v0.2.0
-	github.com/stretchr/testify v1.7.1
+	github.com/stretchr/testify v1.8.4
 	go.uber.org/mock v0.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b // indirect
 )
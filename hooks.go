@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// HookCommand describes a command run via a Hooks runner.
+type HookCommand struct {
+	// Command is the command being run.
+	Command string
+
+	// Args are the arguments the command is run with.
+	Args []string
+}
+
+// Hooks is a Runner that wraps another Runner, and calls the given functions
+// before and after each command is run. This provides a single place to
+// observe, veto, or modify commands, without implementing a full Runner.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Hooks struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// BeforeRun, when set, is called before each command is run. It may
+	// modify cmd to change the command which is run. If it returns an error,
+	// the command is not run, AfterRun is not called, and the error is
+	// returned.
+	BeforeRun func(ctx context.Context, cmd *HookCommand) error
+
+	// AfterRun, when set, is called after each command has been run, with the
+	// command as it was run, the error returned by the underlying Runner, and
+	// how long it took to run.
+	AfterRun func(
+		ctx context.Context,
+		cmd HookCommand,
+		err error,
+		duration time.Duration,
+	)
+}
+
+var _ Runner = &Hooks{}
+
+// Run calls Run on the underlying Runner, calling BeforeRun and AfterRun
+// before and after.
+//
+// Will panic if Runner field is nil.
+func (r *Hooks) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.run(
+		context.Background(), command, args,
+		func(cmd HookCommand) error {
+			return r.Runner.Run(stdin, stdout, stderr, cmd.Command, cmd.Args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, calling BeforeRun and
+// AfterRun before and after.
+//
+// Will panic if Runner field is nil.
+func (r *Hooks) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.run(
+		ctx, command, args,
+		func(cmd HookCommand) error {
+			return r.Runner.RunContext(
+				ctx, stdin, stdout, stderr, cmd.Command, cmd.Args...,
+			)
+		},
+	)
+}
+
+func (r *Hooks) run(
+	ctx context.Context,
+	command string,
+	args []string,
+	run func(cmd HookCommand) error,
+) error {
+	cmd := HookCommand{Command: command, Args: copyStrings(args)}
+
+	if r.BeforeRun != nil {
+		err := r.BeforeRun(ctx, &cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	err := run(cmd)
+
+	if r.AfterRun != nil {
+		r.AfterRun(ctx, cmd, err, time.Since(start))
+	}
+
+	return err
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Hooks) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var hooksTestCases = []struct {
+	name        string
+	before      func(ctx context.Context, cmd *HookCommand) error
+	command     string
+	args        []string
+	err         error
+	wantRun     bool
+	wantCommand string
+	wantArgs    []string
+	wantAfter   bool
+	wantErr     string
+}{
+	{
+		name:        "no modifications",
+		command:     "docker",
+		args:        []string{"ps", "-a"},
+		wantRun:     true,
+		wantCommand: "docker",
+		wantArgs:    []string{"ps", "-a"},
+		wantAfter:   true,
+	},
+	{
+		name: "modified by BeforeRun",
+		before: func(_ context.Context, cmd *HookCommand) error {
+			cmd.Command = "podman"
+			cmd.Args[0] = "container"
+			cmd.Args = append(cmd.Args, "--all")
+
+			return nil
+		},
+		command:     "docker",
+		args:        []string{"ps"},
+		wantRun:     true,
+		wantCommand: "podman",
+		wantArgs:    []string{"container", "--all"},
+		wantAfter:   true,
+	},
+	{
+		name: "vetoed by BeforeRun",
+		before: func(_ context.Context, _ *HookCommand) error {
+			return errors.New("rate limit exceeded")
+		},
+		command: "docker",
+		args:    []string{"ps"},
+		wantErr: "rate limit exceeded",
+	},
+	{
+		name:        "error",
+		command:     "zfs",
+		args:        []string{"list"},
+		err:         errors.New("zfs: command not found"),
+		wantRun:     true,
+		wantCommand: "zfs",
+		wantArgs:    []string{"list"},
+		wantAfter:   true,
+		wantErr:     "zfs: command not found",
+	},
+}
+
+func TestHooks_Run(t *testing.T) {
+	for _, tt := range hooksTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantRun {
+				r.EXPECT().Run(nil, nil, nil, tt.wantCommand, tt.wantArgs).
+					Return(tt.err)
+			}
+
+			h, after := newTestHooks(t, r, tt.before)
+			args := append([]string(nil), tt.args...)
+			err := h.Run(nil, nil, nil, tt.command, args...)
+
+			assertHooksResult(
+				t, err, tt.wantErr, *after, tt.wantAfter,
+				HookCommand{Command: tt.wantCommand, Args: tt.wantArgs},
+			)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestHooks_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range hooksTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantRun {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx), nil, nil, nil,
+					tt.wantCommand, tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			h, after := newTestHooks(t, r, tt.before)
+			args := append([]string(nil), tt.args...)
+			err := h.RunContext(ctx, nil, nil, nil, tt.command, args...)
+
+			assertHooksResult(
+				t, err, tt.wantErr, *after, tt.wantAfter,
+				HookCommand{Command: tt.wantCommand, Args: tt.wantArgs},
+			)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+type hooksAfterCall struct {
+	called   bool
+	cmd      HookCommand
+	err      error
+	duration time.Duration
+}
+
+func newTestHooks(
+	t *testing.T,
+	r Runner,
+	before func(ctx context.Context, cmd *HookCommand) error,
+) (*Hooks, *hooksAfterCall) {
+	t.Helper()
+
+	after := &hooksAfterCall{}
+
+	return &Hooks{
+		Runner:    r,
+		BeforeRun: before,
+		AfterRun: func(
+			ctx context.Context,
+			cmd HookCommand,
+			err error,
+			duration time.Duration,
+		) {
+			assert.NotNil(t, ctx)
+			*after = hooksAfterCall{
+				called: true, cmd: cmd, err: err, duration: duration,
+			}
+		},
+	}, after
+}
+
+func assertHooksResult(
+	t *testing.T,
+	err error,
+	wantErr string,
+	after hooksAfterCall,
+	wantAfter bool,
+	wantCmd HookCommand,
+) {
+	t.Helper()
+
+	if wantErr != "" {
+		assert.EqualError(t, err, wantErr)
+	} else {
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, wantAfter, after.called)
+	if wantAfter {
+		assert.Equal(t, wantCmd, after.cmd)
+		assert.Equal(t, err, after.err)
+		assert.GreaterOrEqual(t, after.duration, time.Duration(0))
+	}
+}
+
+func TestHooks_nilHooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Run(nil, nil, nil, "echo", []string{"hi"}).Return(nil)
+
+	h := &Hooks{Runner: r}
+	err := h.Run(nil, nil, nil, "echo", "hi")
+
+	assert.NoError(t, err)
+}
+
+func TestHooks_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	h := &Hooks{Runner: r}
+	h.Env("FOO=BAR", "PORT=8080")
+}
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrInventory             = fmt.Errorf("%w: inventory", Err)
+	ErrInventoryHostNotFound = fmt.Errorf("%w: host not found", ErrInventory)
+	ErrInventoryNoRunner     = fmt.Errorf("%w: runner not set", ErrInventory)
+	ErrInventorySelector     = fmt.Errorf("%w: invalid selector", ErrInventory)
+	ErrInventoryNoHosts      = fmt.Errorf(
+		"%w: no hosts match selector", ErrInventory,
+	)
+)
+
+// InventoryHost is a host in an Inventory, with its SSH settings, labels and
+// groups.
+type InventoryHost struct {
+	// Name identifies the host within the Inventory.
+	Name string `json:"name"`
+
+	// Destination is the SSH destination of the host, as with
+	// SSHCLI.Destination. Defaults to Name.
+	Destination string `json:"destination,omitempty"`
+
+	// Port is the SSH port of the host. Defaults to the Inventory's Port.
+	Port int `json:"port,omitempty"`
+
+	// IdentityFile is the SSH identity file for the host. Defaults to the
+	// Inventory's IdentityFile.
+	IdentityFile string `json:"identity_file,omitempty"`
+
+	// Login is the SSH login for the host. Defaults to the Inventory's Login.
+	Login string `json:"login,omitempty"`
+
+	// Args are extra arguments passed to ssh for the host, after the
+	// Inventory's Args.
+	Args []string `json:"args,omitempty"`
+
+	// Labels are arbitrary key/value pairs, which may be matched by
+	// selectors.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Groups are the names of groups the host belongs to, which may be
+	// matched by selectors.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// InGroup reports if the host belongs to the named group.
+func (h *InventoryHost) InGroup(group string) bool {
+	for _, g := range h.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Inventory is a list of hosts and their SSH settings, which produces
+// configured SSHCLI runners for individual hosts, and FanOut runners for
+// groups of hosts matching a selector.
+//
+// Selectors are a comma separated list of terms, all of which a host must
+// match to be selected. Each term is one of:
+//
+//   - "*", matching all hosts.
+//   - "name=<host>", matching the host with the given name.
+//   - "<key>=<value>", matching hosts with the given label value.
+//   - "<group>", matching hosts in the given group.
+//
+// Any term may be prefixed with "!" to match hosts which do not match it. For
+// example "db,env=production,!primary" selects production hosts in the "db"
+// group which are not in the "primary" group. An empty selector matches all
+// hosts.
+type Inventory struct {
+	// Runner is the underlying Runner used by SSHCLI runners produced by the
+	// Inventory, typically a *Local.
+	Runner Runner `json:"-"`
+
+	// Hosts are the hosts in the inventory.
+	Hosts []InventoryHost `json:"hosts"`
+
+	// Port is the default SSH port of hosts.
+	Port int `json:"port,omitempty"`
+
+	// IdentityFile is the default SSH identity file of hosts.
+	IdentityFile string `json:"identity_file,omitempty"`
+
+	// Login is the default SSH login of hosts.
+	Login string `json:"login,omitempty"`
+
+	// Args are extra arguments passed to ssh for all hosts.
+	Args []string `json:"args,omitempty"`
+}
+
+// Host returns the named host, or ErrInventoryHostNotFound.
+func (inv *Inventory) Host(name string) (*InventoryHost, error) {
+	for i := range inv.Hosts {
+		if inv.Hosts[i].Name == name {
+			return &inv.Hosts[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrInventoryHostNotFound, name)
+}
+
+// Groups returns the sorted names of all groups hosts belong to.
+func (inv *Inventory) Groups() []string {
+	seen := map[string]bool{}
+	groups := []string{}
+	for _, h := range inv.Hosts {
+		for _, g := range h.Groups {
+			if !seen[g] {
+				seen[g] = true
+				groups = append(groups, g)
+			}
+		}
+	}
+	sort.Strings(groups)
+
+	return groups
+}
+
+// Select returns the hosts matching selector, in the order of Hosts.
+func (inv *Inventory) Select(selector string) ([]InventoryHost, error) {
+	match, err := parseInventorySelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []InventoryHost
+	for _, h := range inv.Hosts {
+		h := h
+		if match(&h) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts, nil
+}
+
+// SSHCLI returns a new SSHCLI runner for the named host, configured with its
+// SSH settings.
+func (inv *Inventory) SSHCLI(name string) (*SSHCLI, error) {
+	h, err := inv.Host(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return inv.sshCLI(h)
+}
+
+// FanOut returns a new FanOut runner for the hosts matching selector, with an
+// SSHCLI runner for each host. Returns ErrInventoryNoHosts if no hosts match.
+func (inv *Inventory) FanOut(selector string) (*FanOut, error) {
+	hosts, err := inv.Select(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInventoryNoHosts, selector)
+	}
+
+	fo := &FanOut{Hosts: make([]FanOutHost, 0, len(hosts))}
+	for i := range hosts {
+		r, err := inv.sshCLI(&hosts[i])
+		if err != nil {
+			return nil, err
+		}
+
+		fo.Hosts = append(fo.Hosts, FanOutHost{Name: hosts[i].Name, Runner: r})
+	}
+
+	return fo, nil
+}
+
+func (inv *Inventory) sshCLI(h *InventoryHost) (*SSHCLI, error) {
+	if inv.Runner == nil {
+		return nil, ErrInventoryNoRunner
+	}
+
+	r := &SSHCLI{
+		Runner:       inv.Runner,
+		Destination:  h.Destination,
+		Port:         h.Port,
+		IdentityFile: h.IdentityFile,
+		Login:        h.Login,
+		Args:         append(copyStrings(inv.Args), h.Args...),
+	}
+	if r.Destination == "" {
+		r.Destination = h.Name
+	}
+	if r.Port == 0 {
+		r.Port = inv.Port
+	}
+	if r.IdentityFile == "" {
+		r.IdentityFile = inv.IdentityFile
+	}
+	if r.Login == "" {
+		r.Login = inv.Login
+	}
+
+	return r, nil
+}
+
+func parseInventorySelector(
+	selector string,
+) (func(h *InventoryHost) bool, error) {
+	var matchers []func(h *InventoryHost) bool
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		negate := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+
+		var match func(h *InventoryHost) bool
+		key, value, isLabel := strings.Cut(term, "=")
+		switch {
+		case term == "*":
+			match = func(*InventoryHost) bool { return true }
+		case isLabel && key == "":
+			return nil, fmt.Errorf("%w: %q", ErrInventorySelector, selector)
+		case isLabel && key == "name":
+			match = func(h *InventoryHost) bool { return h.Name == value }
+		case isLabel:
+			match = func(h *InventoryHost) bool {
+				v, ok := h.Labels[key]
+
+				return ok && v == value
+			}
+		case term == "":
+			return nil, fmt.Errorf("%w: %q", ErrInventorySelector, selector)
+		default:
+			group := term
+			match = func(h *InventoryHost) bool { return h.InGroup(group) }
+		}
+
+		if negate {
+			m := match
+			match = func(h *InventoryHost) bool { return !m(h) }
+		}
+		matchers = append(matchers, match)
+	}
+
+	return func(h *InventoryHost) bool {
+		for _, match := range matchers {
+			if !match(h) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
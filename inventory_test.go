@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInventory() *Inventory {
+	return &Inventory{
+		Runner:       &Local{},
+		Port:         2222,
+		IdentityFile: "/etc/ssh/id_ops",
+		Login:        "ops",
+		Args:         []string{"-oBatchMode=yes"},
+		Hosts: []InventoryHost{
+			{
+				Name:   "db1",
+				Labels: map[string]string{"env": "production"},
+				Groups: []string{"db", "primary"},
+			},
+			{
+				Name:   "db2",
+				Labels: map[string]string{"env": "production"},
+				Groups: []string{"db"},
+			},
+			{
+				Name:   "db3",
+				Labels: map[string]string{"env": "staging"},
+				Groups: []string{"db"},
+			},
+			{
+				Name:         "web1",
+				Destination:  "ssh://web1.example.com:22",
+				Port:         22,
+				IdentityFile: "/root/.ssh/id_web",
+				Login:        "root",
+				Args:         []string{"-v"},
+				Labels:       map[string]string{"env": "production"},
+				Groups:       []string{"web"},
+			},
+		},
+	}
+}
+
+func hostNames(hosts []InventoryHost) []string {
+	names := []string{}
+	for _, h := range hosts {
+		names = append(names, h.Name)
+	}
+
+	return names
+}
+
+func TestInventory_Select(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+		wantErr  error
+	}{
+		{selector: "", want: []string{"db1", "db2", "db3", "web1"}},
+		{selector: "*", want: []string{"db1", "db2", "db3", "web1"}},
+		{selector: "db", want: []string{"db1", "db2", "db3"}},
+		{selector: "db,!primary", want: []string{"db2", "db3"}},
+		{
+			selector: "env=production",
+			want:     []string{"db1", "db2", "web1"},
+		},
+		{selector: "db, env=production", want: []string{"db1", "db2"}},
+		{selector: "!env=production", want: []string{"db3"}},
+		{selector: "name=web1", want: []string{"web1"}},
+		{selector: "cache", want: []string{}},
+		{selector: "=x", wantErr: ErrInventorySelector},
+		{selector: "!", wantErr: ErrInventorySelector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			hosts, err := testInventory().Select(tt.selector)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, hostNames(hosts))
+		})
+	}
+}
+
+func TestInventory_SSHCLI(t *testing.T) {
+	inv := testInventory()
+
+	r, err := inv.SSHCLI("db1")
+	require.NoError(t, err)
+	assert.Equal(t, &SSHCLI{
+		Runner:       inv.Runner,
+		Destination:  "db1",
+		Port:         2222,
+		IdentityFile: "/etc/ssh/id_ops",
+		Login:        "ops",
+		Args:         []string{"-oBatchMode=yes"},
+	}, r)
+
+	r, err = inv.SSHCLI("web1")
+	require.NoError(t, err)
+	assert.Equal(t, &SSHCLI{
+		Runner:       inv.Runner,
+		Destination:  "ssh://web1.example.com:22",
+		Port:         22,
+		IdentityFile: "/root/.ssh/id_web",
+		Login:        "root",
+		Args:         []string{"-oBatchMode=yes", "-v"},
+	}, r)
+	assert.Equal(t, []string{"-oBatchMode=yes"}, inv.Args)
+
+	_, err = inv.SSHCLI("nope")
+	assert.ErrorIs(t, err, ErrInventoryHostNotFound)
+
+	inv.Runner = nil
+	_, err = inv.SSHCLI("db1")
+	assert.ErrorIs(t, err, ErrInventoryNoRunner)
+}
+
+func TestInventory_FanOut(t *testing.T) {
+	inv := testInventory()
+
+	fo, err := inv.FanOut("db,env=production")
+	require.NoError(t, err)
+	require.Len(t, fo.Hosts, 2)
+	assert.Equal(t, "db1", fo.Hosts[0].Name)
+	assert.Equal(t, "db2", fo.Hosts[1].Name)
+	assert.Equal(t, "db2", fo.Hosts[1].Runner.(*SSHCLI).Destination)
+
+	_, err = inv.FanOut("cache")
+	assert.ErrorIs(t, err, ErrInventoryNoHosts)
+
+	_, err = inv.FanOut("=")
+	assert.ErrorIs(t, err, ErrInventorySelector)
+}
+
+func TestInventory_Groups(t *testing.T) {
+	assert.Equal(
+		t, []string{"db", "primary", "web"}, testInventory().Groups(),
+	)
+	assert.Equal(t, []string{}, (&Inventory{}).Groups())
+}
@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	ErrJexec       = fmt.Errorf("%w: jexec", Err)
+	ErrJexecNoJail = fmt.Errorf("%w: jail must be set", ErrJexec)
+)
+
+// Jexec is a Runner that wraps another Runner and runs commands inside a
+// FreeBSD jail via jexec.
+//
+// The underlying Runner must be able to execute jexec with sufficient
+// privileges, which typically means running as root. To do so from an
+// unprivileged process, use a Sudo runner as the underlying Runner:
+//
+//	j := &runner.Jexec{
+//		Runner: &runner.Sudo{Runner: runner.New()},
+//		Jail:   "www",
+//	}
+//
+// Environment variables and working directories given for a single command
+// with RunWith are applied inside the jail, via the env command and sh
+// respectively.
+type Jexec struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with jexec. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Jail is the jail ID (jid) or name of the jail to execute commands in.
+	Jail string
+
+	// User is the username from the host environment to run commands as
+	// inside the jail, passed via the -u flag. When empty, no -u flag will be
+	// used.
+	User string
+
+	// JailUser is the username from the jailed environment to run commands
+	// as, passed via the -U flag. When empty, no -U flag will be used. Takes
+	// precedence over User if both are set.
+	JailUser string
+
+	// Clean indicates if the -l flag should be passed to jexec, causing
+	// commands to run in a clean environment resembling a login shell.
+	Clean bool
+
+	// Args is a string slice of extra arguments to pass to jexec.
+	Args []string
+
+	env   []string
+	envMu sync.RWMutex
+}
+
+var _ Runner = &Jexec{}
+
+var _ Validator = &Jexec{}
+
+// Validate checks the Jexec is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner and Jail are set, and
+// that values passed to jexec do not contain illegal characters, or begin
+// with "-" where they could be mistaken for flags. The underlying Runner is
+// validated too.
+func (r *Jexec) Validate() error {
+	if r.Jail == "" {
+		return &ValidationError{
+			Field: "Jexec.Jail",
+			Msg:   "must be set",
+			Err:   ErrJexecNoJail,
+		}
+	}
+
+	return firstError(
+		validateRunner("Jexec.Runner", r.Runner),
+		validateWord("Jexec.Jail", r.Jail),
+		validateWord("Jexec.User", r.User),
+		validateWord("Jexec.JailUser", r.JailUser),
+		validateEnv("Jexec.Env", r.environ()),
+		Validate(r.Runner),
+	)
+}
+
+// Run executes the command inside the jail by calling Run on the underlying
+// Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Jail field is empty.
+func (r *Jexec) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	jexecArgs, err := r.args(context.Background(), command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.Run(stdin, stdout, stderr, "jexec", jexecArgs...)
+}
+
+// RunContext executes the command inside the jail by calling RunContext on the
+// underlying Runner.
+//
+// Will panic if Runner field is nil.
+// Will return a error if Jail field is empty.
+func (r *Jexec) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	jexecArgs, err := r.args(ctx, command, args)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(
+		withoutCallOptions(ctx), stdin, stdout, stderr, "jexec", jexecArgs...,
+	)
+}
+
+// jexecChdirScript is run by sh inside the jail to change to the directory
+// given as its first argument, before executing the remaining arguments.
+const jexecChdirScript = `cd "$1" || exit; shift; exec "$@"`
+
+func (r *Jexec) args(
+	ctx context.Context,
+	command string,
+	args []string,
+) ([]string, error) {
+	if r.Jail == "" {
+		return nil, ErrJexecNoJail
+	}
+
+	jexecArgs := []string{}
+
+	if r.Clean {
+		jexecArgs = append(jexecArgs, "-l")
+	}
+	if r.JailUser != "" {
+		jexecArgs = append(jexecArgs, "-U", r.JailUser)
+	} else if r.User != "" {
+		jexecArgs = append(jexecArgs, "-u", r.User)
+	}
+	jexecArgs = append(jexecArgs, r.Args...)
+	jexecArgs = append(jexecArgs, r.Jail)
+
+	if dir := callDir(ctx); dir != "" {
+		jexecArgs = append(jexecArgs, "sh", "-c", jexecChdirScript, "sh", dir)
+	}
+	if env := DedupEnv(mergeEnv(r.environ(), callEnv(ctx))...); len(env) > 0 {
+		jexecArgs = append(jexecArgs, "env")
+		jexecArgs = append(jexecArgs, env...)
+	}
+	jexecArgs = append(jexecArgs, command)
+	jexecArgs = append(jexecArgs, args...)
+
+	return jexecArgs, nil
+}
+
+// Env sets the environment variables which will be provided to commands run
+// inside the jail, via the env command.
+func (r *Jexec) Env(env ...string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+func (r *Jexec) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
+}
@@ -0,0 +1,251 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var jexecTestCases = []struct {
+	name        string
+	env         []string
+	runner      *Jexec
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	command     string
+	args        []string
+	err         error
+	wantCommand string
+	wantArgs    []string
+	wantErr     string
+}{
+	{
+		name:        "jail",
+		runner:      &Jexec{Jail: "www"},
+		stdout:      &bytes.Buffer{},
+		stderr:      &bytes.Buffer{},
+		command:     "service",
+		args:        []string{"nginx", "status"},
+		wantCommand: "jexec",
+		wantArgs:    []string{"www", "service", "nginx", "status"},
+	},
+	{
+		name:        "jid",
+		runner:      &Jexec{Jail: "12"},
+		stdin:       bytes.NewBufferString("foo\nbar"),
+		command:     "cat",
+		wantCommand: "jexec",
+		wantArgs:    []string{"12", "cat"},
+	},
+	{
+		name:        "with User",
+		runner:      &Jexec{Jail: "www", User: "web"},
+		command:     "whoami",
+		wantCommand: "jexec",
+		wantArgs:    []string{"-u", "web", "www", "whoami"},
+	},
+	{
+		name:        "with JailUser",
+		runner:      &Jexec{Jail: "www", JailUser: "www"},
+		command:     "whoami",
+		wantCommand: "jexec",
+		wantArgs:    []string{"-U", "www", "www", "whoami"},
+	},
+	{
+		name:        "JailUser takes precedence over User",
+		runner:      &Jexec{Jail: "www", User: "web", JailUser: "www"},
+		command:     "whoami",
+		wantCommand: "jexec",
+		wantArgs:    []string{"-U", "www", "www", "whoami"},
+	},
+	{
+		name:        "with Clean",
+		runner:      &Jexec{Jail: "www", Clean: true},
+		command:     "env",
+		wantCommand: "jexec",
+		wantArgs:    []string{"-l", "www", "env"},
+	},
+	{
+		name:        "with Env",
+		env:         []string{"FOO=BAR", "PORT=8080"},
+		runner:      &Jexec{Jail: "www"},
+		command:     "myapp",
+		args:        []string{"run", "-a"},
+		wantCommand: "jexec",
+		wantArgs: []string{
+			"www", "env", "FOO=BAR", "PORT=8080", "myapp", "run", "-a",
+		},
+	},
+	{
+		name: "with Clean, User, Args and Env",
+		env:  []string{"FOO=BAR"},
+		runner: &Jexec{
+			Jail:  "www",
+			User:  "web",
+			Clean: true,
+			Args:  []string{"-d", "/tmp"},
+		},
+		command:     "myapp",
+		wantCommand: "jexec",
+		wantArgs: []string{
+			"-l", "-u", "web", "-d", "/tmp", "www", "env", "FOO=BAR", "myapp",
+		},
+	},
+	{
+		name:        "error",
+		runner:      &Jexec{Jail: "www"},
+		command:     "zfs",
+		args:        []string{"list"},
+		err:         errors.New("zfs: command not found"),
+		wantCommand: "jexec",
+		wantArgs:    []string{"www", "zfs", "list"},
+		wantErr:     "zfs: command not found",
+	},
+	{
+		name:    "no jail",
+		runner:  &Jexec{},
+		command: "whoami",
+		wantErr: "runner: jexec: jail must be set",
+	},
+}
+
+func TestJexec_Run(t *testing.T) {
+	for _, tt := range jexecTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantCommand != "" {
+				r.EXPECT().Run(
+					tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			j := tt.runner
+			j.Runner = r
+			if len(tt.env) > 0 {
+				j.Env(tt.env...)
+			}
+
+			err := j.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJexec_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range jexecTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantCommand != "" {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx),
+					tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+				).Return(tt.err)
+			}
+
+			j := tt.runner
+			j.Runner = r
+			if len(tt.env) > 0 {
+				j.Env(tt.env...)
+			}
+
+			err := j.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestJexec_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+
+	j := &Jexec{Runner: r, Jail: "www"}
+	j.Env("FOO=BAR", "PORT=8080")
+
+	assert.Equal(t, []string{"FOO=BAR", "PORT=8080"}, j.env)
+}
+
+func TestJexec_withSudo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Run(
+		nil, nil, nil, "sudo",
+		[]string{"-n", "--", "jexec", "-u", "web", "www", "whoami"},
+	).Return(nil)
+
+	j := &Jexec{Runner: &Sudo{Runner: r}, Jail: "www", User: "web"}
+
+	err := j.Run(nil, nil, nil, "whoami")
+
+	assert.NoError(t, err)
+}
+
+func TestJexec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Jexec
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "valid",
+			runner: &Jexec{Runner: &Local{}, Jail: "www", JailUser: "www"},
+		},
+		{
+			name:    "no jail",
+			runner:  &Jexec{Runner: &Local{}},
+			wantErr: "runner: invalid: Jexec.Jail: must be set",
+			wantIs:  ErrJexecNoJail,
+		},
+		{
+			name:    "no runner",
+			runner:  &Jexec{Jail: "www"},
+			wantErr: "runner: invalid: Jexec.Runner: must be set",
+		},
+		{
+			name:   "jail user with space",
+			runner: &Jexec{Runner: &Local{}, Jail: "www", JailUser: "w w"},
+			wantErr: "runner: invalid: Jexec.JailUser: " +
+				"contains illegal character ' '",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
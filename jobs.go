@@ -0,0 +1,348 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobs        = fmt.Errorf("%w: jobs", Err)
+	ErrJobNotFound = fmt.Errorf("%w: job not found", ErrJobs)
+	ErrJobRunning  = fmt.Errorf("%w: job is running", ErrJobs)
+)
+
+// JobState is the state of a job started by Jobs.
+type JobState string
+
+// States of jobs started by Jobs.
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// JobStatus describes a job started by Jobs.
+type JobStatus struct {
+	// ID identifies the job.
+	ID string
+
+	// Command is the command the job runs.
+	Command string
+
+	// Args are the arguments the command is run with.
+	Args []string
+
+	// State is the state of the job.
+	State JobState
+
+	// StartedAt is the time the job was started.
+	StartedAt time.Time
+
+	// EndedAt is the time the job's command exited, or the zero time if it
+	// is still running.
+	EndedAt time.Time
+
+	// ExitCode is the exit code of the command, or -1 if it is still running,
+	// or failed without an exit code.
+	ExitCode int
+
+	// Err is the error returned by the command, if any.
+	Err error
+}
+
+// Jobs runs commands asynchronously on a Runner as jobs, which are identified
+// by an ID, and may be listed, polled for their status, have their output
+// streamed, and be canceled. It is intended for exposing "start task, check
+// task" semantics, like over an HTTP API.
+//
+// The status and combined stdout and stderr output of jobs are held in memory
+// until they are removed with Remove.
+type Jobs struct {
+	// Runner is the Runner to run commands with. If not set, running jobs
+	// will cause a panic.
+	Runner Runner
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	order []string
+}
+
+type job struct {
+	status JobStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+	output *jobOutput
+}
+
+// Start starts the given command as a new job, and returns its ID. The job is
+// run with a context carrying the values of ctx, but which is only canceled
+// by Cancel, so the job outlives ctx.
+func (j *Jobs) Start(
+	ctx context.Context,
+	command string,
+	args ...string,
+) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(valuesContext{ctx})
+	jb := &job{
+		status: JobStatus{
+			ID:        id,
+			Command:   command,
+			Args:      copyStrings(args),
+			State:     JobRunning,
+			StartedAt: time.Now(),
+			ExitCode:  -1,
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+		output: newJobOutput(),
+	}
+
+	j.mu.Lock()
+	if j.jobs == nil {
+		j.jobs = map[string]*job{}
+	}
+	j.jobs[id] = jb
+	j.order = append(j.order, id)
+	j.mu.Unlock()
+
+	go func() {
+		defer close(jb.done)
+		defer cancel()
+
+		err := j.Runner.RunContext(
+			ctx, nil, jb.output, jb.output, command, args...,
+		)
+		jb.output.close()
+
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		jb.status.EndedAt = time.Now()
+		jb.status.ExitCode = ExitCode(err)
+		jb.status.Err = err
+		switch {
+		case err == nil:
+			jb.status.State = JobSucceeded
+		case jb.status.State == JobCanceled:
+		default:
+			jb.status.State = JobFailed
+		}
+	}()
+
+	return id, nil
+}
+
+// Status returns the status of the job with the given ID.
+func (j *Jobs) Status(id string) (JobStatus, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jb, err := j.get(id)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	return jb.status, nil
+}
+
+// List returns the status of all jobs, in the order they were started.
+func (j *Jobs) List() []JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(j.order))
+	for _, id := range j.order {
+		statuses = append(statuses, j.jobs[id].status)
+	}
+
+	return statuses
+}
+
+// Cancel cancels the job with the given ID, by canceling the context its
+// command is run with. It does not wait for the command to exit. Canceling a
+// job which has completed has no effect.
+func (j *Jobs) Cancel(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jb, err := j.get(id)
+	if err != nil {
+		return err
+	}
+
+	if jb.status.State == JobRunning {
+		jb.status.State = JobCanceled
+		jb.cancel()
+	}
+
+	return nil
+}
+
+// Wait waits for the job with the given ID to complete, and returns its
+// status. If ctx is done first, ctx's error is returned.
+func (j *Jobs) Wait(ctx context.Context, id string) (JobStatus, error) {
+	j.mu.Lock()
+	jb, err := j.get(id)
+	j.mu.Unlock()
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	select {
+	case <-jb.done:
+	case <-ctx.Done():
+		return JobStatus{}, ctx.Err()
+	}
+
+	return j.Status(id)
+}
+
+// Output returns the combined stdout and stderr output of the job with the
+// given ID so far.
+func (j *Jobs) Output(id string) ([]byte, error) {
+	j.mu.Lock()
+	jb, err := j.get(id)
+	j.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	b, _, _ := jb.output.from(0)
+
+	return b, nil
+}
+
+// Stream writes the combined stdout and stderr output of the job with the
+// given ID to w, starting with output written so far, and then following new
+// output as it is written, until the job completes or ctx is done.
+func (j *Jobs) Stream(ctx context.Context, id string, w io.Writer) error {
+	j.mu.Lock()
+	jb, err := j.get(id)
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for {
+		b, changed, closed := jb.output.from(offset)
+		if len(b) > 0 {
+			_, err := w.Write(b)
+			if err != nil {
+				return err
+			}
+			offset += len(b)
+		}
+		if closed {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Remove removes the completed job with the given ID, releasing its output.
+// Returns ErrJobRunning if the job has not completed.
+func (j *Jobs) Remove(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jb, err := j.get(id)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-jb.done:
+	default:
+		return fmt.Errorf("%w: %s", ErrJobRunning, id)
+	}
+
+	delete(j.jobs, id)
+	for i, oid := range j.order {
+		if oid == id {
+			j.order = append(j.order[:i], j.order[i+1:]...)
+
+			break
+		}
+	}
+
+	return nil
+}
+
+// get returns the job with the given ID. It must be called with mu held.
+func (j *Jobs) get(id string) (*job, error) {
+	jb, ok := j.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+
+	return jb, nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrJobs, err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// jobOutput records the output of a job, and notifies readers following it
+// when output is written.
+type jobOutput struct {
+	mu      sync.Mutex
+	buf     []byte
+	changed chan struct{}
+	closed  bool
+}
+
+func newJobOutput() *jobOutput {
+	return &jobOutput{changed: make(chan struct{})}
+}
+
+func (o *jobOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf, p...)
+	close(o.changed)
+	o.changed = make(chan struct{})
+
+	return len(p), nil
+}
+
+func (o *jobOutput) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+	close(o.changed)
+	o.changed = make(chan struct{})
+}
+
+// from returns a copy of the output from offset, a channel which is closed
+// when further output is written, and if no further output will be written.
+func (o *jobOutput) from(offset int) ([]byte, <-chan struct{}, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]byte(nil), o.buf[offset:]...), o.changed, o.closed
+}
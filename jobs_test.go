@@ -0,0 +1,262 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs_Start(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     FakeResponse
+		state    JobState
+		exitCode int
+		output   string
+		wantErr  bool
+	}{
+		{
+			name:     "success",
+			resp:     FakeResponse{Stdout: "hello\n", Stderr: "world\n"},
+			state:    JobSucceeded,
+			exitCode: 0,
+			output:   "hello\nworld\n",
+		},
+		{
+			name:     "failure",
+			resp:     FakeResponse{Stderr: "oops\n", ExitCode: 3},
+			state:    JobFailed,
+			exitCode: 3,
+			output:   "oops\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("echo", []string{"hi"}, tt.resp)
+			j := &Jobs{Runner: f}
+
+			id, err := j.Start(context.Background(), "echo", "hi")
+			require.NoError(t, err)
+			assert.Len(t, id, 16)
+
+			st, err := j.Wait(context.Background(), id)
+			require.NoError(t, err)
+
+			assert.Equal(t, id, st.ID)
+			assert.Equal(t, "echo", st.Command)
+			assert.Equal(t, []string{"hi"}, st.Args)
+			assert.Equal(t, tt.state, st.State)
+			assert.Equal(t, tt.exitCode, st.ExitCode)
+			assert.False(t, st.StartedAt.IsZero())
+			assert.False(t, st.EndedAt.Before(st.StartedAt))
+			if tt.wantErr {
+				assert.Error(t, st.Err)
+			} else {
+				assert.NoError(t, st.Err)
+			}
+
+			out, err := j.Output(id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.output, string(out))
+		})
+	}
+}
+
+func TestJobs_Start_outlivesContext(t *testing.T) {
+	release := make(chan struct{})
+	stub := &Stub{Fallback: func(
+		ctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+	j := &Jobs{Runner: stub}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	id, err := j.Start(ctx, "sleep", "10")
+	require.NoError(t, err)
+	cancel()
+
+	st, err := j.Status(id)
+	require.NoError(t, err)
+	assert.Equal(t, JobRunning, st.State)
+	assert.Equal(t, -1, st.ExitCode)
+
+	close(release)
+	st, err = j.Wait(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, JobSucceeded, st.State)
+}
+
+func TestJobs_Cancel(t *testing.T) {
+	stub := &Stub{Fallback: func(
+		ctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}}
+	j := &Jobs{Runner: stub}
+
+	id, err := j.Start(context.Background(), "sleep", "10")
+	require.NoError(t, err)
+
+	require.NoError(t, j.Cancel(id))
+	st, err := j.Wait(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, JobCanceled, st.State)
+	assert.ErrorIs(t, st.Err, context.Canceled)
+
+	require.NoError(t, j.Cancel(id))
+	st, err = j.Status(id)
+	require.NoError(t, err)
+	assert.Equal(t, JobCanceled, st.State)
+
+	assert.ErrorIs(t, j.Cancel("nope"), ErrJobNotFound)
+}
+
+func TestJobs_Stream(t *testing.T) {
+	step := make(chan struct{})
+	stub := &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = stdout.Write([]byte("one\n"))
+		<-step
+		_, _ = stderr.Write([]byte("two\n"))
+		<-step
+		_, _ = stdout.Write([]byte("three\n"))
+
+		return nil
+	}}
+	j := &Jobs{Runner: stub}
+
+	id, err := j.Start(context.Background(), "build")
+	require.NoError(t, err)
+
+	writes := make(chan string, 3)
+	w := writerFunc(func(p []byte) (int, error) {
+		writes <- string(p)
+
+		return len(p), nil
+	})
+	done := make(chan error)
+	go func() { done <- j.Stream(context.Background(), id, w) }()
+
+	assert.Equal(t, "one\n", <-writes)
+	step <- struct{}{}
+	assert.Equal(t, "two\n", <-writes)
+	step <- struct{}{}
+	assert.Equal(t, "three\n", <-writes)
+	require.NoError(t, <-done)
+
+	var buf bytes.Buffer
+	require.NoError(t, j.Stream(context.Background(), id, &buf))
+	assert.Equal(t, "one\ntwo\nthree\n", buf.String())
+
+	assert.ErrorIs(
+		t, j.Stream(context.Background(), "nope", &buf), ErrJobNotFound,
+	)
+}
+
+func TestJobs_Stream_contextDone(t *testing.T) {
+	stub := &Stub{Fallback: func(
+		ctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}}
+	j := &Jobs{Runner: stub}
+
+	id, err := j.Start(context.Background(), "sleep", "10")
+	require.NoError(t, err)
+	defer func() { _ = j.Cancel(id) }()
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 10*time.Millisecond,
+	)
+	defer cancel()
+
+	err = j.Stream(ctx, id, io.Discard)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestJobs_ListRemove(t *testing.T) {
+	release := make(chan struct{})
+	stub := &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		command string,
+		_ ...string,
+	) error {
+		if command == "slow" {
+			<-release
+		}
+
+		return nil
+	}}
+	j := &Jobs{Runner: stub}
+
+	fast, err := j.Start(context.Background(), "fast")
+	require.NoError(t, err)
+	slow, err := j.Start(context.Background(), "slow")
+	require.NoError(t, err)
+
+	list := j.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, fast, list[0].ID)
+	assert.Equal(t, slow, list[1].ID)
+
+	_, err = j.Wait(context.Background(), fast)
+	require.NoError(t, err)
+	require.NoError(t, j.Remove(fast))
+	assert.ErrorIs(t, j.Remove(slow), ErrJobRunning)
+	assert.ErrorIs(t, j.Remove(fast), ErrJobNotFound)
+
+	_, err = j.Status(fast)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	list = j.List()
+	require.Len(t, list, 1)
+	assert.Equal(t, slow, list[0].ID)
+
+	close(release)
+	_, err = j.Wait(context.Background(), slow)
+	require.NoError(t, err)
+	require.NoError(t, j.Remove(slow))
+	assert.Empty(t, j.List())
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
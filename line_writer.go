@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"bytes"
+	"sync"
+)
+
+// lineWriter is an io.Writer which splits written data into lines, calling fn
+// with each complete line, excluding its trailing newline and any carriage
+// return preceding it. Flush must be called once all data has been written,
+// to emit any final line which lacks a trailing newline.
+type lineWriter struct {
+	fn  func(line string)
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newLineWriter(fn func(line string)) *lineWriter {
+	return &lineWriter{fn: fn}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.fn(string(bytes.TrimSuffix(w.buf[:i], []byte{'\r'})))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush emits any buffered data which does not end with a newline as a final
+// line.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.fn(string(bytes.TrimSuffix(w.buf, []byte{'\r'})))
+		w.buf = nil
+	}
+}
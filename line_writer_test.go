@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "empty",
+			writes: []string{},
+			want:   nil,
+		},
+		{
+			name:   "single line",
+			writes: []string{"hello world\n"},
+			want:   []string{"hello world"},
+		},
+		{
+			name:   "multiple lines in one write",
+			writes: []string{"foo\nbar\nbaz\n"},
+			want:   []string{"foo", "bar", "baz"},
+		},
+		{
+			name:   "line split across writes",
+			writes: []string{"hel", "lo wo", "rld\nfoo", "\n"},
+			want:   []string{"hello world", "foo"},
+		},
+		{
+			name:   "empty lines",
+			writes: []string{"\n\nfoo\n\n"},
+			want:   []string{"", "", "foo", ""},
+		},
+		{
+			name:   "carriage returns",
+			writes: []string{"foo\r\nbar\r", "\n"},
+			want:   []string{"foo", "bar"},
+		},
+		{
+			name:   "no trailing newline",
+			writes: []string{"foo\nbar"},
+			want:   []string{"foo", "bar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			w := newLineWriter(func(line string) {
+				got = append(got, line)
+			})
+
+			for _, s := range tt.writes {
+				n, err := w.Write([]byte(s))
+				assert.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			w.Flush()
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
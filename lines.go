@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// RunLines runs the given command via RunContext on r, splitting its stdout
+// and stderr into lines, and calling onStdoutLine and onStderrLine with each
+// line as it arrives. Lines exclude their trailing newline. Either callback
+// may be nil, in which case the corresponding output is discarded.
+//
+// Callbacks are never called concurrently, and the command blocks while
+// writing output until the callback for it has returned.
+func RunLines(
+	ctx context.Context,
+	r Runner,
+	onStdoutLine func(line string),
+	onStderrLine func(line string),
+	command string,
+	args ...string,
+) error {
+	return runLines(ctx, r, nil, onStdoutLine, onStderrLine, command, args)
+}
+
+func runLines(
+	ctx context.Context,
+	r Runner,
+	stdin io.Reader,
+	onStdoutLine func(line string),
+	onStderrLine func(line string),
+	command string,
+	args []string,
+) error {
+	var mu sync.Mutex
+	var stdout, stderr io.Writer
+	var writers []*lineWriter
+
+	if onStdoutLine != nil {
+		w := newLineWriter(func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			onStdoutLine(line)
+		})
+		writers = append(writers, w)
+		stdout = w
+	}
+	if onStderrLine != nil {
+		w := newLineWriter(func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			onStderrLine(line)
+		})
+		writers = append(writers, w)
+		stderr = w
+	}
+
+	err := r.RunContext(ctx, stdin, stdout, stderr, command, args...)
+
+	for _, w := range writers {
+		w.Flush()
+	}
+
+	return err
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		noStdout   bool
+		noStderr   bool
+		wantStdout []string
+		wantStderr []string
+		wantErr    string
+	}{
+		{
+			name:       "stdout",
+			script:     "echo foo; echo bar",
+			wantStdout: []string{"foo", "bar"},
+		},
+		{
+			name:       "stdout and stderr",
+			script:     "echo foo; echo oops >&2; printf bar",
+			wantStdout: []string{"foo", "bar"},
+			wantStderr: []string{"oops"},
+		},
+		{
+			name:       "progress with carriage returns",
+			script:     `printf '10%%\r\n50%%\r\n100%%\r\n'`,
+			wantStdout: []string{"10%", "50%", "100%"},
+		},
+		{
+			name:       "nil stdout callback",
+			script:     "echo foo; echo oops >&2",
+			noStdout:   true,
+			wantStderr: []string{"oops"},
+		},
+		{
+			name:       "nil stderr callback",
+			script:     "echo foo; echo oops >&2",
+			noStderr:   true,
+			wantStdout: []string{"foo"},
+		},
+		{
+			name:       "error",
+			script:     "echo foo; exit 3",
+			wantStdout: []string{"foo"},
+			wantErr:    "sh: exit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr []string
+			onStdout := func(line string) { stdout = append(stdout, line) }
+			onStderr := func(line string) { stderr = append(stderr, line) }
+			if tt.noStdout {
+				onStdout = nil
+			}
+			if tt.noStderr {
+				onStderr = nil
+			}
+
+			err := RunLines(
+				context.Background(), New(), onStdout, onStderr,
+				"sh", "-c", tt.script,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout)
+			assert.Equal(t, tt.wantStderr, stderr)
+		})
+	}
+}
+
+func TestRunLines_mock(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, gomock.Any(), nil,
+		"zfs", []string{"send", "tank@snap"},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = io.WriteString(stdout, "full send of tank@snap\nsize\t")
+		_, _ = io.WriteString(stdout, "1024\n")
+
+		return errors.New("broken pipe")
+	})
+
+	var lines []string
+	err := RunLines(
+		ctx, r, func(line string) { lines = append(lines, line) }, nil,
+		"zfs", "send", "tank@snap",
+	)
+
+	assert.EqualError(t, err, "broken pipe")
+	assert.Equal(t, []string{"full send of tank@snap", "size\t1024"}, lines)
+}
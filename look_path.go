@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// LookPather is implemented by runners which can resolve commands themselves,
+// rather than by running "command -v" in a shell. It is used by LookPath.
+type LookPather interface {
+	// LookPath returns the path of the executable the command would be run
+	// with, or an error matching ErrCommandNotFound if there is none.
+	LookPath(ctx context.Context, command string) (string, error)
+}
+
+// LookPath returns the path of the executable r would run command with, or an
+// error matching ErrCommandNotFound if it does not exist. For shell builtins
+// the name of the builtin is returned.
+//
+// If r implements LookPather, its LookPath method is used. Otherwise
+// "command -v" is run within "sh -c" via RunContext on r, which requires r to
+// pass arguments through to the executed command verbatim.
+func LookPath(ctx context.Context, r Runner, command string) (string, error) {
+	if lp, ok := r.(LookPather); ok {
+		return lp.LookPath(ctx, command)
+	}
+
+	return lookPathCommandV(
+		ctx, r, command, "sh", "-c", commandVScript, "sh", command,
+	)
+}
+
+// commandVScript is run via "sh -c" to look up the command given as its first
+// argument. As command is a shell builtin, it cannot be executed directly, or
+// via the env command.
+const commandVScript = `command -v -- "$1"`
+
+// Exists reports if r can find an executable for command. It returns an error
+// only if this could not be determined. See LookPath for details.
+func Exists(ctx context.Context, r Runner, command string) (bool, error) {
+	_, err := LookPath(ctx, r, command)
+	if errors.Is(err, ErrCommandNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// lookPathCommandV runs the given "command -v" invocation on r, returning its
+// output as the path of command.
+func lookPathCommandV(
+	ctx context.Context,
+	r Runner,
+	command string,
+	name string,
+	args ...string,
+) (string, error) {
+	var stdout bytes.Buffer
+	err := r.RunContext(ctx, nil, &stdout, nil, name, args...)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) &&
+		(exitErr.ExitCode() == 1 || exitErr.ExitCode() == 127) {
+		return "", errCommandNotFound(command)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	if path == "" {
+		return "", errCommandNotFound(command)
+	}
+
+	return path, nil
+}
+
+func errCommandNotFound(command string) error {
+	return fmt.Errorf(
+		"%w: %w", ErrCommandNotFound,
+		&exec.Error{Name: command, Err: exec.ErrNotFound},
+	)
+}
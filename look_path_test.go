@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func exitErrorWithCode(t *testing.T, code int) error {
+	t.Helper()
+
+	err := exec.Command("sh", "-c", "exit "+strconv.Itoa(code)).Run()
+	require.Error(t, err)
+
+	return err
+}
+
+func TestLookPath_Local(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "runner-test-cmd", "true")
+
+	sh, err := exec.LookPath("sh")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		runner    Runner
+		command   string
+		want      string
+		wantFound bool
+	}{
+		{
+			name:      "Local",
+			runner:    &Local{},
+			command:   "sh",
+			want:      sh,
+			wantFound: true,
+		},
+		{
+			name:      "Local SearchPath",
+			runner:    &Local{SearchPath: []string{dir}},
+			command:   "runner-test-cmd",
+			want:      filepath.Join(dir, "runner-test-cmd"),
+			wantFound: true,
+		},
+		{
+			name:    "Local not in SearchPath",
+			runner:  &Local{SearchPath: []string{dir}},
+			command: "sh",
+		},
+		{
+			name:    "Local not found",
+			runner:  &Local{},
+			command: "runner-test-command-that-does-not-exist",
+		},
+		{
+			name:    "Local missing path",
+			runner:  &Local{},
+			command: filepath.Join(dir, "nope"),
+		},
+		{
+			name:      "command -v",
+			runner:    &Testing{Runner: &Local{}, TestingT: &fakeTestingT{}},
+			command:   "sh",
+			want:      sh,
+			wantFound: true,
+		},
+		{
+			name:      "command -v builtin",
+			runner:    &Testing{Runner: &Local{}, TestingT: &fakeTestingT{}},
+			command:   "cd",
+			want:      "cd",
+			wantFound: true,
+		},
+		{
+			name:    "command -v not found",
+			runner:  &Testing{Runner: &Local{}, TestingT: &fakeTestingT{}},
+			command: "runner-test-command-that-does-not-exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			got, err := LookPath(ctx, tt.runner, tt.command)
+			if tt.wantFound {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.ErrorIs(t, err, ErrCommandNotFound)
+				assert.Empty(t, got)
+			}
+
+			found, err := Exists(ctx, tt.runner, tt.command)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFound, found)
+		})
+	}
+}
+
+func TestLookPath_SSHCLI(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       []string
+		wantEnv   []interface{}
+		stdout    string
+		err       error
+		want      string
+		wantErr   string
+		wantErrIs error
+	}{
+		{
+			name:   "found",
+			stdout: "/usr/bin/docker\n",
+			want:   "/usr/bin/docker",
+		},
+		{
+			name:    "found with env",
+			env:     []string{"PATH=/opt/bin:/usr/bin"},
+			wantEnv: []interface{}{"env", "PATH=/opt/bin:/usr/bin"},
+			stdout:  "/opt/bin/docker\n",
+			want:    "/opt/bin/docker",
+		},
+		{
+			name: "not found",
+			err:  exitErrorWithCode(t, 1),
+			wantErr: `runner: command not found: exec: "docker": ` +
+				"executable file not found in $PATH",
+			wantErrIs: ErrCommandNotFound,
+		},
+		{
+			name:      "not found 127",
+			err:       exitErrorWithCode(t, 127),
+			wantErrIs: ErrCommandNotFound,
+		},
+		{
+			name:    "connection error",
+			err:     errors.New("ssh: connection refused"),
+			wantErr: "ssh: connection refused",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			args := []interface{}{"narnia.local", "--"}
+			args = append(args, tt.wantEnv...)
+			args = append(args,
+				"sh", "-c", `'command -v -- "$1"'`, "sh", "'docker'",
+			)
+			r.EXPECT().RunContext(
+				ctx, nil, gomock.Any(), nil, "ssh", args...,
+			).DoAndReturn(func(
+				_ context.Context,
+				_ io.Reader,
+				stdout, _ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				_, _ = io.WriteString(stdout, tt.stdout)
+
+				return tt.err
+			})
+
+			s := &SSHCLI{Runner: r, Destination: "narnia.local"}
+			s.Env(tt.env...)
+			got, err := LookPath(ctx, s, "docker")
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+			if tt.wantErrIs != nil {
+				assert.ErrorIs(t, err, tt.wantErrIs)
+			}
+			if tt.wantErr == "" && tt.wantErrIs == nil {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLookPath_SSHCLI_env(t *testing.T) {
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "runner-test-tool")
+	require.NoError(t, os.WriteFile(tool, []byte("#!/bin/sh\n"), 0o755))
+
+	ssh, _ := localSSH(t)
+	s := &SSHCLI{Runner: ssh, Destination: "narnia.local"}
+	s.Env("PATH="+dir+":/usr/bin:/bin", "FOO=bar")
+
+	got, err := LookPath(context.Background(), s, "runner-test-tool")
+	require.NoError(t, err)
+	assert.Equal(t, tool, got)
+
+	found, err := Exists(context.Background(), s, "no-such-runner-tool")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
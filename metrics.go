@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsSink receives metrics emitted by a Metrics runner. Implementations
+// must be safe for concurrent use.
+type MetricsSink interface {
+	// Count adds value to the counter name.
+	Count(name string, value int64, tags []string)
+
+	// Timing records a duration for the timer name.
+	Timing(name string, d time.Duration, tags []string)
+}
+
+// Metrics is a Runner that wraps another Runner, and emits metrics for each
+// command run to Sink. Once a command completes, it emits:
+//
+//   - A "commands" count of 1.
+//   - A "command_duration" timing of how long the command took.
+//   - A "command_failures" count of 1, if the command failed.
+//
+// Each metric is tagged with "command:<command>" and "exit_code:<code>", in
+// the "key:value" form used by DogStatsD, followed by Tags.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Metrics struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Sink receives the emitted metrics. If not set, running commands will
+	// cause a panic.
+	Sink MetricsSink
+
+	// Prefix is prepended to the name of each metric, like "runner.".
+	Prefix string
+
+	// Tags are added to the tags of each metric.
+	Tags []string
+}
+
+var _ Runner = &Metrics{}
+
+// Run executes the command with the underlying Runner, and emits metrics.
+func (r *Metrics) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.Run(stdin, stdout, stderr, command, args...)
+	r.emit(command, err, time.Since(start))
+
+	return err
+}
+
+// RunContext executes the command with the underlying Runner, and emits
+// metrics.
+func (r *Metrics) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	r.emit(command, err, time.Since(start))
+
+	return err
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Metrics) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Metrics) emit(command string, err error, duration time.Duration) {
+	tags := make([]string, 0, len(r.Tags)+2)
+	tags = append(tags,
+		"command:"+command,
+		"exit_code:"+strconv.Itoa(ExitCode(err)),
+	)
+	tags = append(tags, r.Tags...)
+
+	r.Sink.Count(r.Prefix+"commands", 1, tags)
+	r.Sink.Timing(r.Prefix+"command_duration", duration, tags)
+	if err != nil {
+		r.Sink.Count(r.Prefix+"command_failures", 1, tags)
+	}
+}
+
+// StatsdSink is a MetricsSink which writes metrics in the statsd line
+// protocol to a writer, typically a UDP connection to a statsd server, with
+// one metric per write.
+//
+// Write errors are ignored, as metrics are sent on a best effort basis.
+type StatsdSink struct {
+	// Writer is written to for each metric.
+	Writer io.Writer
+
+	// DogStatsD indicates if tags should be included using the DogStatsD
+	// extension to the protocol. Plain statsd does not support tags, so they
+	// are dropped when false.
+	DogStatsD bool
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+var _ MetricsSink = &StatsdSink{}
+
+// NewStatsdSink returns a StatsdSink which sends metrics to the statsd server
+// at addr, like "127.0.0.1:8125", over UDP.
+func NewStatsdSink(addr string, dogStatsD bool) (*StatsdSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsdSink{Writer: conn, DogStatsD: dogStatsD}, nil
+}
+
+// Count writes a "c" metric.
+func (s *StatsdSink) Count(name string, value int64, tags []string) {
+	s.write(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+// Timing writes a "ms" metric, with the duration in milliseconds.
+func (s *StatsdSink) Timing(name string, d time.Duration, tags []string) {
+	ms := strconv.FormatFloat(
+		float64(d)/float64(time.Millisecond), 'f', -1, 64,
+	)
+	s.write(name, ms, "ms", tags)
+}
+
+// Close closes Writer, if it is an io.Closer.
+func (s *StatsdSink) Close() error {
+	if c, ok := s.Writer.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func (s *StatsdSink) write(name, value, typ string, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf[:0], statsdName(name)...)
+	s.buf = append(s.buf, ':')
+	s.buf = append(s.buf, value...)
+	s.buf = append(s.buf, '|')
+	s.buf = append(s.buf, typ...)
+	if s.DogStatsD && len(tags) > 0 {
+		s.buf = append(s.buf, "|#"...)
+		for i, tag := range tags {
+			if i > 0 {
+				s.buf = append(s.buf, ',')
+			}
+			s.buf = append(s.buf, statsdTag(tag)...)
+		}
+	}
+
+	_, _ = s.Writer.Write(s.buf)
+}
+
+// statsdName replaces characters which are reserved by the statsd protocol in
+// metric names.
+var statsdName = strings.NewReplacer(
+	":", "_", "|", "_", "@", "_", "\n", "_",
+).Replace
+
+// statsdTag replaces characters which are reserved by the DogStatsD protocol
+// in tags.
+var statsdTag = strings.NewReplacer(
+	",", "_", "|", "_", "#", "_", "\n", "_",
+).Replace
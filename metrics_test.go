@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type metric struct {
+	Kind  string
+	Name  string
+	Value int64
+	Tags  []string
+}
+
+type memorySink struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func (s *memorySink) Count(name string, value int64, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics = append(s.metrics, metric{"count", name, value, tags})
+}
+
+func (s *memorySink) Timing(name string, _ time.Duration, tags []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics = append(s.metrics, metric{"timing", name, 0, tags})
+}
+
+func TestMetrics_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	tests := []struct {
+		name    string
+		command string
+		tags    []string
+		wantErr string
+		want    []metric
+	}{
+		{
+			name:    "success",
+			command: "true",
+			want: []metric{
+				{"count", "runner.commands", 1, []string{
+					"command:true", "exit_code:0",
+				}},
+				{"timing", "runner.command_duration", 0, []string{
+					"command:true", "exit_code:0",
+				}},
+			},
+		},
+		{
+			name:    "failure",
+			command: "false",
+			tags:    []string{"service:api"},
+			wantErr: "false: exit status 1",
+			want: []metric{
+				{"count", "runner.commands", 1, []string{
+					"command:false", "exit_code:1", "service:api",
+				}},
+				{"timing", "runner.command_duration", 0, []string{
+					"command:false", "exit_code:1", "service:api",
+				}},
+				{"count", "runner.command_failures", 1, []string{
+					"command:false", "exit_code:1", "service:api",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, withCtx := range []bool{false, true} {
+				sink := &memorySink{}
+				r := &Metrics{
+					Runner: f, Sink: sink, Prefix: "runner.", Tags: tt.tags,
+				}
+
+				var err error
+				if withCtx {
+					err = r.RunContext(
+						context.Background(), nil, nil, nil, tt.command,
+					)
+				} else {
+					err = r.Run(nil, nil, nil, tt.command)
+				}
+
+				if tt.wantErr != "" {
+					assert.EqualError(t, err, tt.wantErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, tt.want, sink.metrics)
+			}
+		})
+	}
+}
+
+func TestMetrics_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=bar"})
+
+	m := &Metrics{Runner: r, Sink: &memorySink{}}
+	m.Env("FOO=bar")
+}
+
+type packetWriter struct {
+	packets []string
+}
+
+func (w *packetWriter) Write(p []byte) (int, error) {
+	w.packets = append(w.packets, string(p))
+
+	return len(p), nil
+}
+
+func TestStatsdSink(t *testing.T) {
+	tests := []struct {
+		name      string
+		dogStatsD bool
+		want      []string
+	}{
+		{
+			name: "statsd",
+			want: []string{
+				"runner.commands:1|c",
+				"runner.command_duration:1.5|ms",
+				"bad_name_:2|c",
+			},
+		},
+		{
+			name:      "dogstatsd",
+			dogStatsD: true,
+			want: []string{
+				"runner.commands:1|c|#command:zfs,exit_code:0",
+				"runner.command_duration:1.5|ms|#command:zfs,exit_code:0",
+				"bad_name_:2|c|#a_b_c",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &packetWriter{}
+			s := &StatsdSink{Writer: w, DogStatsD: tt.dogStatsD}
+			tags := []string{"command:zfs", "exit_code:0"}
+
+			s.Count("runner.commands", 1, tags)
+			s.Timing("runner.command_duration", 1500*time.Microsecond, tags)
+			s.Count("bad:name|", 2, []string{"a,b|c"})
+
+			assert.Equal(t, tt.want, w.packets)
+			assert.NoError(t, s.Close())
+		})
+	}
+}
+
+func TestNewStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewStatsdSink(conn.LocalAddr().String(), true)
+	require.NoError(t, err)
+
+	f := &Fake{}
+	f.Respond("true", nil, FakeResponse{})
+	r := &Metrics{Runner: f, Sink: s}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+	require.NoError(t, s.Close())
+
+	var got []string
+	buf := make([]byte, 1024)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		got = append(got, string(buf[:n]))
+	}
+
+	assert.Equal(t, "commands:1|c|#command:true,exit_code:0", got[0])
+	assert.True(t, strings.HasPrefix(got[1], "command_duration:"))
+}
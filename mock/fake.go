@@ -0,0 +1,130 @@
+package mock_runner
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// FakeCall is a call to Run or RunContext on a FakeRunner.
+type FakeCall struct {
+	// Ctx is the context given to RunContext, or nil for calls to Run.
+	Ctx context.Context
+
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Command string
+	Args    []string
+}
+
+// FakeRunner is a hand-written fake implementation of the Runner interface,
+// which does not require gomock. Calls are recorded, and can be inspected with
+// Calls and EnvCalls.
+//
+// By default, calls to Run and RunContext write Stdout and Stderr to the given
+// writers, and return Err. Set Stub to control behavior per call.
+//
+// FakeRunner is safe for concurrent use, and its zero value is ready to use.
+// Its fields must not be modified while it is in use.
+type FakeRunner struct {
+	// Stub, when set, is called for each call to Run and RunContext, and its
+	// error is returned. Stdout, Stderr and Err are ignored.
+	Stub func(call FakeCall) error
+
+	// Stdout is written to the stdout writer of each call, if not nil.
+	Stdout string
+
+	// Stderr is written to the stderr writer of each call, if not nil.
+	Stderr string
+
+	// Err is returned by each call.
+	Err error
+
+	mu       sync.Mutex
+	calls    []FakeCall
+	envCalls [][]string
+}
+
+// Run records the call, and returns Err, or the result of Stub if set.
+func (f *FakeRunner) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return f.call(FakeCall{
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Command: command,
+		Args:    append([]string(nil), args...),
+	})
+}
+
+// RunContext records the call, and returns Err, or the result of Stub if set.
+func (f *FakeRunner) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return f.call(FakeCall{
+		Ctx:     ctx,
+		Stdin:   stdin,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Command: command,
+		Args:    append([]string(nil), args...),
+	})
+}
+
+func (f *FakeRunner) call(call FakeCall) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+
+	if f.Stub != nil {
+		return f.Stub(call)
+	}
+
+	if call.Stdout != nil && f.Stdout != "" {
+		if _, err := io.WriteString(call.Stdout, f.Stdout); err != nil {
+			return err
+		}
+	}
+	if call.Stderr != nil && f.Stderr != "" {
+		if _, err := io.WriteString(call.Stderr, f.Stderr); err != nil {
+			return err
+		}
+	}
+
+	return f.Err
+}
+
+// Env records the call.
+func (f *FakeRunner) Env(env ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.envCalls = append(f.envCalls, append([]string(nil), env...))
+}
+
+// Calls returns the calls made to Run and RunContext, in order.
+func (f *FakeRunner) Calls() []FakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeCall(nil), f.calls...)
+}
+
+// EnvCalls returns the arguments of each call made to Env, in order.
+func (f *FakeRunner) EnvCalls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([][]string(nil), f.envCalls...)
+}
@@ -0,0 +1,72 @@
+package mock_runner_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	runner "github.com/krystal/go-runner"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ runner.Runner = &mock_runner.FakeRunner{}
+
+func TestFakeRunner(t *testing.T) {
+	f := &mock_runner.FakeRunner{Stdout: "tank\n", Stderr: "warning\n"}
+
+	var stdout, stderr bytes.Buffer
+	err := f.Run(nil, &stdout, &stderr, "zfs", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "tank\n", stdout.String())
+	assert.Equal(t, "warning\n", stderr.String())
+
+	ctx := context.Background()
+	out, err := runner.CombinedOutput(ctx, f, "zfs", "list", "-H")
+	require.NoError(t, err)
+	assert.Equal(t, "tank\nwarning\n", out)
+
+	f.Env("FOO=bar")
+
+	calls := f.Calls()
+	require.Len(t, calls, 2)
+	assert.Nil(t, calls[0].Ctx)
+	assert.Equal(t, "zfs", calls[0].Command)
+	assert.Equal(t, []string{"list"}, calls[0].Args)
+	assert.Equal(t, &stdout, calls[0].Stdout)
+	assert.Equal(t, ctx, calls[1].Ctx)
+	assert.Equal(t, []string{"list", "-H"}, calls[1].Args)
+	assert.Equal(t, [][]string{{"FOO=bar"}}, f.EnvCalls())
+}
+
+func TestFakeRunner_Err(t *testing.T) {
+	errBoom := errors.New("boom")
+	f := &mock_runner.FakeRunner{Err: errBoom}
+
+	err := f.Run(nil, nil, nil, "false")
+	assert.ErrorIs(t, err, errBoom)
+}
+
+func TestFakeRunner_Stub(t *testing.T) {
+	f := &mock_runner.FakeRunner{
+		Stdout: "ignored",
+		Stub: func(call mock_runner.FakeCall) error {
+			if call.Command == "false" {
+				return errors.New("exit status 1")
+			}
+			_, err := call.Stdout.Write([]byte(call.Command + "\n"))
+
+			return err
+		},
+	}
+
+	var stdout bytes.Buffer
+	require.NoError(t, f.Run(nil, &stdout, nil, "hostname"))
+	assert.Equal(t, "hostname\n", stdout.String())
+
+	err := f.RunContext(context.Background(), nil, nil, nil, "false")
+	assert.EqualError(t, err, "exit status 1")
+	assert.Len(t, f.Calls(), 2)
+}
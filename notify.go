@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Notification describes a failed or slow command, and is passed to
+// Notify.Func.
+type Notification struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Err is the error returned by the underlying Runner, if any.
+	Err error
+
+	// ExitCode is the exit code of the command.
+	ExitCode int
+
+	// Duration is how long the command took to run.
+	Duration time.Duration
+
+	// Slow indicates the command took at least Notify.SlowThreshold to run.
+	Slow bool
+
+	// Failures is the number of consecutive times the command has failed,
+	// including this one. It is 0 if the command succeeded.
+	Failures int
+}
+
+// Notify is a Runner that wraps another Runner, and calls Func when a command
+// fails, or takes longer than SlowThreshold to run. Func can for example log,
+// page, or post to a webhook.
+//
+// Failures are counted per command, and reset when the command succeeds, so
+// MinFailures can be used to only notify about repeated failures.
+//
+// Func is called synchronously once the command completes, before Run or
+// RunContext returns, and may be called concurrently if commands are run
+// concurrently. Calls to Env are passed directly to the underlying Runner.
+type Notify struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Func is called with a Notification for each failed or slow command. The
+	// context is the one given to RunContext, or a background context for
+	// Run. If not set, no notifications are sent.
+	Func func(ctx context.Context, n Notification)
+
+	// SlowThreshold is the duration at or above which commands are considered
+	// slow. When zero, slow commands are not notified about.
+	SlowThreshold time.Duration
+
+	// MinFailures is the number of consecutive failures of a command needed
+	// before failures are notified about. When zero or one, every failure is
+	// notified about.
+	MinFailures int
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+var _ Runner = &Notify{}
+
+// Run executes the command with the underlying Runner, and calls Func if it
+// failed or was slow.
+func (r *Notify) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.Run(stdin, stdout, stderr, command, args...)
+	r.notify(context.Background(), command, args, err, time.Since(start))
+
+	return err
+}
+
+// RunContext executes the command with the underlying Runner, and calls Func
+// if it failed or was slow.
+func (r *Notify) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	r.notify(ctx, command, args, err, time.Since(start))
+
+	return err
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Notify) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Notify) notify(
+	ctx context.Context,
+	command string,
+	args []string,
+	err error,
+	duration time.Duration,
+) {
+	failures := r.countFailure(command, err != nil)
+
+	n := Notification{
+		Command:  command,
+		Args:     args,
+		Err:      err,
+		ExitCode: ExitCode(err),
+		Duration: duration,
+		Slow:     r.SlowThreshold > 0 && duration >= r.SlowThreshold,
+		Failures: failures,
+	}
+
+	failed := err != nil && failures >= r.MinFailures
+	if r.Func != nil && (failed || n.Slow) {
+		r.Func(ctx, n)
+	}
+}
+
+// countFailure updates the consecutive failure count of command, and returns
+// it.
+func (r *Notify) countFailure(command string, failed bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !failed {
+		delete(r.failures, command)
+
+		return 0
+	}
+
+	if r.failures == nil {
+		r.failures = map[string]int{}
+	}
+	r.failures[command]++
+
+	return r.failures[command]
+}
@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type notifications struct {
+	mu   sync.Mutex
+	list []Notification
+}
+
+func (n *notifications) add(_ context.Context, notif Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	notif.Duration = 0
+	n.list = append(n.list, notif)
+}
+
+func TestNotify_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("check", nil, FakeResponse{})
+	f.Respond("check", []string{"fail"}, FakeResponse{ExitCode: 1})
+	f.Respond("other", nil, FakeResponse{})
+
+	fail := Notification{
+		Command: "check", Args: []string{"fail"}, ExitCode: 1,
+	}
+	failN := func(n int) Notification {
+		notif := fail
+		notif.Failures = n
+
+		return notif
+	}
+
+	tests := []struct {
+		name        string
+		minFailures int
+		args        [][]string
+		want        []Notification
+	}{
+		{
+			name: "success",
+			args: [][]string{{"ok"}, {"ok"}},
+		},
+		{
+			name: "every failure",
+			args: [][]string{{"fail"}, {"ok"}, {"fail"}},
+			want: []Notification{failN(1), failN(1)},
+		},
+		{
+			name:        "repeated failures",
+			minFailures: 2,
+			args: [][]string{
+				{"fail"}, {"ok"}, {"fail"}, {"fail"}, {"fail"},
+			},
+			want: []Notification{failN(2), failN(3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &notifications{}
+			r := &Notify{Runner: f, Func: got.add, MinFailures: tt.minFailures}
+
+			for _, args := range tt.args {
+				_ = r.Run(nil, nil, nil, "check", args...)
+
+				// Other commands do not reset the failure count.
+				require.NoError(t, r.Run(nil, nil, nil, "other"))
+			}
+
+			for i := range got.list {
+				assert.Error(t, got.list[i].Err)
+				got.list[i].Err = nil
+			}
+			assert.Equal(t, tt.want, got.list)
+		})
+	}
+}
+
+func TestNotify_RunContext_slow(t *testing.T) {
+	ctx := context.WithValue(context.Background(), callDirKey{}, "marker")
+
+	d := &Delay{Runner: &Fake{}, Delay: 20 * time.Millisecond}
+	d.Runner.(*Fake).Respond("sleep", nil, FakeResponse{})
+
+	var got []Notification
+	r := &Notify{
+		Runner:        d,
+		SlowThreshold: 10 * time.Millisecond,
+		Func: func(nctx context.Context, n Notification) {
+			assert.Equal(t, ctx, nctx)
+			got = append(got, n)
+		},
+	}
+
+	err := r.RunContext(ctx, nil, nil, nil, "sleep", "1")
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	assert.True(t, got[0].Slow)
+	assert.GreaterOrEqual(t, got[0].Duration, 20*time.Millisecond)
+	assert.Equal(t, "sleep", got[0].Command)
+	assert.Equal(t, []string{"1"}, got[0].Args)
+	assert.Zero(t, got[0].Failures)
+	assert.NoError(t, got[0].Err)
+}
+
+func TestNotify_noFunc(t *testing.T) {
+	f := &Fake{}
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	r := &Notify{Runner: f}
+	assert.Error(t, r.Run(nil, nil, nil, "false"))
+}
+
+func TestNotify_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=bar"})
+
+	n := &Notify{Runner: r}
+	n.Env("FOO=bar")
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Option configures a Local runner created with New. Options which configure
+// a single command run via RunWith are RunOptions instead.
+type Option func(r *Local)
+
+// WithRunnerEnv sets environment variables on the runner, as with Local.Env.
+// Each entry is of the form "key=value". Multiple WithRunnerEnv options are
+// combined.
+func WithRunnerEnv(env ...string) Option {
+	return func(r *Local) {
+		r.Env(append(r.environ(), env...)...)
+	}
+}
+
+// WithRunnerDir sets the working directory of commands, as with Local.Dir.
+func WithRunnerDir(dir string) Option {
+	return func(r *Local) {
+		r.Dir = dir
+	}
+}
+
+// WithInheritedEnv causes commands to be started with the environment of the
+// current process, with any variables set via WithRunnerEnv or Env layered on
+// top, as with Local.InheritOSEnv.
+func WithInheritedEnv() Option {
+	return func(r *Local) {
+		r.InheritOSEnv = true
+	}
+}
+
+// WithDefaultTimeout sets the timeout of commands run without a deadline, as
+// with Local.DefaultTimeout.
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(r *Local) {
+		r.DefaultTimeout = timeout
+	}
+}
+
+// WithCmdFunc sets the function called with each *exec.Cmd before it is
+// started, as with Local.CmdFunc.
+func WithCmdFunc(fn func(cmd *exec.Cmd)) Option {
+	return func(r *Local) {
+		r.CmdFunc = fn
+	}
+}
@@ -0,0 +1,154 @@
+// Package otelrunner provides a runner.Runner which traces commands with
+// OpenTelemetry.
+package otelrunner
+
+import (
+	"context"
+	"io"
+
+	runner "github.com/krystal/go-runner"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer used when Trace.Tracer is not set.
+const TracerName = "github.com/krystal/go-runner/otelrunner"
+
+// Trace is a runner.Runner which wraps another Runner, and runs each command
+// within a span named after the command. Spans have the following attributes:
+//
+//   - "process.command": The command.
+//   - "process.command_args": The command and its arguments, with the values
+//     of "key=value" arguments matching RedactKeys redacted.
+//   - "process.exit.code": The exit code, once the command has completed.
+//   - "server.address": The destination, when Runner is a *runner.SSHCLI.
+//
+// Errors are recorded on the span, and set its status to codes.Error.
+//
+// Commands run with RunContext start child spans of any span in the context,
+// and the span is carried by the context given to the underlying Runner.
+type Trace struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner runner.Runner
+
+	// Tracer is used to start spans. Defaults to a tracer named TracerName
+	// from the global TracerProvider.
+	Tracer trace.Tracer
+
+	// RedactKeys is a list of keys whose values are redacted in
+	// "key=value" arguments, as supported by runner.RedactEnv.
+	RedactKeys []string
+
+	// Attributes are added to each span.
+	Attributes []attribute.KeyValue
+}
+
+var _ runner.Runner = &Trace{}
+
+// Run calls RunContext with a background context, so the command is run within
+// a new root span.
+func (r *Trace) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext executes the command with the underlying Runner within a new
+// span, which is a child of any span in ctx. The span is carried by the
+// context given to the underlying Runner.
+func (r *Trace) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	ctx, span := r.start(ctx, command, args)
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	end(span, err)
+
+	return err
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Trace) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Trace) start(
+	ctx context.Context,
+	command string,
+	args []string,
+) (context.Context, trace.Span) {
+	tracer := r.Tracer
+	if tracer == nil {
+		tracer = otel.Tracer(TracerName)
+	}
+
+	cmdline := make([]string, 0, len(args)+1)
+	cmdline = append(cmdline, command)
+	cmdline = append(cmdline, runner.RedactEnv(args, r.RedactKeys)...)
+
+	attrs := make([]attribute.KeyValue, 0, len(r.Attributes)+3)
+	attrs = append(attrs,
+		attribute.String("process.command", command),
+		attribute.StringSlice("process.command_args", cmdline),
+	)
+	if ssh, ok := r.Runner.(*runner.SSHCLI); ok {
+		attrs = append(attrs,
+			attribute.String("server.address", ssh.Destination),
+		)
+	}
+	attrs = append(attrs, r.Attributes...)
+
+	return tracer.Start(
+		ctx, command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func end(span trace.Span, err error) {
+	span.SetAttributes(
+		attribute.Int("process.exit.code", runner.ExitCode(err)),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// TraceparentEnv returns TRACEPARENT and TRACESTATE env vars for the span in
+// ctx, in the W3C Trace Context format, or nil if ctx carries no valid span.
+// It is intended for use as runner.ContextEnv.FromContext, so commands can log
+// or propagate the trace ID.
+//
+// When used with Trace, ContextEnv should be the underlying Runner, so the env
+// vars refer to the span of the command.
+func TraceparentEnv(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	var env []string
+	if v := carrier.Get("traceparent"); v != "" {
+		env = append(env, "TRACEPARENT="+v)
+	}
+	if v := carrier.Get("tracestate"); v != "" {
+		env = append(env, "TRACESTATE="+v)
+	}
+
+	return env
+}
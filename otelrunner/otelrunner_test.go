@@ -0,0 +1,193 @@
+package otelrunner
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	runner "github.com/krystal/go-runner"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+)
+
+func newTracer() (trace.Tracer, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	return tp.Tracer("test"), sr
+}
+
+func TestTrace_Run(t *testing.T) {
+	f := &runner.Fake{}
+	f.Respond("true", nil, runner.FakeResponse{})
+	f.Respond("false", nil, runner.FakeResponse{ExitCode: 3})
+
+	tests := []struct {
+		name       string
+		trace      Trace
+		command    string
+		args       []string
+		wantErr    string
+		wantAttrs  []attribute.KeyValue
+		wantStatus codes.Code
+	}{
+		{
+			name:    "success",
+			command: "true",
+			args:    []string{"-v"},
+			wantAttrs: []attribute.KeyValue{
+				attribute.String("process.command", "true"),
+				attribute.StringSlice(
+					"process.command_args", []string{"true", "-v"},
+				),
+				attribute.Int("process.exit.code", 0),
+			},
+			wantStatus: codes.Unset,
+		},
+		{
+			name: "failure",
+			trace: Trace{
+				RedactKeys: []string{"*_TOKEN"},
+				Attributes: []attribute.KeyValue{
+					attribute.String("host.name", "web1"),
+				},
+			},
+			command: "false",
+			args:    []string{"API_TOKEN=secret", "FOO=bar"},
+			wantErr: "false: exit status 3",
+			wantAttrs: []attribute.KeyValue{
+				attribute.String("process.command", "false"),
+				attribute.StringSlice(
+					"process.command_args",
+					[]string{"false", "API_TOKEN=***", "FOO=bar"},
+				),
+				attribute.String("host.name", "web1"),
+				attribute.Int("process.exit.code", 3),
+			},
+			wantStatus: codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, sr := newTracer()
+
+			r := tt.trace
+			r.Runner = f
+			r.Tracer = tracer
+
+			err := r.Run(nil, nil, nil, tt.command, tt.args...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			spans := sr.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+
+			assert.Equal(t, tt.command, span.Name())
+			assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+			assert.Equal(t, tt.wantAttrs, span.Attributes())
+			assert.Equal(t, tt.wantStatus, span.Status().Code)
+			assert.False(t, span.Parent().IsValid())
+
+			if tt.wantErr != "" {
+				require.Len(t, span.Events(), 1)
+				assert.Equal(t, "exception", span.Events()[0].Name)
+			} else {
+				assert.Empty(t, span.Events())
+			}
+		})
+	}
+}
+
+func TestTrace_RunContext(t *testing.T) {
+	tracer, sr := newTracer()
+	ctx, parent := tracer.Start(context.Background(), "provision")
+
+	var innerSpan trace.SpanContext
+	m := &runner.Stub{
+		Handlers: map[string]runner.StubHandler{
+			"true": func(
+				ctx context.Context,
+				_ io.Reader,
+				_ io.Writer,
+				_ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				innerSpan = trace.SpanContextFromContext(ctx)
+
+				return nil
+			},
+		},
+	}
+
+	r := &Trace{Runner: m, Tracer: tracer}
+	require.NoError(t, r.RunContext(ctx, nil, nil, nil, "true"))
+	parent.End()
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, parent.SpanContext(), spans[0].Parent())
+	assert.Equal(t, spans[0].SpanContext(), innerSpan)
+}
+
+func TestTrace_SSHCLI(t *testing.T) {
+	tracer, sr := newTracer()
+
+	f := &runner.Fake{}
+	f.Respond("ssh", nil, runner.FakeResponse{})
+
+	r := &Trace{
+		Runner: &runner.SSHCLI{Runner: f, Destination: "deploy@web1"},
+		Tracer: tracer,
+	}
+	require.NoError(t, r.Run(nil, nil, nil, "uptime"))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	assert.Contains(
+		t, spans[0].Attributes(),
+		attribute.String("server.address", "deploy@web1"),
+	)
+}
+
+func TestTrace_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Trace{Runner: m}
+	r.Env("FOO=bar")
+}
+
+func TestTraceparentEnv(t *testing.T) {
+	assert.Nil(t, TraceparentEnv(context.Background()))
+
+	tracer, _ := newTracer()
+
+	f := &runner.Fake{}
+	f.Respond("true", nil, runner.FakeResponse{})
+
+	r := &Trace{
+		Runner: &runner.ContextEnv{Runner: f, FromContext: TraceparentEnv},
+		Tracer: tracer,
+	}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+
+	calls := f.Calls()
+	require.Len(t, calls, 1)
+	require.Len(t, calls[0].Env, 1)
+	assert.Regexp(
+		t, `^TRACEPARENT=00-[0-9a-f]{32}-[0-9a-f]{16}-01$`, calls[0].Env[0],
+	)
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// Output runs the given command via RunContext on r, and returns its stdout.
+// Output written to stderr is not returned, but when r is a Local, its tail is
+// included in the *ExitError returned if the command fails.
+func Output(
+	ctx context.Context,
+	r Runner,
+	command string,
+	args ...string,
+) (string, error) {
+	var stdout bytes.Buffer
+	err := r.RunContext(ctx, nil, &stdout, nil, command, args...)
+
+	return stdout.String(), err
+}
+
+// CombinedOutput runs the given command via RunContext on r, and returns its
+// combined stdout and stderr.
+func CombinedOutput(
+	ctx context.Context,
+	r Runner,
+	command string,
+	args ...string,
+) (string, error) {
+	var buf bytes.Buffer
+	w := &SyncWriter{w: &buf}
+	err := r.RunContext(ctx, nil, w, w, command, args...)
+
+	return buf.String(), err
+}
+
+// SyncWriter is an io.Writer which serializes writes to the underlying writer,
+// allowing it to be safely used for both stdout and stderr, or shared by
+// several commands run concurrently.
+//
+// Each write is forwarded as is, so output from concurrent commands may still
+// be interleaved mid-line. Use LineWriter to give each command a writer which
+// only forwards complete lines.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ io.Writer = &SyncWriter{}
+
+// NewSyncWriter returns a SyncWriter which writes to w.
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+// NewSyncMultiWriter returns a SyncWriter which duplicates its writes to all
+// the given writers, like io.MultiWriter.
+func NewSyncMultiWriter(writers ...io.Writer) *SyncWriter {
+	return &SyncWriter{w: io.MultiWriter(writers...)}
+}
+
+func (w *SyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
+
+// syncIfSame returns stdout and stderr wrapped in a single SyncWriter if they
+// are the same non-nil writer, and as given otherwise. Wrapping runners which
+// give the underlying Runner distinct writers for stdout and stderr use it to
+// keep writes to a writer given for both serialized.
+func syncIfSame(stdout, stderr io.Writer) (io.Writer, io.Writer) {
+	if stdout != nil && sameWriter(stdout, stderr) {
+		w := &SyncWriter{w: stdout}
+
+		return w, w
+	}
+
+	return stdout, stderr
+}
+
+// LineWriter returns a PrefixWriter which writes to w, prefixing each line
+// with prefix, which may be empty. As it only forwards complete lines, lines
+// written to different LineWriters of the same SyncWriter are never
+// interleaved mid-line.
+//
+// Flush must be called on the returned PrefixWriter once the command has
+// completed, to forward any final line which lacks a trailing newline.
+func (w *SyncWriter) LineWriter(prefix string) *PrefixWriter {
+	return NewPrefixWriter(w, prefix)
+}
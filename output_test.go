@@ -0,0 +1,222 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "stdout",
+			command: "echo",
+			args:    []string{"hello", "world"},
+			want:    "hello world\n",
+		},
+		{
+			name:    "stderr is discarded",
+			command: "sh",
+			args:    []string{"-c", "echo out; echo err >&2"},
+			want:    "out\n",
+		},
+		{
+			name:    "error",
+			command: "sh",
+			args:    []string{"-c", "echo out; exit 3"},
+			want:    "out\n",
+			wantErr: "sh: exit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Output(
+				context.Background(), New(), tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "stdout",
+			command: "echo",
+			args:    []string{"hello", "world"},
+			want:    "hello world\n",
+		},
+		{
+			name:    "stdout and stderr",
+			command: "sh",
+			args:    []string{"-c", "echo out; echo err >&2; echo out2"},
+			want:    "out\nerr\nout2\n",
+		},
+		{
+			name:    "error",
+			command: "sh",
+			args:    []string{"-c", "echo oops >&2; exit 3"},
+			want:    "oops\n",
+			wantErr: "sh: exit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CombinedOutput(
+				context.Background(), New(), tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCombinedOutput_concurrentWrites(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, gomock.Any(), gomock.Any(), "myapp",
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				_, _ = io.WriteString(stderr, "e")
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			_, _ = io.WriteString(stdout, "o")
+		}
+		<-done
+
+		return errors.New("boom")
+	})
+
+	got, err := CombinedOutput(ctx, r, "myapp")
+
+	assert.EqualError(t, err, "boom")
+	assert.Len(t, got, 200)
+}
+
+func TestSyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSyncWriter(&buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = io.WriteString(w, "x")
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, strings.Repeat("x", 1000), buf.String())
+}
+
+func TestNewSyncMultiWriter(t *testing.T) {
+	var a, b bytes.Buffer
+	w := NewSyncMultiWriter(&a, &b)
+
+	n, err := io.WriteString(w, "hello")
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, "hello", a.String())
+	assert.Equal(t, "hello", b.String())
+}
+
+func TestSyncWriter_LineWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSyncWriter(&buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			lw := w.LineWriter(fmt.Sprintf("[%d] ", i))
+			for j := 0; j < 50; j++ {
+				// Write lines in pieces, as commands often do.
+				_, _ = io.WriteString(lw, "line ")
+				_, _ = io.WriteString(lw, strconv.Itoa(j))
+				_, _ = io.WriteString(lw, "\n")
+			}
+			_, _ = io.WriteString(lw, "line end")
+			_, _ = io.WriteString(lw, "\n")
+			assert.NoError(t, lw.Flush())
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	count := 0
+	for _, line := range lines {
+		if strings.HasSuffix(line, "\n") {
+			assert.Regexp(t, `^\[\d\] line (\d+|end)\n$`, line)
+			count++
+		}
+	}
+	assert.Equal(t, 255, count)
+}
+
+func TestSyncIfSame(t *testing.T) {
+	var a, b bytes.Buffer
+
+	stdout, stderr := syncIfSame(&a, &a)
+	assert.IsType(t, &SyncWriter{}, stdout)
+	assert.Same(t, stdout, stderr)
+
+	stdout, stderr = syncIfSame(&a, &b)
+	assert.Same(t, &a, stdout)
+	assert.Same(t, &b, stderr)
+
+	stdout, stderr = syncIfSame(nil, nil)
+	assert.Nil(t, stdout)
+	assert.Nil(t, stderr)
+}
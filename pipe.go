@@ -0,0 +1,188 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrPipe         = fmt.Errorf("%w: pipe", Err)
+	ErrPipeNoStages = fmt.Errorf("%w: no stages", ErrPipe)
+)
+
+// PipeStage is a command run as one stage of a Pipe.
+type PipeStage struct {
+	// Command is the command to run.
+	Command string
+
+	// Args are the arguments to run the command with. They are passed to the
+	// Runner as is, and are never interpreted by a shell.
+	Args []string
+
+	// Runner, when set, is the Runner to run the stage with, instead of the
+	// Pipe's Runner. This allows a pipeline to span hosts, like reading a
+	// file on a remote host via SSHCLI, and processing it locally.
+	Runner Runner
+}
+
+// PipeError is returned by Pipe when a stage fails. It matches ErrPipe with
+// errors.Is, and the error of the failed stage with errors.Is and errors.As.
+type PipeError struct {
+	// Stage is the index of the failed stage.
+	Stage int
+
+	// Command is the command of the failed stage.
+	Command string
+
+	// Args are the arguments of the failed stage.
+	Args []string
+
+	// Err is the error returned by the failed stage.
+	Err error
+}
+
+var _ error = &PipeError{}
+
+func (e *PipeError) Error() string {
+	cmdline := strings.Join(append([]string{e.Command}, e.Args...), " ")
+
+	return fmt.Sprintf(
+		"%s: stage %d: %s: %s", ErrPipe.Error(), e.Stage, cmdline, e.Err,
+	)
+}
+
+// Unwrap returns ErrPipe, and the error returned by the failed stage.
+func (e *PipeError) Unwrap() []error {
+	return []error{ErrPipe, e.Err}
+}
+
+// Pipe runs a pipeline of commands, connecting the stdout of each stage to the
+// stdin of the next, like "cmd1 | cmd2 | cmd3" in a shell, but without a shell
+// interpreting the command line. All stages run concurrently.
+//
+// When a stage exits, its stdin is closed, so preceding stages writing to it
+// fail, like with SIGPIPE in a shell. Such failures are ignored if the stage
+// reading their output succeeded, so "yes | head -n 1" succeeds.
+//
+// The first stage to otherwise fail cancels the context of the remaining
+// stages, and its error is returned as a *PipeError.
+type Pipe struct {
+	// Runner is the Runner to run stages with, unless a stage sets its own.
+	// If neither are set, running the stage will cause a panic.
+	Runner Runner
+
+	// Stages are the commands of the pipeline, in order.
+	Stages []PipeStage
+}
+
+// Run runs the pipeline until all stages have exited, or ctx is done. stdin
+// is given to the first stage, and stdout receives the output of the last
+// stage. stderr receives the stderr output of all stages, with writes to it
+// serialized.
+func (p *Pipe) Run(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) error {
+	if len(p.Stages) == 0 {
+		return ErrPipeNoStages
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if stderr != nil {
+		stderr = NewSyncWriter(stderr)
+	}
+
+	n := len(p.Stages)
+	readers := make([]*io.PipeReader, n)
+	writers := make([]*io.PipeWriter, n)
+	for i := 1; i < n; i++ {
+		readers[i], writers[i-1] = io.Pipe()
+	}
+
+	state := &pipeState{succeeded: make([]bool, n), cancel: cancel}
+	var wg sync.WaitGroup
+	for i, stage := range p.Stages {
+		var in io.Reader = stdin
+		if readers[i] != nil {
+			in = readers[i]
+		}
+		var out io.Writer = stdout
+		if writers[i] != nil {
+			out = writers[i]
+		}
+		r := stage.Runner
+		if r == nil {
+			r = p.Runner
+		}
+
+		wg.Add(1)
+		go func(i int, stage PipeStage, r Runner, in io.Reader, out io.Writer) {
+			defer wg.Done()
+
+			err := r.RunContext(
+				ctx, in, out, stderr, stage.Command, stage.Args...,
+			)
+
+			// Record the outcome before closing the pipes, so a preceding
+			// stage failing to write to this one sees whether it succeeded.
+			state.record(i, stage, err)
+
+			if readers[i] != nil {
+				readers[i].CloseWithError(io.ErrClosedPipe)
+			}
+			if writers[i] != nil {
+				writers[i].Close()
+			}
+		}(i, stage, r, in, out)
+	}
+	wg.Wait()
+
+	if state.err != nil {
+		return state.err
+	}
+
+	return nil
+}
+
+type pipeState struct {
+	mu        sync.Mutex
+	succeeded []bool
+	err       *PipeError
+	cancel    context.CancelFunc
+}
+
+// record records the outcome of stage i, keeping the first failure of a stage
+// whose output was still wanted, and canceling the remaining stages.
+func (s *pipeState) record(i int, stage PipeStage, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err == nil {
+		s.succeeded[i] = true
+
+		return
+	}
+	if i+1 < len(s.succeeded) && s.succeeded[i+1] {
+		// The failure was caused by the next stage no longer reading output,
+		// so is treated as success for stages preceding this one too.
+		s.succeeded[i] = true
+
+		return
+	}
+	if s.err == nil {
+		s.err = &PipeError{
+			Stage:   i,
+			Command: stage.Command,
+			Args:    stage.Args,
+			Err:     err,
+		}
+		s.cancel()
+	}
+}
@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipe_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		stages     []PipeStage
+		stdin      string
+		wantStdout string
+		wantStage  int
+		wantCode   int
+		wantErr    error
+	}{
+		{
+			name: "single stage",
+			stages: []PipeStage{
+				{Command: "echo", Args: []string{"hello"}},
+			},
+			wantStdout: "hello\n",
+		},
+		{
+			name: "multiple stages",
+			stages: []PipeStage{
+				{Command: "printf", Args: []string{"b\na\nc\n"}},
+				{Command: "sort"},
+				{Command: "tr", Args: []string{"a-z", "A-Z"}},
+			},
+			wantStdout: "A\nB\nC\n",
+		},
+		{
+			name: "arguments are not interpreted by a shell",
+			stages: []PipeStage{
+				{Command: "echo", Args: []string{"$HOME | `id`; *"}},
+				{Command: "cat"},
+			},
+			wantStdout: "$HOME | `id`; *\n",
+		},
+		{
+			name:   "stdin",
+			stdin:  "one\ntwo\nthree\n",
+			stages: []PipeStage{{Command: "grep", Args: []string{"t"}}},
+
+			wantStdout: "two\nthree\n",
+		},
+		{
+			name: "stage exits early",
+			stages: []PipeStage{
+				{Command: "yes"},
+				{Command: "cat"},
+				{Command: "head", Args: []string{"-n", "2"}},
+			},
+			wantStdout: "y\ny\n",
+		},
+		{
+			name: "first stage fails",
+			stages: []PipeStage{
+				{Command: "sh", Args: []string{"-c", "exit 3"}},
+				{Command: "cat"},
+			},
+			wantStage: 0,
+			wantCode:  3,
+			wantErr:   ErrPipe,
+		},
+		{
+			name: "last stage fails",
+			stages: []PipeStage{
+				{Command: "echo", Args: []string{"hello"}},
+				{Command: "grep", Args: []string{"nope"}},
+			},
+			wantStage: 1,
+			wantCode:  1,
+			wantErr:   ErrPipe,
+		},
+		{
+			name:    "no stages",
+			wantErr: ErrPipeNoStages,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Pipe{Runner: &Local{}, Stages: tt.stages}
+
+			var stdin io.Reader
+			if tt.stdin != "" {
+				stdin = strings.NewReader(tt.stdin)
+			}
+			var stdout, stderr bytes.Buffer
+			err := p.Run(context.Background(), stdin, &stdout, &stderr)
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+
+				var pipeErr *PipeError
+				if errors.As(err, &pipeErr) {
+					assert.Equal(t, tt.wantStage, pipeErr.Stage)
+					assert.Equal(t, tt.wantCode, ExitCode(err))
+				}
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+		})
+	}
+}
+
+func TestPipe_Run_stageRunner(t *testing.T) {
+	remote := &Fake{}
+	remote.Respond("cat", []string{"/etc/hostname"}, FakeResponse{
+		Stdout: "web1\n",
+	})
+
+	p := &Pipe{
+		Runner: &Local{},
+		Stages: []PipeStage{
+			{
+				Command: "cat",
+				Args:    []string{"/etc/hostname"},
+				Runner:  remote,
+			},
+			{Command: "tr", Args: []string{"a-z", "A-Z"}},
+		},
+	}
+
+	var stdout bytes.Buffer
+	err := p.Run(context.Background(), nil, &stdout, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "WEB1\n", stdout.String())
+	assert.Len(t, remote.Calls(), 1)
+}
+
+func TestPipe_Run_cancelsRemainingStages(t *testing.T) {
+	failure := errors.New("boom")
+	stub := &Stub{Fallback: func(
+		ctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		command string,
+		_ ...string,
+	) error {
+		if command == "fail" {
+			return failure
+		}
+		<-ctx.Done()
+
+		return ctx.Err()
+	}}
+	p := &Pipe{
+		Runner: stub,
+		Stages: []PipeStage{
+			{Command: "wait"},
+			{Command: "fail"},
+			{Command: "wait"},
+		},
+	}
+
+	err := p.Run(context.Background(), nil, nil, nil)
+	require.ErrorIs(t, err, failure)
+
+	var pipeErr *PipeError
+	require.ErrorAs(t, err, &pipeErr)
+	assert.Equal(t, 1, pipeErr.Stage)
+	assert.Equal(t, "runner: pipe: stage 1: fail: boom", err.Error())
+}
+
+func TestPipe_Run_contextCanceled(t *testing.T) {
+	p := &Pipe{
+		Runner: &Local{},
+		Stages: []PipeStage{
+			{Command: "sleep", Args: []string{"10"}},
+			{Command: "cat"},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond,
+	)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Run(ctx, nil, nil, nil)
+
+	assert.ErrorIs(t, err, ErrPipe)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,267 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPlan                  = fmt.Errorf("%w: plan", Err)
+	ErrPlanInvalid           = fmt.Errorf("%w: invalid", ErrPlan)
+	ErrPlanDuplicateStep     = fmt.Errorf("%w: duplicate step", ErrPlanInvalid)
+	ErrPlanUnknownDependency = fmt.Errorf(
+		"%w: unknown dependency", ErrPlanInvalid,
+	)
+	ErrPlanCycle = fmt.Errorf("%w: dependency cycle", ErrPlanInvalid)
+)
+
+// PlanStep is a command in a Plan, which is only run once the steps it
+// depends on have succeeded.
+type PlanStep struct {
+	BatchCommand
+
+	// Name identifies the step within the Plan.
+	Name string
+
+	// DependsOn are the names of steps which must succeed before this step is
+	// run.
+	DependsOn []string
+}
+
+// PlanResult is the outcome of a step run by a Plan.
+type PlanResult struct {
+	BatchResult
+
+	// Name is the name of the step.
+	Name string
+}
+
+// PlanError is returned by Plan when one or more steps fail. It matches
+// ErrPlan with errors.Is, and the errors of each failed step with errors.Is
+// and errors.As.
+type PlanError struct {
+	// Failed are the results of the steps which failed, in the order of
+	// Plan.Steps. Skipped steps are not included.
+	Failed []*PlanResult
+
+	// Skipped are the names of steps which were not run, in the order of
+	// Plan.Steps.
+	Skipped []string
+}
+
+var _ error = &PlanError{}
+
+func (e *PlanError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, res := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", res.Name, res.Err))
+	}
+
+	msg := fmt.Sprintf(
+		"%s: %d steps failed: %s",
+		ErrPlan.Error(), len(e.Failed), strings.Join(msgs, "; "),
+	)
+	if len(e.Skipped) > 0 {
+		msg += fmt.Sprintf(
+			" (skipped: %s)", strings.Join(e.Skipped, ", "),
+		)
+	}
+
+	return msg
+}
+
+// Unwrap returns ErrPlan, and the errors of the failed steps.
+func (e *PlanError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed)+1)
+	errs = append(errs, ErrPlan)
+	for _, res := range e.Failed {
+		errs = append(errs, res.Err)
+	}
+
+	return errs
+}
+
+// Plan is a set of steps, each a command which may depend on other steps.
+// Steps are run on a Runner with as much parallelism as their dependencies,
+// and Concurrency, allow.
+//
+// A step is only run once all the steps it depends on have succeeded, so the
+// steps which depend on a failed step, directly or indirectly, are skipped.
+// Unless ContinueOnFailure is set, no further steps are started once any step
+// fails, though steps already running are allowed to complete.
+type Plan struct {
+	// Runner is the Runner to run steps with. If not set, running steps will
+	// cause a panic.
+	Runner Runner
+
+	// Steps are the steps of the plan.
+	Steps []PlanStep
+
+	// Concurrency is the maximum number of steps run at once. When zero or
+	// negative, there is no limit.
+	Concurrency int
+
+	// ContinueOnFailure causes steps which do not depend on a failed step to
+	// still be run after a step fails.
+	ContinueOnFailure bool
+}
+
+// Validate checks that step names are unique, that all dependencies refer to
+// steps in the plan, and that there are no dependency cycles.
+func (p *Plan) Validate() error {
+	_, _, err := p.graph()
+
+	return err
+}
+
+// Run validates the plan, and runs its steps via RunContext on the Runner. It
+// returns the result of each step in the order of Steps. If any step fails, a
+// *PlanError is returned alongside the results.
+//
+// Steps which have not started when ctx is done are skipped, and ctx's error
+// is returned if no step failed.
+func (p *Plan) Run(ctx context.Context) ([]*PlanResult, error) {
+	pending, dependents, err := p.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*PlanResult, len(p.Steps))
+	var ready []int
+	for i, step := range p.Steps {
+		results[i] = &PlanResult{
+			Name: step.Name,
+			BatchResult: BatchResult{
+				Command:  step.Command,
+				Args:     step.Args,
+				ExitCode: -1,
+				Skipped:  true,
+			},
+		}
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	done := make(chan int)
+	running := 0
+	failed := false
+	for {
+		for len(ready) > 0 && ctx.Err() == nil &&
+			(!failed || p.ContinueOnFailure) &&
+			(p.Concurrency <= 0 || running < p.Concurrency) {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+
+			go func(i int) {
+				runBatchCommand(
+					ctx, p.Runner, p.Steps[i].BatchCommand,
+					&results[i].BatchResult,
+				)
+				done <- i
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+
+		i := <-done
+		running--
+		if results[i].Err != nil {
+			failed = true
+
+			continue
+		}
+
+		for _, j := range dependents[i] {
+			pending[j]--
+			if pending[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	perr := &PlanError{}
+	for _, res := range results {
+		switch {
+		case res.Err != nil:
+			perr.Failed = append(perr.Failed, res)
+		case res.Skipped:
+			perr.Skipped = append(perr.Skipped, res.Name)
+		}
+	}
+	if len(perr.Failed) > 0 {
+		return results, perr
+	}
+
+	return results, ctx.Err()
+}
+
+// graph returns the number of dependencies of each step, and the indexes of
+// the steps depending on each step.
+func (p *Plan) graph() ([]int, [][]int, error) {
+	index := make(map[string]int, len(p.Steps))
+	for i, step := range p.Steps {
+		if _, ok := index[step.Name]; ok {
+			return nil, nil, fmt.Errorf(
+				"%w: %q", ErrPlanDuplicateStep, step.Name,
+			)
+		}
+		index[step.Name] = i
+	}
+
+	pending := make([]int, len(p.Steps))
+	dependents := make([][]int, len(p.Steps))
+	for i, step := range p.Steps {
+		for _, dep := range step.DependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, nil, fmt.Errorf(
+					"%w: %q depends on %q",
+					ErrPlanUnknownDependency, step.Name, dep,
+				)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	// Check for cycles by removing steps without pending dependencies until
+	// none remain.
+	remaining := append([]int(nil), pending...)
+	var queue []int
+	for i, n := range remaining {
+		if n == 0 {
+			queue = append(queue, i)
+		}
+	}
+	visited := 0
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		visited++
+
+		for _, j := range dependents[i] {
+			remaining[j]--
+			if remaining[j] == 0 {
+				queue = append(queue, j)
+			}
+		}
+	}
+	if visited < len(p.Steps) {
+		var names []string
+		for i, n := range remaining {
+			if n > 0 {
+				names = append(names, p.Steps[i].Name)
+			}
+		}
+
+		return nil, nil, fmt.Errorf(
+			"%w: %s", ErrPlanCycle, strings.Join(names, ", "),
+		)
+	}
+
+	return pending, dependents, nil
+}
@@ -0,0 +1,242 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// orderStub returns a Stub which records the order commands start and finish
+// in, failing commands named "fail".
+func orderStub(mu *sync.Mutex, events *[]string) *Stub {
+	return &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, _ io.Writer,
+		command string,
+		args ...string,
+	) error {
+		mu.Lock()
+		*events = append(*events, "start "+args[0])
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+		_, _ = io.WriteString(stdout, args[0])
+
+		mu.Lock()
+		*events = append(*events, "end "+args[0])
+		mu.Unlock()
+
+		if command == "fail" {
+			return &FakeExitError{Command: command, Code: 1}
+		}
+
+		return nil
+	}}
+}
+
+func step(name, command string, deps ...string) PlanStep {
+	return PlanStep{
+		Name:         name,
+		BatchCommand: BatchCommand{Command: command, Args: []string{name}},
+		DependsOn:    deps,
+	}
+}
+
+func TestPlan_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		steps       []PlanStep
+		continueOn  bool
+		concurrency int
+		wantRun     []string
+		wantSkipped []string
+		wantErr     string
+		// wantBefore lists pairs of steps where the first must end before
+		// the second starts.
+		wantBefore [][2]string
+	}{
+		{
+			name: "diamond",
+			steps: []PlanStep{
+				step("d", "ok", "b", "c"),
+				step("b", "ok", "a"),
+				step("c", "ok", "a"),
+				step("a", "ok"),
+			},
+			wantRun: []string{"a", "b", "c", "d"},
+			wantBefore: [][2]string{
+				{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"},
+			},
+		},
+		{
+			name: "stop on failure",
+			steps: []PlanStep{
+				step("a", "fail"),
+				step("b", "ok", "a"),
+				step("c", "ok", "x"),
+				step("x", "ok"),
+			},
+			concurrency: 1,
+			wantRun:     []string{"a"},
+			wantSkipped: []string{"b", "c", "x"},
+			wantErr: "runner: plan: 1 steps failed: " +
+				"a: fail: exit status 1 (skipped: b, c, x)",
+		},
+		{
+			name: "continue on failure",
+			steps: []PlanStep{
+				step("a", "fail"),
+				step("b", "ok", "a"),
+				step("c", "ok", "b"),
+				step("x", "ok"),
+				step("y", "ok", "x"),
+			},
+			continueOn:  true,
+			concurrency: 1,
+			wantRun:     []string{"a", "x", "y"},
+			wantSkipped: []string{"b", "c"},
+			wantErr: "runner: plan: 1 steps failed: " +
+				"a: fail: exit status 1 (skipped: b, c)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var events []string
+			p := &Plan{
+				Runner:            orderStub(&mu, &events),
+				Steps:             tt.steps,
+				Concurrency:       tt.concurrency,
+				ContinueOnFailure: tt.continueOn,
+			}
+
+			results, err := p.Run(context.Background())
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrPlan)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			require.Len(t, results, len(tt.steps))
+			var run, skipped []string
+			for i, res := range results {
+				assert.Equal(t, tt.steps[i].Name, res.Name)
+				if res.Skipped {
+					skipped = append(skipped, res.Name)
+
+					continue
+				}
+				run = append(run, res.Name)
+				assert.Equal(t, res.Name, string(res.Stdout))
+			}
+			assert.ElementsMatch(t, tt.wantRun, run)
+			assert.ElementsMatch(t, tt.wantSkipped, skipped)
+
+			pos := map[string]int{}
+			for i, e := range events {
+				pos[e] = i
+			}
+			for _, pair := range tt.wantBefore {
+				assert.Less(
+					t, pos["end "+pair[0]], pos["start "+pair[1]],
+					"%s must end before %s starts", pair[0], pair[1],
+				)
+			}
+		})
+	}
+}
+
+func TestPlan_Run_parallel(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	p := &Plan{
+		Runner: orderStub(&mu, &events),
+		Steps: []PlanStep{
+			step("a", "ok"),
+			step("b", "ok"),
+			step("c", "ok", "a", "b"),
+		},
+	}
+
+	_, err := p.Run(context.Background())
+	require.NoError(t, err)
+
+	// a and b have no dependencies, so both start before either ends.
+	assert.ElementsMatch(t, []string{"start a", "start b"}, events[:2])
+	assert.Equal(t, []string{"start c", "end c"}, events[4:])
+}
+
+func TestPlan_Run_contextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := &Plan{Runner: &Fake{}, Steps: []PlanStep{step("a", "ok")}}
+	results, err := p.Run(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, results[0].Skipped)
+}
+
+func TestPlan_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []PlanStep
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			steps: []PlanStep{step("a", "ok"), step("b", "ok", "a", "a")},
+		},
+		{
+			name:    "duplicate",
+			steps:   []PlanStep{step("a", "ok"), step("a", "ok")},
+			wantErr: `runner: plan: invalid: duplicate step: "a"`,
+		},
+		{
+			name:  "unknown dependency",
+			steps: []PlanStep{step("a", "ok", "b")},
+			wantErr: `runner: plan: invalid: unknown dependency: ` +
+				`"a" depends on "b"`,
+		},
+		{
+			name: "cycle",
+			steps: []PlanStep{
+				step("a", "ok"),
+				step("b", "ok", "a", "d"),
+				step("c", "ok", "b"),
+				step("d", "ok", "c"),
+			},
+			wantErr: "runner: plan: invalid: dependency cycle: b, c, d",
+		},
+		{
+			name:    "self dependency",
+			steps:   []PlanStep{step("a", "ok", "a")},
+			wantErr: "runner: plan: invalid: dependency cycle: a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Plan{Steps: tt.steps}
+
+			err := p.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrPlanInvalid)
+
+			_, err = p.Run(context.Background())
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrPolicy       = fmt.Errorf("%w: policy", Err)
+	ErrPolicyDenied = fmt.Errorf("%w: command denied", ErrPolicy)
+)
+
+// PolicyRule matches commands for a Policy.
+type PolicyRule struct {
+	// Command is a glob pattern as supported by path.Match, which must match
+	// the command. Patterns without a "/" also match the base name of commands
+	// given as a path, so "rm" matches "rm", "/bin/rm", and "./rm", while
+	// patterns with a "/" must match the command exactly as given.
+	Command string
+
+	// Args is a list of glob patterns matched against the leading arguments
+	// of commands, in order. Commands may have further arguments beyond those
+	// matched. When empty, commands with any arguments are matched.
+	Args []string
+}
+
+// Match reports if the given command and arguments match the rule.
+func (pr PolicyRule) Match(command string, args []string) bool {
+	return pr.match(command, args, true)
+}
+
+// match reports if the given command and arguments match the rule. The base
+// name of command is only considered when base is true.
+func (pr PolicyRule) match(command string, args []string, base bool) bool {
+	ok, _ := path.Match(pr.Command, command)
+	if !ok && base && !strings.Contains(pr.Command, "/") {
+		ok, _ = path.Match(pr.Command, filepath.Base(command))
+	}
+	if !ok {
+		return false
+	}
+	if len(args) < len(pr.Args) {
+		return false
+	}
+
+	for i, pattern := range pr.Args {
+		if ok, _ := path.Match(pattern, args[i]); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PolicyError is returned by Policy when a command is denied.
+type PolicyError struct {
+	// Command is the denied command.
+	Command string
+
+	// Args are the arguments of the denied command.
+	Args []string
+
+	// Rule is the deny rule the command matched, or nil if it was denied for
+	// not matching any allow rule.
+	Rule *PolicyRule
+}
+
+var _ error = &PolicyError{}
+
+func (e *PolicyError) Error() string {
+	cmdline := strings.Join(append([]string{e.Command}, e.Args...), " ")
+
+	return fmt.Sprintf("%s: %s", ErrPolicyDenied.Error(), cmdline)
+}
+
+// Unwrap returns ErrPolicyDenied.
+func (e *PolicyError) Unwrap() error {
+	return ErrPolicyDenied
+}
+
+// Policy is a Runner that wraps another Runner, and only runs commands which
+// are permitted by its rules, returning a *PolicyError for any others.
+//
+// Rules are evaluated against the command and arguments Policy is given. To
+// evaluate them against the final command after any wrapping by runners like
+// Sudo or SSHCLI, Policy must wrap the runner which executes commands, and
+// be wrapped by the others. Rules do not see through sudo or ssh commands
+// given by such layers above Policy, so when Policy is wrapped by Sudo, a rule
+// denying "rm" does not deny the "sudo -n -- rm" command it is given, and
+// rules must be written against the arguments of sudo instead.
+//
+// Deny rules match commands given as a path by their base name, as described
+// by PolicyRule.Match, and are also evaluated against commands run via the
+// env command, so a rule denying "rm" also denies "/bin/rm" and
+// "/usr/bin/env rm". When any Deny rules are set, env commands given options
+// which are not recognised are denied, as the command they run cannot be
+// determined. Allow rules only match commands exactly as given, so a
+// rule allowing "ls" does not allow "/tmp/ls". As commands can be run in many
+// other indirect ways, like via "sh -c", Allow rules should be preferred
+// where possible.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Policy struct {
+	// Runner is the underlying Runner to run permitted commands with. If not
+	// set, running commands will cause a panic.
+	Runner Runner
+
+	// Allow is a list of rules, one of which commands must match to be run.
+	// When empty, all commands not matching a Deny rule are run.
+	Allow []PolicyRule
+
+	// Deny is a list of rules which commands must not match to be run. Deny
+	// rules take precedence over Allow rules.
+	Deny []PolicyRule
+}
+
+var _ Runner = &Policy{}
+
+// Run calls Run on the underlying Runner if the command is permitted.
+//
+// Will panic if Runner field is nil.
+func (r *Policy) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := r.Check(command, args...)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext calls RunContext on the underlying Runner if the command is
+// permitted.
+//
+// Will panic if Runner field is nil.
+func (r *Policy) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := r.Check(command, args...)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Check returns a *PolicyError if the given command is not permitted, and nil
+// otherwise.
+func (r *Policy) Check(command string, args ...string) error {
+	if rule, denied := r.denyRule(command, args); denied {
+		return newPolicyError(command, args, rule)
+	}
+
+	if len(r.Allow) == 0 {
+		return nil
+	}
+	for _, rule := range r.Allow {
+		if rule.match(command, args, false) {
+			return nil
+		}
+	}
+
+	return newPolicyError(command, args, nil)
+}
+
+// denyRule returns the first Deny rule matching the command, or the command
+// run by it when it is the env command. denied is also true without a rule
+// when the command run by env cannot be determined, as it is given options
+// which are not recognised.
+func (r *Policy) denyRule(
+	command string,
+	args []string,
+) (rule *PolicyRule, denied bool) {
+	for {
+		for i := range r.Deny {
+			if r.Deny[i].Match(command, args) {
+				return &r.Deny[i], true
+			}
+		}
+
+		if len(r.Deny) == 0 || filepath.Base(command) != "env" {
+			return nil, false
+		}
+
+		var err error
+		command, args, err = envCommand(args)
+		if err != nil {
+			return nil, true
+		}
+		if command == "" {
+			return nil, false
+		}
+	}
+}
+
+var errEnvUnknownOption = errors.New("unknown env option")
+
+// envCommand returns the command and arguments run by the env command when
+// given args, skipping any options and "key=value" variables before it. The
+// command is empty if env is not given one. Both GNU and BSD options are
+// supported, and an error is returned for any others.
+func envCommand(args []string) (string, []string, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var name, value string
+		var hasValue bool
+		switch {
+		case arg == "--":
+			if i+1 == len(args) {
+				return "", nil, nil
+			}
+
+			return args[i+1], args[i+2:], nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue = strings.Cut(arg[2:], "=")
+			switch name {
+			case "ignore-environment", "null", "debug", "help", "version",
+				"list-signal-handling", "default-signal", "ignore-signal",
+				"block-signal":
+				continue
+			case "unset", "chdir", "split-string":
+			default:
+				return "", nil, errEnvUnknownOption
+			}
+		case arg == "-":
+			continue
+		case strings.HasPrefix(arg, "-"):
+			// Short options may be combined, with the first which takes a
+			// value taking the rest of the argument, if any.
+			for j := 1; j < len(arg) && name == ""; j++ {
+				switch c := arg[j]; {
+				case c == 'i' || c == '0' || c == 'v':
+				case strings.IndexByte("uCSPLU", c) >= 0:
+					name, value = string(c), arg[j+1:]
+					hasValue = value != ""
+				default:
+					return "", nil, errEnvUnknownOption
+				}
+			}
+			if name == "" {
+				continue
+			}
+		case strings.Contains(arg, "="):
+			continue
+		default:
+			return arg, args[i+1:], nil
+		}
+
+		if !hasValue {
+			if i+1 == len(args) {
+				return "", nil, nil
+			}
+			i++
+			value = args[i]
+		}
+		if name == "S" || name == "split-string" {
+			args = append(strings.Fields(value), args[i+1:]...)
+			i = -1
+		}
+	}
+
+	return "", nil, nil
+}
+
+func newPolicyError(command string, args []string, rule *PolicyRule) error {
+	e := &PolicyError{Command: command, Args: copyStrings(args)}
+	if rule != nil {
+		ruleCopy := *rule
+		e.Rule = &ruleCopy
+	}
+
+	return e
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Policy) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,298 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var policyTestCases = []struct {
+	name     string
+	allow    []PolicyRule
+	deny     []PolicyRule
+	command  string
+	args     []string
+	err      error
+	wantRule *PolicyRule
+	wantErr  string
+}{
+	{
+		name:    "no rules",
+		command: "rm",
+		args:    []string{"-rf", "/"},
+	},
+	{
+		name:    "allowed",
+		allow:   []PolicyRule{{Command: "ls"}, {Command: "git"}},
+		command: "git",
+		args:    []string{"status"},
+	},
+	{
+		name:    "not allowed",
+		allow:   []PolicyRule{{Command: "ls"}, {Command: "git"}},
+		command: "rm",
+		args:    []string{"-rf", "/"},
+		wantErr: "runner: policy: command denied: rm -rf /",
+	},
+	{
+		name:    "allowed by glob",
+		allow:   []PolicyRule{{Command: "systemctl", Args: []string{"status"}}},
+		command: "systemctl",
+		args:    []string{"status", "nginx"},
+	},
+	{
+		name: "not allowed by args",
+		allow: []PolicyRule{
+			{Command: "systemctl", Args: []string{"status", "*"}},
+		},
+		command: "systemctl",
+		args:    []string{"stop", "nginx"},
+		wantErr: "runner: policy: command denied: systemctl stop nginx",
+	},
+	{
+		name: "too few args",
+		allow: []PolicyRule{
+			{Command: "systemctl", Args: []string{"status", "*"}},
+		},
+		command: "systemctl",
+		args:    []string{"status"},
+		wantErr: "runner: policy: command denied: systemctl status",
+	},
+	{
+		name:    "command must match exactly",
+		allow:   []PolicyRule{{Command: "ls"}},
+		command: "/tmp/ls",
+		wantErr: "runner: policy: command denied: /tmp/ls",
+	},
+	{
+		name:    "glob does not match path",
+		allow:   []PolicyRule{{Command: "*"}},
+		command: "/tmp/ls",
+		wantErr: "runner: policy: command denied: /tmp/ls",
+	},
+	{
+		name:     "denied",
+		deny:     []PolicyRule{{Command: "rm", Args: []string{"-rf"}}},
+		command:  "rm",
+		args:     []string{"-rf", "/"},
+		wantRule: &PolicyRule{Command: "rm", Args: []string{"-rf"}},
+		wantErr:  "runner: policy: command denied: rm -rf /",
+	},
+	{
+		name:     "denied by path",
+		deny:     []PolicyRule{{Command: "rm"}},
+		command:  "/bin/rm",
+		args:     []string{"-rf", "/"},
+		wantRule: &PolicyRule{Command: "rm"},
+		wantErr:  "runner: policy: command denied: /bin/rm -rf /",
+	},
+	{
+		name:     "denied by relative path",
+		deny:     []PolicyRule{{Command: "rm"}},
+		command:  "./rm",
+		args:     []string{"-rf", "/"},
+		wantRule: &PolicyRule{Command: "rm"},
+		wantErr:  "runner: policy: command denied: ./rm -rf /",
+	},
+	{
+		name:     "denied via env",
+		deny:     []PolicyRule{{Command: "rm", Args: []string{"-rf"}}},
+		command:  "/usr/bin/env",
+		args:     []string{"-i", "-u", "HOME", "FOO=bar", "rm", "-rf", "/"},
+		wantRule: &PolicyRule{Command: "rm", Args: []string{"-rf"}},
+		wantErr: "runner: policy: command denied: " +
+			"/usr/bin/env -i -u HOME FOO=bar rm -rf /",
+	},
+	{
+		name:     "denied via env split string",
+		deny:     []PolicyRule{{Command: "rm", Args: []string{"-rf"}}},
+		command:  "env",
+		args:     []string{"-S", "/bin/rm -rf", "/"},
+		wantRule: &PolicyRule{Command: "rm", Args: []string{"-rf"}},
+		wantErr:  "runner: policy: command denied: env -S /bin/rm -rf /",
+	},
+	{
+		name:     "denied via env combined options",
+		deny:     []PolicyRule{{Command: "rm"}},
+		command:  "env",
+		args:     []string{"-iu", "X", "rm", "-rf", "/"},
+		wantRule: &PolicyRule{Command: "rm"},
+		wantErr:  "runner: policy: command denied: env -iu X rm -rf /",
+	},
+	{
+		name:     "denied via env combined split string",
+		deny:     []PolicyRule{{Command: "rm"}},
+		command:  "env",
+		args:     []string{"-iS", "rm -rf /"},
+		wantRule: &PolicyRule{Command: "rm"},
+		wantErr:  "runner: policy: command denied: env -iS rm -rf /",
+	},
+	{
+		name:     "denied via env attached option values",
+		deny:     []PolicyRule{{Command: "rm"}},
+		command:  "env",
+		args:     []string{"-uX", "--chdir=/", "--unset", "Y", "rm"},
+		wantRule: &PolicyRule{Command: "rm"},
+		wantErr: "runner: policy: command denied: " +
+			"env -uX --chdir=/ --unset Y rm",
+	},
+	{
+		name:    "denied via env unknown option",
+		deny:    []PolicyRule{{Command: "rm"}},
+		command: "env",
+		args:    []string{"-iX", "rm", "-rf", "/"},
+		wantErr: "runner: policy: command denied: env -iX rm -rf /",
+	},
+	{
+		name:    "env not denied",
+		deny:    []PolicyRule{{Command: "rm"}},
+		command: "env",
+		args:    []string{"-iu", "X", "--", "ls", "rm"},
+	},
+	{
+		name:    "denied path pattern must match exactly",
+		deny:    []PolicyRule{{Command: "/bin/rm"}},
+		command: "/usr/bin/rm",
+		args:    []string{"foo.txt"},
+	},
+	{
+		name:    "env without command",
+		deny:    []PolicyRule{{Command: "rm"}},
+		command: "env",
+		args:    []string{"-i", "FOO=bar"},
+	},
+	{
+		name:    "not denied",
+		deny:    []PolicyRule{{Command: "rm", Args: []string{"-rf"}}},
+		command: "rm",
+		args:    []string{"foo.txt"},
+	},
+	{
+		name:     "deny takes precedence",
+		allow:    []PolicyRule{{Command: "sudo"}},
+		deny:     []PolicyRule{{Command: "sudo", Args: []string{"-n", "su*"}}},
+		command:  "sudo",
+		args:     []string{"-n", "su", "-"},
+		wantRule: &PolicyRule{Command: "sudo", Args: []string{"-n", "su*"}},
+		wantErr:  "runner: policy: command denied: sudo -n su -",
+	},
+	{
+		name:    "underlying error",
+		allow:   []PolicyRule{{Command: "zfs"}},
+		command: "zfs",
+		args:    []string{"list"},
+		err:     errors.New("zfs: command not found"),
+		wantErr: "zfs: command not found",
+	},
+}
+
+func TestPolicy_Run(t *testing.T) {
+	for _, tt := range policyTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			denied := tt.wantErr != "" && tt.err == nil
+			if !denied {
+				r.EXPECT().Run(nil, nil, nil, tt.command, tt.args).
+					Return(tt.err)
+			}
+
+			p := &Policy{Runner: r, Allow: tt.allow, Deny: tt.deny}
+			err := p.Run(nil, nil, nil, tt.command, tt.args...)
+
+			assertPolicyErr(t, err, denied, tt.wantErr, tt.wantRule)
+		})
+	}
+}
+
+func TestPolicy_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range policyTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			denied := tt.wantErr != "" && tt.err == nil
+			if !denied {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx), nil, nil, nil, tt.command, tt.args,
+				).Return(tt.err)
+			}
+
+			p := &Policy{Runner: r, Allow: tt.allow, Deny: tt.deny}
+			err := p.RunContext(ctx, nil, nil, nil, tt.command, tt.args...)
+
+			assertPolicyErr(t, err, denied, tt.wantErr, tt.wantRule)
+		})
+	}
+}
+
+func assertPolicyErr(
+	t *testing.T,
+	err error,
+	denied bool,
+	wantErr string,
+	wantRule *PolicyRule,
+) {
+	t.Helper()
+
+	if wantErr == "" {
+		assert.NoError(t, err)
+
+		return
+	}
+
+	assert.EqualError(t, err, wantErr)
+	if !denied {
+		assert.NotErrorIs(t, err, ErrPolicy)
+
+		return
+	}
+
+	assert.ErrorIs(t, err, ErrPolicyDenied)
+	assert.ErrorIs(t, err, Err)
+
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Equal(t, wantRule, policyErr.Rule)
+}
+
+func TestPolicy_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	p := &Policy{Runner: r}
+	p.Env("FOO=BAR", "PORT=8080")
+}
+
+func TestPolicy_wrapped(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Run(
+		nil, nil, nil, "sudo", []string{"-n", "--", "whoami"},
+	).Return(nil)
+
+	s := &Sudo{
+		Runner: &Policy{
+			Runner: r,
+			Deny: []PolicyRule{
+				{Command: "sudo", Args: []string{"-n", "--", "su"}},
+			},
+		},
+	}
+
+	err := s.Run(nil, nil, nil, "whoami")
+	assert.NoError(t, err)
+
+	err = s.Run(nil, nil, nil, "su", "-")
+	assert.ErrorIs(t, err, ErrPolicyDenied)
+}
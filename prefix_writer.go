@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// PrefixWriter is an io.Writer which prefixes each line written to it with a
+// fixed prefix, like "[host1/stderr] ", before forwarding it to the underlying
+// writer.
+//
+// Partial lines are buffered until they are completed by a newline, and all
+// complete lines in each write are forwarded with a single call to the
+// underlying writer's Write method. So when several PrefixWriters share an
+// underlying writer which serializes writes, like a SyncWriter, lines from
+// each are never interleaved mid-line.
+//
+// Flush must be called once all data has been written, to forward any final
+// line which lacks a trailing newline. PrefixWriter is safe for concurrent
+// use.
+type PrefixWriter struct {
+	w      io.Writer
+	prefix []byte
+
+	mu      sync.Mutex
+	partial []byte
+	out     []byte
+}
+
+var _ io.WriteCloser = &PrefixWriter{}
+
+// NewPrefixWriter returns a PrefixWriter which writes lines prefixed with
+// prefix to w.
+func NewPrefixWriter(w io.Writer, prefix string) *PrefixWriter {
+	return &PrefixWriter{w: w, prefix: []byte(prefix)}
+}
+
+// Write buffers p, and forwards any lines it completes to the underlying
+// writer.
+func (pw *PrefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.out = pw.out[:0]
+	for rest := p; len(rest) > 0; {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			pw.partial = append(pw.partial, rest...)
+
+			break
+		}
+
+		pw.out = append(pw.out, pw.prefix...)
+		pw.out = append(pw.out, pw.partial...)
+		pw.out = append(pw.out, rest[:i+1]...)
+		pw.partial = pw.partial[:0]
+		rest = rest[i+1:]
+	}
+
+	if len(pw.out) > 0 {
+		_, err := pw.w.Write(pw.out)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush forwards any buffered partial line to the underlying writer, without
+// adding a trailing newline.
+func (pw *PrefixWriter) Flush() error {
+	return pw.flush(false)
+}
+
+// flush forwards any buffered partial line to the underlying writer, adding a
+// trailing newline if newline is true.
+func (pw *PrefixWriter) flush(newline bool) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.partial) == 0 {
+		return nil
+	}
+
+	pw.out = append(append(pw.out[:0], pw.prefix...), pw.partial...)
+	if newline {
+		pw.out = append(pw.out, '\n')
+	}
+	pw.partial = pw.partial[:0]
+
+	_, err := pw.w.Write(pw.out)
+
+	return err
+}
+
+// Close calls Flush. It does not close the underlying writer.
+func (pw *PrefixWriter) Close() error {
+	return pw.Flush()
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeRecorder records each call to Write.
+type writeRecorder struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writes = append(w.writes, string(p))
+
+	return len(p), nil
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		writes     []string
+		wantWrites []string
+		wantFlush  string
+	}{
+		{
+			name:       "lines",
+			writes:     []string{"hello\nworld\n"},
+			wantWrites: []string{"[a] hello\n[a] world\n"},
+		},
+		{
+			name:   "partial lines",
+			writes: []string{"hel", "lo\nwor", "ld", "\n\n", "end"},
+			wantWrites: []string{
+				"[a] hello\n", "[a] world\n[a] \n",
+			},
+			wantFlush: "[a] end",
+		},
+		{
+			name:   "empty writes",
+			writes: []string{"", "a\n", ""},
+			wantWrites: []string{
+				"[a] a\n",
+			},
+		},
+		{
+			name:   "CRLF",
+			writes: []string{"a\r\n"},
+			wantWrites: []string{
+				"[a] a\r\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &writeRecorder{}
+			pw := NewPrefixWriter(w, "[a] ")
+
+			for _, s := range tt.writes {
+				n, err := pw.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+			assert.Equal(t, tt.wantWrites, w.writes)
+
+			require.NoError(t, pw.Close())
+			want := tt.wantWrites
+			if tt.wantFlush != "" {
+				want = append(want, tt.wantFlush)
+			}
+			assert.Equal(t, want, w.writes)
+
+			require.NoError(t, pw.Flush())
+			assert.Equal(t, want, w.writes)
+		})
+	}
+}
+
+func TestPrefixWriter_error(t *testing.T) {
+	errBoom := errors.New("boom")
+	pw := NewPrefixWriter(&errWriter{err: errBoom}, "> ")
+
+	n, err := pw.Write([]byte("partial"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+
+	n, err = pw.Write([]byte("\n"))
+	assert.ErrorIs(t, err, errBoom)
+	assert.Zero(t, n)
+
+	_, _ = pw.Write([]byte("end"))
+	assert.ErrorIs(t, pw.Flush(), errBoom)
+}
+
+func TestPrefixWriter_concurrent(t *testing.T) {
+	w := &writeRecorder{}
+	r := &Local{}
+
+	var wg sync.WaitGroup
+	for _, host := range []string{"host1", "host2", "host3"} {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+
+			stdout := NewPrefixWriter(w, "["+host+"/stdout] ")
+			stderr := NewPrefixWriter(w, "["+host+"/stderr] ")
+			defer stdout.Close()
+			defer stderr.Close()
+
+			err := r.Run(
+				nil, stdout, stderr, "sh", "-c",
+				`for i in 1 2 3; do printf 'out '; echo $i; `+
+					`printf 'err ' >&2; echo $i >&2; done`,
+			)
+			assert.NoError(t, err)
+		}(host)
+	}
+	wg.Wait()
+
+	var lines []string
+	for _, s := range w.writes {
+		lines = append(lines, strings.SplitAfter(s, "\n")...)
+	}
+
+	counts := map[string]int{}
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		assert.Regexp(
+			t, `^\[host\d/(stdout|stderr)\] (out|err) \d\n$`, line,
+		)
+		counts[line]++
+	}
+	assert.Len(t, counts, 18)
+}
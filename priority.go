@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// IOClass is an I/O scheduling class as understood by ionice.
+type IOClass int
+
+const (
+	// IOClassNone leaves the I/O scheduling class unchanged, meaning ionice
+	// will not be used.
+	IOClassNone IOClass = 0
+
+	// IOClassRealtime gives commands first access to the disk, regardless of
+	// what else is going on in the system.
+	IOClassRealtime IOClass = 1
+
+	// IOClassBestEffort is the default scheduling class for processes that
+	// have not asked for a specific I/O priority.
+	IOClassBestEffort IOClass = 2
+
+	// IOClassIdle only gives commands disk time when no other program has
+	// asked for disk I/O for a defined grace period.
+	IOClassIdle IOClass = 3
+)
+
+// Priority is a Runner that wraps another Runner and runs commands with
+// adjusted CPU and I/O scheduling priorities via nice and ionice.
+//
+// As nice and ionice pass their own environment and working directory through
+// to the command they execute, calls to Env are passed directly to the
+// underlying Runner, as are environment variables and working directories
+// given for a single command with RunWith.
+type Priority struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with nice and/or ionice. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Niceness is the niceness adjustment passed to nice via the -n flag.
+	// Positive values lower the priority of commands, while negative values
+	// raise it. When 0, nice will not be used.
+	Niceness int
+
+	// IOClass is the I/O scheduling class passed to ionice via the -c flag.
+	// When IOClassNone, ionice will not be used.
+	IOClass IOClass
+
+	// IOLevel is the I/O scheduling priority within IOClass, from 0 (highest)
+	// to 7 (lowest), passed to ionice via the -n flag. It is only used with the
+	// IOClassRealtime and IOClassBestEffort classes.
+	IOLevel int
+}
+
+var _ Runner = &Priority{}
+
+var _ Validator = &Priority{}
+
+// Validate checks the Priority is configured correctly, so misconfiguration
+// is reported before any command is run. It checks Runner is set, that
+// IOClass is a known class, and that IOLevel is within 0 to 7. The underlying
+// Runner is validated too.
+func (r *Priority) Validate() error {
+	var err error
+	switch {
+	case r.IOClass < IOClassNone || r.IOClass > IOClassIdle:
+		err = &ValidationError{
+			Field: "Priority.IOClass",
+			Msg:   fmt.Sprintf("unknown class %d", r.IOClass),
+		}
+	case r.IOLevel < 0 || r.IOLevel > 7:
+		err = &ValidationError{
+			Field: "Priority.IOLevel",
+			Msg:   fmt.Sprintf("invalid level %d", r.IOLevel),
+		}
+	}
+
+	return firstError(
+		validateRunner("Priority.Runner", r.Runner),
+		err,
+		Validate(r.Runner),
+	)
+}
+
+// Run executes the command via nice and/or ionice by calling Run on the
+// underlying Runner. Will panic if Runner field is nil.
+func (r *Priority) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	command, args = r.args(command, args)
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext executes the command via nice and/or ionice by calling RunContext
+// on the underlying Runner. Will panic if Runner field is nil.
+func (r *Priority) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	command, args = r.args(command, args)
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+func (r *Priority) args(command string, args []string) (string, []string) {
+	if r.IOClass != IOClassNone {
+		ioniceArgs := []string{"-c", strconv.Itoa(int(r.IOClass))}
+		if r.IOClass == IOClassRealtime || r.IOClass == IOClassBestEffort {
+			ioniceArgs = append(ioniceArgs, "-n", strconv.Itoa(r.IOLevel))
+		}
+		ioniceArgs = append(ioniceArgs, "--", command)
+		command, args = "ionice", append(ioniceArgs, args...)
+	}
+
+	if r.Niceness != 0 {
+		niceArgs := []string{"-n", strconv.Itoa(r.Niceness), "--", command}
+		command, args = "nice", append(niceArgs, args...)
+	}
+
+	return command, args
+}
+
+// Env sets the environment by calling Env on the underlying Runner. Will panic
+// if Runner field is nil.
+func (r *Priority) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,231 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var priorityTestCases = []struct {
+	name        string
+	runner      Priority
+	stdin       io.Reader
+	stdout      io.Writer
+	stderr      io.Writer
+	command     string
+	args        []string
+	err         error
+	wantCommand string
+	wantArgs    []string
+	wantErr     string
+}{
+	{
+		name:        "no priority changes",
+		runner:      Priority{},
+		stdout:      &bytes.Buffer{},
+		stderr:      &bytes.Buffer{},
+		command:     "tar",
+		args:        []string{"-czf", "backup.tgz", "/srv"},
+		wantCommand: "tar",
+		wantArgs:    []string{"-czf", "backup.tgz", "/srv"},
+	},
+	{
+		name:        "with Niceness",
+		runner:      Priority{Niceness: 10},
+		stdin:       bytes.NewBufferString("foo\nbar"),
+		command:     "gzip",
+		wantCommand: "nice",
+		wantArgs:    []string{"-n", "10", "--", "gzip"},
+	},
+	{
+		name:        "with negative Niceness",
+		runner:      Priority{Niceness: -5},
+		command:     "myapp",
+		wantCommand: "nice",
+		wantArgs:    []string{"-n", "-5", "--", "myapp"},
+	},
+	{
+		name:        "with IOClassIdle",
+		runner:      Priority{IOClass: IOClassIdle, IOLevel: 4},
+		command:     "rsync",
+		args:        []string{"-a", "src/", "dst/"},
+		wantCommand: "ionice",
+		wantArgs:    []string{"-c", "3", "--", "rsync", "-a", "src/", "dst/"},
+	},
+	{
+		name:        "with IOClassBestEffort",
+		runner:      Priority{IOClass: IOClassBestEffort, IOLevel: 7},
+		command:     "rsync",
+		wantCommand: "ionice",
+		wantArgs:    []string{"-c", "2", "-n", "7", "--", "rsync"},
+	},
+	{
+		name:        "with IOClassRealtime",
+		runner:      Priority{IOClass: IOClassRealtime},
+		command:     "rsync",
+		wantCommand: "ionice",
+		wantArgs:    []string{"-c", "1", "-n", "0", "--", "rsync"},
+	},
+	{
+		name: "with Niceness and IOClass",
+		runner: Priority{
+			Niceness: 19,
+			IOClass:  IOClassBestEffort,
+			IOLevel:  6,
+		},
+		command:     "pg_dump",
+		args:        []string{"mydb"},
+		wantCommand: "nice",
+		wantArgs: []string{
+			"-n", "19", "--",
+			"ionice", "-c", "2", "-n", "6", "--",
+			"pg_dump", "mydb",
+		},
+	},
+	{
+		name:        "error",
+		runner:      Priority{Niceness: 10},
+		command:     "zfs",
+		args:        []string{"list"},
+		err:         errors.New("zfs: command not found"),
+		wantCommand: "nice",
+		wantArgs:    []string{"-n", "10", "--", "zfs", "list"},
+		wantErr:     "zfs: command not found",
+	},
+}
+
+func TestPriority_Run(t *testing.T) {
+	for _, tt := range priorityTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().Run(
+				tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+			).Return(tt.err)
+
+			p := tt.runner
+			p.Runner = r
+
+			err := p.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPriority_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range priorityTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				tt.stdin, tt.stdout, tt.stderr, tt.wantCommand, tt.wantArgs,
+			).Return(tt.err)
+
+			p := tt.runner
+			p.Runner = r
+
+			err := p.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPriority_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env("FOO=BAR", "PORT=8080")
+
+	p := &Priority{Runner: r}
+	p.Env("FOO=BAR", "PORT=8080")
+}
+
+func TestPriority_RunWith(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+
+	p := &Priority{Runner: &Local{}, Niceness: 5}
+	err := RunWith(
+		context.Background(), p,
+		[]RunOption{
+			WithEnv("FOO=call"), WithDir(dir), WithStdout(&stdout),
+		},
+		"sh", "-c", `echo "$FOO"; pwd -P`,
+	)
+	assert.NoError(t, err)
+
+	wantDir, err := filepath.EvalSymlinks(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "call\n"+wantDir+"\n", stdout.String())
+}
+
+func TestPriority_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Priority
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name: "valid",
+			runner: &Priority{
+				Runner: &Local{}, Niceness: 10, IOClass: IOClassIdle,
+			},
+		},
+		{
+			name:    "no runner",
+			runner:  &Priority{Niceness: 10},
+			wantErr: "runner: invalid: Priority.Runner: must be set",
+		},
+		{
+			name:    "unknown class",
+			runner:  &Priority{Runner: &Local{}, IOClass: 4},
+			wantErr: "runner: invalid: Priority.IOClass: unknown class 4",
+		},
+		{
+			name: "invalid level",
+			runner: &Priority{
+				Runner: &Local{}, IOClass: IOClassBestEffort, IOLevel: 8,
+			},
+			wantErr: "runner: invalid: Priority.IOLevel: invalid level 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+package runner
+
+import "fmt"
+
+var ErrProcessGroupUnsupported = fmt.Errorf(
+	"%w: process groups not supported on this platform", Err,
+)
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"os"
+)
+
+// Process hooks allow helpers which run commands via the Runner interface to
+// observe the lifecycle of the underlying process, without extending the
+// interface itself. Hooks are carried by the context passed to RunContext, so
+// they pass through wrapper runners, and are called by runners which start
+// processes, like Local.
+
+type (
+	startedFuncKey struct{}
+	exitedFuncKey  struct{}
+)
+
+// withStartedFunc returns a copy of ctx carrying fn, which is called with the
+// process ID once the process has started. Any function already carried by ctx
+// is also called.
+func withStartedFunc(ctx context.Context, fn func(pid int)) context.Context {
+	if parent := startedFunc(ctx); parent != nil {
+		child := fn
+		fn = func(pid int) {
+			parent(pid)
+			child(pid)
+		}
+	}
+
+	return context.WithValue(ctx, startedFuncKey{}, fn)
+}
+
+func startedFunc(ctx context.Context) func(pid int) {
+	fn, _ := ctx.Value(startedFuncKey{}).(func(pid int))
+
+	return fn
+}
+
+// withExitedFunc returns a copy of ctx carrying fn, which is called with the
+// state of the process once it has exited. Any function already carried by ctx
+// is also called.
+func withExitedFunc(
+	ctx context.Context,
+	fn func(state *os.ProcessState),
+) context.Context {
+	if parent := exitedFunc(ctx); parent != nil {
+		child := fn
+		fn = func(state *os.ProcessState) {
+			parent(state)
+			child(state)
+		}
+	}
+
+	return context.WithValue(ctx, exitedFuncKey{}, fn)
+}
+
+func exitedFunc(ctx context.Context) func(state *os.ProcessState) {
+	fn, _ := ctx.Value(exitedFuncKey{}).(func(state *os.ProcessState))
+
+	return fn
+}
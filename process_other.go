@@ -0,0 +1,20 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd, bool, bool) error {
+	return ErrProcessGroupUnsupported
+}
+
+func setKillProcessGroup(*exec.Cmd) error {
+	return ErrProcessGroupUnsupported
+}
+
+func signalProcessGroup(*os.Process, os.Signal) error {
+	return ErrProcessGroupUnsupported
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup configures cmd to start in a new session when setsid is
+// true, or otherwise in a new process group when setpgid is true.
+func setProcessGroup(cmd *exec.Cmd, setsid bool, setpgid bool) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	// A session leader is always also a process group leader, and it is not
+	// permitted to change its process group.
+	if setsid {
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setpgid = false
+		cmd.SysProcAttr.Pgid = 0
+	} else if setpgid {
+		cmd.SysProcAttr.Setpgid = true
+		cmd.SysProcAttr.Pgid = 0
+	}
+
+	return nil
+}
+
+// setKillProcessGroup configures cmd to be started in a new process group,
+// and for the whole process group to be killed when the context of cmd is
+// done.
+func setKillProcessGroup(cmd *exec.Cmd) error {
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setsid {
+		err := setProcessGroup(cmd, false, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	cmd.Cancel = func() error {
+		return signalProcessGroup(cmd.Process, syscall.SIGKILL)
+	}
+
+	return nil
+}
+
+// signalProcessGroup sends sig to the process group led by p.
+func signalProcessGroup(p *os.Process, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return p.Signal(sig)
+	}
+
+	err := syscall.Kill(-p.Pid, s)
+	if errors.Is(err, syscall.ESRCH) {
+		return os.ErrProcessDone
+	}
+
+	return err
+}
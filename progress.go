@@ -0,0 +1,194 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressInterval is the default value for Progress.Interval.
+const DefaultProgressInterval = time.Second
+
+// ProgressUpdate describes the progress of a running command, and is passed
+// to Progress.Func.
+type ProgressUpdate struct {
+	// Command is the command being run.
+	Command string
+
+	// Args are the arguments the command is being run with.
+	Args []string
+
+	// Elapsed is the time since the command was started.
+	Elapsed time.Duration
+
+	// Stdout is the number of bytes written to stdout so far.
+	Stdout int64
+
+	// Stderr is the number of bytes written to stderr so far.
+	Stderr int64
+
+	// Done indicates the command has completed, and this is the final update.
+	Done bool
+}
+
+// Progress is a Runner that wraps another Runner, and calls Func periodically
+// while each command runs, with the elapsed time and the number of bytes
+// written to stdout and stderr so far. This allows reporting progress, or
+// logging that long running commands are still alive.
+//
+// Func is called every Interval while the command runs, and once more with
+// Done set when it completes, before Run or RunContext returns. Calls for a
+// single command are never concurrent.
+//
+// Nil stdout and stderr writers are passed to the underlying Runner as is, so
+// output written to them is not counted. Calls to Env are passed directly to
+// the underlying Runner.
+type Progress struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Func is called with progress updates. The context is the one given to
+	// RunContext, or a background context for Run. If not set, commands are
+	// run as is.
+	Func func(ctx context.Context, u ProgressUpdate)
+
+	// Interval is how often Func is called while a command runs. Defaults to
+	// DefaultProgressInterval.
+	Interval time.Duration
+}
+
+var _ Runner = &Progress{}
+
+// Run calls RunContext with a background context.
+//
+// Will panic if Runner field is nil.
+func (r *Progress) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if r.Func == nil {
+		return r.Runner.Run(stdin, stdout, stderr, command, args...)
+	}
+
+	return r.run(
+		context.Background(), stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.Run(stdin, stdout, stderr, command, args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, and calls Func with
+// progress updates while it runs.
+//
+// Will panic if Runner field is nil.
+func (r *Progress) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if r.Func == nil {
+		return r.Runner.RunContext(
+			ctx, stdin, stdout, stderr, command, args...,
+		)
+	}
+
+	return r.run(
+		ctx, stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.RunContext(
+				ctx, stdin, stdout, stderr, command, args...,
+			)
+		},
+	)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Progress) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Progress) run(
+	ctx context.Context,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args []string,
+	run func(stdout, stderr io.Writer) error,
+) error {
+	stdout, stderr = syncIfSame(stdout, stderr)
+
+	outCount := &countingWriter{w: stdout}
+	errCount := &countingWriter{w: stderr}
+	if stdout != nil {
+		stdout = outCount
+	}
+	if stderr != nil {
+		stderr = errCount
+	}
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	start := time.Now()
+	update := func(done bool) {
+		r.Func(ctx, ProgressUpdate{
+			Command: command,
+			Args:    args,
+			Elapsed: time.Since(start),
+			Stdout:  outCount.n.Load(),
+			Stderr:  errCount.n.Load(),
+			Done:    done,
+		})
+	}
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				update(false)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	err := run(stdout, stderr)
+
+	close(stop)
+	<-stopped
+	update(true)
+
+	return err
+}
+
+// countingWriter is an io.Writer which counts the bytes written to the
+// underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n.Add(int64(n))
+
+	return n, err
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type progressUpdates struct {
+	mu      sync.Mutex
+	updates []ProgressUpdate
+}
+
+func (p *progressUpdates) add(_ context.Context, u ProgressUpdate) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.updates = append(p.updates, u)
+}
+
+func TestProgress_Run(t *testing.T) {
+	got := &progressUpdates{}
+	r := &Progress{
+		Runner:   &Local{},
+		Func:     got.add,
+		Interval: 20 * time.Millisecond,
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := r.Run(
+		nil, &stdout, &stderr, "sh", "-c",
+		"printf abc; sleep 0.2; printf de >&2; printf fg",
+	)
+	require.NoError(t, err)
+
+	updates := got.updates
+	require.Greater(t, len(updates), 3)
+
+	first := updates[0]
+	assert.Equal(t, "sh", first.Command)
+	assert.False(t, first.Done)
+	assert.Equal(t, int64(3), first.Stdout)
+	assert.Equal(t, int64(0), first.Stderr)
+
+	for i, u := range updates[:len(updates)-1] {
+		assert.False(t, u.Done)
+		if i > 0 {
+			assert.Greater(t, u.Elapsed, updates[i-1].Elapsed)
+		}
+	}
+
+	last := updates[len(updates)-1]
+	assert.True(t, last.Done)
+	assert.GreaterOrEqual(t, last.Elapsed, 200*time.Millisecond)
+	assert.Equal(t, int64(5), last.Stdout)
+	assert.Equal(t, int64(2), last.Stderr)
+	assert.Equal(t, "abcfg", stdout.String())
+	assert.Equal(t, "de", stderr.String())
+}
+
+func TestProgress_RunContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), callDirKey{}, "marker")
+
+	f := &Fake{}
+	f.Respond("echo", nil, FakeResponse{Stdout: "hello\n", ExitCode: 2})
+
+	var got []ProgressUpdate
+	r := &Progress{
+		Runner: f,
+		Func: func(uctx context.Context, u ProgressUpdate) {
+			assert.Equal(t, ctx, uctx)
+			u.Elapsed = 0
+			got = append(got, u)
+		},
+	}
+
+	var buf bytes.Buffer
+	err := r.RunContext(ctx, nil, &buf, &buf, "echo", "hello")
+	assert.EqualError(t, err, "echo: exit status 2")
+
+	assert.Equal(t, []ProgressUpdate{
+		{
+			Command: "echo",
+			Args:    []string{"hello"},
+			Stdout:  6,
+			Done:    true,
+		},
+	}, got)
+}
+
+func TestProgress_nilWriters(t *testing.T) {
+	f := &Fake{}
+	f.Respond("echo", nil, FakeResponse{Stdout: "hello\n"})
+
+	var got []ProgressUpdate
+	r := &Progress{
+		Runner: f,
+		Func: func(_ context.Context, u ProgressUpdate) {
+			got = append(got, u)
+		},
+	}
+
+	require.NoError(t, r.Run(nil, nil, nil, "echo"))
+	require.Len(t, got, 1)
+	assert.Zero(t, got[0].Stdout)
+}
+
+func TestProgress_noFunc(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Run(nil, nil, nil, "true", []string{}).Return(nil)
+	m.EXPECT().RunContext(
+		context.Background(), nil, nil, nil, "true", []string{},
+	).Return(nil)
+
+	r := &Progress{Runner: m}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+	err := r.RunContext(context.Background(), nil, nil, nil, "true")
+	require.NoError(t, err)
+}
+
+func TestProgress_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Progress{Runner: m}
+	r.Env("FOO=bar")
+}
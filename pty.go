@@ -0,0 +1,43 @@
+package runner
+
+import "fmt"
+
+var (
+	ErrPTY            = fmt.Errorf("%w: pty", Err)
+	ErrPTYUnsupported = fmt.Errorf(
+		"%w: not supported on this platform", ErrPTY,
+	)
+)
+
+// PTYSize is the size of a pseudo-terminal window, in characters.
+type PTYSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// PTY describes a pseudo-terminal which commands executed by Local are run
+// within, for commands which require a terminal, or which change their
+// behaviour when not attached to one, like showing progress bars.
+//
+// A new pseudo-terminal is created for each command, and becomes the
+// controlling terminal of a new session the command is started in. The
+// terminal is used for the command's stdin, stdout, and stderr, so output
+// written to stdout and stderr is merged and written to the stdout writer
+// given to Run or RunContext, with line endings translated to "\r\n" as by
+// any terminal. Input is echoed back to the output by the terminal, unless
+// the command disables echoing.
+//
+// When stdin is given, it is copied to the terminal, followed by an
+// end-of-file character once it has been read in full. PTYs are only
+// supported on Unix-like systems.
+type PTY struct {
+	// Size is the initial size of the terminal window. When zero, the size is
+	// left as the platform's default, typically zero rows and columns.
+	Size PTYSize
+
+	// Resize, when set, is received from while the command runs, and the
+	// terminal window is resized to each size received, as when a user
+	// resizes their terminal. It should only be set when a single command is
+	// run at a time.
+	Resize <-chan PTYSize
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package runner
+
+import (
+	"io"
+	"os/exec"
+)
+
+type ptySession struct{}
+
+func (*PTY) apply(*exec.Cmd, io.Reader, io.Writer) (*ptySession, error) {
+	return nil, ErrPTYUnsupported
+}
+
+func (*ptySession) started() {}
+
+func (*ptySession) wait() {}
+
+func (*ptySession) close() {}
@@ -0,0 +1,147 @@
+//go:build unix
+
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/creack/pty"
+)
+
+// ptySession copies input and output between a pseudo-terminal and the
+// stdin and stdout of a command.
+type ptySession struct {
+	ptmx   *os.File
+	tty    *os.File
+	stdin  io.Reader
+	stdout io.Writer
+	resize <-chan PTYSize
+
+	done     chan struct{}
+	copied   sync.WaitGroup
+	resizing sync.WaitGroup
+}
+
+// apply creates a new pseudo-terminal, and configures cmd to be started in a
+// new session with the terminal as its controlling terminal, stdin, stdout,
+// and stderr. Once cmd has been started, started must be called on the
+// returned session, and wait once it has exited. close must always be called
+// once the session is no longer needed.
+func (p *PTY) apply(
+	cmd *exec.Cmd,
+	stdin io.Reader,
+	stdout io.Writer,
+) (*ptySession, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPTY, err)
+	}
+
+	s := &ptySession{
+		ptmx:   ptmx,
+		tty:    tty,
+		stdin:  stdin,
+		stdout: stdout,
+		resize: p.Resize,
+		done:   make(chan struct{}),
+	}
+	if s.stdout == nil {
+		s.stdout = io.Discard
+	}
+
+	if p.Size != (PTYSize{}) {
+		err = s.setSize(p.Size)
+		if err != nil {
+			s.close()
+
+			return nil, err
+		}
+	}
+
+	err = setProcessGroup(cmd, true, false)
+	if err != nil {
+		s.close()
+
+		return nil, err
+	}
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+
+	return s, nil
+}
+
+func (s *ptySession) setSize(size PTYSize) error {
+	err := pty.Setsize(s.ptmx, &pty.Winsize{Rows: size.Rows, Cols: size.Cols})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPTY, err)
+	}
+
+	return nil
+}
+
+// started closes this process's copy of the terminal given to the command,
+// and starts copying input and output.
+func (s *ptySession) started() {
+	_ = s.tty.Close()
+
+	if s.stdin != nil {
+		go func() {
+			_, err := io.Copy(s.ptmx, s.stdin)
+			if err == nil {
+				// Signal end-of-file to the command, as a user would with
+				// Ctrl+D.
+				_, _ = s.ptmx.Write([]byte{4})
+			}
+		}()
+	}
+
+	if s.resize != nil {
+		s.resizing.Add(1)
+		go func() {
+			defer s.resizing.Done()
+
+			for {
+				select {
+				case size := <-s.resize:
+					_ = s.setSize(size)
+				case <-s.done:
+					return
+				}
+			}
+		}()
+	}
+
+	s.copied.Add(1)
+	go func() {
+		defer s.copied.Done()
+
+		// Reading fails with EIO once the terminal has been closed by all
+		// processes using it.
+		_, _ = io.Copy(s.stdout, s.ptmx)
+	}()
+}
+
+// wait waits for all output to be copied, and closes the terminal.
+func (s *ptySession) wait() {
+	s.copied.Wait()
+	s.close()
+}
+
+// close closes the terminal, and may be called more than once.
+func (s *ptySession) close() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.resizing.Wait()
+
+	_ = s.tty.Close()
+	_ = s.ptmx.Close()
+}
@@ -0,0 +1,121 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_pty(t *testing.T) {
+	tests := []struct {
+		name       string
+		pty        *PTY
+		stdin      string
+		script     string
+		wantStdout string
+		wantErr    string
+	}{
+		{
+			name:       "without pty",
+			script:     "test -t 0 && echo tty || echo notty",
+			wantStdout: "notty\n",
+		},
+		{
+			name:       "with pty",
+			pty:        &PTY{},
+			script:     "test -t 0 && test -t 1 && test -t 2 && echo tty",
+			wantStdout: "tty\r\n",
+		},
+		{
+			name:       "size",
+			pty:        &PTY{Size: PTYSize{Rows: 24, Cols: 132}},
+			script:     "stty size",
+			wantStdout: "24 132\r\n",
+		},
+		{
+			name:       "merged stderr",
+			pty:        &PTY{},
+			script:     "echo out; sleep 0.05; echo err >&2",
+			wantStdout: "out\r\nerr\r\n",
+		},
+		{
+			name:       "stdin",
+			pty:        &PTY{},
+			stdin:      "hello\n",
+			script:     "read -r line; echo \"got $line\"",
+			wantStdout: "hello\r\ngot hello\r\n",
+		},
+		{
+			name:       "exit status",
+			pty:        &PTY{},
+			script:     "echo failing; exit 3",
+			wantStdout: "failing\r\n",
+			wantErr:    "sh: exit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Local{PTY: tt.pty}
+
+			var stdout, stderr bytes.Buffer
+			var err error
+			if tt.stdin != "" {
+				err = r.Run(
+					strings.NewReader(tt.stdin), &stdout, &stderr,
+					"sh", "-c", tt.script,
+				)
+			} else {
+				err = r.Run(nil, &stdout, &stderr, "sh", "-c", tt.script)
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Empty(t, stderr.String())
+		})
+	}
+}
+
+func TestLocal_RunContext_ptyResize(t *testing.T) {
+	resize := make(chan PTYSize)
+	r := &Local{PTY: &PTY{
+		Size:   PTYSize{Rows: 10, Cols: 20},
+		Resize: resize,
+	}}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		resize <- PTYSize{Rows: 50, Cols: 100}
+	}()
+
+	var stdout bytes.Buffer
+	err := r.RunContext(
+		context.Background(), nil, &stdout, nil,
+		"sh", "-c", "stty size; sleep 0.3; stty size",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10 20\r\n50 100\r\n", stdout.String())
+}
+
+func TestLocal_RunContext_ptyCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 100*time.Millisecond,
+	)
+	defer cancel()
+
+	r := &Local{PTY: &PTY{}, KillProcessGroup: true}
+	err := r.RunContext(ctx, nil, nil, nil, "sh", "-c", "sleep 5 & wait")
+
+	assert.EqualError(t, err, "sh: signal: killed")
+}
@@ -0,0 +1,274 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrRecord = fmt.Errorf("%w: record", Err)
+
+// Recording is a record of a single command run via a Record runner.
+type Recording struct {
+	// Command is the command which was run.
+	Command string `json:"command"`
+
+	// Args are the arguments the command was run with.
+	Args []string `json:"args"`
+
+	// Env is the environment variables set on the Record runner via Env,
+	// followed by any given with WithEnv.
+	Env []string `json:"env,omitempty"`
+
+	// Stdin holds the bytes read from stdin by the command.
+	Stdin []byte `json:"stdin,omitempty"`
+
+	// Stdout holds the bytes written to stdout by the command.
+	Stdout []byte `json:"stdout,omitempty"`
+
+	// Stderr holds the bytes written to stderr by the command.
+	Stderr []byte `json:"stderr,omitempty"`
+
+	// ExitCode is the exit code of the command. It is 0 when the command
+	// succeeded, and -1 when it failed without an exit code, for example
+	// when it could not be started, or was terminated by a signal.
+	ExitCode int `json:"exit_code"`
+
+	// Error is the error message returned by the underlying Runner, if any.
+	Error string `json:"error,omitempty"`
+
+	// Start is the time the command was started.
+	Start time.Time `json:"start"`
+
+	// Duration is how long the command took to run.
+	Duration time.Duration `json:"duration"`
+}
+
+// RecordStore persists Recordings produced by a Record runner.
+type RecordStore interface {
+	Save(ctx context.Context, rec *Recording) error
+}
+
+// RecordStoreFunc is an adapter to allow the use of an ordinary function as a
+// RecordStore.
+type RecordStoreFunc func(ctx context.Context, rec *Recording) error
+
+var _ RecordStore = RecordStoreFunc(nil)
+
+// Save calls f(ctx, rec).
+func (f RecordStoreFunc) Save(ctx context.Context, rec *Recording) error {
+	return f(ctx, rec)
+}
+
+// RecordWriter is a RecordStore which writes each Recording to an io.Writer
+// as a single line of JSON. It is safe for concurrent use.
+type RecordWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ RecordStore = &RecordWriter{}
+
+// NewRecordWriter returns a RecordWriter which writes to w.
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{w: w}
+}
+
+// CreateRecordFile opens the file at path for appending, creating it if
+// needed, and returns a RecordWriter which writes to it. The caller must call
+// Close when done.
+func CreateRecordFile(path string) (*RecordWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRecordWriter(f), nil
+}
+
+// Save writes rec as a line of JSON.
+func (rw *RecordWriter) Save(_ context.Context, rec *Recording) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	_, err = rw.w.Write(append(b, '\n'))
+
+	return err
+}
+
+// Close closes the underlying io.Writer if it implements io.Closer.
+func (rw *RecordWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if c, ok := rw.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// Record is a Runner that wraps another Runner, and saves a Recording of each
+// command run to Store, including its stdin, stdout and stderr data, exit
+// code, and duration. This allows auditing commands, and building tests from
+// real interactions.
+//
+// As output is captured, nil stdout and stderr writers are not passed to the
+// underlying Runner as is. With Local, this means the stderr output of failed
+// commands is not included in the error message, though it is still available
+// from ExitError.Stderr and the Recording.
+//
+// Calls to Env are passed to the underlying Runner, and also retained to be
+// included in Recordings.
+type Record struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Store is where Recordings are saved. If not set, running commands will
+	// cause a panic.
+	Store RecordStore
+
+	envMu sync.RWMutex
+	env   []string
+}
+
+var _ Runner = &Record{}
+
+// Run calls Run on the underlying Runner, and saves a Recording of it to
+// Store.
+//
+// If the command succeeds but the Recording could not be saved, an error
+// wrapping ErrRecord is returned. If the command fails, its error is returned
+// regardless.
+//
+// Will panic if Runner or Store fields are nil.
+func (r *Record) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.run(
+		context.Background(), stdin, stdout, stderr, command, args,
+		func(stdin io.Reader, stdout, stderr io.Writer) error {
+			return r.Runner.Run(stdin, stdout, stderr, command, args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, and saves a Recording
+// of it to Store.
+//
+// If the command succeeds but the Recording could not be saved, an error
+// wrapping ErrRecord is returned. If the command fails, its error is returned
+// regardless.
+//
+// Will panic if Runner or Store fields are nil.
+func (r *Record) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.run(
+		ctx, stdin, stdout, stderr, command, args,
+		func(stdin io.Reader, stdout, stderr io.Writer) error {
+			return r.Runner.RunContext(
+				ctx, stdin, stdout, stderr, command, args...,
+			)
+		},
+	)
+}
+
+func (r *Record) run(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args []string,
+	run func(stdin io.Reader, stdout, stderr io.Writer) error,
+) error {
+	rec := &Recording{
+		Command: command,
+		Args:    copyStrings(args),
+		Env:     mergeEnv(r.environ(), callEnv(ctx)),
+	}
+
+	var stdinBuf, stdoutBuf, stderrBuf bytes.Buffer
+	if stdin != nil {
+		stdin = io.TeeReader(stdin, &stdinBuf)
+	}
+
+	stdout, stderr = syncIfSame(stdout, stderr)
+
+	rec.Start = time.Now()
+	err := run(
+		stdin, teeWriter(stdout, &stdoutBuf), teeWriter(stderr, &stderrBuf),
+	)
+	rec.Duration = time.Since(rec.Start)
+
+	rec.Stdin = bufBytes(&stdinBuf)
+	rec.Stdout = bufBytes(&stdoutBuf)
+	rec.Stderr = bufBytes(&stderrBuf)
+	rec.ExitCode = ExitCode(err)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	saveErr := r.Store.Save(ctx, rec)
+	if err == nil && saveErr != nil {
+		return fmt.Errorf("%w: %w", ErrRecord, saveErr)
+	}
+
+	return err
+}
+
+// Env calls Env on the underlying Runner, and retains env to be included in
+// Recordings.
+func (r *Record) Env(env ...string) {
+	r.envMu.Lock()
+	r.env = copyStrings(env)
+	r.envMu.Unlock()
+
+	r.Runner.Env(env...)
+}
+
+func (r *Record) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return copyStrings(r.env)
+}
+
+// teeWriter returns a writer which writes to both w and buf, or only to buf
+// if w is nil.
+func teeWriter(w io.Writer, buf io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+
+	return io.MultiWriter(w, buf)
+}
+
+func bufBytes(buf *bytes.Buffer) []byte {
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	return buf.Bytes()
+}
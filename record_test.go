@@ -0,0 +1,271 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type recordingsStore struct {
+	recs []*Recording
+}
+
+func (s *recordingsStore) Save(_ context.Context, rec *Recording) error {
+	s.recs = append(s.recs, rec)
+
+	return nil
+}
+
+func TestRecord_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        []string
+		stdin      string
+		nilWriters bool
+		command    string
+		args       []string
+		want       Recording
+		wantStdout string
+		wantStderr string
+		wantErr    string
+	}{
+		{
+			name:    "success",
+			command: "sh",
+			args:    []string{"-c", "echo out; echo err >&2"},
+			want: Recording{
+				Command: "sh",
+				Args:    []string{"-c", "echo out; echo err >&2"},
+				Stdout:  []byte("out\n"),
+				Stderr:  []byte("err\n"),
+			},
+			wantStdout: "out\n",
+			wantStderr: "err\n",
+		},
+		{
+			name:    "stdin and env",
+			env:     []string{"FOO=bar"},
+			stdin:   "hello",
+			command: "sh",
+			args:    []string{"-c", "cat; echo $FOO"},
+			want: Recording{
+				Command: "sh",
+				Args:    []string{"-c", "cat; echo $FOO"},
+				Env:     []string{"FOO=bar"},
+				Stdin:   []byte("hello"),
+				Stdout:  []byte("hellobar\n"),
+			},
+			wantStdout: "hellobar\n",
+		},
+		{
+			name:       "nil writers",
+			nilWriters: true,
+			command:    "sh",
+			args:       []string{"-c", "echo out; echo err >&2"},
+			want: Recording{
+				Command: "sh",
+				Args:    []string{"-c", "echo out; echo err >&2"},
+				Stdout:  []byte("out\n"),
+				Stderr:  []byte("err\n"),
+			},
+		},
+		{
+			name:    "exit code",
+			command: "sh",
+			args:    []string{"-c", "echo oops >&2; exit 3"},
+			want: Recording{
+				Command:  "sh",
+				Args:     []string{"-c", "echo oops >&2; exit 3"},
+				Stderr:   []byte("oops\n"),
+				ExitCode: 3,
+				Error:    "sh: exit status 3",
+			},
+			wantStderr: "oops\n",
+			wantErr:    "sh: exit status 3",
+		},
+		{
+			name:    "command not found",
+			command: "runner-test-no-such-command",
+			want: Recording{
+				Command:  "runner-test-no-such-command",
+				ExitCode: -1,
+				Error: "runner: command not found: exec: " +
+					`"runner-test-no-such-command": ` +
+					"executable file not found in $PATH",
+			},
+			wantErr: "runner: command not found: exec: " +
+				`"runner-test-no-such-command": ` +
+				"executable file not found in $PATH",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingsStore{}
+			r := &Record{Runner: &Local{}, Store: store}
+			if tt.env != nil {
+				r.Env(tt.env...)
+			}
+
+			var stdout, stderr bytes.Buffer
+			var err error
+			before := time.Now()
+			if tt.nilWriters {
+				err = r.Run(nil, nil, nil, tt.command, tt.args...)
+			} else {
+				err = r.Run(
+					strings.NewReader(tt.stdin), &stdout, &stderr,
+					tt.command, tt.args...,
+				)
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+
+			require.Len(t, store.recs, 1)
+			got := store.recs[0]
+			assert.False(t, got.Start.Before(before))
+			assert.Greater(t, got.Duration, time.Duration(0))
+
+			got.Start = time.Time{}
+			got.Duration = 0
+			assert.Equal(t, &tt.want, got)
+		})
+	}
+}
+
+func TestRecord_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=bar"})
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, gomock.Any(), gomock.Any(),
+		"echo", []string{"hello"},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = stdout.Write([]byte("hello\n"))
+
+		return nil
+	})
+
+	store := &recordingsStore{}
+	rec := &Record{Runner: r, Store: store}
+	rec.Env("FOO=bar")
+
+	var stdout bytes.Buffer
+	err := rec.RunContext(
+		withCallEnv(ctx, []string{"BAR=baz"}), nil, &stdout, nil,
+		"echo", "hello",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello\n", stdout.String())
+	require.Len(t, store.recs, 1)
+	assert.Equal(t, "echo", store.recs[0].Command)
+	assert.Equal(t, []string{"hello"}, store.recs[0].Args)
+	assert.Equal(t, []string{"FOO=bar", "BAR=baz"}, store.recs[0].Env)
+	assert.Equal(t, []byte("hello\n"), store.recs[0].Stdout)
+}
+
+func TestRecord_storeError(t *testing.T) {
+	saveErr := errors.New("disk full")
+	store := RecordStoreFunc(func(_ context.Context, _ *Recording) error {
+		return saveErr
+	})
+
+	r := &Record{Runner: &Local{}, Store: store}
+
+	err := r.Run(nil, nil, nil, "true")
+	assert.EqualError(t, err, "runner: record: disk full")
+	assert.ErrorIs(t, err, ErrRecord)
+	assert.ErrorIs(t, err, saveErr)
+
+	err = r.Run(nil, nil, nil, "false")
+	assert.EqualError(t, err, "false: exit status 1")
+	assert.NotErrorIs(t, err, ErrRecord)
+}
+
+func TestRecord_sameWriter(t *testing.T) {
+	store := &recordingsStore{}
+	r := &Record{Runner: &Local{}, Store: store}
+
+	var buf bytes.Buffer
+	err := r.Run(nil, &buf, &buf, "sh", "-c", "echo out; echo err >&2")
+	require.NoError(t, err)
+
+	assert.ElementsMatch(
+		t, []string{"out", "err"}, strings.Fields(buf.String()),
+	)
+	require.Len(t, store.recs, 1)
+	assert.Equal(t, []byte("out\n"), store.recs[0].Stdout)
+	assert.Equal(t, []byte("err\n"), store.recs[0].Stderr)
+}
+
+func TestRecordWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	rw, err := CreateRecordFile(path)
+	require.NoError(t, err)
+
+	r := &Record{Runner: &Local{}, Store: rw}
+	err = r.Run(strings.NewReader("hi"), nil, nil, "cat")
+	require.NoError(t, err)
+	err = r.Run(nil, nil, nil, "sh", "-c", "exit 2")
+	require.Error(t, err)
+	require.NoError(t, rw.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second Recording
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+
+	assert.Equal(t, "cat", first.Command)
+	assert.Equal(t, []byte("hi"), first.Stdin)
+	assert.Equal(t, []byte("hi"), first.Stdout)
+	assert.Equal(t, 0, first.ExitCode)
+
+	assert.Equal(t, "sh", second.Command)
+	assert.Equal(t, []string{"-c", "exit 2"}, second.Args)
+	assert.Equal(t, 2, second.ExitCode)
+	assert.Equal(t, "sh: exit status 2", second.Error)
+}
+
+func TestRecord_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	rec := &Record{Runner: r}
+	env := []string{"FOO=BAR", "PORT=8080"}
+	rec.Env(env...)
+	env[0] = "FOO=changed"
+
+	assert.Equal(t, []string{"FOO=BAR", "PORT=8080"}, rec.environ())
+}
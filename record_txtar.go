@@ -0,0 +1,416 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrTxtar = fmt.Errorf("%w: txtar", Err)
+
+// txtarNoNewline is appended to the name of txtar files whose content does not
+// end with a newline, as the format requires one.
+const txtarNoNewline = " (no newline)"
+
+// FormatRecordingsTxtar formats recs as a txtar archive, as used by
+// testscript, which is readable and editable by hand. Each Recording is a
+// series of files, starting with a "cmd" file:
+//
+//	-- cmd --
+//	sh -c 'echo hello; exit 3'
+//	-- env --
+//	FOO=bar
+//	-- stdin --
+//	...
+//	-- stdout --
+//	hello
+//	-- stderr --
+//	...
+//	-- exit --
+//	3
+//	-- error --
+//	...
+//
+// The cmd file holds the shell-quoted command and arguments, and the env file
+// holds one shell-quoted "key=value" entry per line. All files other than cmd
+// are omitted when empty, and error is only included when it differs from the
+// message implied by exit. Files whose content does not end with a newline
+// have " (no newline)" appended to their name, like "stdout (no newline)".
+//
+// The Start and Duration fields are not included, so fixtures do not change
+// each time they are recorded.
+//
+// An error wrapping ErrTxtar is returned if any stdin, stdout, or stderr data
+// contains a line which would be read as a txtar file marker.
+func FormatRecordingsTxtar(recs []*Recording) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		err := formatRecordingTxtar(&buf, rec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatRecordingTxtar(buf *bytes.Buffer, rec *Recording) error {
+	cmdline := formatCmdline(rec.Command, rec.Args)
+	writeTxtarFile(buf, "cmd", []byte(cmdline+"\n"))
+
+	if len(rec.Env) > 0 {
+		var env bytes.Buffer
+		for _, entry := range rec.Env {
+			env.WriteString(quoteWord(entry) + "\n")
+		}
+		writeTxtarFile(buf, "env", env.Bytes())
+	}
+
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{name: "stdin", data: rec.Stdin},
+		{name: "stdout", data: rec.Stdout},
+		{name: "stderr", data: rec.Stderr},
+	} {
+		if len(f.data) == 0 {
+			continue
+		}
+		if hasTxtarMarker(f.data) {
+			return fmt.Errorf(
+				"%w: %s of %q contains a file marker line",
+				ErrTxtar, f.name, rec.Command,
+			)
+		}
+		writeTxtarFile(buf, f.name, f.data)
+	}
+
+	if rec.ExitCode != 0 {
+		writeTxtarFile(buf, "exit", []byte(strconv.Itoa(rec.ExitCode)+"\n"))
+	}
+
+	defaultErr := ""
+	if rec.ExitCode != 0 {
+		defaultErr = (&ReplayError{Recording: &Recording{
+			Command: rec.Command, ExitCode: rec.ExitCode,
+		}}).Error()
+	}
+	if rec.Error != defaultErr {
+		if strings.Contains(rec.Error, "\n") {
+			return fmt.Errorf(
+				"%w: error of %q contains a newline", ErrTxtar, rec.Command,
+			)
+		}
+		writeTxtarFile(buf, "error", []byte(rec.Error+"\n"))
+	}
+
+	return nil
+}
+
+func writeTxtarFile(buf *bytes.Buffer, name string, data []byte) {
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		name += txtarNoNewline
+		data = append(data[:len(data):len(data)], '\n')
+	}
+
+	buf.WriteString("-- " + name + " --\n")
+	buf.Write(data)
+}
+
+// ParseRecordingsTxtar parses Recordings from a txtar archive in the format
+// written by FormatRecordingsTxtar. Any comment text before the first file is
+// ignored.
+func ParseRecordingsTxtar(data []byte) ([]*Recording, error) {
+	recs := []*Recording{}
+
+	var rec *Recording
+	for _, f := range parseTxtar(data) {
+		name, content := f.name, f.data
+		if strings.HasSuffix(name, txtarNoNewline) {
+			name = strings.TrimSuffix(name, txtarNoNewline)
+			content = bytes.TrimSuffix(content, []byte("\n"))
+		}
+
+		if name == "cmd" {
+			words, err := splitWords(string(content))
+			if err != nil {
+				return nil, fmt.Errorf("%w: cmd: %w", ErrTxtar, err)
+			}
+			if len(words) == 0 {
+				return nil, fmt.Errorf("%w: cmd: empty command", ErrTxtar)
+			}
+
+			rec = &Recording{Command: words[0]}
+			if len(words) > 1 {
+				rec.Args = words[1:]
+			}
+			recs = append(recs, rec)
+
+			continue
+		}
+
+		if rec == nil {
+			return nil, fmt.Errorf(
+				"%w: %s: file before first cmd file", ErrTxtar, name,
+			)
+		}
+
+		var err error
+		switch name {
+		case "env":
+			rec.Env, err = splitWords(string(content))
+		case "stdin":
+			rec.Stdin = content
+		case "stdout":
+			rec.Stdout = content
+		case "stderr":
+			rec.Stderr = content
+		case "exit":
+			rec.ExitCode, err = strconv.Atoi(strings.TrimSpace(string(content)))
+		case "error":
+			rec.Error = strings.TrimSuffix(string(content), "\n")
+		default:
+			err = fmt.Errorf("unknown file")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTxtar, name, err)
+		}
+	}
+
+	for _, rec := range recs {
+		if rec.Error == "" && rec.ExitCode != 0 {
+			rec.Error = (&ReplayError{Recording: rec}).Error()
+		}
+	}
+
+	return recs, nil
+}
+
+// LoadTxtarRecordFile reads Recordings from the txtar file at path, as
+// written by CreateTxtarRecordFile.
+func LoadTxtarRecordFile(path string) ([]*Recording, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRecordingsTxtar(b)
+}
+
+// TxtarRecordWriter is a RecordStore which writes each Recording to an
+// io.Writer in the txtar format described by FormatRecordingsTxtar. It is
+// safe for concurrent use.
+type TxtarRecordWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ RecordStore = &TxtarRecordWriter{}
+
+// NewTxtarRecordWriter returns a TxtarRecordWriter which writes to w.
+func NewTxtarRecordWriter(w io.Writer) *TxtarRecordWriter {
+	return &TxtarRecordWriter{w: w}
+}
+
+// CreateTxtarRecordFile opens the file at path for appending, creating it if
+// needed, and returns a TxtarRecordWriter which writes to it. The caller must
+// call Close when done.
+func CreateTxtarRecordFile(path string) (*TxtarRecordWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTxtarRecordWriter(f), nil
+}
+
+// Save writes rec in the txtar format.
+func (tw *TxtarRecordWriter) Save(_ context.Context, rec *Recording) error {
+	b, err := FormatRecordingsTxtar([]*Recording{rec})
+	if err != nil {
+		return err
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	_, err = tw.w.Write(b)
+
+	return err
+}
+
+// Close closes the underlying io.Writer if it implements io.Closer.
+func (tw *TxtarRecordWriter) Close() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if c, ok := tw.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+type txtarFile struct {
+	name string
+	data []byte
+}
+
+// parseTxtar parses the files of a txtar archive, discarding any leading
+// comment.
+func parseTxtar(data []byte) []txtarFile {
+	files := []txtarFile{}
+
+	var cur *txtarFile
+	for len(data) > 0 {
+		line := data
+		rest := []byte(nil)
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, rest = data[:i+1], data[i+1:]
+		}
+		data = rest
+
+		if name, ok := txtarMarker(line); ok {
+			files = append(files, txtarFile{name: name})
+			cur = &files[len(files)-1]
+
+			continue
+		}
+		if cur != nil {
+			cur.data = append(cur.data, line...)
+		}
+	}
+
+	// The final file may lack a trailing newline, which is implied.
+	if cur != nil && len(cur.data) > 0 &&
+		!bytes.HasSuffix(cur.data, []byte("\n")) {
+		cur.data = append(cur.data, '\n')
+	}
+
+	return files
+}
+
+// txtarMarker returns the file name if line is a txtar file marker line, like
+// "-- name --".
+func txtarMarker(line []byte) (string, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("-- ")) ||
+		!bytes.HasSuffix(line, []byte(" --")) ||
+		len(line) < len("-- x --") {
+		return "", false
+	}
+
+	name := strings.TrimSpace(string(line[3 : len(line)-3]))
+
+	return name, name != ""
+}
+
+func hasTxtarMarker(data []byte) bool {
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if _, ok := txtarMarker(line); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatCmdline returns command and args as a shell command line, quoting
+// them as needed.
+func formatCmdline(command string, args []string) string {
+	return quoteWords(append([]string{command}, args...))
+}
+
+// quoteWords returns words quoted as needed for a POSIX shell, and joined by
+// spaces.
+func quoteWords(words []string) string {
+	quoted := make([]string, 0, len(words))
+	for _, s := range words {
+		quoted = append(quoted, quoteWord(s))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// quoteWord returns s quoted for a POSIX shell, leaving it as is when it
+// contains no special characters.
+func quoteWord(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	for _, c := range s {
+		if !isSafeWordChar(c) {
+			return shellQuote(s)
+		}
+	}
+
+	return s
+}
+
+func isSafeWordChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || strings.ContainsRune("_@%+=:,./-", c)
+}
+
+// splitWords splits s into words using POSIX shell quoting rules, supporting
+// single quotes, double quotes, and backslash escapes. Variable expansion and
+// other shell features are not supported.
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single-quoted string")
+			}
+			word.WriteString(s[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) &&
+					strings.IndexByte("$`\"\\\n", s[i+1]) >= 0 {
+					i++
+				}
+				word.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated double-quoted string")
+			}
+			inWord = true
+		case c == '\\':
+			if i+1 < len(s) {
+				i++
+				word.WriteByte(s[i])
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
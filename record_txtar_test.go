@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRecordingsTxtar(t *testing.T) {
+	tests := []struct {
+		name    string
+		recs    []*Recording
+		want    string
+		wantErr string
+	}{
+		{
+			name: "empty",
+			recs: []*Recording{},
+			want: "",
+		},
+		{
+			name: "full",
+			recs: []*Recording{
+				{
+					Command:  "sh",
+					Args:     []string{"-c", "cat; echo it's $FOO", ""},
+					Env:      []string{"FOO=bar", "MSG=hello world"},
+					Stdin:    []byte("input\n"),
+					Stdout:   []byte("input\nit's bar\n"),
+					Stderr:   []byte("warning"),
+					ExitCode: 3,
+					Error:    "sh: exit status 3",
+				},
+				{
+					Command:  "runner-test-no-such-command",
+					ExitCode: -1,
+					Error:    "runner: command not found",
+				},
+			},
+			want: "-- cmd --\n" +
+				`sh -c 'cat; echo it'\''s $FOO' ''` + "\n" +
+				"-- env --\n" +
+				"FOO=bar\n" +
+				"'MSG=hello world'\n" +
+				"-- stdin --\n" +
+				"input\n" +
+				"-- stdout --\n" +
+				"input\nit's bar\n" +
+				"-- stderr (no newline) --\n" +
+				"warning\n" +
+				"-- exit --\n" +
+				"3\n" +
+				"-- cmd --\n" +
+				"runner-test-no-such-command\n" +
+				"-- exit --\n" +
+				"-1\n" +
+				"-- error --\n" +
+				"runner: command not found\n",
+		},
+		{
+			name: "file marker in output",
+			recs: []*Recording{
+				{Command: "cat", Stdout: []byte("a\n-- cmd --\nb\n")},
+			},
+			wantErr: `runner: txtar: stdout of "cat" contains a file ` +
+				"marker line",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatRecordingsTxtar(tt.recs)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrTxtar)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, string(got))
+
+				recs, err := ParseRecordingsTxtar(got)
+				require.NoError(t, err)
+				assert.Equal(t, tt.recs, recs)
+			}
+		})
+	}
+}
+
+func TestParseRecordingsTxtar(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []*Recording
+		wantErr string
+	}{
+		{
+			name: "comment and quoting",
+			data: "Fixture for listing pools.\n\n" +
+				"-- cmd --\n" +
+				`zpool list -H "-o" name\ size` + "\n" +
+				"-- stdout --\n" +
+				"tank\t1T",
+			want: []*Recording{
+				{
+					Command: "zpool",
+					Args:    []string{"list", "-H", "-o", "name size"},
+					Stdout:  []byte("tank\t1T\n"),
+				},
+			},
+		},
+		{
+			name: "exit without error",
+			data: "-- cmd --\nfalse\n-- exit --\n1\n",
+			want: []*Recording{
+				{
+					Command:  "false",
+					ExitCode: 1,
+					Error:    "false: exit status 1",
+				},
+			},
+		},
+		{
+			name:    "file before cmd",
+			data:    "-- stdout --\nhello\n",
+			wantErr: "runner: txtar: stdout: file before first cmd file",
+		},
+		{
+			name:    "empty cmd",
+			data:    "-- cmd --\n\n",
+			wantErr: "runner: txtar: cmd: empty command",
+		},
+		{
+			name: "unterminated quote",
+			data: "-- cmd --\necho 'hello\n",
+			wantErr: "runner: txtar: cmd: " +
+				"unterminated single-quoted string",
+		},
+		{
+			name: "invalid exit",
+			data: "-- cmd --\nfalse\n-- exit --\none\n",
+			wantErr: `runner: txtar: exit: strconv.Atoi: parsing "one": ` +
+				"invalid syntax",
+		},
+		{
+			name:    "unknown file",
+			data:    "-- cmd --\nfalse\n-- output --\nhello\n",
+			wantErr: "runner: txtar: output: unknown file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRecordingsTxtar([]byte(tt.data))
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrTxtar)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTxtarRecordWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.txtar")
+	tw, err := CreateTxtarRecordFile(path)
+	require.NoError(t, err)
+
+	rec := &Record{Runner: &Local{}, Store: tw}
+	err = rec.Run(strings.NewReader("hi"), nil, nil, "cat")
+	require.NoError(t, err)
+	err = rec.Run(nil, nil, nil, "sh", "-c", "echo oops >&2; exit 2")
+	require.Error(t, err)
+	require.NoError(t, tw.Close())
+
+	recs, err := LoadTxtarRecordFile(path)
+	require.NoError(t, err)
+	require.Len(t, recs, 2)
+
+	r := &Replay{Recordings: recs, Mode: ReplayStrict}
+
+	var stdout, stderr bytes.Buffer
+	err = r.Run(strings.NewReader("hi"), &stdout, &stderr, "cat")
+	require.NoError(t, err)
+	assert.Equal(t, "hi", stdout.String())
+
+	err = r.Run(
+		nil, &stdout, &stderr, "sh", "-c", "echo oops >&2; exit 2",
+	)
+	assert.EqualError(t, err, "sh: exit status 2")
+	assert.Equal(t, 2, ExitCode(err))
+	assert.Equal(t, "oops\n", stderr.String())
+	assert.Empty(t, r.Unused())
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr string
+	}{
+		{s: "", want: []string{}},
+		{s: "  a  b\tc\n", want: []string{"a", "b", "c"}},
+		{s: `'a b' "c d" e\ f`, want: []string{"a b", "c d", "e f"}},
+		{s: `a'b'"c"`, want: []string{"abc"}},
+		{s: `'' ""`, want: []string{"", ""}},
+		{s: `"a \"b\" \$c \d"`, want: []string{`a "b" $c \d`}},
+		{s: `'it'\''s'`, want: []string{"it's"}},
+		{s: `"abc`, wantErr: "unterminated double-quoted string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := splitWords(tt.s)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
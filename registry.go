@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrRegistry          = fmt.Errorf("%w: registry", Err)
+	ErrRegistryNotFound  = fmt.Errorf("%w: runner not found", ErrRegistry)
+	ErrRegistryDuplicate = fmt.Errorf("%w: duplicate name", ErrRegistry)
+	ErrRegistryClosed    = fmt.Errorf("%w: closed", ErrRegistry)
+)
+
+// Registry holds runners by name, like "hypervisor-3" or "build-sandbox", so
+// differently configured runners can be set up once, and retrieved where they
+// are needed. It is safe for concurrent use, and its zero value is ready to
+// use.
+//
+// Resources used by a runner, like the sink of a Metrics runner, or the writer
+// of a Record runner, may be released when it is unregistered, or the
+// registry is closed, by adding hooks with OnClose. Runners which implement
+// io.Closer themselves are closed too.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+	closed  bool
+}
+
+type registryEntry struct {
+	runner  Runner
+	onClose []func() error
+}
+
+// Register adds r to the registry under name. Returns ErrRegistryDuplicate if
+// a runner is already registered under name.
+func (reg *Registry) Register(name string, r Runner) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if reg.closed {
+		return ErrRegistryClosed
+	}
+	if _, ok := reg.entries[name]; ok {
+		return fmt.Errorf("%w: %q", ErrRegistryDuplicate, name)
+	}
+	if reg.entries == nil {
+		reg.entries = map[string]*registryEntry{}
+	}
+	reg.entries[name] = &registryEntry{runner: r}
+
+	return nil
+}
+
+// OnClose adds a hook which is called when the named runner is unregistered,
+// or the registry is closed. Hooks are called in the reverse order they were
+// added, like deferred calls.
+func (reg *Registry) OnClose(name string, fn func() error) error {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	e, err := reg.entry(name)
+	if err != nil {
+		return err
+	}
+	e.onClose = append(e.onClose, fn)
+
+	return nil
+}
+
+// Get returns the runner registered under name. Returns ErrRegistryNotFound
+// if there is none.
+func (reg *Registry) Get(name string) (Runner, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	e, err := reg.entry(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.runner, nil
+}
+
+// MustGet is like Get, but panics if no runner is registered under name. It
+// suits wiring up services at startup.
+func (reg *Registry) MustGet(name string) Runner {
+	r, err := reg.Get(name)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Names returns the names of all registered runners, sorted.
+func (reg *Registry) Names() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	names := make([]string, 0, len(reg.entries))
+	for name := range reg.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Unregister removes the runner registered under name, and closes it.
+func (reg *Registry) Unregister(name string) error {
+	reg.mu.Lock()
+	e, err := reg.entry(name)
+	if err == nil {
+		delete(reg.entries, name)
+	}
+	reg.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return e.close(name)
+}
+
+// Close unregisters and closes all runners, in order of their names, and
+// returns any errors joined together. Further calls to Register return
+// ErrRegistryClosed.
+func (reg *Registry) Close() error {
+	reg.mu.Lock()
+	entries := reg.entries
+	reg.entries = nil
+	reg.closed = true
+	reg.mu.Unlock()
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, entries[name].close(name))
+	}
+
+	return errors.Join(errs...)
+}
+
+// entry returns the entry registered under name. It must be called with mu
+// held.
+func (reg *Registry) entry(name string) (*registryEntry, error) {
+	e, ok := reg.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrRegistryNotFound, name)
+	}
+
+	return e, nil
+}
+
+// close calls the entry's hooks in reverse order, and then closes its runner
+// if it implements io.Closer.
+func (e *registryEntry) close(name string) error {
+	var errs []error
+	for i := len(e.onClose) - 1; i >= 0; i-- {
+		errs = append(errs, e.onClose[i]())
+	}
+	if c, ok := e.runner.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: closing %q: %w", ErrRegistry, name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closerRunner struct {
+	Fake
+	closed int
+	err    error
+}
+
+func (r *closerRunner) Close() error {
+	r.closed++
+
+	return r.err
+}
+
+func TestRegistry(t *testing.T) {
+	reg := &Registry{}
+	local := &Local{}
+	sandbox := &Fake{}
+
+	require.NoError(t, reg.Register("local", local))
+	require.NoError(t, reg.Register("build-sandbox", sandbox))
+	assert.ErrorIs(t, reg.Register("local", &Local{}), ErrRegistryDuplicate)
+
+	got, err := reg.Get("local")
+	require.NoError(t, err)
+	assert.Same(t, local, got)
+	assert.Same(t, sandbox, reg.MustGet("build-sandbox"))
+
+	_, err = reg.Get("hypervisor-3")
+	assert.EqualError(
+		t, err, `runner: registry: runner not found: "hypervisor-3"`,
+	)
+	assert.ErrorIs(t, err, ErrRegistryNotFound)
+	assert.Panics(t, func() { reg.MustGet("hypervisor-3") })
+
+	assert.Equal(t, []string{"build-sandbox", "local"}, reg.Names())
+}
+
+func TestRegistry_Unregister(t *testing.T) {
+	reg := &Registry{}
+	r := &closerRunner{}
+	require.NoError(t, reg.Register("a", r))
+
+	var calls []string
+	require.NoError(t, reg.OnClose("a", func() error {
+		calls = append(calls, "first")
+
+		return nil
+	}))
+	require.NoError(t, reg.OnClose("a", func() error {
+		calls = append(calls, "second")
+
+		return nil
+	}))
+	assert.ErrorIs(
+		t, reg.OnClose("b", func() error { return nil }), ErrRegistryNotFound,
+	)
+
+	require.NoError(t, reg.Unregister("a"))
+	assert.Equal(t, []string{"second", "first"}, calls)
+	assert.Equal(t, 1, r.closed)
+	assert.Empty(t, reg.Names())
+
+	assert.ErrorIs(t, reg.Unregister("a"), ErrRegistryNotFound)
+	assert.Equal(t, 1, r.closed)
+}
+
+func TestRegistry_Close(t *testing.T) {
+	reg := &Registry{}
+	hookErr := errors.New("flush failed")
+	closeErr := errors.New("close failed")
+
+	a := &closerRunner{}
+	b := &closerRunner{err: closeErr}
+	require.NoError(t, reg.Register("a", a))
+	require.NoError(t, reg.Register("b", b))
+	require.NoError(t, reg.Register("c", &Local{}))
+	require.NoError(t, reg.OnClose("c", func() error { return hookErr }))
+
+	err := reg.Close()
+	assert.ErrorIs(t, err, ErrRegistry)
+	assert.ErrorIs(t, err, hookErr)
+	assert.ErrorIs(t, err, closeErr)
+	assert.EqualError(t, err, "runner: registry: closing \"b\": close failed\n"+
+		"runner: registry: closing \"c\": flush failed")
+
+	assert.Equal(t, 1, a.closed)
+	assert.Equal(t, 1, b.closed)
+	assert.Empty(t, reg.Names())
+	assert.ErrorIs(t, reg.Register("d", &Local{}), ErrRegistryClosed)
+
+	assert.NoError(t, reg.Close())
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// RunRemoteScript uploads script to a temporary file on the remote host of
+// ssh, makes it executable, and executes it with the given arguments, before
+// removing it again. This avoids having to quote a complex script so it
+// survives being passed through the remote user's shell.
+//
+// As the script is executed directly, it should start with a "#!" line naming
+// its interpreter. The file is only accessible to the remote user, and is
+// removed even if the script fails, or ctx is done.
+//
+// Like RunContextResult, the returned RunResult is never nil, even when an
+// error is returned. Its resource usage is that of the ssh command.
+func RunRemoteScript(
+	ctx context.Context,
+	ssh *SSHCLI,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	script string,
+	args ...string,
+) (*RunResult, error) {
+	file, err := writeTempScript(ctx, ssh, true, script)
+	if err != nil {
+		return &RunResult{ExitCode: -1}, err
+	}
+
+	res, err := runTempScript(ctx, ssh, stdin, stdout, stderr, file, args)
+
+	rmErr := removeTempScript(ctx, ssh, true, file)
+	if err == nil {
+		err = rmErr
+	}
+
+	return res, err
+}
+
+func runTempScript(
+	ctx context.Context,
+	ssh *SSHCLI,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	file string,
+	args []string,
+) (*RunResult, error) {
+	err := ssh.RunContext(
+		ctx, nil, nil, stderr, "chmod", "700", "--", shellQuote(file),
+	)
+	if err != nil {
+		return &RunResult{ExitCode: -1}, fmt.Errorf(
+			"%w: chmod %s: %w", ErrScript, file, err,
+		)
+	}
+
+	return RunContextResult(
+		ctx, ssh, stdin, stdout, stderr,
+		shellQuote(file), quoteArgs(ssh, true, copyStrings(args))...,
+	)
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localSSH returns a Runner which emulates ssh, by running the command line
+// given after "--" with a local shell, like sshd does with the remote user's
+// shell.
+func localSSH(t *testing.T) (Runner, *[]string) {
+	t.Helper()
+
+	var cmdlines []string
+	stub := &Stub{Fallback: func(
+		ctx context.Context,
+		stdin io.Reader,
+		stdout, stderr io.Writer,
+		command string,
+		args ...string,
+	) error {
+		require.Equal(t, "ssh", command)
+		for i, arg := range args {
+			if arg == "--" {
+				args = args[i+1:]
+
+				break
+			}
+		}
+		cmdline := strings.Join(args, " ")
+		cmdlines = append(cmdlines, cmdline)
+
+		return (&Local{}).RunContext(
+			ctx, stdin, stdout, stderr, "sh", "-c", cmdline,
+		)
+	}}
+
+	return stub, &cmdlines
+}
+
+func TestRunRemoteScript(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		stdin      string
+		args       []string
+		wantStdout string
+		wantCode   int
+	}{
+		{
+			name: "script with arguments",
+			script: "#!/bin/sh\n" +
+				"printf '%s|' \"$#\" \"$@\"\n" +
+				"echo \"it's $(echo done)\"\n",
+			args:       []string{"two words", "$HOME", "a;b"},
+			wantStdout: "3|two words|$HOME|a;b|it's done\n",
+		},
+		{
+			name:       "stdin",
+			script:     "#!/bin/sh\ntr a-z A-Z\n",
+			stdin:      "hello\n",
+			wantStdout: "HELLO\n",
+		},
+		{
+			name:       "failure",
+			script:     "#!/bin/sh\necho before\nexit 3\n",
+			wantStdout: "before\n",
+			wantCode:   3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, cmdlines := localSSH(t)
+			ssh := &SSHCLI{Runner: r, Destination: "web1"}
+
+			var stdin io.Reader
+			if tt.stdin != "" {
+				stdin = strings.NewReader(tt.stdin)
+			}
+			var stdout bytes.Buffer
+			res, err := RunRemoteScript(
+				context.Background(), ssh, stdin, &stdout, nil,
+				tt.script, tt.args...,
+			)
+			require.NotNil(t, res)
+
+			if tt.wantCode != 0 {
+				assert.Equal(t, tt.wantCode, ExitCode(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCode, res.ExitCode)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+
+			// Upload, chmod, execute, and remove.
+			require.Len(t, *cmdlines, 4)
+			file := strings.Fields((*cmdlines)[1])[3]
+			file = strings.Trim(file, "'")
+			assert.Equal(t, shellQuote(file), strings.Fields((*cmdlines)[2])[0])
+			_, err = os.Stat(file)
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
+
+func TestRunRemoteScript_uploadFails(t *testing.T) {
+	f := &Fake{}
+	f.Respond("ssh", nil, FakeResponse{Stderr: "denied\n", ExitCode: 255})
+	ssh := &SSHCLI{Runner: f, Destination: "web1"}
+
+	res, err := RunRemoteScript(
+		context.Background(), ssh, nil, nil, nil, "#!/bin/sh\n",
+	)
+	require.NotNil(t, res)
+
+	assert.ErrorIs(t, err, ErrScript)
+	assert.Equal(t, 255, ExitCode(err))
+	assert.Equal(t, -1, res.ExitCode)
+	assert.Len(t, f.Calls(), 1)
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// RemoteSudo is a Runner which runs commands on a remote host via SSHCLI, and
+// then as another user via sudo, like "ssh host -- sudo -n -u user -- cmd".
+//
+// Environment variables set with Env or WithEnv are passed to the final
+// command via the env command, run by sudo, rather than to sudo itself, so
+// they reach the command without the sudoers policy having to permit setting
+// them. Neither ssh nor sudo are affected by them. A working directory set
+// with WithDir is changed to by the remote shell before sudo is run, which
+// sudo retains by default.
+//
+// As with SSHCLI, arguments are interpreted by the remote user's shell.
+type RemoteSudo struct {
+	// SSH is the SSHCLI runner which runs sudo on the remote host. If not
+	// set, running commands will cause a panic.
+	SSH *SSHCLI
+
+	// User is the user to run commands as, passed to sudo via the -u flag.
+	// When empty, sudo runs commands as root.
+	User string
+
+	// Args is a string slice of extra arguments to pass to sudo.
+	Args []string
+
+	env   []string
+	envMu sync.RWMutex
+}
+
+var _ Runner = &RemoteSudo{}
+
+// NewRemoteSudo returns a RemoteSudo which runs commands as user on
+// destination, by running ssh with r. The returned runner's SSH field may be
+// used to set further SSH options, like Port and IdentityFile.
+func NewRemoteSudo(r Runner, destination string, user string) *RemoteSudo {
+	return &RemoteSudo{
+		SSH:  &SSHCLI{Runner: r, Destination: destination},
+		User: user,
+	}
+}
+
+// NewValidatedRemoteSudo is like NewRemoteSudo, but also validates the
+// returned RemoteSudo, returning an error matching ErrInvalid if destination
+// or user are invalid, or r is nil.
+func NewValidatedRemoteSudo(
+	r Runner,
+	destination string,
+	user string,
+) (*RemoteSudo, error) {
+	rs := NewRemoteSudo(r, destination, user)
+	if err := rs.Validate(); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+var _ Validator = &RemoteSudo{}
+
+// Validate checks the RemoteSudo is configured correctly, so misconfiguration
+// is reported before any command is run. It checks SSH is set and valid, and
+// that values passed to sudo do not contain illegal characters, or begin with
+// "-" where they could be mistaken for flags.
+func (r *RemoteSudo) Validate() error {
+	if r.SSH == nil {
+		return &ValidationError{Field: "RemoteSudo.SSH", Msg: "must be set"}
+	}
+
+	return firstError(
+		validateWord("RemoteSudo.User", r.User),
+		validateEnv("RemoteSudo.Env", r.environ()),
+		r.SSH.Validate(),
+	)
+}
+
+// Run executes the command remotely via ssh and sudo.
+//
+// Will panic if SSH field is nil.
+func (r *RemoteSudo) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext executes the command remotely via ssh and sudo.
+//
+// Will panic if SSH field is nil.
+func (r *RemoteSudo) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	sudoArgs := []string{"-n"}
+	if r.User != "" {
+		sudoArgs = append(sudoArgs, "-u", r.User)
+	}
+	sudoArgs = append(sudoArgs, r.Args...)
+	sudoArgs = append(sudoArgs, "--")
+
+	if env := DedupEnv(mergeEnv(r.environ(), callEnv(ctx))...); len(env) > 0 {
+		sudoArgs = append(sudoArgs, "env")
+		sudoArgs = append(sudoArgs, env...)
+	}
+	sudoArgs = append(sudoArgs, command)
+	sudoArgs = append(sudoArgs, args...)
+
+	sshCtx := withoutCallOptions(ctx)
+	if dir := callDir(ctx); dir != "" {
+		sshCtx = withCallDir(sshCtx, dir)
+	}
+
+	return r.SSH.RunContext(
+		sshCtx, stdin, stdout, stderr, "sudo", sudoArgs...,
+	)
+}
+
+// Env sets the environment variables which will be provided to commands run
+// via sudo on the remote host. Env is not called on SSH.
+func (r *RemoteSudo) Env(env ...string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+func (r *RemoteSudo) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
+}
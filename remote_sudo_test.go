@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteSudo(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		args     []string
+		env      []string
+		opts     []RunOption
+		setup    func(r *RemoteSudo)
+		wantArgs []string
+	}{
+		{
+			name: "root",
+			wantArgs: []string{
+				"web1", "--", "sudo", "-n", "--",
+				"systemctl", "restart", "nginx",
+			},
+		},
+		{
+			name: "user",
+			user: "deploy",
+			wantArgs: []string{
+				"web1", "--", "sudo", "-n", "-u", "deploy", "--",
+				"systemctl", "restart", "nginx",
+			},
+		},
+		{
+			name: "env reaches final command",
+			user: "deploy",
+			env:  []string{"A=1", "B=2"},
+			opts: []RunOption{WithEnv("B=3")},
+			wantArgs: []string{
+				"web1", "--", "sudo", "-n", "-u", "deploy", "--",
+				"env", "A=1", "B=3", "systemctl", "restart", "nginx",
+			},
+		},
+		{
+			name: "dir changed to before sudo",
+			opts: []RunOption{WithDir("/srv/app")},
+			wantArgs: []string{
+				"web1", "--", "cd", "'/srv/app'", "&&",
+				"sudo", "-n", "--", "systemctl", "restart", "nginx",
+			},
+		},
+		{
+			name: "ssh and sudo options",
+			setup: func(r *RemoteSudo) {
+				r.SSH.Port = 2222
+				r.SSH.Login = "admin"
+				r.Args = []string{"-H"}
+			},
+			wantArgs: []string{
+				"-p", "2222", "-l", "admin", "web1", "--",
+				"sudo", "-n", "-H", "--", "systemctl", "restart", "nginx",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond("ssh", nil, FakeResponse{})
+
+			r := NewRemoteSudo(f, "web1", tt.user)
+			if tt.setup != nil {
+				tt.setup(r)
+			}
+			if tt.env != nil {
+				r.Env(tt.env...)
+			}
+
+			err := RunWith(
+				context.Background(), r, tt.opts,
+				"systemctl", "restart", "nginx",
+			)
+			require.NoError(t, err)
+
+			calls := f.Calls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, "ssh", calls[0].Command)
+			assert.Equal(t, tt.wantArgs, calls[0].Args)
+			assert.Empty(t, calls[0].Env)
+		})
+	}
+}
+
+func TestRemoteSudo_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("ssh", nil, FakeResponse{ExitCode: 1})
+
+	r := NewRemoteSudo(f, "web1", "")
+	err := r.Run(nil, nil, nil, "true")
+
+	assert.Equal(t, 1, ExitCode(err))
+	assert.Equal(
+		t, []string{"web1", "--", "sudo", "-n", "--", "true"},
+		f.Calls()[0].Args,
+	)
+}
+
+func TestRemoteSudo_Validate(t *testing.T) {
+	assert.NoError(t, NewRemoteSudo(&Local{}, "web1", "deploy").Validate())
+
+	assert.EqualError(
+		t, (&RemoteSudo{}).Validate(),
+		"runner: invalid: RemoteSudo.SSH: must be set",
+	)
+	assert.EqualError(
+		t, NewRemoteSudo(&Local{}, "web1", "-s").Validate(),
+		"runner: invalid: RemoteSudo.User: must not begin with -",
+	)
+	assert.EqualError(
+		t, NewRemoteSudo(nil, "web1", "").Validate(),
+		"runner: invalid: SSHCLI.Runner: must be set",
+	)
+	assert.ErrorIs(
+		t, NewRemoteSudo(&Local{}, "", "").Validate(), ErrSSHCLINoDestination,
+	)
+}
+
+func TestNewValidatedRemoteSudo(t *testing.T) {
+	r, err := NewValidatedRemoteSudo(&Local{}, "web1", "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, NewRemoteSudo(&Local{}, "web1", "deploy"), r)
+
+	r, err = NewValidatedRemoteSudo(&Local{}, "web1 rm", "")
+	assert.EqualError(
+		t, err,
+		`runner: invalid: SSHCLI.Destination: contains illegal character ' '`,
+	)
+	assert.Nil(t, r)
+}
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrReplay      = fmt.Errorf("%w: replay", Err)
+	ErrNoRecording = fmt.Errorf("%w: no matching recording", ErrReplay)
+)
+
+// ReplayMode controls how a Replay runner matches commands to Recordings.
+type ReplayMode int
+
+const (
+	// ReplayLenient matches commands to Recordings by command and arguments
+	// only. Recordings for the same command and arguments are replayed in
+	// order, after which the last of them is replayed for any further runs.
+	ReplayLenient ReplayMode = iota
+
+	// ReplayStrict matches commands to Recordings by command, arguments, and
+	// stdin data. Each Recording is replayed at most once, with Recordings
+	// for the same command replayed in order.
+	ReplayStrict
+)
+
+// ReplayError is returned by Replay when replaying a Recording of a command
+// which failed.
+type ReplayError struct {
+	// Recording is the replayed Recording.
+	Recording *Recording
+}
+
+var _ error = &ReplayError{}
+
+// Error returns the error message of the recorded command, or its exit code
+// if no message was recorded.
+func (e *ReplayError) Error() string {
+	if e.Recording.Error != "" {
+		return e.Recording.Error
+	}
+
+	return fmt.Sprintf(
+		"%s: exit status %d", e.Recording.Command, e.Recording.ExitCode,
+	)
+}
+
+// ExitCode returns the recorded exit code of the command.
+func (e *ReplayError) ExitCode() int {
+	return e.Recording.ExitCode
+}
+
+// LoadRecordings reads Recordings from r, in the format written by
+// RecordWriter, with one JSON encoded Recording per line.
+func LoadRecordings(r io.Reader) ([]*Recording, error) {
+	recs := []*Recording{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		rec := &Recording{}
+		err := json.Unmarshal(b, rec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrReplay, line, err)
+		}
+		recs = append(recs, rec)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// LoadRecordFile reads Recordings from the file at path, as written by
+// CreateRecordFile.
+func LoadRecordFile(path string) ([]*Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadRecordings(f)
+}
+
+// Replay is a Runner which does not run commands, but instead replays the
+// stdout and stderr data, and exit codes from previously captured Recordings,
+// like those saved by a Record runner. This allows tests to exercise code
+// which runs commands without touching the system.
+//
+// Commands which were recorded as failing return a *ReplayError, which
+// reports the recorded exit code via its ExitCode method. Commands which do
+// not match any available Recording return an error wrapping ErrNoRecording.
+//
+// Stdin is always read to completion before replaying. Calls to Env are
+// ignored.
+//
+// Replay is safe for concurrent use.
+type Replay struct {
+	// Recordings are the Recordings to replay.
+	Recordings []*Recording
+
+	// Mode controls how commands are matched to Recordings. The default is
+	// ReplayLenient.
+	Mode ReplayMode
+
+	mu   sync.Mutex
+	used map[int]bool
+}
+
+var _ Runner = &Replay{}
+
+// Run replays the Recording matching the given command and arguments.
+func (r *Replay) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext replays the Recording matching the given command and arguments.
+// If ctx is done, its error is returned without replaying anything.
+func (r *Replay) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := ctx.Err()
+	if err != nil {
+		return err
+	}
+
+	var input []byte
+	if stdin != nil {
+		input, err = io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	rec := r.match(command, args, input)
+	if rec == nil {
+		cmdline := strings.Join(append([]string{command}, args...), " ")
+
+		return fmt.Errorf("%w: %s", ErrNoRecording, cmdline)
+	}
+
+	if stdout != nil && len(rec.Stdout) > 0 {
+		_, err = stdout.Write(rec.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	if stderr != nil && len(rec.Stderr) > 0 {
+		_, err = stderr.Write(rec.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rec.ExitCode != 0 || rec.Error != "" {
+		return &ReplayError{Recording: rec}
+	}
+
+	return nil
+}
+
+// Env does nothing, as environment variables are not used when replaying.
+func (r *Replay) Env(...string) {}
+
+// Unused returns the Recordings which have not yet been replayed, allowing
+// tests to verify that all expected commands were run.
+func (r *Replay) Unused() []*Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := []*Recording{}
+	for i, rec := range r.Recordings {
+		if !r.used[i] {
+			unused = append(unused, rec)
+		}
+	}
+
+	return unused
+}
+
+func (r *Replay) match(
+	command string,
+	args []string,
+	stdin []byte,
+) *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.used == nil {
+		r.used = map[int]bool{}
+	}
+
+	last := -1
+	for i, rec := range r.Recordings {
+		if rec.Command != command || !equalStrings(rec.Args, args) {
+			continue
+		}
+		if r.Mode == ReplayStrict && !bytes.Equal(rec.Stdin, stdin) {
+			continue
+		}
+
+		last = i
+		if !r.used[i] {
+			r.used[i] = true
+
+			return rec
+		}
+	}
+
+	if r.Mode == ReplayLenient && last >= 0 {
+		return r.Recordings[last]
+	}
+
+	return nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
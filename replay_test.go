@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replayTestRecordings() []*Recording {
+	return []*Recording{
+		{
+			Command: "zfs",
+			Args:    []string{"list"},
+			Stdout:  []byte("tank\n"),
+		},
+		{
+			Command: "zfs",
+			Args:    []string{"list"},
+			Stdout:  []byte("tank\ntank/data\n"),
+		},
+		{
+			Command:  "zfs",
+			Args:     []string{"destroy", "tank"},
+			Stderr:   []byte("dataset is busy\n"),
+			ExitCode: 1,
+			Error:    "zfs: exit status 1",
+		},
+		{
+			Command: "cat",
+			Stdin:   []byte("hello"),
+			Stdout:  []byte("hello"),
+		},
+	}
+}
+
+type replayRun struct {
+	command    string
+	args       []string
+	stdin      string
+	wantStdout string
+	wantStderr string
+	wantCode   int
+	wantErr    string
+}
+
+func TestReplay_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       ReplayMode
+		runs       []replayRun
+		wantUnused int
+	}{
+		{
+			name: "lenient",
+			mode: ReplayLenient,
+			runs: []replayRun{
+				{command: "zfs", args: []string{"list"}, wantStdout: "tank\n"},
+				{
+					command:    "zfs",
+					args:       []string{"list"},
+					wantStdout: "tank\ntank/data\n",
+				},
+				{
+					command:    "zfs",
+					args:       []string{"list"},
+					wantStdout: "tank\ntank/data\n",
+				},
+				{
+					command:    "zfs",
+					args:       []string{"destroy", "tank"},
+					wantStderr: "dataset is busy\n",
+					wantCode:   1,
+					wantErr:    "zfs: exit status 1",
+				},
+				{command: "cat", stdin: "other", wantStdout: "hello"},
+				{
+					command:  "zfs",
+					args:     []string{"list", "-H"},
+					wantCode: -1,
+					wantErr: "runner: replay: no matching recording: " +
+						"zfs list -H",
+				},
+			},
+		},
+		{
+			name: "strict",
+			mode: ReplayStrict,
+			runs: []replayRun{
+				{command: "zfs", args: []string{"list"}, wantStdout: "tank\n"},
+				{
+					command:    "zfs",
+					args:       []string{"list"},
+					wantStdout: "tank\ntank/data\n",
+				},
+				{
+					command:  "zfs",
+					args:     []string{"list"},
+					wantCode: -1,
+					wantErr:  "runner: replay: no matching recording: zfs list",
+				},
+				{
+					command:  "cat",
+					stdin:    "other",
+					wantCode: -1,
+					wantErr:  "runner: replay: no matching recording: cat",
+				},
+				{command: "cat", stdin: "hello", wantStdout: "hello"},
+			},
+			wantUnused: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Replay{Recordings: replayTestRecordings(), Mode: tt.mode}
+
+			for _, run := range tt.runs {
+				var stdout, stderr bytes.Buffer
+				err := r.Run(
+					strings.NewReader(run.stdin), &stdout, &stderr,
+					run.command, run.args...,
+				)
+
+				if run.wantErr != "" {
+					assert.EqualError(t, err, run.wantErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				assert.Equal(t, run.wantCode, ExitCode(err))
+				assert.Equal(t, run.wantStdout, stdout.String())
+				assert.Equal(t, run.wantStderr, stderr.String())
+			}
+
+			assert.Len(t, r.Unused(), tt.wantUnused)
+		})
+	}
+}
+
+func TestReplay_RunContext(t *testing.T) {
+	r := &Replay{Recordings: replayTestRecordings()}
+
+	var stdout bytes.Buffer
+	err := r.RunContext(
+		context.Background(), nil, &stdout, nil, "zfs", "list",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "tank\n", stdout.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = r.RunContext(ctx, nil, &stdout, nil, "zfs", "list")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, "tank\n", stdout.String())
+}
+
+func TestReplay_ReplayError(t *testing.T) {
+	r := &Replay{Recordings: []*Recording{
+		{Command: "false", ExitCode: 1},
+	}}
+
+	err := r.Run(nil, nil, nil, "false")
+
+	var replayErr *ReplayError
+	require.ErrorAs(t, err, &replayErr)
+	assert.EqualError(t, err, "false: exit status 1")
+	assert.Equal(t, 1, replayErr.ExitCode())
+}
+
+func TestReplay_recorded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	rw, err := CreateRecordFile(path)
+	require.NoError(t, err)
+
+	rec := &Record{Runner: &Local{}, Store: rw}
+	err = rec.Run(nil, nil, nil, "sh", "-c", "echo hello; exit 3")
+	require.Error(t, err)
+	require.NoError(t, rw.Close())
+
+	recs, err := LoadRecordFile(path)
+	require.NoError(t, err)
+
+	r := &Replay{Recordings: recs, Mode: ReplayStrict}
+
+	var stdout bytes.Buffer
+	err = r.Run(nil, &stdout, nil, "sh", "-c", "echo hello; exit 3")
+	assert.EqualError(t, err, "sh: exit status 3")
+	assert.Equal(t, 3, ExitCode(err))
+	assert.Equal(t, "hello\n", stdout.String())
+	assert.Empty(t, r.Unused())
+}
+
+func TestLoadRecordings(t *testing.T) {
+	recs, err := LoadRecordings(strings.NewReader(
+		`{"command":"ls","args":["-l"],"stdout":"Zm9vCg=="}` + "\n\n" +
+			`{"command":"false","args":null,"exit_code":1}` + "\n",
+	))
+	require.NoError(t, err)
+	assert.Equal(t, []*Recording{
+		{Command: "ls", Args: []string{"-l"}, Stdout: []byte("foo\n")},
+		{Command: "false", ExitCode: 1},
+	}, recs)
+
+	_, err = LoadRecordings(strings.NewReader(
+		`{"command":"ls"}` + "\n" + `{"command":` + "\n",
+	))
+	assert.ErrorIs(t, err, ErrReplay)
+	assert.EqualError(
+		t, err, "runner: replay: line 2: unexpected end of JSON input",
+	)
+}
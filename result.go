@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// RunResult describes the outcome of a command run with RunContextResult.
+type RunResult struct {
+	// ExitCode is the exit code of the command. It is -1 if the command did
+	// not run, or was terminated by a signal.
+	ExitCode int
+
+	// Duration is the wall-clock time taken to run the command.
+	Duration time.Duration
+
+	// UserTime is the user CPU time used by the process. It is only available
+	// from runners which start processes, like Local.
+	UserTime time.Duration
+
+	// SystemTime is the system CPU time used by the process. It is only
+	// available from runners which start processes, like Local.
+	SystemTime time.Duration
+
+	// MaxRSS is the maximum resident set size of the process in bytes. It is
+	// only available on Unix-like systems from runners which start processes,
+	// like Local.
+	MaxRSS int64
+
+	// MinorPageFaults is the number of page faults serviced without any I/O,
+	// like those reclaiming pages from the page cache. It is only available
+	// on Unix-like systems from runners which start processes, like Local.
+	MinorPageFaults int64
+
+	// MajorPageFaults is the number of page faults which required I/O, like
+	// reading a page from disk or swap. It is only available on Unix-like
+	// systems from runners which start processes, like Local.
+	MajorPageFaults int64
+
+	// VoluntaryContextSwitches is the number of times the process gave up the
+	// CPU, typically to wait for I/O. It is only available on Unix-like
+	// systems from runners which start processes, like Local.
+	VoluntaryContextSwitches int64
+
+	// InvoluntaryContextSwitches is the number of times the process was
+	// preempted, typically as its time slice ended. It is only available on
+	// Unix-like systems from runners which start processes, like Local.
+	InvoluntaryContextSwitches int64
+}
+
+// CPUTime returns the total CPU time used by the process, the sum of UserTime
+// and SystemTime.
+func (r *RunResult) CPUTime() time.Duration {
+	return r.UserTime + r.SystemTime
+}
+
+// RunContextResult runs the given command via RunContext on r, and returns a
+// RunResult describing its outcome. The returned RunResult is never nil, even
+// when an error is returned, so the exit code of a failed command can be
+// inspected.
+//
+// When r is a wrapper Runner, the resource usage reported is that of the
+// wrapping command, like sudo or ssh.
+func RunContextResult(
+	ctx context.Context,
+	r Runner,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) (*RunResult, error) {
+	res := &RunResult{}
+	ctx = withExitedFunc(ctx, func(state *os.ProcessState) {
+		res.UserTime = state.UserTime()
+		res.SystemTime = state.SystemTime()
+		res.MaxRSS = maxRSS(state)
+		res.MinorPageFaults, res.MajorPageFaults,
+			res.VoluntaryContextSwitches,
+			res.InvoluntaryContextSwitches = rusageCounters(state)
+	})
+
+	start := time.Now()
+	err := r.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	res.Duration = time.Since(start)
+	res.ExitCode = ExitCode(err)
+
+	return res, err
+}
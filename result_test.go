@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunContextResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		wantExitCode int
+		wantErr      string
+	}{
+		{
+			name:         "success",
+			script:       "sleep 0.05",
+			wantExitCode: 0,
+		},
+		{
+			name:         "failure",
+			script:       "sleep 0.05; exit 7",
+			wantExitCode: 7,
+			wantErr:      "sh: exit status 7",
+		},
+		{
+			name:         "killed",
+			script:       "kill -9 $$",
+			wantExitCode: -1,
+			wantErr:      "sh: signal: killed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := RunContextResult(
+				context.Background(), New(), nil, nil, nil,
+				"sh", "-c", tt.script,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantExitCode, res.ExitCode)
+			assert.Greater(t, res.Duration, time.Duration(0))
+			if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+				assert.Greater(t, res.MaxRSS, int64(0))
+				assert.Greater(t, res.MinorPageFaults, int64(0))
+				assert.Greater(
+					t, res.VoluntaryContextSwitches+
+						res.InvoluntaryContextSwitches,
+					int64(0),
+				)
+			}
+		})
+	}
+}
+
+func TestRunContextResult_mock(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, nil, nil, "zfs", []string{"list"},
+	).Return(errors.New("zfs: command not found"))
+
+	res, err := RunContextResult(ctx, r, nil, nil, nil, "zfs", "list")
+
+	assert.EqualError(t, err, "zfs: command not found")
+	assert.Equal(t, -1, res.ExitCode)
+	assert.Equal(t, int64(0), res.MaxRSS)
+	assert.Equal(t, time.Duration(0), res.UserTime)
+	assert.Equal(t, int64(0), res.MinorPageFaults)
+	assert.Equal(t, int64(0), res.MajorPageFaults)
+}
+
+func TestRunContextResult_wrapper(t *testing.T) {
+	res, err := RunContextResult(
+		context.Background(),
+		&Testing{Runner: New(), TestingT: t},
+		nil, nil, nil,
+		"sh", "-c", "exit 2",
+	)
+
+	assert.EqualError(t, err, "sh: exit status 2")
+	assert.Equal(t, 2, res.ExitCode)
+	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
+		assert.Greater(t, res.MaxRSS, int64(0))
+	}
+}
+
+func TestRunResult_CPUTime(t *testing.T) {
+	res := &RunResult{
+		UserTime:   1500 * time.Millisecond,
+		SystemTime: 250 * time.Millisecond,
+	}
+
+	assert.Equal(t, 1750*time.Millisecond, res.CPUTime())
+}
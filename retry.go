@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// RetryAttempt describes a failed attempt at running a command, and is passed
+// to a RetryPolicy.
+type RetryAttempt struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+
+	// Err is the error returned by the attempt.
+	Err error
+
+	// ExitCode is the exit code of the attempt, or -1 if it failed without an
+	// exit code.
+	ExitCode int
+}
+
+// RetryPolicy decides if a failed attempt at running a command should be
+// retried. It is only called while attempts remain.
+type RetryPolicy func(a RetryAttempt) bool
+
+// DefaultRetryPolicy only retries attempts which are known not to have run
+// the command, which are injected transport errors from Chaos. Attempts which
+// failed due to context cancellation or timeouts are never retried.
+//
+// Failures to connect to a remote host are not retried by default, as they
+// cannot be told apart reliably from the command failing. ssh exits with code
+// 255 when it fails to connect, but also when the remote command exits with
+// 255 itself. Retrying them is opt-in via RetryOnExitCodes(255), which is only
+// safe for idempotent commands.
+func DefaultRetryPolicy(a RetryAttempt) bool {
+	if isContextError(a.Err) {
+		return false
+	}
+
+	return errors.Is(a.Err, ErrChaosTransport)
+}
+
+// RetryOnExitCodes returns a RetryPolicy which retries attempts that exited
+// with any of the given exit codes.
+func RetryOnExitCodes(codes ...int) RetryPolicy {
+	return func(a RetryAttempt) bool {
+		if isContextError(a.Err) {
+			return false
+		}
+		for _, code := range codes {
+			if a.ExitCode == code {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// RetryOnErrors returns a RetryPolicy which retries attempts whose error
+// matches any of the given target errors, as reported by errors.Is.
+func RetryOnErrors(targets ...error) RetryPolicy {
+	return func(a RetryAttempt) bool {
+		for _, target := range targets {
+			if errors.Is(a.Err, target) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryRule sets how a Retry runner retries commands matching its
+// PolicyRule.
+type RetryRule struct {
+	PolicyRule
+
+	// MaxAttempts is the maximum number of attempts at running matching
+	// commands. When 1, matching commands are never retried, which is useful
+	// for commands which are not idempotent.
+	MaxAttempts int
+
+	// Policy decides if failed attempts are retried. When nil, the Policy of
+	// the Retry runner is used.
+	Policy RetryPolicy
+}
+
+// Retry is a Runner that wraps another Runner, and retries commands which
+// fail, as decided by a RetryPolicy. Retried attempts are delayed by an
+// exponential backoff.
+//
+// If retries are possible and stdin is not nil, stdin is read into memory in
+// full before the first attempt, so it can be given to each attempt. Output of
+// failed attempts is written to stdout and stderr as it happens, so callers
+// which capture output may see output from several attempts.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Retry struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// MaxAttempts is the maximum number of attempts at running commands which
+	// do not match any of the Rules. When zero or one, they are not retried.
+	MaxAttempts int
+
+	// Policy decides if failed attempts are retried. Defaults to
+	// DefaultRetryPolicy.
+	Policy RetryPolicy
+
+	// Backoff is the delay before the first retry, which doubles for each
+	// further retry.
+	Backoff time.Duration
+
+	// MaxBackoff, when non-zero, is the maximum delay between retries.
+	MaxBackoff time.Duration
+
+	// Rules sets how specific commands are retried. The first matching rule is
+	// used instead of MaxAttempts and Policy.
+	Rules []RetryRule
+}
+
+var _ Runner = &Retry{}
+
+// Run calls Run on the underlying Runner, retrying failed attempts.
+//
+// Will panic if Runner field is nil.
+func (r *Retry) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.retry(
+		context.Background(), stdin, command, args,
+		func(_ context.Context, stdin io.Reader) error {
+			return r.Runner.Run(stdin, stdout, stderr, command, args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, retrying failed
+// attempts. No further attempts are made once ctx is done.
+//
+// Will panic if Runner field is nil.
+func (r *Retry) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.retry(
+		ctx, stdin, command, args,
+		func(ctx context.Context, stdin io.Reader) error {
+			return r.Runner.RunContext(
+				ctx, stdin, stdout, stderr, command, args...,
+			)
+		},
+	)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Retry) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Retry) retry(
+	ctx context.Context,
+	stdin io.Reader,
+	command string,
+	args []string,
+	run func(ctx context.Context, stdin io.Reader) error,
+) error {
+	maxAttempts, policy := r.policy(command, args)
+	if maxAttempts <= 1 {
+		return run(ctx, stdin)
+	}
+
+	var input []byte
+	if stdin != nil {
+		var err error
+		input, err = io.ReadAll(stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := r.Backoff
+	for attempt := 1; ; attempt++ {
+		if stdin != nil {
+			stdin = bytes.NewReader(input)
+		}
+
+		err := run(ctx, stdin)
+		if err == nil || attempt >= maxAttempts {
+			return err
+		}
+
+		retry := policy(RetryAttempt{
+			Command:  command,
+			Args:     args,
+			Attempt:  attempt,
+			Err:      err,
+			ExitCode: ExitCode(err),
+		})
+		if !retry {
+			return err
+		}
+
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return err
+		}
+
+		backoff *= 2
+		if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+			backoff = r.MaxBackoff
+		}
+	}
+}
+
+func (r *Retry) policy(command string, args []string) (int, RetryPolicy) {
+	maxAttempts, policy := r.MaxAttempts, r.Policy
+	for _, rule := range r.Rules {
+		if rule.Match(command, args) {
+			maxAttempts = rule.MaxAttempts
+			if rule.Policy != nil {
+				policy = rule.Policy
+			}
+
+			break
+		}
+	}
+
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
+
+	return maxAttempts, policy
+}
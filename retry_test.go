@@ -0,0 +1,268 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// sequenceStub returns a Stub which returns each of errs in turn, recording
+// the stdin data given to each attempt.
+func sequenceStub(stdins *[]string, errs ...error) *Stub {
+	i := 0
+
+	return &Stub{Fallback: func(
+		_ context.Context,
+		stdin io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		b, _ := io.ReadAll(stdin)
+		*stdins = append(*stdins, string(b))
+
+		err := errs[i]
+		if i < len(errs)-1 {
+			i++
+		}
+
+		return err
+	}}
+}
+
+func TestRetry_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+	sshErr := &FakeExitError{Command: "ssh", Code: 255}
+	exitErr := &FakeExitError{Command: "ssh", Code: 1}
+
+	tests := []struct {
+		name         string
+		retry        Retry
+		args         []string
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "success",
+			retry:        Retry{MaxAttempts: 3},
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name: "retries transport failures",
+			retry: Retry{
+				MaxAttempts: 3,
+				Policy:      RetryOnExitCodes(255),
+			},
+			errs:         []error{sshErr, sshErr, nil},
+			wantAttempts: 3,
+		},
+		{
+			name: "gives up after max attempts",
+			retry: Retry{
+				MaxAttempts: 3,
+				Policy:      RetryOnExitCodes(255),
+			},
+			errs:         []error{sshErr},
+			wantErr:      sshErr,
+			wantAttempts: 3,
+		},
+		{
+			name:         "does not retry other exit codes",
+			retry:        Retry{MaxAttempts: 3},
+			errs:         []error{exitErr, nil},
+			wantErr:      exitErr,
+			wantAttempts: 1,
+		},
+		{
+			name:         "does not retry context errors",
+			retry:        Retry{MaxAttempts: 3, Policy: RetryOnErrors(errBoom)},
+			errs:         []error{context.DeadlineExceeded, nil},
+			wantErr:      context.DeadlineExceeded,
+			wantAttempts: 1,
+		},
+		{
+			name: "custom policy",
+			retry: Retry{
+				MaxAttempts: 3,
+				Policy:      RetryOnErrors(errBoom),
+			},
+			errs:         []error{errBoom, nil},
+			wantAttempts: 2,
+		},
+		{
+			name:         "no retries by default",
+			errs:         []error{sshErr, nil},
+			wantErr:      sshErr,
+			wantAttempts: 1,
+		},
+		{
+			name: "rule disables retries",
+			retry: Retry{
+				MaxAttempts: 3,
+				Policy:      RetryOnExitCodes(255),
+				Rules: []RetryRule{
+					{
+						PolicyRule: PolicyRule{
+							Command: "ssh", Args: []string{"rm"},
+						},
+						MaxAttempts: 1,
+					},
+				},
+			},
+			args:         []string{"rm", "-rf", "/srv/app"},
+			errs:         []error{sshErr, nil},
+			wantErr:      sshErr,
+			wantAttempts: 1,
+		},
+		{
+			name: "rule with policy",
+			retry: Retry{
+				MaxAttempts: 3,
+				Rules: []RetryRule{
+					{
+						PolicyRule: PolicyRule{
+							Command: "ssh", Args: []string{"apt-get"},
+						},
+						MaxAttempts: 5,
+						Policy:      RetryOnExitCodes(100),
+					},
+				},
+			},
+			args: []string{"apt-get", "update"},
+			errs: []error{
+				&FakeExitError{Command: "ssh", Code: 100},
+				&FakeExitError{Command: "ssh", Code: 100},
+				&FakeExitError{Command: "ssh", Code: 100},
+				nil,
+			},
+			wantAttempts: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, withCtx := range []bool{false, true} {
+				var stdins []string
+				r := tt.retry
+				r.Runner = sequenceStub(&stdins, tt.errs...)
+
+				stdin := strings.NewReader("input")
+				var err error
+				if withCtx {
+					err = r.RunContext(
+						context.Background(), stdin, nil, nil,
+						"ssh", tt.args...,
+					)
+				} else {
+					err = r.Run(stdin, nil, nil, "ssh", tt.args...)
+				}
+
+				assert.Equal(t, tt.wantErr, err)
+				assert.Len(t, stdins, tt.wantAttempts)
+				for _, s := range stdins {
+					assert.Equal(t, "input", s)
+				}
+			}
+		})
+	}
+}
+
+func TestRetry_backoff(t *testing.T) {
+	var stdins []string
+	sshErr := &FakeExitError{Command: "ssh", Code: 255}
+
+	r := &Retry{
+		Runner:      sequenceStub(&stdins, sshErr),
+		MaxAttempts: 4,
+		Policy:      RetryOnExitCodes(255),
+		Backoff:     20 * time.Millisecond,
+		MaxBackoff:  30 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := r.Run(nil, nil, nil, "ssh")
+	assert.Equal(t, sshErr, err)
+	assert.Len(t, stdins, 4)
+
+	// 20ms + 30ms + 30ms.
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestRetry_RunContext_canceledDuringBackoff(t *testing.T) {
+	var stdins []string
+	sshErr := &FakeExitError{Command: "ssh", Code: 255}
+
+	r := &Retry{
+		Runner:      sequenceStub(&stdins, sshErr),
+		MaxAttempts: 3,
+		Policy:      RetryOnExitCodes(255),
+		Backoff:     time.Hour,
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 20*time.Millisecond,
+	)
+	defer cancel()
+
+	err := r.RunContext(ctx, nil, nil, nil, "ssh")
+	assert.Equal(t, sshErr, err)
+	assert.Len(t, stdins, 1)
+}
+
+func TestRetry_output(t *testing.T) {
+	f := &Fake{}
+	f.Respond("ssh", nil, FakeResponse{Stdout: "attempt\n", ExitCode: 255})
+
+	r := &Retry{Runner: f, MaxAttempts: 2, Policy: RetryOnExitCodes(255)}
+
+	var stdout bytes.Buffer
+	err := r.Run(nil, &stdout, nil, "ssh", "host")
+	assert.EqualError(t, err, "ssh: exit status 255")
+	assert.Equal(t, "attempt\nattempt\n", stdout.String())
+	assert.Len(t, f.Calls(), 2)
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "exit code 255", err: &FakeExitError{Code: 255}, want: false},
+		{name: "exit code", err: &FakeExitError{Code: 1}, want: false},
+		{
+			name: "chaos transport error",
+			err:  ErrChaosTransport,
+			want: true,
+		},
+		{name: "other error", err: errors.New("boom"), want: false},
+		{name: "canceled", err: context.Canceled, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultRetryPolicy(RetryAttempt{
+				Attempt: 1, Err: tt.err, ExitCode: ExitCode(tt.err),
+			})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRetry_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Retry{Runner: m}
+	r.Env("FOO=bar")
+}
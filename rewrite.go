@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+// Rewrite is a Runner that wraps another Runner, and rewrites commands and
+// their arguments before passing them to the underlying Runner. This allows
+// targeting hosts which provide different tooling, for example running podman
+// in place of docker.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Rewrite struct {
+	// Runner is the underlying Runner to run rewritten commands with. If not
+	// set, running commands will cause a panic.
+	Runner Runner
+
+	// Commands maps command names to the command to run in their place, with
+	// the same arguments. Commands are matched exactly as given.
+	Commands map[string]string
+
+	// Func, when set, is called with each command and its arguments after
+	// Commands has been applied, and returns the command and arguments to
+	// run. The args slice may be modified, as it is a copy.
+	Func func(command string, args []string) (string, []string)
+}
+
+var _ Runner = &Rewrite{}
+
+// Run rewrites the command and calls Run on the underlying Runner.
+//
+// Will panic if Runner field is nil.
+func (r *Rewrite) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	command, args = r.rewrite(command, args)
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext rewrites the command and calls RunContext on the underlying
+// Runner.
+//
+// Will panic if Runner field is nil.
+func (r *Rewrite) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	command, args = r.rewrite(command, args)
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+func (r *Rewrite) rewrite(
+	command string,
+	args []string,
+) (string, []string) {
+	if c, ok := r.Commands[command]; ok {
+		command = c
+	}
+
+	if r.Func != nil {
+		command, args = r.Func(command, copyStrings(args))
+	}
+
+	return command, args
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Rewrite) Env(env ...string) {
+	r.Runner.Env(env...)
+}
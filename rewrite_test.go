@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var rewriteTestCases = []struct {
+	name        string
+	commands    map[string]string
+	fn          func(command string, args []string) (string, []string)
+	command     string
+	args        []string
+	err         error
+	wantCommand string
+	wantArgs    []string
+	wantErr     string
+}{
+	{
+		name:        "no rewrites",
+		command:     "docker",
+		args:        []string{"ps", "-a"},
+		wantCommand: "docker",
+		wantArgs:    []string{"ps", "-a"},
+	},
+	{
+		name:        "Commands",
+		commands:    map[string]string{"docker": "podman"},
+		command:     "docker",
+		args:        []string{"ps", "-a"},
+		wantCommand: "podman",
+		wantArgs:    []string{"ps", "-a"},
+	},
+	{
+		name:        "Commands without match",
+		commands:    map[string]string{"docker": "podman"},
+		command:     "/usr/bin/docker",
+		args:        []string{"ps"},
+		wantCommand: "/usr/bin/docker",
+		wantArgs:    []string{"ps"},
+	},
+	{
+		name:     "Func",
+		commands: map[string]string{"iptables": "iptables-nft"},
+		fn: func(command string, args []string) (string, []string) {
+			if command == "iptables-nft" {
+				args[0] = "--list"
+				args = append([]string{"-w"}, args...)
+			}
+
+			return command, args
+		},
+		command:     "iptables",
+		args:        []string{"-L", "INPUT"},
+		wantCommand: "iptables-nft",
+		wantArgs:    []string{"-w", "--list", "INPUT"},
+	},
+	{
+		name:        "error",
+		commands:    map[string]string{"zfs": "zfs-fuse"},
+		command:     "zfs",
+		args:        []string{"list"},
+		err:         errors.New("zfs-fuse: command not found"),
+		wantCommand: "zfs-fuse",
+		wantArgs:    []string{"list"},
+		wantErr:     "zfs-fuse: command not found",
+	},
+}
+
+func TestRewrite_Run(t *testing.T) {
+	for _, tt := range rewriteTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().Run(
+				nil, nil, nil, tt.wantCommand, tt.wantArgs,
+			).Return(tt.err)
+
+			args := append([]string(nil), tt.args...)
+			rw := &Rewrite{Runner: r, Commands: tt.commands, Func: tt.fn}
+			err := rw.Run(nil, nil, nil, tt.command, args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestRewrite_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range rewriteTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx), nil, nil, nil, tt.wantCommand, tt.wantArgs,
+			).Return(tt.err)
+
+			args := append([]string(nil), tt.args...)
+			rw := &Rewrite{Runner: r, Commands: tt.commands, Func: tt.fn}
+			err := rw.RunContext(ctx, nil, nil, nil, tt.command, args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestRewrite_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	rw := &Rewrite{Runner: r}
+	rw.Env("FOO=BAR", "PORT=8080")
+}
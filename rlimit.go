@@ -0,0 +1,41 @@
+package runner
+
+import "fmt"
+
+var (
+	ErrRLimit            = fmt.Errorf("%w: rlimit", Err)
+	ErrRLimitUnsupported = fmt.Errorf(
+		"%w: not supported on this platform", ErrRLimit,
+	)
+	ErrRLimitUnknownResource = fmt.Errorf("%w: unknown resource", ErrRLimit)
+)
+
+// RLimInfinity is the value representing no limit for a RLimit's Soft or Hard
+// value.
+const RLimInfinity = ^uint64(0)
+
+// RLimitResource is the name of a resource which can be limited via RLimit.
+type RLimitResource string
+
+const (
+	RLimitAS     RLimitResource = "as"
+	RLimitCore   RLimitResource = "core"
+	RLimitCPU    RLimitResource = "cpu"
+	RLimitData   RLimitResource = "data"
+	RLimitFsize  RLimitResource = "fsize"
+	RLimitNofile RLimitResource = "nofile"
+	RLimitNproc  RLimitResource = "nproc"
+	RLimitStack  RLimitResource = "stack"
+)
+
+// RLimit describes a resource limit as set by setrlimit(2).
+type RLimit struct {
+	// Resource is the resource to limit.
+	Resource RLimitResource
+
+	// Soft is the soft limit, which is the value enforced for the resource.
+	Soft uint64
+
+	// Hard is the hard limit, which acts as a ceiling for the soft limit.
+	Hard uint64
+}
@@ -0,0 +1,58 @@
+//go:build linux
+
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// prlimitPath is the prlimit(1) command used to apply resource limits.
+const prlimitPath = "prlimit"
+
+var rlimitResources = map[RLimitResource]bool{
+	RLimitAS:     true,
+	RLimitCore:   true,
+	RLimitCPU:    true,
+	RLimitData:   true,
+	RLimitFsize:  true,
+	RLimitNofile: true,
+	RLimitNproc:  true,
+	RLimitStack:  true,
+}
+
+// applyRLimits validates limits, and rewrites cmd to be executed via
+// prlimit(1), which sets the limits on itself before executing the original
+// command, so they are in effect from its very first instruction.
+func applyRLimits(cmd *exec.Cmd, limits []RLimit) error {
+	args := make([]string, 0, len(limits)+len(cmd.Args)+2)
+	args = append(args, prlimitPath)
+	for _, l := range limits {
+		if !rlimitResources[l.Resource] {
+			return fmt.Errorf("%w: %q", ErrRLimitUnknownResource, l.Resource)
+		}
+		args = append(args, fmt.Sprintf(
+			"--%s=%s:%s", l.Resource, rlimitValue(l.Soft), rlimitValue(l.Hard),
+		))
+	}
+
+	path, err := exec.LookPath(prlimitPath)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRLimit, err)
+	}
+
+	args = append(args, "--", cmd.Path)
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = path
+
+	return nil
+}
+
+func rlimitValue(v uint64) string {
+	if v == RLimInfinity {
+		return "unlimited"
+	}
+
+	return strconv.FormatUint(v, 10)
+}
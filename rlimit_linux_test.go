@@ -0,0 +1,104 @@
+//go:build linux
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_rlimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		rlimits    []RLimit
+		script     string
+		wantStdout string
+		wantErr    error
+	}{
+		{
+			name: "nofile",
+			rlimits: []RLimit{
+				{Resource: RLimitNofile, Soft: 64, Hard: 128},
+			},
+			script:     "ulimit -Sn; ulimit -Hn",
+			wantStdout: "64\n128\n",
+		},
+		{
+			name: "core and fsize",
+			rlimits: []RLimit{
+				{Resource: RLimitCore, Soft: 0, Hard: 0},
+				{Resource: RLimitFsize, Soft: 1024 * 512, Hard: 1024 * 512},
+			},
+			// The shell reports core and fsize limits in 512 byte blocks.
+			script:     "ulimit -c; ulimit -f",
+			wantStdout: "0\n1024\n",
+		},
+		{
+			name: "infinity",
+			rlimits: []RLimit{
+				{Resource: RLimitCPU, Soft: RLimInfinity, Hard: RLimInfinity},
+			},
+			script:     "ulimit -t",
+			wantStdout: "unlimited\n",
+		},
+		{
+			name: "unknown resource",
+			rlimits: []RLimit{
+				{Resource: "nope", Soft: 1, Hard: 1},
+			},
+			script:  "true",
+			wantErr: ErrRLimitUnknownResource,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{RLimits: tt.rlimits}
+
+			err := r.Run(nil, &stdout, nil, "sh", "-c", tt.script)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+		})
+	}
+}
+
+func TestLocal_Run_rlimitsWithUmask(t *testing.T) {
+	var stdout bytes.Buffer
+	umask := os.FileMode(0o027)
+	r := &Local{
+		RLimits: []RLimit{{Resource: RLimitNofile, Soft: 64, Hard: 128}},
+		Umask:   &umask,
+	}
+
+	err := r.Run(nil, &stdout, nil, "sh", "-c", "umask; ulimit -Sn")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0027\n64\n", stdout.String())
+}
+
+func TestLocal_Run_rlimitsErrors(t *testing.T) {
+	r := &Local{
+		RLimits: []RLimit{{Resource: RLimitNofile, Soft: 64, Hard: 128}},
+	}
+
+	err := r.Run(nil, nil, nil, "sh", "-c", "exit 3")
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "sh", exitErr.Command)
+	assert.Equal(t, []string{"-c", "exit 3"}, exitErr.Args)
+	assert.Equal(t, 3, exitErr.ExitCode())
+
+	err = r.Run(nil, nil, nil, "/nonexistent/runner-test-command")
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+}
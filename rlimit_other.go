@@ -0,0 +1,9 @@
+//go:build !linux
+
+package runner
+
+import "os/exec"
+
+func applyRLimits(*exec.Cmd, []RLimit) error {
+	return ErrRLimitUnsupported
+}
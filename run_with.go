@@ -0,0 +1,155 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// RunOption configures a single command run via RunWith.
+type RunOption func(o *runOptions)
+
+type runOptions struct {
+	env     []string
+	dir     string
+	timeout time.Duration
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// WithEnv adds environment variables for a single command, in addition to any
+// set on the runner via Env. Each entry is of the form "key=value", and takes
+// precedence over entries with the same key set on the runner. Multiple
+// WithEnv options are combined.
+func WithEnv(env ...string) RunOption {
+	return func(o *runOptions) {
+		o.env = append(o.env, env...)
+	}
+}
+
+// WithDir sets the working directory of a single command, taking precedence
+// over any directory configured on the runner.
+func WithDir(dir string) RunOption {
+	return func(o *runOptions) {
+		o.dir = dir
+	}
+}
+
+// WithTimeout limits how long a single command may run for, after which its
+// context is cancelled.
+func WithTimeout(timeout time.Duration) RunOption {
+	return func(o *runOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithStdin sets the stdin of a single command.
+func WithStdin(stdin io.Reader) RunOption {
+	return func(o *runOptions) {
+		o.stdin = stdin
+	}
+}
+
+// WithStdout sets the stdout of a single command.
+func WithStdout(stdout io.Writer) RunOption {
+	return func(o *runOptions) {
+		o.stdout = stdout
+	}
+}
+
+// WithStderr sets the stderr of a single command.
+func WithStderr(stderr io.Writer) RunOption {
+	return func(o *runOptions) {
+		o.stderr = stderr
+	}
+}
+
+// RunWith runs the given command via RunContext on r, configured by opts.
+//
+// Unlike calling Env on a runner, options only affect the one command, making
+// RunWith safe to use with runners shared between goroutines.
+//
+// The env and dir options are carried by the context passed to RunContext.
+// They are applied by Local, and by Sudo and SSHCLI to the command they wrap.
+// Other wrapper runners pass them through to their underlying runner.
+func RunWith(
+	ctx context.Context,
+	r Runner,
+	opts []RunOption,
+	command string,
+	args ...string,
+) error {
+	o := &runOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if len(o.env) > 0 {
+		ctx = withCallEnv(ctx, o.env)
+	}
+	if o.dir != "" {
+		ctx = withCallDir(ctx, o.dir)
+	}
+
+	return r.RunContext(ctx, o.stdin, o.stdout, o.stderr, command, args...)
+}
+
+type (
+	callEnvKey struct{}
+	callDirKey struct{}
+)
+
+// withCallEnv returns a copy of ctx carrying env, which is added to the
+// environment of the command run with ctx. Any env already carried by ctx is
+// retained, with env taking precedence.
+func withCallEnv(ctx context.Context, env []string) context.Context {
+	env = append(append([]string(nil), callEnv(ctx)...), env...)
+
+	return context.WithValue(ctx, callEnvKey{}, env)
+}
+
+func callEnv(ctx context.Context) []string {
+	env, _ := ctx.Value(callEnvKey{}).([]string)
+
+	return env
+}
+
+// withCallDir returns a copy of ctx carrying dir, which is used as the working
+// directory of the command run with ctx.
+func withCallDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, callDirKey{}, dir)
+}
+
+func callDir(ctx context.Context) string {
+	dir, _ := ctx.Value(callDirKey{}).(string)
+
+	return dir
+}
+
+// withoutCallOptions returns a copy of ctx which no longer carries any env or
+// dir. It is used by wrapper runners which apply them to the command they
+// wrap, so they are not also applied to the wrapping command itself.
+func withoutCallOptions(ctx context.Context) context.Context {
+	if callEnv(ctx) == nil && callDir(ctx) == "" {
+		return ctx
+	}
+
+	ctx = context.WithValue(ctx, callEnvKey{}, []string(nil))
+
+	return context.WithValue(ctx, callDirKey{}, "")
+}
+
+// mergeEnv returns base with extra appended, without modifying base.
+func mergeEnv(base, extra []string) []string {
+	if len(extra) == 0 {
+		return base
+	}
+
+	return append(append([]string(nil), base...), extra...)
+}
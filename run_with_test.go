@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRunWith(t *testing.T) {
+	stdin := bytes.NewBufferString("hello")
+	var stdout, stderr bytes.Buffer
+
+	tests := []struct {
+		name       string
+		opts       []RunOption
+		wantStdin  io.Reader
+		wantStdout io.Writer
+		wantStderr io.Writer
+		wantEnv    []string
+		wantDir    string
+		deadline   bool
+	}{
+		{
+			name: "no options",
+		},
+		{
+			name: "stdio",
+			opts: []RunOption{
+				WithStdin(stdin),
+				WithStdout(&stdout),
+				WithStderr(&stderr),
+			},
+			wantStdin:  stdin,
+			wantStdout: &stdout,
+			wantStderr: &stderr,
+		},
+		{
+			name: "env",
+			opts: []RunOption{
+				WithEnv("FOO=bar", "BAZ=qux"),
+				WithEnv("FOO=foo"),
+			},
+			wantEnv: []string{"FOO=bar", "BAZ=qux", "FOO=foo"},
+		},
+		{
+			name:    "dir",
+			opts:    []RunOption{WithDir("/tmp")},
+			wantDir: "/tmp",
+		},
+		{
+			name:     "timeout",
+			opts:     []RunOption{WithTimeout(time.Minute)},
+			deadline: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomock.Any(),
+				tt.wantStdin,
+				tt.wantStdout,
+				tt.wantStderr,
+				"echo",
+				"hi",
+			).DoAndReturn(func(
+				ctx context.Context,
+				_ io.Reader,
+				_, _ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				assert.Equal(t, tt.wantEnv, callEnv(ctx))
+				assert.Equal(t, tt.wantDir, callDir(ctx))
+
+				_, ok := ctx.Deadline()
+				assert.Equal(t, tt.deadline, ok)
+
+				return nil
+			})
+
+			err := RunWith(context.Background(), r, tt.opts, "echo", "hi")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRunWith_Local(t *testing.T) {
+	t.Setenv("RUNNER_TEST_INHERITED", "inherited")
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		env     []string
+		opts    []RunOption
+		want    string
+		wantDir string
+	}{
+		{
+			name: "runner env only",
+			env:  []string{"FOO=runner"},
+			want: "runner\n",
+		},
+		{
+			name: "call env overrides runner env",
+			env:  []string{"FOO=runner"},
+			opts: []RunOption{WithEnv("FOO=call")},
+			want: "call\n",
+		},
+		{
+			name: "call env inherits OS env when runner env unset",
+			opts: []RunOption{WithEnv("FOO=call")},
+			want: "call inherited\n",
+		},
+		{
+			name:    "dir",
+			opts:    []RunOption{WithDir(dir)},
+			want:    "inherited\n",
+			wantDir: dir,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, pwd bytes.Buffer
+			r := &Local{env: tt.env}
+
+			opts := append([]RunOption{WithStdout(&stdout)}, tt.opts...)
+			err := RunWith(
+				context.Background(), r, opts,
+				"sh", "-c", `echo $FOO $RUNNER_TEST_INHERITED`,
+			)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+
+			if tt.wantDir == "" {
+				return
+			}
+
+			opts = append([]RunOption{WithStdout(&pwd)}, tt.opts...)
+			err = RunWith(context.Background(), r, opts, "pwd")
+			require.NoError(t, err)
+
+			want, err := filepath.EvalSymlinks(tt.wantDir)
+			require.NoError(t, err)
+			got, err := filepath.EvalSymlinks(strings.TrimSpace(pwd.String()))
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.Empty(t, r.Dir)
+		})
+	}
+}
+
+func TestRunWith_timeout(t *testing.T) {
+	r := &Local{}
+
+	start := time.Now()
+	err := RunWith(
+		context.Background(), r,
+		[]RunOption{WithTimeout(100 * time.Millisecond)},
+		"sleep", "5",
+	)
+
+	assert.EqualError(t, err, "sleep: signal: killed")
+	assert.Less(t, time.Since(start), 4*time.Second)
+}
+
+func TestRunWith_wrappers(t *testing.T) {
+	tests := []struct {
+		name     string
+		wrap     func(r Runner) Runner
+		wantCmd  string
+		wantArgs []string
+	}{
+		{
+			name: "Sudo",
+			wrap: func(r Runner) Runner {
+				s := &Sudo{Runner: r, Dir: "/srv"}
+				s.Env("FOO=runner")
+
+				return s
+			},
+			wantCmd: "sudo",
+			wantArgs: []string{
+				"-n", "-D", "/var/www", "FOO=call", "--", "whoami",
+			},
+		},
+		{
+			name: "SSHCLI",
+			wrap: func(r Runner) Runner {
+				s := &SSHCLI{Runner: r, Destination: "host", Dir: "/srv"}
+				s.Env("FOO=runner")
+
+				return s
+			},
+			wantCmd: "ssh",
+			wantArgs: []string{
+				"host", "--", "cd", "'/var/www'", "&&",
+				"env", "FOO=call", "whoami",
+			},
+		},
+		{
+			name: "Jexec",
+			wrap: func(r Runner) Runner {
+				j := &Jexec{Runner: r, Jail: "www"}
+				j.Env("FOO=runner")
+
+				return j
+			},
+			wantCmd: "jexec",
+			wantArgs: []string{
+				"www", "sh", "-c", jexecChdirScript, "sh", "/var/www",
+				"env", "FOO=call", "whoami",
+			},
+		},
+		{
+			name: "Bwrap",
+			wrap: func(r Runner) Runner {
+				b := &Bwrap{Runner: r, Chdir: "/srv"}
+				b.Env("FOO=runner")
+
+				return b
+			},
+			wantCmd: "bwrap",
+			wantArgs: []string{
+				"--chdir", "/var/www", "--setenv", "FOO", "call",
+				"--", "whoami",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+
+			args := make([]interface{}, 0, len(tt.wantArgs))
+			for _, a := range tt.wantArgs {
+				args = append(args, a)
+			}
+			r.EXPECT().RunContext(
+				gomock.Any(), nil, nil, nil, tt.wantCmd, args...,
+			).DoAndReturn(func(
+				ctx context.Context,
+				_ io.Reader,
+				_, _ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				assert.Nil(t, callEnv(ctx))
+				assert.Empty(t, callDir(ctx))
+
+				return nil
+			})
+
+			err := RunWith(
+				context.Background(), tt.wrap(r),
+				[]RunOption{WithEnv("FOO=call"), WithDir("/var/www")},
+				"whoami",
+			)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -10,8 +10,16 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 )
 
 //go:generate go run go.uber.org/mock/mockgen@v0.3.0 -source=$GOFILE -destination=mock/${GOFILE}
@@ -45,32 +53,191 @@ type Runner interface {
 	// Env specifies the environment variables which will be available to all
 	// commands invoked by the runner. Each entry is of the form "key=value".
 	// Entries with duplicate keys will cause all but the last to be ignored.
+	// The entries are copied, so modifying the given slice afterwards has no
+	// effect.
 	//
-	// Multiple calls to Env will overwrite any previous calls to Env.
+	// Multiple calls to Env will overwrite any previous calls to Env. The
+	// runners in this package allow Env to be called concurrently with Run
+	// and RunContext, without affecting commands which are already running.
 	//
 	// If no env is set, no environment variables will be set for executed
 	// commands.
 	//
 	// To set the environment to match that of the Go runtime, call Env with
-	// os.Environ().
+	// os.Environ(), or for Local, set InheritOSEnv to layer env on top of it.
 	Env(env ...string)
 }
 
 // Local is a Runner implementation that executes commands locally on the
 // host machine.
+//
+// Local is safe for concurrent use by multiple goroutines. Env, Setenv,
+// Unsetenv and AppendEnv may be called while other commands are running, and
+// only affect commands started afterwards. Exported fields however must not
+// be modified while commands may be running.
 type Local struct {
-	env []string
+	// Dir specifies the working directory of commands. When empty, commands
+	// run in the current process's working directory.
+	Dir string
+
+	// ExtraFiles specifies additional open files to be inherited by commands.
+	// Entry i becomes file descriptor 3+i in the child process. It is not
+	// supported on Windows.
+	ExtraFiles []*os.File
+
+	// InheritOSEnv causes commands to be started with the environment of the
+	// current process, as returned by os.Environ, with any variables set via
+	// Env layered on top. Without it, calling Env replaces the environment of
+	// commands entirely, meaning variables like PATH and HOME are only set if
+	// explicitly included.
+	InheritOSEnv bool
+
+	// SearchPath, when set, is the list of directories commands given without
+	// a path separator are looked up in, instead of the PATH of the current
+	// process. Relative directories are ignored. This prevents changes to
+	// PATH from changing which executable is run.
+	SearchPath []string
+
+	// RequireAbsPath causes commands which are not given as an absolute path
+	// to be rejected with ErrCommandNotAbsolute. It takes precedence over
+	// SearchPath.
+	RequireAbsPath bool
+
+	// EnvProvider, when set, is resolved each time a command is run, and the
+	// variables it returns are layered on top of those set via Env. If it
+	// returns an error, the command is not run. The variables are only set on
+	// the process Local starts, so when Local is wrapped by a runner like
+	// SSHCLI or Sudo, they are set on ssh or sudo, and do not reach the
+	// wrapped command. Use ProvidedEnv to provide env vars to such commands.
+	EnvProvider EnvProvider
+
+	// Cgroup, when set, causes each command to be executed within a new cgroup
+	// v2, which enforces the resource limits it describes. The cgroup is
+	// removed once the command exits.
+	Cgroup *Cgroup
+
+	// PTY, when set, causes each command to be run within a new
+	// pseudo-terminal, for commands which require a terminal. Output written
+	// to stderr is merged into stdout.
+	PTY *PTY
+
+	// Watchdog, when set, causes the resource usage of each command to be
+	// sampled while it runs, killing it if any of the thresholds it describes
+	// are exceeded.
+	Watchdog *Watchdog
+
+	// RLimits is a list of resource limits to apply to each command. Commands
+	// are executed via prlimit(1), which applies the limits before executing
+	// the command itself, and hence must be installed. Resource limits are
+	// only supported on Linux.
+	RLimits []RLimit
+
+	// Umask, when set, is the file mode creation mask commands are started
+	// with. Commands are executed via /bin/sh, which sets the umask before
+	// executing the command itself, so the umask of the current process is
+	// never changed. Umask is only supported on Unix-like systems.
+	Umask *os.FileMode
+
+	// Uid, when set, is the user ID commands are run as. Changing the user ID
+	// typically requires the current process to be running as root.
+	Uid *uint32
+
+	// Gid, when set, is the group ID commands are run as.
+	Gid *uint32
+
+	// Groups is the list of supplementary group IDs commands are run with
+	// when Uid or Gid is set. When empty, commands will have no supplementary
+	// groups.
+	//
+	// Uid, Gid and Groups are only supported on Unix-like systems.
+	Groups []uint32
+
+	// SysProcAttr, when set, holds optional, operating system-specific
+	// attributes for commands, enabling advanced use cases like setting a
+	// parent death signal, a chroot, or starting a new session. It is copied
+	// for each command, and any other fields of Local take precedence over
+	// conflicting attributes.
+	SysProcAttr *syscall.SysProcAttr
+
+	// Setsid starts commands in a new session, detaching them from the
+	// controlling terminal of the current process, and making them the leader
+	// of a new process group. This prevents commands from receiving signals
+	// sent to the terminal's foreground process group, like SIGINT on Ctrl+C.
+	// It is only supported on Unix-like systems.
+	Setsid bool
+
+	// Setpgid starts commands in a new process group, of which they are the
+	// leader. It is implied by Setsid, and is only supported on Unix-like
+	// systems.
+	Setpgid bool
+
+	// KillProcessGroup causes commands run with RunContext to be started in a
+	// new process group, and for the whole process group to be killed when
+	// the context becomes done. This ensures any child processes spawned by
+	// the command are also killed, rather than just the command itself. As
+	// commands are no longer in the foreground process group, they will not
+	// be able to read from a terminal. It is only supported on Unix-like
+	// systems.
+	KillProcessGroup bool
+
+	// GracePeriod, when non-zero, causes commands run with RunContext to be
+	// terminated gracefully when the context becomes done. Commands are first
+	// sent TerminationSignal, and are killed if they are still running once
+	// GracePeriod has elapsed.
+	GracePeriod time.Duration
+
+	// TerminationSignal is the signal sent to commands run with RunContext
+	// when the context becomes done. When nil, SIGTERM is used if GracePeriod
+	// is non-zero, otherwise commands are killed immediately.
+	//
+	// When set and GracePeriod is zero, commands are sent the signal and then
+	// waited on until they exit on their own, without ever being killed.
+	//
+	// Signals other than os.Kill are not supported on Windows.
+	TerminationSignal os.Signal
+
+	// CmdFunc, when set, is called with the fully configured *exec.Cmd of each
+	// command just before it is started. This allows customizing fields which
+	// Local does not otherwise expose, like WaitDelay, Cancel, and ExtraFiles.
+	// When Umask or RLimits are set, the command has already been rewritten
+	// to be executed via /bin/sh or prlimit(1).
+	CmdFunc func(cmd *exec.Cmd)
+
+	// DefaultTimeout, when non-zero, is the maximum time commands may run for
+	// when no other deadline applies. Commands run with Run are run as if
+	// with RunContext and a context which times out after DefaultTimeout.
+	// Commands run with RunContext get the same timeout only if the given
+	// context has no deadline.
+	DefaultTimeout time.Duration
+
+	env   []string
+	envMu sync.RWMutex
 }
 
 var _ Runner = &Local{}
 
 // New returns a Local instance which meets the Runner interface, and executes
-// commands locally on the host machine.
-func New() Runner {
-	return &Local{}
+// commands locally on the host machine, configured by opts. This allows a
+// fully configured Local to be created in a single expression:
+//
+//	r := runner.New(
+//		runner.WithInheritedEnv(),
+//		runner.WithRunnerEnv("LC_ALL=C"),
+//		runner.WithRunnerDir("/srv/app"),
+//		runner.WithDefaultTimeout(time.Minute),
+//	)
+func New(opts ...Option) Runner {
+	r := &Local{}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
 }
 
-// Run executes the given command locally on the host machine.
+// Run executes the given command locally on the host machine. If
+// DefaultTimeout is set, the process is killed if it has not completed once
+// the timeout has elapsed.
 func (r *Local) Run(
 	stdin io.Reader,
 	stdout io.Writer,
@@ -78,14 +245,27 @@ func (r *Local) Run(
 	command string,
 	args ...string,
 ) error {
-	cmd := exec.Command(command, args...)
+	if r.DefaultTimeout > 0 {
+		return r.RunContext(
+			context.Background(), stdin, stdout, stderr, command, args...,
+		)
+	}
 
-	return r.run(cmd, stdin, stdout, stderr)
+	path, err := r.lookPath(command)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Args[0] = command
+
+	return r.run(context.Background(), cmd, stdin, stdout, stderr)
 }
 
 // RunContext executes the given command locally on the host machine, using the
 // provided context to kill the process if the context becomes done before the
-// command completes on its own.
+// command completes on its own. If the context has no deadline and
+// DefaultTimeout is set, the timeout also applies.
 func (r *Local) RunContext(
 	ctx context.Context,
 	stdin io.Reader,
@@ -94,36 +274,392 @@ func (r *Local) RunContext(
 	command string,
 	args ...string,
 ) error {
-	cmd := exec.CommandContext(ctx, command, args...)
+	if _, ok := ctx.Deadline(); !ok && r.DefaultTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.DefaultTimeout)
+		defer cancel()
+	}
 
-	return r.run(cmd, stdin, stdout, stderr)
+	path, err := r.lookPath(command)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Args[0] = command
+
+	return r.run(ctx, cmd, stdin, stdout, stderr)
 }
 
 func (r *Local) run(
+	ctx context.Context,
 	cmd *exec.Cmd,
 	stdin io.Reader,
 	stdout io.Writer,
 	stderr io.Writer,
-) error {
+) (err error) {
+	stderrTail := r.setIO(cmd, stdin, stdout, stderr)
+
+	err = r.configure(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	t := r.terminator(cmd)
+	if t != nil {
+		cmd.Cancel = t.cancel
+		defer t.stop()
+	}
+
+	var session *ptySession
+	if r.PTY != nil {
+		session, err = r.PTY.apply(cmd, stdin, stdout)
+		if err != nil {
+			return err
+		}
+		stderrTail = nil
+		defer session.close()
+	}
+
+	// The command and arguments as given, before cmd is rewritten to be
+	// executed via another command, which errors must not report.
+	cmdArgs := cmd.Args
+	if r.Umask != nil || len(r.RLimits) > 0 {
+		err = checkCommandExists(cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Umask != nil {
+		err = applyUmask(cmd, *r.Umask)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(r.RLimits) > 0 {
+		err = applyRLimits(cmd, r.RLimits)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Cgroup != nil {
+		cleanup, cgErr := r.Cgroup.apply(cmd)
+		if cgErr != nil {
+			return cgErr
+		}
+		defer func() {
+			if cleanupErr := cleanup(); err == nil {
+				err = cleanupErr
+			}
+		}()
+	}
+
+	if r.Watchdog != nil {
+		err = checkWatchdogSupported()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.CmdFunc != nil {
+		r.CmdFunc(cmd)
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return wrapStartError(cmd, err)
+	}
+
+	if session != nil {
+		session.started()
+	}
+
+	var stopWatchdog func() *WatchdogError
+	if r.Watchdog != nil {
+		stopWatchdog = r.Watchdog.watch(cmd.Process, r.KillProcessGroup)
+	}
+
+	if fn := startedFunc(ctx); fn != nil {
+		fn(cmd.Process.Pid)
+	}
+
+	err = cmd.Wait()
+	if t != nil {
+		// Stop the grace period timer before anything else, so the process,
+		// which has now been reaped, is never killed after its PID or process
+		// group has been reused.
+		t.stop()
+	}
+	if session != nil {
+		session.wait()
+	}
+
+	if fn := exitedFunc(ctx); fn != nil && cmd.ProcessState != nil {
+		fn(cmd.ProcessState)
+	}
+
+	err = wrapExitError(cmdArgs, err, stderrTail)
+	if stopWatchdog != nil {
+		if wdErr := stopWatchdog(); wdErr != nil {
+			wdErr.Err = err
+
+			return wdErr
+		}
+	}
+
+	return err
+}
+
+// setIO sets the stdin, stdout, and stderr of cmd, returning a buffer which
+// retains the tail of stderr.
+//
+// When stderr is nil, its tail is still retained so it can be reported by the
+// resulting error. When stderr is an *os.File, or the same writer as stdout,
+// it is passed directly to os/exec, and no tail is retained. This preserves
+// the ability of os/exec to give the process the file directly, or to use a
+// single pipe for both stdout and stderr, retaining the ordering of output.
+func (r *Local) setIO(
+	cmd *exec.Cmd,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) *tailBuffer {
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
 	if stdout == nil {
 		stdout = io.Discard
 	}
+	cmd.Stdout = stdout
+
 	if stderr == nil {
-		stderr = io.Discard
+		tail := newTailBuffer(StderrTailSize)
+		tail.captured = true
+		cmd.Stderr = tail
+
+		return tail
 	}
+	if _, ok := stderr.(*os.File); ok || sameWriter(stdout, stderr) {
+		cmd.Stderr = stderr
 
-	cmd.Stdout = stdout
-	cmd.Stderr = stderr
-	cmd.Env = r.env
-	if stdin != nil {
-		cmd.Stdin = stdin
+		return nil
+	}
+
+	tail := newTailBuffer(StderrTailSize)
+	cmd.Stderr = io.MultiWriter(stderr, tail)
+
+	return tail
+}
+
+// configure sets up cmd based on the fields of r, and any per-call options
+// carried by ctx.
+func (r *Local) configure(ctx context.Context, cmd *exec.Cmd) error {
+	cmd.Env = r.environ()
+	if r.InheritOSEnv {
+		cmd.Env = mergeEnv(os.Environ(), cmd.Env)
+	}
+
+	var extra []string
+	if r.EnvProvider != nil {
+		env, err := r.EnvProvider.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrEnvProvider, err)
+		}
+		extra = env
+	}
+	extra = mergeEnv(extra, callEnv(ctx))
+
+	if len(extra) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = mergeEnv(cmd.Env, extra)
+	}
+
+	cmd.Dir = r.Dir
+	if dir := callDir(ctx); dir != "" {
+		cmd.Dir = dir
+	}
+	cmd.ExtraFiles = r.ExtraFiles
+
+	if r.SysProcAttr != nil {
+		attr := *r.SysProcAttr
+		cmd.SysProcAttr = &attr
+	}
+
+	if r.Setsid || r.Setpgid {
+		err := setProcessGroup(cmd, r.Setsid, r.Setpgid)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Cancel is only set when cmd was created with a context.
+	if r.KillProcessGroup && cmd.Cancel != nil {
+		err := setKillProcessGroup(cmd)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Uid != nil || r.Gid != nil {
+		err := setCredential(cmd, r.Uid, r.Gid, r.Groups)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// terminator returns a terminator for cmd if graceful termination is
+// configured and cmd was created with a context, otherwise nil.
+func (r *Local) terminator(cmd *exec.Cmd) *terminator {
+	if (r.GracePeriod <= 0 && r.TerminationSignal == nil) || cmd.Cancel == nil {
+		return nil
+	}
+
+	t := &terminator{
+		cmd:         cmd,
+		signal:      r.TerminationSignal,
+		gracePeriod: r.GracePeriod,
+		group:       r.KillProcessGroup,
+	}
+	if t.signal == nil {
+		t.signal = syscall.SIGTERM
 	}
 
-	return cmd.Run()
+	return t
+}
+
+// wrapStartError wraps err with ErrCommandNotFound if it indicates that the
+// executable of cmd could not be found.
+//
+// Commands given as a path are not looked up in PATH, so failing to start them
+// with ENOENT is only treated as not found if the path itself does not exist.
+// ENOENT is also returned when the working directory or a script's
+// interpreter is missing.
+func wrapStartError(cmd *exec.Cmd, err error) error {
+	notFound := errors.Is(err, exec.ErrNotFound)
+
+	var pathErr *fs.PathError
+	if !notFound && errors.As(err, &pathErr) &&
+		errors.Is(err, fs.ErrNotExist) {
+		_, statErr := os.Stat(cmd.Path)
+		notFound = errors.Is(statErr, fs.ErrNotExist)
+	}
+
+	if !notFound {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", ErrCommandNotFound, err)
+}
+
+// checkCommandExists returns an error matching ErrCommandNotFound if the
+// executable of cmd does not exist. It is used before cmd is rewritten to be
+// executed via another command, as the error returned by Start would
+// otherwise refer to that command instead.
+func checkCommandExists(cmd *exec.Cmd) error {
+	if cmd.Err != nil {
+		return wrapStartError(cmd, cmd.Err)
+	}
+
+	path := cmd.Path
+	if !filepath.IsAbs(path) && cmd.Dir != "" {
+		path = filepath.Join(cmd.Dir, path)
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrCommandNotFound, err)
+	}
+
+	return nil
+}
+
+// wrapExitError wraps err in an *ExitError if it is an *exec.ExitError. The
+// command and arguments reported are taken from args, which is of the same
+// form as exec.Cmd.Args.
+func wrapExitError(args []string, err error, stderrTail *tailBuffer) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	e := &ExitError{
+		Command: args[0],
+		Args:    append([]string(nil), args[1:]...),
+		Err:     exitErr,
+	}
+	if stderrTail != nil {
+		e.Stderr = stderrTail.Bytes()
+		e.stderrCaptured = stderrTail.captured
+	}
+
+	return e
+}
+
+// sameWriter reports if a and b are the same writer, without panicking if
+// their dynamic types are not comparable.
+func sameWriter(a, b io.Writer) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+
+	return a == b
 }
 
 // Env sets the environment which will apply to all commands invoked by the
 // runner. Each entry is of the form "key=value".
 func (r *Local) Env(env ...string) {
-	r.env = env
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+// Setenv sets the value of the environment variable named by key, in addition
+// to the environment set by Env.
+func (r *Local) Setenv(key, value string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	e := NewEnvSet(r.env...)
+	e.Setenv(key, value)
+	r.env = e.Environ()
+}
+
+// Unsetenv removes the environment variable named by key from the environment
+// set by Env.
+func (r *Local) Unsetenv(key string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	e := NewEnvSet(r.env...)
+	e.Unsetenv(key)
+	r.env = e.Environ()
+}
+
+// AppendEnv adds the given "key=value" entries to the environment set by Env,
+// replacing the value of any existing entries with the same key.
+func (r *Local) AppendEnv(env ...string) {
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	e := NewEnvSet(r.env...)
+	e.AppendEnv(env...)
+	r.env = e.Environ()
+}
+
+// environ returns the environment set by Env. The returned slice is never
+// modified by r, and hence is safe to use without holding envMu.
+func (r *Local) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
 }
@@ -127,7 +127,7 @@ func ExampleRunner_failure() {
 	}
 
 	// Output:
-	// exit status 3: Oh noes! :(
+	// sh: exit status 3: Oh noes! :(
 }
 
 func ExampleRunner_context() {
@@ -170,5 +170,5 @@ func ExampleRunner_contextTimeout() {
 	}
 
 	// Output:
-	// signal: killed
+	// sh: signal: killed
 }
@@ -0,0 +1,175 @@
+//go:build linux
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_sysProcAttr(t *testing.T) {
+	// Prints the PID and session ID of the shell process.
+	script := `read -r _ _ _ _ _ sid _ < /proc/$$/stat; echo "$$ $sid"`
+
+	tests := []struct {
+		name        string
+		sysProcAttr *syscall.SysProcAttr
+		wantSetsid  bool
+	}{
+		{
+			name:        "nil",
+			sysProcAttr: nil,
+			wantSetsid:  false,
+		},
+		{
+			name:        "Setsid",
+			sysProcAttr: &syscall.SysProcAttr{Setsid: true},
+			wantSetsid:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{SysProcAttr: tt.sysProcAttr}
+
+			err := r.Run(nil, &stdout, nil, "sh", "-c", script)
+			require.NoError(t, err)
+
+			ids := strings.Fields(stdout.String())
+			require.Len(t, ids, 2)
+			if tt.wantSetsid {
+				assert.Equal(t, ids[0], ids[1])
+			} else {
+				assert.NotEqual(t, ids[0], ids[1])
+			}
+		})
+	}
+}
+
+func TestLocal_Run_sysProcAttrNotModified(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing user requires root")
+	}
+
+	uid := uint32(65534)
+	attr := &syscall.SysProcAttr{Setsid: true}
+	r := &Local{Uid: &uid, SysProcAttr: attr}
+
+	err := r.Run(nil, nil, nil, "true")
+	require.NoError(t, err)
+
+	assert.Equal(t, &syscall.SysProcAttr{Setsid: true}, attr)
+}
+
+func TestLocal_Run_processGroup(t *testing.T) {
+	// Prints the PID, process group ID and session ID of the shell process.
+	script := `read -r _ _ _ _ pgid sid _ < /proc/$$/stat; ` +
+		`echo "$$ $pgid $sid"`
+
+	tests := []struct {
+		name        string
+		setsid      bool
+		setpgid     bool
+		wantPgid    bool
+		wantSession bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "Setpgid",
+			setpgid:  true,
+			wantPgid: true,
+		},
+		{
+			name:        "Setsid",
+			setsid:      true,
+			wantPgid:    true,
+			wantSession: true,
+		},
+		{
+			name:        "Setsid and Setpgid",
+			setsid:      true,
+			setpgid:     true,
+			wantPgid:    true,
+			wantSession: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{Setsid: tt.setsid, Setpgid: tt.setpgid}
+
+			err := r.Run(nil, &stdout, nil, "sh", "-c", script)
+			require.NoError(t, err)
+
+			ids := strings.Fields(stdout.String())
+			require.Len(t, ids, 3)
+			assert.Equal(t, tt.wantPgid, ids[0] == ids[1])
+			assert.Equal(t, tt.wantSession, ids[0] == ids[2])
+		})
+	}
+}
+
+func TestLocal_RunContext_killProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Start a grandchild process which outlives the shell unless it is killed
+	// along with it.
+	pr, pw := io.Pipe()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		pw.Close()
+	}()
+
+	var stdout bytes.Buffer
+	r := &Local{KillProcessGroup: true}
+
+	err := r.RunContext(
+		ctx, pr, &stdout, nil,
+		"sh", "-c", "sleep 30 & echo $!; cat; wait",
+	)
+
+	assert.EqualError(t, err, "sh: signal: killed")
+
+	pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return !processRunning(pid)
+	}, 2*time.Second, 10*time.Millisecond, "grandchild still running")
+}
+
+func TestLocal_Run_killProcessGroupWithoutContext(t *testing.T) {
+	var stdout bytes.Buffer
+	r := &Local{KillProcessGroup: true}
+
+	err := r.Run(nil, &stdout, nil, "echo", "hello")
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello\n", stdout.String())
+}
+
+// processRunning reports if the process with the given pid exists, and is not
+// a zombie.
+func processRunning(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(b))
+
+	return len(fields) > 2 && fields[2] != "Z"
+}
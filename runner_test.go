@@ -3,8 +3,15 @@ package runner
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
@@ -21,6 +28,39 @@ func TestNew(t *testing.T) {
 	assert.Implements(t, (*Runner)(nil), r)
 }
 
+func TestNew_options(t *testing.T) {
+	dir := t.TempDir()
+	var started []string
+	cmdFunc := func(cmd *exec.Cmd) {
+		started = append(started, cmd.Path)
+	}
+
+	r := New(
+		WithInheritedEnv(),
+		WithRunnerEnv("A=1", "B=2"),
+		WithRunnerEnv("B=3"),
+		WithRunnerDir(dir),
+		WithDefaultTimeout(time.Minute),
+		WithCmdFunc(cmdFunc),
+	)
+
+	l, ok := r.(*Local)
+	require.True(t, ok)
+	assert.Equal(t, dir, l.Dir)
+	assert.True(t, l.InheritOSEnv)
+	assert.Equal(t, time.Minute, l.DefaultTimeout)
+	assert.NotNil(t, l.CmdFunc)
+
+	var stdout bytes.Buffer
+	err := r.Run(nil, &stdout, nil, "sh", "-c", `echo "$A $B"; pwd`)
+	require.NoError(t, err)
+
+	wantDir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "1 3\n"+wantDir+"\n", stdout.String())
+	assert.Len(t, started, 1)
+}
+
 func TestLocal_Run(t *testing.T) {
 	dir := t.TempDir()
 	f, err := os.CreateTemp(dir, "helloworld")
@@ -129,7 +169,7 @@ echo "API_KEY: $API_KEY"
 			name:    "error with no output",
 			command: "sh",
 			args:    []string{"-c", `exit 42`},
-			wantErr: "exit status 42",
+			wantErr: "sh: exit status 42",
 		},
 		{
 			name:    "error with stderr output",
@@ -137,7 +177,7 @@ echo "API_KEY: $API_KEY"
 			args: []string{
 				"-c", `echo "\n\noops broken\n\n" >&2; exit 42`,
 			},
-			wantErr:    "exit status 42",
+			wantErr:    "sh: exit status 42",
 			wantStderr: []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -145,7 +185,7 @@ echo "API_KEY: $API_KEY"
 			command:    "sh",
 			args:       []string{"-c", `echo 'hello world again'; exit 84`},
 			wantStdout: []byte("hello world again\n"),
-			wantErr:    "exit status 84",
+			wantErr:    "sh: exit status 84",
 		},
 		{
 			name:    "error with stdout and stderr output",
@@ -158,7 +198,7 @@ exit 84
 `,
 			},
 			wantStdout: []byte("hello world again\n"),
-			wantErr:    "exit status 84",
+			wantErr:    "sh: exit status 84",
 			wantStderr: []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -173,7 +213,7 @@ exit 84
 			},
 			discardStderr: true,
 			wantStdout:    []byte("hello world again\n"),
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84: oops broken",
 		},
 		{
 			name:    "error with discarded stdout",
@@ -186,7 +226,7 @@ exit 84
 `,
 			},
 			discardStdout: true,
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84",
 			wantStderr:    []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -201,7 +241,7 @@ exit 84
 			},
 			discardStdout: true,
 			discardStderr: true,
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84: oops broken",
 		},
 	}
 	for _, tt := range tests {
@@ -373,7 +413,7 @@ echo "API_KEY: $API_KEY"
 			ctx:     ctx,
 			command: "sh",
 			args:    []string{"-c", `exit 42`},
-			wantErr: "exit status 42",
+			wantErr: "sh: exit status 42",
 		},
 		{
 			name:    "error with stderr output",
@@ -382,7 +422,7 @@ echo "API_KEY: $API_KEY"
 			args: []string{
 				"-c", `echo "\n\noops broken\n\n" >&2; exit 42`,
 			},
-			wantErr:    "exit status 42",
+			wantErr:    "sh: exit status 42",
 			wantStderr: []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -391,7 +431,7 @@ echo "API_KEY: $API_KEY"
 			command:    "sh",
 			args:       []string{"-c", `echo 'hello world again'; exit 84`},
 			wantStdout: []byte("hello world again\n"),
-			wantErr:    "exit status 84",
+			wantErr:    "sh: exit status 84",
 		},
 		{
 			name:    "error with stdout and stderr output",
@@ -405,7 +445,7 @@ exit 84
 `,
 			},
 			wantStdout: []byte("hello world again\n"),
-			wantErr:    "exit status 84",
+			wantErr:    "sh: exit status 84",
 			wantStderr: []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -421,7 +461,7 @@ exit 84
 			},
 			discardStderr: true,
 			wantStdout:    []byte("hello world again\n"),
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84: oops broken",
 		},
 		{
 			name:    "error with discarded stdout",
@@ -435,7 +475,7 @@ exit 84
 `,
 			},
 			discardStdout: true,
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84",
 			wantStderr:    []byte("\n\noops broken\n\n\n"),
 		},
 		{
@@ -451,7 +491,7 @@ exit 84
 			},
 			discardStdout: true,
 			discardStderr: true,
-			wantErr:       "exit status 84",
+			wantErr:       "sh: exit status 84: oops broken",
 		},
 		{
 			name:       "no context timeout",
@@ -469,7 +509,7 @@ exit 84
 			command:    "sh",
 			args:       []string{"-c", "sleep 1 && echo 'hello'"},
 			ctxTimeout: 100 * time.Millisecond,
-			wantErr:    "signal: killed",
+			wantErr:    "sh: signal: killed",
 		},
 	}
 	for _, tt := range tests {
@@ -533,6 +573,310 @@ exit 84
 	}
 }
 
+func TestLocal_RunContext_termination(t *testing.T) {
+	tests := []struct {
+		name              string
+		gracePeriod       time.Duration
+		terminationSignal os.Signal
+		script            string
+		wantStdout        string
+		wantErr           string
+		wantMinDuration   time.Duration
+	}{
+		{
+			name:        "terminates on SIGTERM",
+			gracePeriod: 200 * time.Millisecond,
+			script: `trap 'echo terminated; exit 3' TERM
+echo ready
+while :; do sleep 0.01; done`,
+			wantStdout: "ready\nterminated\n",
+			wantErr:    "sh: exit status 3",
+		},
+		{
+			name:              "terminates on custom signal",
+			gracePeriod:       200 * time.Millisecond,
+			terminationSignal: syscall.SIGINT,
+			script: `trap 'echo interrupted; exit 4' INT
+echo ready
+while :; do sleep 0.01; done`,
+			wantStdout: "ready\ninterrupted\n",
+			wantErr:    "sh: exit status 4",
+		},
+		{
+			name:        "killed after grace period",
+			gracePeriod: 200 * time.Millisecond,
+			script: `trap '' TERM
+echo ready
+while :; do sleep 0.01; done`,
+			wantStdout:      "ready\n",
+			wantErr:         "sh: signal: killed",
+			wantMinDuration: 300 * time.Millisecond,
+		},
+		{
+			name:              "custom signal without grace period",
+			terminationSignal: syscall.SIGINT,
+			script: `trap 'sleep 0.5; echo checkpointed; exit 5' INT
+echo ready
+while :; do sleep 0.01; done`,
+			wantStdout:      "ready\ncheckpointed\n",
+			wantErr:         "sh: exit status 5",
+			wantMinDuration: 600 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(
+				context.Background(), 100*time.Millisecond,
+			)
+			defer cancel()
+
+			var stdout bytes.Buffer
+			r := &Local{
+				GracePeriod:       tt.gracePeriod,
+				TerminationSignal: tt.terminationSignal,
+			}
+
+			start := time.Now()
+			err := r.RunContext(ctx, nil, &stdout, nil, "sh", "-c", tt.script)
+
+			assert.EqualError(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.GreaterOrEqual(t, time.Since(start), tt.wantMinDuration)
+		})
+	}
+}
+
+func TestLocal_DefaultTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		runContext  bool
+		script      string
+		wantErr     string
+		maxDuration time.Duration
+	}{
+		{
+			name:        "Run times out",
+			script:      "exec sleep 5",
+			wantErr:     "sh: signal: killed",
+			maxDuration: 2 * time.Second,
+		},
+		{
+			name:   "Run completes",
+			script: "exit 0",
+		},
+		{
+			name:        "RunContext without deadline times out",
+			runContext:  true,
+			script:      "exec sleep 5",
+			wantErr:     "sh: signal: killed",
+			maxDuration: 2 * time.Second,
+		},
+		{
+			name:       "RunContext with later deadline is not bounded",
+			runContext: true,
+			timeout:    time.Minute,
+			script:     "sleep 0.5",
+		},
+		{
+			name:        "RunContext with earlier deadline",
+			runContext:  true,
+			timeout:     100 * time.Millisecond,
+			script:      "exec sleep 5",
+			wantErr:     "sh: signal: killed",
+			maxDuration: 2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Local{DefaultTimeout: 200 * time.Millisecond}
+
+			start := time.Now()
+			var err error
+			if tt.runContext {
+				ctx := context.Background()
+				if tt.timeout > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+					defer cancel()
+				}
+				err = r.RunContext(ctx, nil, nil, nil, "sh", "-c", tt.script)
+			} else {
+				err = r.Run(nil, nil, nil, "sh", "-c", tt.script)
+			}
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.maxDuration > 0 {
+				assert.Less(t, time.Since(start), tt.maxDuration)
+			}
+		})
+	}
+}
+
+func TestLocal_Run_dir(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	r := &Local{Dir: dir}
+
+	err := r.Run(nil, &stdout, nil, "pwd")
+	require.NoError(t, err)
+
+	want, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	got, err := filepath.EvalSymlinks(strings.TrimSpace(stdout.String()))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestLocal_Run_extraFiles(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pr.Close()
+
+	r := &Local{ExtraFiles: []*os.File{pw}}
+
+	err = r.Run(nil, nil, nil, "sh", "-c", "echo 'hello fd 3' >&3")
+	pw.Close()
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(pr)
+	require.NoError(t, err)
+	assert.Equal(t, "hello fd 3\n", string(b))
+}
+
+func TestLocal_Run_cmdFunc(t *testing.T) {
+	dir := t.TempDir()
+	var stdout bytes.Buffer
+	var called *exec.Cmd
+	r := &Local{
+		CmdFunc: func(cmd *exec.Cmd) {
+			called = cmd
+			cmd.Dir = dir
+		},
+	}
+
+	err := r.Run(nil, &stdout, nil, "pwd")
+	require.NoError(t, err)
+
+	require.NotNil(t, called)
+	assert.Equal(t, &stdout, called.Stdout)
+	want, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+	got, err := filepath.EvalSymlinks(strings.TrimSpace(stdout.String()))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestLocal_Run_commandNotFound(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		command  string
+		args     []string
+		wantErr  error
+		wantNone bool
+	}{
+		{
+			name:    "not in PATH",
+			command: "runner-test-command-that-does-not-exist",
+			wantErr: exec.ErrNotFound,
+		},
+		{
+			name: "missing path",
+			command: filepath.Join(
+				os.TempDir(), "runner-test-nope", "command",
+			),
+			wantErr: fs.ErrNotExist,
+		},
+		{
+			name:     "command fails",
+			command:  "sh",
+			args:     []string{"-c", "exit 127"},
+			wantNone: true,
+		},
+		{
+			name:     "missing dir",
+			dir:      filepath.Join(os.TempDir(), "runner-test-nope"),
+			command:  "sh",
+			args:     []string{"-c", "true"},
+			wantNone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Local{Dir: tt.dir}
+
+			err := r.Run(nil, nil, nil, tt.command, tt.args...)
+			require.Error(t, err)
+
+			if tt.wantNone {
+				assert.NotErrorIs(t, err, ErrCommandNotFound)
+
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrCommandNotFound)
+			assert.ErrorIs(t, err, Err)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Contains(t, err.Error(), tt.command)
+		})
+	}
+}
+
+func TestLocal_Run_inheritOSEnv(t *testing.T) {
+	t.Setenv("RUNNER_TEST_INHERITED", "inherited")
+	t.Setenv("RUNNER_TEST_OVERRIDDEN", "os")
+
+	tests := []struct {
+		name         string
+		inheritOSEnv bool
+		env          []string
+		want         string
+	}{
+		{
+			name: "no env",
+			want: "inherited os\n",
+		},
+		{
+			name: "env replaces OS env",
+			env:  []string{"RUNNER_TEST_OVERRIDDEN=runner"},
+			want: "runner\n",
+		},
+		{
+			name:         "inherit without env",
+			inheritOSEnv: true,
+			want:         "inherited os\n",
+		},
+		{
+			name:         "inherit with env layered on top",
+			inheritOSEnv: true,
+			env:          []string{"RUNNER_TEST_OVERRIDDEN=runner"},
+			want:         "inherited runner\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{InheritOSEnv: tt.inheritOSEnv}
+			if tt.env != nil {
+				r.Env(tt.env...)
+			}
+
+			err := r.Run(
+				nil, &stdout, nil, "/bin/sh", "-c",
+				"echo $RUNNER_TEST_INHERITED $RUNNER_TEST_OVERRIDDEN",
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
 func TestLocal_Env(t *testing.T) {
 	type fields struct {
 		env []string
@@ -582,3 +926,70 @@ func TestLocal_Env(t *testing.T) {
 		})
 	}
 }
+
+func TestLocal_Env_copy(t *testing.T) {
+	env := []string{"FOO=bar"}
+	r := &Local{}
+	r.Env(env...)
+	env[0] = "FOO=modified"
+
+	var stdout bytes.Buffer
+	err := r.Run(nil, &stdout, nil, "/bin/sh", "-c", "echo $FOO")
+	require.NoError(t, err)
+
+	assert.Equal(t, "bar\n", stdout.String())
+}
+
+func TestLocal_Env_concurrent(t *testing.T) {
+	r := &Local{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			r.Env(fmt.Sprintf("N=%d", i))
+			r.Setenv("FOO", "bar")
+			r.AppendEnv("BAR=baz")
+			r.Unsetenv("BAR")
+		}(i)
+		go func() {
+			defer wg.Done()
+			var stdout bytes.Buffer
+			err := r.Run(nil, &stdout, nil, "/bin/sh", "-c", "echo $FOO")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLocal_Setenv(t *testing.T) {
+	r := &Local{env: []string{"foo=bar", "HELLO=WORLD"}}
+
+	r.Setenv("HELLO", "there")
+	r.Setenv("API_KEY", "12345")
+
+	assert.Equal(t, []string{"foo=bar", "HELLO=there", "API_KEY=12345"}, r.env)
+}
+
+func TestLocal_Unsetenv(t *testing.T) {
+	r := &Local{env: []string{"foo=bar", "HELLO=WORLD"}}
+
+	r.Unsetenv("foo")
+	r.Unsetenv("nope")
+
+	assert.Equal(t, []string{"HELLO=WORLD"}, r.env)
+}
+
+func TestLocal_AppendEnv(t *testing.T) {
+	r := &Local{env: []string{"foo=bar"}}
+
+	r.AppendEnv("HELLO=WORLD", "foo=baz")
+
+	assert.Equal(t, []string{"foo=baz", "HELLO=WORLD"}, r.env)
+
+	var stdout bytes.Buffer
+	err := r.Run(nil, &stdout, nil, "sh", "-c", "echo $foo $HELLO")
+	require.NoError(t, err)
+	assert.Equal(t, "baz WORLD\n", stdout.String())
+}
@@ -0,0 +1,20 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// rusageCounters returns the minor and major page faults, and voluntary and
+// involuntary context switches, of the process.
+func rusageCounters(state *os.ProcessState) (int64, int64, int64, int64) {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return 0, 0, 0, 0
+	}
+
+	return int64(ru.Minflt), int64(ru.Majflt),
+		int64(ru.Nvcsw), int64(ru.Nivcsw)
+}
@@ -0,0 +1,18 @@
+//go:build darwin
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size of the process in bytes.
+func maxRSS(state *os.ProcessState) int64 {
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok && ru != nil {
+		// macOS reports ru_maxrss in bytes.
+		return ru.Maxrss
+	}
+
+	return 0
+}
@@ -0,0 +1,18 @@
+//go:build linux
+
+package runner
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxRSS returns the maximum resident set size of the process in bytes.
+func maxRSS(state *os.ProcessState) int64 {
+	if ru, ok := state.SysUsage().(*syscall.Rusage); ok && ru != nil {
+		// Linux reports ru_maxrss in kilobytes.
+		return int64(ru.Maxrss) * 1024
+	}
+
+	return 0
+}
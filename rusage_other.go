@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package runner
+
+import "os"
+
+func maxRSS(*os.ProcessState) int64 {
+	return 0
+}
+
+func rusageCounters(*os.ProcessState) (int64, int64, int64, int64) {
+	return 0, 0, 0, 0
+}
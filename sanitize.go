@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrSanitize    = fmt.Errorf("%w: sanitize", Err)
+	ErrInvalidArgs = fmt.Errorf("%w: invalid argument", ErrSanitize)
+)
+
+// ArgError is returned by Sanitize when the command or an argument contains a
+// disallowed character.
+type ArgError struct {
+	// Index is the position of the invalid argument, where 0 is the command,
+	// and 1 is the first argument.
+	Index int
+
+	// Arg is the invalid argument.
+	Arg string
+
+	// Char is the first disallowed character found in Arg.
+	Char rune
+}
+
+var _ error = &ArgError{}
+
+func (e *ArgError) Error() string {
+	return fmt.Sprintf(
+		"%s %d: disallowed character %U",
+		ErrInvalidArgs.Error(), e.Index, e.Char,
+	)
+}
+
+// Unwrap returns ErrInvalidArgs.
+func (e *ArgError) Unwrap() error {
+	return ErrInvalidArgs
+}
+
+// Sanitize is a Runner that wraps another Runner, and rejects commands whose
+// command or arguments contain NUL bytes, newlines, or other control
+// characters, returning an *ArgError without running them.
+//
+// This guards against injection when arguments are built from user input, for
+// example when they are passed on to a remote shell by SSHCLI.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Sanitize struct {
+	// Runner is the underlying Runner to run valid commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// AllowChars is a set of control characters, like "\t\n", which are
+	// permitted in arguments. NUL bytes are never permitted.
+	AllowChars string
+}
+
+var _ Runner = &Sanitize{}
+
+// Run calls Run on the underlying Runner if the command and arguments are
+// valid.
+//
+// Will panic if Runner field is nil.
+func (r *Sanitize) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := r.Check(command, args...)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext calls RunContext on the underlying Runner if the command and
+// arguments are valid.
+//
+// Will panic if Runner field is nil.
+func (r *Sanitize) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	err := r.Check(command, args...)
+	if err != nil {
+		return err
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Check returns an *ArgError if the command or any of the arguments contain a
+// disallowed character, and nil otherwise.
+func (r *Sanitize) Check(command string, args ...string) error {
+	for i, arg := range append([]string{command}, args...) {
+		for _, c := range arg {
+			if r.disallowed(c) {
+				return &ArgError{Index: i, Arg: arg, Char: c}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Sanitize) disallowed(c rune) bool {
+	if c == 0 {
+		return true
+	}
+
+	return unicode.IsControl(c) && !strings.ContainsRune(r.AllowChars, c)
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Sanitize) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,171 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+var sanitizeTestCases = []struct {
+	name       string
+	allowChars string
+	command    string
+	args       []string
+	err        error
+	wantArgErr *ArgError
+	wantErr    string
+}{
+	{
+		name:    "valid",
+		command: "echo",
+		args:    []string{"hello world", "ünïcödé", "$(whoami)"},
+	},
+	{
+		name:       "NUL byte",
+		command:    "echo",
+		args:       []string{"hello", "wor\x00ld"},
+		wantArgErr: &ArgError{Index: 2, Arg: "wor\x00ld", Char: 0},
+		wantErr: "runner: sanitize: invalid argument 2: " +
+			"disallowed character U+0000",
+	},
+	{
+		name:       "NUL byte is never allowed",
+		allowChars: "\x00",
+		command:    "echo",
+		args:       []string{"\x00"},
+		wantArgErr: &ArgError{Index: 1, Arg: "\x00", Char: 0},
+		wantErr: "runner: sanitize: invalid argument 1: " +
+			"disallowed character U+0000",
+	},
+	{
+		name:       "newline",
+		command:    "echo",
+		args:       []string{"hello\nrm -rf /"},
+		wantArgErr: &ArgError{Index: 1, Arg: "hello\nrm -rf /", Char: '\n'},
+		wantErr: "runner: sanitize: invalid argument 1: " +
+			"disallowed character U+000A",
+	},
+	{
+		name:       "allowed newline",
+		allowChars: "\n",
+		command:    "echo",
+		args:       []string{"hello\nworld"},
+	},
+	{
+		name:       "control character in command",
+		command:    "ec\x1bho",
+		wantArgErr: &ArgError{Index: 0, Arg: "ec\x1bho", Char: '\x1b'},
+		wantErr: "runner: sanitize: invalid argument 0: " +
+			"disallowed character U+001B",
+	},
+	{
+		name:       "C1 control character",
+		command:    "echo",
+		args:       []string{"a\u0085b"},
+		wantArgErr: &ArgError{Index: 1, Arg: "a\u0085b", Char: '\u0085'},
+		wantErr: "runner: sanitize: invalid argument 1: " +
+			"disallowed character U+0085",
+	},
+	{
+		name:       "tab",
+		command:    "printf",
+		args:       []string{"a\tb"},
+		wantArgErr: &ArgError{Index: 1, Arg: "a\tb", Char: '\t'},
+		wantErr: "runner: sanitize: invalid argument 1: " +
+			"disallowed character U+0009",
+	},
+	{
+		name:       "allowed tab",
+		allowChars: "\t\n",
+		command:    "printf",
+		args:       []string{"a\tb"},
+	},
+	{
+		name:    "underlying error",
+		command: "zfs",
+		args:    []string{"list"},
+		err:     errors.New("zfs: command not found"),
+		wantErr: "zfs: command not found",
+	},
+}
+
+func TestSanitize_Run(t *testing.T) {
+	for _, tt := range sanitizeTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgErr == nil {
+				r.EXPECT().Run(nil, nil, nil, tt.command, tt.args).
+					Return(tt.err)
+			}
+
+			s := &Sanitize{Runner: r, AllowChars: tt.allowChars}
+			err := s.Run(nil, nil, nil, tt.command, tt.args...)
+
+			assertSanitizeErr(t, err, tt.wantErr, tt.wantArgErr)
+		})
+	}
+}
+
+func TestSanitize_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range sanitizeTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantArgErr == nil {
+				r.EXPECT().RunContext(
+					gomockctx.Eq(ctx), nil, nil, nil, tt.command, tt.args,
+				).Return(tt.err)
+			}
+
+			s := &Sanitize{Runner: r, AllowChars: tt.allowChars}
+			err := s.RunContext(ctx, nil, nil, nil, tt.command, tt.args...)
+
+			assertSanitizeErr(t, err, tt.wantErr, tt.wantArgErr)
+		})
+	}
+}
+
+func assertSanitizeErr(
+	t *testing.T,
+	err error,
+	wantErr string,
+	wantArgErr *ArgError,
+) {
+	t.Helper()
+
+	if wantErr == "" {
+		assert.NoError(t, err)
+
+		return
+	}
+
+	assert.EqualError(t, err, wantErr)
+	if wantArgErr == nil {
+		return
+	}
+
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+	assert.ErrorIs(t, err, ErrSanitize)
+
+	var argErr *ArgError
+	require.True(t, errors.As(err, &argErr))
+	assert.Equal(t, wantArgErr, argErr)
+}
+
+func TestSanitize_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	s := &Sanitize{Runner: r}
+	s.Env("FOO=BAR", "PORT=8080")
+}
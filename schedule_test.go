@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{expr: "* * * * *"},
+		{expr: "*/15 0-6,22-23 1,15 */2 1-5"},
+		{expr: "0 0 * * 7"},
+		{expr: "@daily"},
+		{expr: "30 4 * *", wantErr: `"30 4 * *": expected 5 fields`},
+		{expr: "60 * * * *", wantErr: `"60" out of range 0-59`},
+		{expr: "* * 0 * *", wantErr: `"0" out of range 1-31`},
+		{expr: "5-1 * * * *", wantErr: `"5-1" out of range 0-59`},
+		{expr: "*/0 * * * *", wantErr: `invalid step "*/0"`},
+		{expr: "a * * * *", wantErr: `invalid value "a"`},
+		{expr: "1-b * * * *", wantErr: `invalid value "1-b"`},
+		{expr: "@often", wantErr: `"@often": expected 5 fields`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrCron)
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Nil(t, s)
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, s)
+		})
+	}
+}
+
+func TestMustParseCron(t *testing.T) {
+	assert.NotPanics(t, func() { MustParseCron("@hourly") })
+	assert.Panics(t, func() { MustParseCron("nope") })
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	// 2024-03-14 is a Thursday.
+	from := time.Date(2024, 3, 14, 10, 17, 42, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{
+			expr: "* * * * *",
+			want: time.Date(2024, 3, 14, 10, 18, 0, 0, time.UTC),
+		},
+		{
+			expr: "*/15 * * * *",
+			want: time.Date(2024, 3, 14, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			expr: "5 9 * * *",
+			want: time.Date(2024, 3, 15, 9, 5, 0, 0, time.UTC),
+		},
+		{
+			expr: "0 0 * * 0",
+			want: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr: "0 0 * * 7",
+			want: time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr: "@monthly",
+			want: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr: "@yearly",
+			want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Day of month or day of week, as both are restricted.
+			expr: "0 12 20 * 5",
+			want: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			expr: "0 0 29 2 *",
+			want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr: "0 0 30 2 *",
+			want: time.Time{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestCronSchedule_Next_location(t *testing.T) {
+	loc := time.FixedZone("UTC+5:30", 5*60*60+30*60)
+	from := time.Date(2024, 3, 14, 10, 17, 0, 0, loc)
+
+	got := MustParseCron("0 * * * *").Next(from)
+
+	assert.Equal(t, time.Date(2024, 3, 14, 11, 0, 0, 0, loc), got)
+}
+
+func TestEvery(t *testing.T) {
+	from := time.Date(2024, 3, 14, 10, 17, 42, 0, time.UTC)
+
+	assert.Equal(t, from.Add(time.Minute), Every(time.Minute).Next(from))
+	assert.True(t, Every(0).Next(from).IsZero())
+}
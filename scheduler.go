@@ -0,0 +1,289 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	ErrScheduler          = fmt.Errorf("%w: scheduler", Err)
+	ErrSchedulerDuplicate = fmt.Errorf("%w: duplicate job", ErrScheduler)
+	ErrSchedulerNoJob     = fmt.Errorf("%w: job not found", ErrScheduler)
+	ErrSchedulerRunning   = fmt.Errorf("%w: already running", ErrScheduler)
+)
+
+// ScheduledJob is a command run by a Scheduler on a Schedule. As the command
+// is run repeatedly, its Stdin is typically nil.
+type ScheduledJob struct {
+	BatchCommand
+
+	// Name identifies the job within the Scheduler.
+	Name string
+
+	// Schedule decides when the job runs. Use Every for fixed intervals, or
+	// ParseCron for cron expressions.
+	Schedule Schedule
+
+	// Jitter is the exclusive upper bound of a random delay added to each
+	// time the job is due, spreading load when many jobs or hosts share a
+	// schedule.
+	Jitter time.Duration
+
+	// AllowOverlap allows the job to start while a previous run of it is
+	// still running. By default, runs which are due while the job is still
+	// running are skipped.
+	AllowOverlap bool
+}
+
+// ScheduledJobStatus describes the state of a job in a Scheduler.
+type ScheduledJobStatus struct {
+	// Name is the name of the job.
+	Name string
+
+	// Running is the number of runs of the job currently running.
+	Running int
+
+	// Runs is the number of runs of the job which have completed.
+	Runs int
+
+	// Failures is the number of completed runs which failed.
+	Failures int
+
+	// Skipped is the number of runs skipped as the job was still running.
+	Skipped int
+
+	// LastResult is the result of the most recently completed run, or nil if
+	// no run has completed.
+	LastResult *BatchResult
+
+	// LastRun is the time the most recently completed run started.
+	LastRun time.Time
+
+	// NextRun is the time the job is next due, before any jitter. It is the
+	// zero time if the Scheduler is not running, or the job will not run
+	// again.
+	NextRun time.Time
+}
+
+// Scheduler runs jobs through a Runner at fixed intervals, or on cron
+// schedules, while its Run method is running.
+//
+// Jobs may be added and removed at any time. The status of each job,
+// including the result of its last run, may be inspected with Status.
+type Scheduler struct {
+	// Runner is the Runner to run jobs with. If not set, running jobs will
+	// cause a panic.
+	Runner Runner
+
+	// OnResult, when set, is called with the result of each run of a job once
+	// it completes. Calls may be concurrent.
+	OnResult func(ctx context.Context, name string, res *BatchResult)
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	ctx     context.Context //nolint:containedctx
+	wg      sync.WaitGroup
+	running bool
+}
+
+type scheduledJob struct {
+	job    ScheduledJob
+	status ScheduledJobStatus
+	cancel context.CancelFunc
+}
+
+// Add adds job to the scheduler. If the scheduler is running, the job is
+// started immediately, and first runs when it is next due.
+func (s *Scheduler) Add(job ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %q", ErrSchedulerDuplicate, job.Name)
+	}
+	if s.jobs == nil {
+		s.jobs = map[string]*scheduledJob{}
+	}
+
+	j := &scheduledJob{
+		job:    job,
+		status: ScheduledJobStatus{Name: job.Name},
+	}
+	s.jobs[job.Name] = j
+
+	if s.running {
+		s.start(j)
+	}
+
+	return nil
+}
+
+// Remove removes the named job from the scheduler, stopping any further runs
+// of it. Runs which are already running are allowed to complete.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrSchedulerNoJob, name)
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	delete(s.jobs, name)
+
+	return nil
+}
+
+// Status returns the status of the named job, and reports if it exists.
+func (s *Scheduler) Status(name string) (ScheduledJobStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.jobs[name]
+	if !ok {
+		return ScheduledJobStatus{}, false
+	}
+
+	return j.status, true
+}
+
+// Run runs jobs as they become due, until ctx is done. It then waits for
+// running jobs, whose context is also done, to complete, and returns ctx's
+// error. Only one call to Run may be active at a time.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+
+		return ErrSchedulerRunning
+	}
+	s.running = true
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.running = false
+	s.ctx = nil
+	for _, j := range s.jobs {
+		j.cancel = nil
+		j.status.NextRun = time.Time{}
+	}
+	s.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// start starts the goroutine which runs j when due. It must be called with mu
+// held.
+func (s *Scheduler) start(j *scheduledJob) {
+	runCtx := s.ctx
+	ctx, cancel := context.WithCancel(runCtx)
+	j.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		s.loop(ctx, runCtx, j)
+	}()
+}
+
+// loop runs j each time it is due until ctx is done. Runs use runCtx, so they
+// are not canceled when j is removed.
+func (s *Scheduler) loop(
+	ctx context.Context,
+	runCtx context.Context,
+	j *scheduledJob,
+) {
+	var runs sync.WaitGroup
+	defer runs.Wait()
+
+	due := time.Now()
+	for {
+		due = j.job.Schedule.Next(due)
+
+		s.mu.Lock()
+		j.status.NextRun = due
+		s.mu.Unlock()
+
+		if due.IsZero() {
+			return
+		}
+
+		delay := time.Until(due)
+		if j.job.Jitter > 0 {
+			delay += time.Duration(
+				rand.Int63n(int64(j.job.Jitter)), //nolint:gosec
+			)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if j.status.Running > 0 && !j.job.AllowOverlap {
+			j.status.Skipped++
+			s.mu.Unlock()
+
+			continue
+		}
+		j.status.Running++
+		s.mu.Unlock()
+
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+
+			s.run(runCtx, j)
+		}()
+
+		// Avoid running a backlog of missed runs if the schedule fell
+		// behind.
+		if now := time.Now(); due.Before(now) {
+			due = now
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob) {
+	res := &BatchResult{
+		Command:  j.job.Command,
+		Args:     j.job.Args,
+		ExitCode: -1,
+		Skipped:  true,
+	}
+
+	start := time.Now()
+	runBatchCommand(ctx, s.Runner, j.job.BatchCommand, res)
+
+	s.mu.Lock()
+	j.status.Running--
+	if res.Skipped {
+		s.mu.Unlock()
+
+		return
+	}
+	j.status.Runs++
+	if res.Err != nil {
+		j.status.Failures++
+	}
+	j.status.LastResult = res
+	j.status.LastRun = start
+	s.mu.Unlock()
+
+	if s.OnResult != nil {
+		s.OnResult(ctx, j.job.Name, res)
+	}
+}
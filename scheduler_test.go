@@ -0,0 +1,195 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	f := &Fake{}
+	f.Respond("zfs", nil, FakeResponse{Stdout: "tank\n"})
+	f.Respond("false", nil, FakeResponse{ExitCode: 1})
+
+	var mu sync.Mutex
+	results := map[string]int{}
+	s := &Scheduler{
+		Runner: f,
+		OnResult: func(_ context.Context, name string, res *BatchResult) {
+			mu.Lock()
+			results[name]++
+			mu.Unlock()
+		},
+	}
+	require.NoError(t, s.Add(ScheduledJob{
+		Name:         "list",
+		BatchCommand: BatchCommand{Command: "zfs", Args: []string{"list"}},
+		Schedule:     Every(10 * time.Millisecond),
+	}))
+	require.NoError(t, s.Add(ScheduledJob{
+		Name:         "fail",
+		BatchCommand: BatchCommand{Command: "false"},
+		Schedule:     Every(10 * time.Millisecond),
+		Jitter:       time.Millisecond,
+	}))
+	assert.ErrorIs(
+		t, s.Add(ScheduledJob{Name: "list"}), ErrSchedulerDuplicate,
+	)
+
+	st, ok := s.Status("list")
+	require.True(t, ok)
+	assert.True(t, st.NextRun.IsZero())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		st, _ := s.Status("list")
+
+		return st.Runs >= 3
+	}, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, s.Run(ctx), ErrSchedulerRunning)
+
+	// Jobs added while running are started.
+	require.NoError(t, s.Add(ScheduledJob{
+		Name:         "late",
+		BatchCommand: BatchCommand{Command: "zfs"},
+		Schedule:     Every(10 * time.Millisecond),
+	}))
+	require.Eventually(t, func() bool {
+		st, _ := s.Status("late")
+
+		return st.Runs >= 1
+	}, time.Second, time.Millisecond)
+
+	st, _ = s.Status("list")
+	assert.False(t, st.NextRun.IsZero())
+	require.NotNil(t, st.LastResult)
+	assert.Equal(t, "tank\n", string(st.LastResult.Stdout))
+	assert.Equal(t, 0, st.Failures)
+
+	st, _ = s.Status("fail")
+	require.NotNil(t, st.LastResult)
+	assert.Equal(t, 1, st.LastResult.ExitCode)
+	assert.Equal(t, st.Runs, st.Failures)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	st, _ = s.Status("list")
+	assert.True(t, st.NextRun.IsZero())
+	mu.Lock()
+	assert.Equal(t, st.Runs, results["list"])
+	mu.Unlock()
+}
+
+func TestScheduler_overlap(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowOverlap bool
+		wantMax      int32
+	}{
+		{name: "prevented", wantMax: 1},
+		{name: "allowed", allowOverlap: true, wantMax: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning atomic.Int32
+			stub := &Stub{Fallback: func(
+				ctx context.Context,
+				_ io.Reader,
+				_, _ io.Writer,
+				_ string,
+				_ ...string,
+			) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+
+				return sleepContext(ctx, 35*time.Millisecond)
+			}}
+
+			s := &Scheduler{Runner: stub}
+			require.NoError(t, s.Add(ScheduledJob{
+				Name:         "slow",
+				BatchCommand: BatchCommand{Command: "slow"},
+				Schedule:     Every(10 * time.Millisecond),
+				AllowOverlap: tt.allowOverlap,
+			}))
+
+			ctx, cancel := context.WithTimeout(
+				context.Background(), 100*time.Millisecond,
+			)
+			defer cancel()
+			_ = s.Run(ctx)
+
+			st, _ := s.Status("slow")
+			assert.Equal(t, 0, st.Running)
+			if tt.allowOverlap {
+				assert.GreaterOrEqual(t, maxRunning.Load(), tt.wantMax)
+				assert.Equal(t, 0, st.Skipped)
+			} else {
+				assert.Equal(t, tt.wantMax, maxRunning.Load())
+				assert.Greater(t, st.Skipped, 0)
+			}
+		})
+	}
+}
+
+func TestScheduler_Remove(t *testing.T) {
+	var count atomic.Int32
+	stub := &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		count.Add(1)
+
+		return nil
+	}}
+
+	s := &Scheduler{Runner: stub}
+	require.NoError(t, s.Add(ScheduledJob{
+		Name:         "job",
+		BatchCommand: BatchCommand{Command: "true"},
+		Schedule:     Every(5 * time.Millisecond),
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return count.Load() > 0
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, s.Remove("job"))
+	assert.ErrorIs(t, s.Remove("job"), ErrSchedulerNoJob)
+	_, ok := s.Status("job")
+	assert.False(t, ok)
+
+	time.Sleep(10 * time.Millisecond)
+	n := count.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, n, count.Load())
+
+	cancel()
+	<-done
+}
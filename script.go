@@ -0,0 +1,193 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrScript       = fmt.Errorf("%w: script", Err)
+	ErrScriptStdin  = fmt.Errorf("%w: stdin requires TempFile", ErrScript)
+	ErrScriptStrict = fmt.Errorf(
+		"%w: Strict requires a shell interpreter", ErrScript,
+	)
+)
+
+// scriptStrictPrelude enables strict mode in POSIX-like shells. pipefail is
+// only enabled if the shell supports it, as older versions of dash do not.
+const scriptStrictPrelude = "set -eu\n" +
+	"if (set -o pipefail) 2>/dev/null; then set -o pipefail; fi\n"
+
+// scriptTempFileScript writes its stdin to a new temporary file, readable
+// only by the user, and prints the file's path.
+const scriptTempFileScript = `f=$(umask 077 && mktemp) || exit
+cat >"$f" || { rm -f "$f"; exit 1; }
+echo "$f"`
+
+// scriptShells are the interpreters treated as POSIX-like shells.
+var scriptShells = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "ash": true, "ksh": true,
+	"zsh": true, "busybox": true,
+}
+
+// Script runs multi-line scripts with an interpreter, like sh, bash, or
+// python3, on a Runner, without having to quote the script as a single
+// argument.
+//
+// By default, the script is streamed to the interpreter's stdin. Shell
+// interpreters are passed "-s --" to read it, and other interpreters "-", as
+// accepted by python, perl, ruby, and node. Arguments given to Run follow, and
+// are available to the script as its positional parameters, or argv.
+//
+// When TempFile is set, the script is instead written to a temporary file on
+// the host the Runner runs commands on, which is given to the interpreter as
+// its first argument, and removed once it exits. This leaves stdin free for
+// the script to read.
+type Script struct {
+	// Runner is the Runner to run scripts with. If not set, running scripts
+	// will cause a panic.
+	Runner Runner
+
+	// Interpreter is the command, and any leading arguments, the script is
+	// run with. Defaults to "sh".
+	Interpreter []string
+
+	// Strict prepends "set -euo pipefail" to the script, so it exits on the
+	// first failing command, use of an unset variable, or failure within a
+	// pipeline. It requires a shell interpreter.
+	Strict bool
+
+	// TempFile causes the script to be run from a temporary file, rather than
+	// streamed to the interpreter's stdin.
+	TempFile bool
+
+	// QuoteArgs causes the arguments passed to Runner to be shell-quoted. It
+	// must be set when Runner runs commands via a shell, like SSHCLI does on
+	// the remote host, and is implied when Runner is a *SSHCLI.
+	QuoteArgs bool
+}
+
+// Run runs script with the given arguments, connecting stdout and stderr to
+// the interpreter's. stdin must be nil unless TempFile is set.
+func (s *Script) Run(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	script string,
+	args ...string,
+) error {
+	interpreter := s.Interpreter
+	if len(interpreter) == 0 {
+		interpreter = []string{"sh"}
+	}
+	isShell := scriptShells[path.Base(interpreter[0])]
+
+	if s.Strict {
+		if !isShell {
+			return ErrScriptStrict
+		}
+		script = scriptStrictPrelude + script
+	}
+
+	if !s.TempFile {
+		if stdin != nil {
+			return ErrScriptStdin
+		}
+
+		readArgs := []string{"-"}
+		if isShell {
+			readArgs = []string{"-s", "--"}
+		}
+
+		return s.run(
+			ctx, strings.NewReader(script), stdout, stderr,
+			interpreter, append(readArgs, args...),
+		)
+	}
+
+	file, err := writeTempScript(ctx, s.Runner, s.QuoteArgs, script)
+	if err != nil {
+		return err
+	}
+
+	err = s.run(
+		ctx, stdin, stdout, stderr,
+		interpreter, append([]string{file}, args...),
+	)
+
+	rmErr := removeTempScript(ctx, s.Runner, s.QuoteArgs, file)
+	if err == nil {
+		err = rmErr
+	}
+
+	return err
+}
+
+func (s *Script) run(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	interpreter []string,
+	args []string,
+) error {
+	args = quoteArgs(
+		s.Runner, s.QuoteArgs, append(copyStrings(interpreter[1:]), args...),
+	)
+
+	return s.Runner.RunContext(
+		ctx, stdin, stdout, stderr, interpreter[0], args...,
+	)
+}
+
+// writeTempScript writes script to a new temporary file on the host r runs
+// commands on, and returns its path.
+func writeTempScript(
+	ctx context.Context,
+	r Runner,
+	quote bool,
+	script string,
+) (string, error) {
+	var stdout, stderr bytes.Buffer
+	err := r.RunContext(
+		ctx, strings.NewReader(script), &stdout, &stderr, "sh",
+		quoteArgs(r, quote, []string{"-c", scriptTempFileScript})...,
+	)
+	if err != nil {
+		return "", fmt.Errorf(
+			"%w: creating temp file: %w: %s", ErrScript, err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	file := strings.TrimSpace(stdout.String())
+	if file == "" {
+		return "", fmt.Errorf("%w: creating temp file: no path", ErrScript)
+	}
+
+	return file, nil
+}
+
+// removeTempScript removes file from the host r runs commands on. It does so
+// even if ctx is done, so the file is not left behind.
+func removeTempScript(
+	ctx context.Context,
+	r Runner,
+	quote bool,
+	file string,
+) error {
+	err := r.RunContext(
+		valuesContext{ctx}, nil, nil, nil,
+		"rm", quoteArgs(r, quote, []string{"-f", "--", file})...,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: removing %s: %w", ErrScript, file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScript_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     *Script
+		stdin      io.Reader
+		source     string
+		args       []string
+		wantStdout string
+		wantCode   int
+		wantErr    error
+	}{
+		{
+			name:   "multi-line script",
+			script: &Script{},
+			source: "echo one\n" +
+				"for i in 2 3; do\n" +
+				"  echo \"$i\"\n" +
+				"done\n",
+			wantStdout: "one\n2\n3\n",
+		},
+		{
+			name:       "arguments",
+			script:     &Script{},
+			source:     `printf '%s|' "$#" "$@"`,
+			args:       []string{"-x", "two words", "$HOME"},
+			wantStdout: "3|-x|two words|$HOME|",
+		},
+		{
+			name:       "interpreter",
+			script:     &Script{Interpreter: []string{"bash", "--norc"}},
+			source:     `echo "${BASH_VERSION:+bash}" "${1^^}"`,
+			args:       []string{"hi"},
+			wantStdout: "bash HI\n",
+		},
+		{
+			name:       "exit code",
+			script:     &Script{},
+			source:     "echo before\nexit 4\necho after\n",
+			wantStdout: "before\n",
+			wantCode:   4,
+		},
+		{
+			name:       "not strict",
+			script:     &Script{},
+			source:     "false\necho $UNSET_VAR_X done\n",
+			wantStdout: "done\n",
+		},
+		{
+			name:     "strict exits on failure",
+			script:   &Script{Strict: true},
+			source:   "false\necho after\n",
+			wantCode: 1,
+		},
+		{
+			name:     "strict exits on unset variable",
+			script:   &Script{Strict: true},
+			source:   "echo $UNSET_VAR_X\n",
+			wantCode: 2,
+		},
+		{
+			name: "strict exits on pipeline failure",
+			script: &Script{
+				Interpreter: []string{"bash"},
+				Strict:      true,
+			},
+			source:   "false | true\necho after\n",
+			wantCode: 1,
+		},
+		{
+			name:    "strict requires a shell",
+			script:  &Script{Interpreter: []string{"python3"}, Strict: true},
+			source:  "print(1)\n",
+			wantErr: ErrScriptStrict,
+		},
+		{
+			name:    "stdin requires temp file",
+			script:  &Script{},
+			stdin:   strings.NewReader("input\n"),
+			source:  "cat\n",
+			wantErr: ErrScriptStdin,
+		},
+		{
+			name:       "temp file",
+			script:     &Script{TempFile: true},
+			stdin:      strings.NewReader("input\n"),
+			source:     "cat\necho \"$@\"\n",
+			args:       []string{"a", "b"},
+			wantStdout: "input\na b\n",
+		},
+		{
+			name:       "temp file exit code",
+			script:     &Script{TempFile: true, Strict: true},
+			source:     "echo before\nfalse\necho after\n",
+			wantStdout: "before\n",
+			wantCode:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.script.Runner = &Local{}
+
+			var stdout bytes.Buffer
+			err := tt.script.Run(
+				context.Background(), tt.stdin, &stdout, nil,
+				tt.source, tt.args...,
+			)
+
+			switch {
+			case tt.wantErr != nil:
+				assert.ErrorIs(t, err, tt.wantErr)
+			case tt.wantCode != 0:
+				assert.Equal(t, tt.wantCode, ExitCode(err))
+			default:
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout.String())
+		})
+	}
+}
+
+func TestScript_Run_tempFileRemoved(t *testing.T) {
+	s := &Script{Runner: &Local{}, TempFile: true}
+
+	var stdout bytes.Buffer
+	err := s.Run(context.Background(), nil, &stdout, nil, `echo "$0"`)
+	require.NoError(t, err)
+
+	file := strings.TrimSpace(stdout.String())
+	require.NotEmpty(t, file)
+	_, err = os.Stat(file)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestScript_Run_arguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    *Script
+		wantCalls []FakeCall
+	}{
+		{
+			name:   "shell",
+			script: &Script{},
+			wantCalls: []FakeCall{
+				{Command: "sh", Args: []string{"-s", "--", "a b"}},
+			},
+		},
+		{
+			name:   "non-shell",
+			script: &Script{Interpreter: []string{"/usr/bin/python3", "-u"}},
+			wantCalls: []FakeCall{
+				{Command: "/usr/bin/python3", Args: []string{"-u", "-", "a b"}},
+			},
+		},
+		{
+			name:   "quoted",
+			script: &Script{QuoteArgs: true},
+			wantCalls: []FakeCall{
+				{Command: "sh", Args: []string{"'-s'", "'--'", "'a b'"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			for _, call := range tt.wantCalls {
+				f.Respond(call.Command, call.Args, FakeResponse{})
+			}
+			tt.script.Runner = f
+
+			err := tt.script.Run(
+				context.Background(), nil, nil, nil, "script", "a b",
+			)
+			require.NoError(t, err)
+
+			calls := f.Calls()
+			require.Len(t, calls, len(tt.wantCalls))
+			for i, want := range tt.wantCalls {
+				assert.Equal(t, want.Command, calls[i].Command)
+				assert.Equal(t, want.Args, calls[i].Args)
+				assert.Equal(t, "script", string(calls[i].Stdin))
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrCommandNotAbsolute = fmt.Errorf(
+	"%w: command must be an absolute path", Err,
+)
+
+// lookPath resolves command according to the SearchPath and RequireAbsPath
+// fields of r. Commands are returned as is when neither is set, leaving them
+// to be resolved by os/exec using the PATH of the current process.
+func (r *Local) lookPath(command string) (string, error) {
+	if r.RequireAbsPath {
+		if !filepath.IsAbs(command) {
+			return "", fmt.Errorf("%w: %s", ErrCommandNotAbsolute, command)
+		}
+
+		return command, nil
+	}
+
+	if len(r.SearchPath) == 0 || strings.ContainsRune(command, '/') ||
+		strings.ContainsRune(command, filepath.Separator) {
+		return command, nil
+	}
+
+	for _, dir := range r.SearchPath {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+
+		path, err := exec.LookPath(filepath.Join(dir, command))
+		if err == nil {
+			return path, nil
+		}
+	}
+
+	return "", errCommandNotFound(command)
+}
+
+var _ LookPather = &Local{}
+
+// LookPath returns the path of the executable the command would be run with,
+// taking SearchPath and RequireAbsPath into account.
+func (r *Local) LookPath(_ context.Context, command string) (string, error) {
+	path, err := r.lookPath(command)
+	if err != nil {
+		return "", err
+	}
+
+	path, err = exec.LookPath(path)
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %w", ErrCommandNotFound, err)
+	}
+
+	return path, err
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScript(t *testing.T, dir, name, body string) {
+	t.Helper()
+
+	err := os.WriteFile(
+		filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), 0o755,
+	)
+	require.NoError(t, err)
+}
+
+func TestLocal_SearchPath(t *testing.T) {
+	trusted := t.TempDir()
+	hijack := t.TempDir()
+	writeScript(t, trusted, "runner-test-cmd", "echo trusted; exit $1")
+	writeScript(t, hijack, "runner-test-cmd", "echo hijacked; exit $1")
+	writeScript(t, hijack, "runner-test-other", "echo hijacked")
+	t.Setenv("PATH", hijack+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		name       string
+		searchPath []string
+		command    string
+		args       []string
+		want       string
+		wantErr    string
+		wantErrIs  []error
+	}{
+		{
+			name:    "process PATH without SearchPath",
+			command: "runner-test-cmd",
+			args:    []string{"0"},
+			want:    "hijacked\n",
+		},
+		{
+			name:       "SearchPath",
+			searchPath: []string{"relative", t.TempDir(), trusted},
+			command:    "runner-test-cmd",
+			args:       []string{"0"},
+			want:       "trusted\n",
+		},
+		{
+			name:       "not in SearchPath",
+			searchPath: []string{trusted},
+			command:    "runner-test-other",
+			wantErr: `runner: command not found: exec: "runner-test-other": ` +
+				"executable file not found in $PATH",
+			wantErrIs: []error{ErrCommandNotFound, exec.ErrNotFound},
+		},
+		{
+			name:       "path commands are not searched",
+			searchPath: []string{trusted},
+			command:    filepath.Join(hijack, "runner-test-other"),
+			want:       "hijacked\n",
+		},
+		{
+			name:       "exit error reports command as given",
+			searchPath: []string{trusted},
+			command:    "runner-test-cmd",
+			args:       []string{"3"},
+			want:       "trusted\n",
+			wantErr:    "runner-test-cmd: exit status 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r := &Local{SearchPath: tt.searchPath}
+
+			err := r.Run(nil, &stdout, nil, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			for _, target := range tt.wantErrIs {
+				assert.ErrorIs(t, err, target)
+			}
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestLocal_RequireAbsPath(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	require.NoError(t, err)
+	sh, err = filepath.Abs(sh)
+	require.NoError(t, err)
+
+	r := &Local{RequireAbsPath: true, SearchPath: []string{"/bin"}}
+
+	var stdout bytes.Buffer
+	err = r.Run(nil, &stdout, nil, sh, "-c", "echo hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", stdout.String())
+
+	for _, command := range []string{"sh", "./sh", "bin/sh"} {
+		err = r.Run(nil, nil, nil, command, "-c", "true")
+		assert.EqualError(
+			t, err,
+			"runner: command must be an absolute path: "+command,
+		)
+		assert.ErrorIs(t, err, ErrCommandNotAbsolute)
+	}
+}
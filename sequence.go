@@ -0,0 +1,131 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+type sequenceOp int
+
+const (
+	sequenceThen sequenceOp = iota
+	sequenceOrElse
+	sequenceFinally
+)
+
+type sequenceStep struct {
+	op      sequenceOp
+	command string
+	args    []string
+}
+
+// Sequence runs a list of commands on a Runner, each conditionally based on
+// the outcome of the commands before it, like a shell list joined with "&&"
+// and "||", but without ever invoking a shell.
+//
+// Like in a shell, the outcome used is that of the most recently run command,
+// and the list is evaluated left to right without precedence. So
+//
+//	seq := &Sequence{Runner: r}
+//	seq.Then("systemctl", "reload", "nginx").
+//		OrElse("systemctl", "restart", "nginx").
+//		Then("curl", "-fsS", "http://localhost/").
+//		Finally("rm", "-f", "/tmp/deploy.lock")
+//
+// behaves like the shell list
+//
+//	systemctl reload nginx || systemctl restart nginx &&
+//		curl -fsS http://localhost/; rm -f /tmp/deploy.lock
+//
+// restarting nginx only if reloading it failed, checking it responds if either
+// succeeded, and always removing the lock file.
+type Sequence struct {
+	// Runner is the Runner to run commands with. If not set, running commands
+	// will cause a panic.
+	Runner Runner
+
+	steps []sequenceStep
+}
+
+// Then adds a command which runs if the most recently run command succeeded,
+// like "&&" in a shell. When it is the first command, it always runs.
+func (s *Sequence) Then(command string, args ...string) *Sequence {
+	return s.add(sequenceThen, command, args)
+}
+
+// OrElse adds a command which runs if the most recently run command failed,
+// like "||" in a shell.
+func (s *Sequence) OrElse(command string, args ...string) *Sequence {
+	return s.add(sequenceOrElse, command, args)
+}
+
+// Finally adds a command which always runs once all commands added with Then
+// and OrElse have been evaluated, regardless of their outcome, in the order
+// Finally was called. It is intended for cleanup, like a deferred call.
+func (s *Sequence) Finally(command string, args ...string) *Sequence {
+	return s.add(sequenceFinally, command, args)
+}
+
+func (s *Sequence) add(
+	op sequenceOp,
+	command string,
+	args []string,
+) *Sequence {
+	s.steps = append(s.steps, sequenceStep{
+		op:      op,
+		command: command,
+		args:    copyStrings(args),
+	})
+
+	return s
+}
+
+// Run runs the sequence, writing the output of each command run to stdout and
+// stderr. It returns the error of the most recently run command added with
+// Then or OrElse, or nil if it succeeded. If they succeeded, but a command
+// added with Finally failed, the first such error is returned.
+//
+// When ctx is done, no further commands added with Then or OrElse are run,
+// and ctx's error is returned. Commands added with Finally are still run, with
+// a context carrying the values of ctx, but which is not canceled.
+func (s *Sequence) Run(
+	ctx context.Context,
+	stdout io.Writer,
+	stderr io.Writer,
+) error {
+	var err error
+	for _, step := range s.steps {
+		switch {
+		case step.op == sequenceFinally,
+			step.op == sequenceThen && err != nil,
+			step.op == sequenceOrElse && err == nil:
+			continue
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+
+			break
+		}
+
+		err = s.Runner.RunContext(
+			ctx, nil, stdout, stderr, step.command, step.args...,
+		)
+	}
+
+	finallyCtx := valuesContext{ctx}
+	for _, step := range s.steps {
+		if step.op != sequenceFinally {
+			continue
+		}
+
+		ferr := s.Runner.RunContext(
+			finallyCtx, nil, stdout, stderr, step.command, step.args...,
+		)
+		if err == nil {
+			err = ferr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequence_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(s *Sequence)
+		wantRan  []string
+		wantCode int
+	}{
+		{
+			name:    "empty",
+			build:   func(s *Sequence) {},
+			wantRan: nil,
+		},
+		{
+			name: "then runs while commands succeed",
+			build: func(s *Sequence) {
+				s.Then("ok", "1").Then("ok", "2").Then("fail", "3").
+					Then("ok", "4")
+			},
+			wantRan:  []string{"ok 1", "ok 2", "fail 3"},
+			wantCode: 3,
+		},
+		{
+			name: "or else runs on failure",
+			build: func(s *Sequence) {
+				s.Then("fail", "1").OrElse("ok", "2").Then("ok", "3")
+			},
+			wantRan: []string{"fail 1", "ok 2", "ok 3"},
+		},
+		{
+			name: "or else skipped on success",
+			build: func(s *Sequence) {
+				s.Then("ok", "1").OrElse("ok", "2").Then("ok", "3")
+			},
+			wantRan: []string{"ok 1", "ok 3"},
+		},
+		{
+			name: "failure skips to next or else",
+			build: func(s *Sequence) {
+				s.Then("fail", "1").Then("ok", "2").Then("ok", "3").
+					OrElse("fail", "4").OrElse("ok", "5")
+			},
+			wantRan: []string{"fail 1", "fail 4", "ok 5"},
+		},
+		{
+			name: "last failure is returned",
+			build: func(s *Sequence) {
+				s.Then("fail", "1").OrElse("fail", "2")
+			},
+			wantRan:  []string{"fail 1", "fail 2"},
+			wantCode: 2,
+		},
+		{
+			name: "finally always runs",
+			build: func(s *Sequence) {
+				s.Finally("ok", "cleanup").Then("fail", "1").
+					Then("ok", "2").Finally("fail", "cleanup")
+			},
+			wantRan:  []string{"fail 1", "ok cleanup", "fail cleanup"},
+			wantCode: 1,
+		},
+		{
+			name: "finally failure returned on success",
+			build: func(s *Sequence) {
+				s.Then("ok", "1").Finally("fail", "5").Finally("fail", "6")
+			},
+			wantRan:  []string{"ok 1", "fail 5", "fail 6"},
+			wantCode: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			for i := 1; i <= 6; i++ {
+				n := string(rune('0' + i))
+				f.Respond("ok", []string{n}, FakeResponse{})
+				f.Respond("fail", []string{n}, FakeResponse{ExitCode: i})
+			}
+			f.Respond("ok", []string{"cleanup"}, FakeResponse{})
+			f.Respond("fail", []string{"cleanup"}, FakeResponse{ExitCode: 9})
+
+			s := &Sequence{Runner: f}
+			tt.build(s)
+
+			err := s.Run(context.Background(), nil, nil)
+
+			var ran []string
+			for _, call := range f.Calls() {
+				ran = append(ran, strings.Join(
+					append([]string{call.Command}, call.Args...), " ",
+				))
+			}
+			assert.Equal(t, tt.wantRan, ran)
+			if tt.wantCode == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.wantCode, ExitCode(err))
+			}
+		})
+	}
+}
+
+func TestSequence_Run_contextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran []string
+	stub := &Stub{Fallback: func(
+		cctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		command string,
+		_ ...string,
+	) error {
+		ran = append(ran, command)
+		if command == "cancel" {
+			cancel()
+		}
+
+		return cctx.Err()
+	}}
+
+	s := &Sequence{Runner: stub}
+	s.Then("cancel").OrElse("recover").Then("next").Finally("cleanup")
+
+	err := s.Run(ctx, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []string{"cancel", "cleanup"}, ran)
+}
@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Session is a Runner that wraps another Runner, and applies shared settings,
+// like environment variables, a working directory, and a timeout, to each
+// command run through it. It suits flows which run many related commands with
+// the same settings, like provisioning a host.
+//
+// Settings are given as RunOptions to NewSession, and each may be overridden
+// for a single command by passing options to RunWith. Environment variables
+// are combined, with those given for the command taking precedence.
+type Session struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	mu   sync.RWMutex
+	opts runOptions
+}
+
+var _ Runner = &Session{}
+
+// NewSession returns a Session which runs commands on r, configured by opts.
+// Options setting stdin, stdout, or stderr are used for commands which are
+// not given their own.
+func NewSession(r Runner, opts ...RunOption) *Session {
+	s := &Session{Runner: r}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+
+	return s
+}
+
+// Run runs the command with the session's settings, by calling RunContext on
+// the underlying Runner.
+//
+// Will panic if Runner field is nil.
+func (s *Session) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return s.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext runs the command with the session's settings, by calling
+// RunContext on the underlying Runner. Environment variables and a working
+// directory carried by ctx, as given to RunWith, take precedence over the
+// session's. The session's timeout applies in addition to any deadline of
+// ctx.
+//
+// Will panic if Runner field is nil.
+func (s *Session) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	o := s.options()
+
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	if len(o.env) > 0 {
+		ctx = context.WithValue(
+			ctx, callEnvKey{}, append(o.env, callEnv(ctx)...),
+		)
+	}
+	if o.dir != "" && callDir(ctx) == "" {
+		ctx = withCallDir(ctx, o.dir)
+	}
+
+	if stdin == nil {
+		stdin = o.stdin
+	}
+	if stdout == nil {
+		stdout = o.stdout
+	}
+	if stderr == nil {
+		stderr = o.stderr
+	}
+
+	return s.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// RunWith runs the command with the session's settings, overridden by opts.
+// Unlike calling the package-level RunWith with the Session, a timeout given
+// in opts replaces the session's timeout, rather than applying in addition to
+// it.
+//
+// Will panic if Runner field is nil.
+func (s *Session) RunWith(
+	ctx context.Context,
+	opts []RunOption,
+	command string,
+	args ...string,
+) error {
+	o := s.options()
+	base := func(ro *runOptions) { *ro = o }
+
+	return RunWith(
+		ctx, s.Runner, append([]RunOption{base}, opts...), command, args...,
+	)
+}
+
+// Env replaces the environment variables the session adds to commands. It
+// does not call Env on the underlying Runner.
+func (s *Session) Env(env ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opts.env = copyStrings(env)
+}
+
+// Chdir sets the working directory of commands subsequently run through the
+// session, like "cd" in a shell.
+func (s *Session) Chdir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.opts.dir = dir
+}
+
+// options returns a copy of the session's options.
+func (s *Session) options() runOptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	o := s.opts
+	o.env = copyStrings(o.env)
+
+	return o
+}
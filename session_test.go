@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSession_RunWith(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+
+	tests := []struct {
+		name    string
+		session []RunOption
+		opts    []RunOption
+		want    string
+	}{
+		{
+			name: "no settings",
+			want: "|\n",
+		},
+		{
+			name: "session settings",
+			session: []RunOption{
+				WithEnv("A=session", "B=session"),
+				WithDir(dir),
+			},
+			want: "session|session\n" + dir + "\n",
+		},
+		{
+			name: "per-call overrides",
+			session: []RunOption{
+				WithEnv("A=session", "B=session"),
+				WithDir(dir),
+			},
+			opts: []RunOption{WithEnv("B=call"), WithDir(other)},
+			want: "session|call\n" + other + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(&Local{}, tt.session...)
+			script := `echo "$A|$B"; [ -z "$A" ] || pwd`
+
+			var stdout bytes.Buffer
+			opts := append([]RunOption{WithStdout(&stdout)}, tt.opts...)
+			err := s.RunWith(context.Background(), opts, "sh", "-c", script)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+
+			// The package-level RunWith gives the same result.
+			stdout.Reset()
+			err = RunWith(context.Background(), s, opts, "sh", "-c", script)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestSession_RunContext(t *testing.T) {
+	var out bytes.Buffer
+	s := NewSession(&Local{}, WithEnv("A=1"), WithStdout(&out))
+
+	err := s.RunContext(
+		context.Background(), nil, nil, nil, "sh", "-c", `echo "a=$A"`,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "a=1\n", out.String())
+
+	var own bytes.Buffer
+	err = s.Run(nil, &own, nil, "sh", "-c", `echo "own=$A"`)
+	require.NoError(t, err)
+	assert.Equal(t, "own=1\n", own.String())
+	assert.Equal(t, "a=1\n", out.String())
+
+	s.Env("A=2")
+	s.Chdir("/")
+	out.Reset()
+	err = s.Run(nil, nil, nil, "sh", "-c", `echo "a=$A"; pwd`)
+	require.NoError(t, err)
+	assert.Equal(t, "a=2\n/\n", out.String())
+}
+
+func TestSession_timeout(t *testing.T) {
+	s := NewSession(&Local{}, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	err := s.Run(nil, nil, nil, "sleep", "5")
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 4*time.Second)
+
+	// A per-call timeout replaces the session's.
+	err = s.RunWith(
+		context.Background(), []RunOption{WithTimeout(5 * time.Second)},
+		"sleep", "0.2",
+	)
+	assert.NoError(t, err)
+}
+
+func TestSession_Env(t *testing.T) {
+	f := &Fake{}
+	f.Respond("env", nil, FakeResponse{})
+	s := NewSession(f, WithEnv("A=1"))
+
+	s.Env("B=2")
+	err := s.RunWith(
+		context.Background(), []RunOption{WithEnv("C=3")}, "env",
+	)
+	require.NoError(t, err)
+	err = s.Run(nil, nil, nil, "env")
+	require.NoError(t, err)
+
+	calls := f.Calls()
+	require.Len(t, calls, 2)
+	assert.Equal(t, []string{"B=2", "C=3"}, calls[0].Env)
+	assert.Equal(t, []string{"B=2"}, calls[1].Env)
+}
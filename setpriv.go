@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Setpriv is a Runner that wraps another Runner and runs commands via setpriv,
+// allowing commands to run with reduced privileges.
+//
+// Note that setpriv will refuse to change the real/effective user or group ID
+// unless supplementary groups are also handled via one of Groups, ClearGroups,
+// InitGroups or KeepGroups.
+//
+// As setpriv passes its own environment through to the command it executes,
+// calls to Env are passed directly to the underlying Runner.
+type Setpriv struct {
+	// Runner is the underlying Runner to run commands with, after wrapping them
+	// with setpriv. If not set, running commands will cause a panic.
+	Runner Runner
+
+	// Reuid is the user name or ID to set the real and effective user ID to,
+	// passed via the --reuid flag. When empty, no --reuid flag will be used.
+	Reuid string
+
+	// Regid is the group name or ID to set the real and effective group ID to,
+	// passed via the --regid flag. When empty, no --regid flag will be used.
+	Regid string
+
+	// Groups is a list of supplementary group names or IDs to set, passed via
+	// the --groups flag. When empty, no --groups flag will be used.
+	Groups []string
+
+	// ClearGroups clears all supplementary groups (--clear-groups).
+	ClearGroups bool
+
+	// InitGroups initializes supplementary groups using initgroups(3)
+	// (--init-groups).
+	InitGroups bool
+
+	// KeepGroups preserves the current supplementary groups (--keep-groups).
+	KeepGroups bool
+
+	// InhCaps is a list of inheritable capabilities to set, passed via the
+	// --inh-caps flag, e.g. []string{"-all"}. When empty, no --inh-caps flag
+	// will be used.
+	InhCaps []string
+
+	// AmbientCaps is a list of ambient capabilities to set, passed via the
+	// --ambient-caps flag. When empty, no --ambient-caps flag will be used.
+	AmbientCaps []string
+
+	// BoundingSet is a list of changes to the capability bounding set, passed
+	// via the --bounding-set flag. When empty, no --bounding-set flag will be
+	// used.
+	BoundingSet []string
+
+	// NoNewPrivs sets the no_new_privs bit (--no-new-privs).
+	NoNewPrivs bool
+
+	// Args is a string slice of extra arguments to pass to setpriv.
+	Args []string
+}
+
+var _ Runner = &Setpriv{}
+
+var _ Validator = &Setpriv{}
+
+// Validate checks the Setpriv is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner is set, that at most
+// one of ClearGroups, InitGroups, and KeepGroups is set, and that values
+// passed to setpriv do not contain illegal characters, or begin with "-" where
+// they could be mistaken for flags. The underlying Runner is validated too.
+func (r *Setpriv) Validate() error {
+	errs := []error{
+		validateRunner("Setpriv.Runner", r.Runner),
+		validateWord("Setpriv.Reuid", r.Reuid),
+		validateWord("Setpriv.Regid", r.Regid),
+	}
+	for i, g := range r.Groups {
+		errs = append(
+			errs, validateWord(fmt.Sprintf("Setpriv.Groups[%d]", i), g),
+		)
+	}
+	for _, caps := range []struct {
+		field string
+		list  []string
+	}{
+		{"InhCaps", r.InhCaps},
+		{"AmbientCaps", r.AmbientCaps},
+		{"BoundingSet", r.BoundingSet},
+	} {
+		for i, c := range caps.list {
+			f := fmt.Sprintf("Setpriv.%s[%d]", caps.field, i)
+			errs = append(errs, validateChars(f, c, true))
+		}
+	}
+
+	groupOpts := 0
+	for _, set := range []bool{r.ClearGroups, r.InitGroups, r.KeepGroups} {
+		if set {
+			groupOpts++
+		}
+	}
+	if groupOpts > 1 {
+		errs = append(errs, &ValidationError{
+			Field: "Setpriv.ClearGroups",
+			Msg: "only one of ClearGroups, InitGroups, and KeepGroups " +
+				"may be set",
+		})
+	}
+
+	return firstError(append(errs, Validate(r.Runner))...)
+}
+
+// Run executes the command via setpriv by calling Run on the underlying
+// Runner. Will panic if Runner field is nil.
+func (r *Setpriv) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.Run(
+		stdin, stdout, stderr, "setpriv", r.args(command, args)...,
+	)
+}
+
+// RunContext executes the command via setpriv by calling RunContext on the
+// underlying Runner. Will panic if Runner field is nil.
+func (r *Setpriv) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.Runner.RunContext(
+		ctx, stdin, stdout, stderr, "setpriv", r.args(command, args)...,
+	)
+}
+
+func (r *Setpriv) args(command string, args []string) []string {
+	setprivArgs := []string{}
+
+	if r.Reuid != "" {
+		setprivArgs = append(setprivArgs, "--reuid", r.Reuid)
+	}
+	if r.Regid != "" {
+		setprivArgs = append(setprivArgs, "--regid", r.Regid)
+	}
+	if len(r.Groups) > 0 {
+		setprivArgs = append(
+			setprivArgs, "--groups", strings.Join(r.Groups, ","),
+		)
+	}
+	if r.ClearGroups {
+		setprivArgs = append(setprivArgs, "--clear-groups")
+	}
+	if r.InitGroups {
+		setprivArgs = append(setprivArgs, "--init-groups")
+	}
+	if r.KeepGroups {
+		setprivArgs = append(setprivArgs, "--keep-groups")
+	}
+	if len(r.InhCaps) > 0 {
+		setprivArgs = append(
+			setprivArgs, "--inh-caps", strings.Join(r.InhCaps, ","),
+		)
+	}
+	if len(r.AmbientCaps) > 0 {
+		setprivArgs = append(
+			setprivArgs, "--ambient-caps", strings.Join(r.AmbientCaps, ","),
+		)
+	}
+	if len(r.BoundingSet) > 0 {
+		setprivArgs = append(
+			setprivArgs, "--bounding-set", strings.Join(r.BoundingSet, ","),
+		)
+	}
+	if r.NoNewPrivs {
+		setprivArgs = append(setprivArgs, "--no-new-privs")
+	}
+	setprivArgs = append(setprivArgs, r.Args...)
+	setprivArgs = append(setprivArgs, "--", command)
+	setprivArgs = append(setprivArgs, args...)
+
+	return setprivArgs
+}
+
+// Env sets the environment by calling Env on the underlying Runner. Will panic
+// if Runner field is nil.
+func (r *Setpriv) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,232 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var setprivTestCases = []struct {
+	name     string
+	runner   Setpriv
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+	command  string
+	args     []string
+	err      error
+	wantArgs []string
+	wantErr  string
+}{
+	{
+		name:     "setpriv",
+		runner:   Setpriv{},
+		stdout:   &bytes.Buffer{},
+		stderr:   &bytes.Buffer{},
+		command:  "id",
+		wantArgs: []string{"--", "id"},
+	},
+	{
+		name:     "stdin",
+		runner:   Setpriv{NoNewPrivs: true},
+		stdin:    bytes.NewBufferString("foo\nbar"),
+		command:  "cat",
+		wantArgs: []string{"--no-new-privs", "--", "cat"},
+	},
+	{
+		name: "with Reuid, Regid and Groups",
+		runner: Setpriv{
+			Reuid:  "1000",
+			Regid:  "web",
+			Groups: []string{"web", "ssl-cert"},
+		},
+		command: "id",
+		wantArgs: []string{
+			"--reuid", "1000", "--regid", "web",
+			"--groups", "web,ssl-cert", "--", "id",
+		},
+	},
+	{
+		name: "with ClearGroups, InitGroups and KeepGroups",
+		runner: Setpriv{
+			ClearGroups: true,
+			InitGroups:  true,
+			KeepGroups:  true,
+		},
+		command: "id",
+		wantArgs: []string{
+			"--clear-groups", "--init-groups", "--keep-groups", "--", "id",
+		},
+	},
+	{
+		name: "with capabilities",
+		runner: Setpriv{
+			InhCaps:     []string{"-all"},
+			AmbientCaps: []string{"+net_bind_service"},
+			BoundingSet: []string{"-all", "+net_bind_service"},
+		},
+		command: "nginx",
+		wantArgs: []string{
+			"--inh-caps", "-all",
+			"--ambient-caps", "+net_bind_service",
+			"--bounding-set", "-all,+net_bind_service",
+			"--", "nginx",
+		},
+	},
+	{
+		name: "with everything",
+		runner: Setpriv{
+			Reuid:       "web",
+			Regid:       "web",
+			InitGroups:  true,
+			InhCaps:     []string{"-all"},
+			NoNewPrivs:  true,
+			Args:        []string{"--reset-env"},
+			BoundingSet: []string{"-all"},
+		},
+		command: "myapp",
+		args:    []string{"serve", "-p", "8080"},
+		wantArgs: []string{
+			"--reuid", "web", "--regid", "web", "--init-groups",
+			"--inh-caps", "-all", "--bounding-set", "-all", "--no-new-privs",
+			"--reset-env", "--", "myapp", "serve", "-p", "8080",
+		},
+	},
+	{
+		name:     "error",
+		runner:   Setpriv{},
+		command:  "zfs",
+		args:     []string{"list"},
+		err:      errors.New("zfs: command not found"),
+		wantArgs: []string{"--", "zfs", "list"},
+		wantErr:  "zfs: command not found",
+	},
+}
+
+func TestSetpriv_Run(t *testing.T) {
+	for _, tt := range setprivTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().Run(
+				tt.stdin, tt.stdout, tt.stderr, "setpriv", tt.wantArgs,
+			).Return(tt.err)
+
+			s := tt.runner
+			s.Runner = r
+
+			err := s.Run(tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetpriv_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	for _, tt := range setprivTestCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			r.EXPECT().RunContext(
+				gomockctx.Eq(ctx),
+				tt.stdin, tt.stdout, tt.stderr, "setpriv", tt.wantArgs,
+			).Return(tt.err)
+
+			s := tt.runner
+			s.Runner = r
+
+			err := s.RunContext(
+				ctx, tt.stdin, tt.stdout, tt.stderr, tt.command, tt.args...,
+			)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetpriv_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env("FOO=BAR", "PORT=8080")
+
+	s := &Setpriv{Runner: r}
+	s.Env("FOO=BAR", "PORT=8080")
+}
+
+func TestSetpriv_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Setpriv
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name: "valid",
+			runner: &Setpriv{
+				Runner:      &Local{},
+				Reuid:       "nobody",
+				ClearGroups: true,
+				InhCaps:     []string{"-all"},
+			},
+		},
+		{
+			name:    "no runner",
+			runner:  &Setpriv{},
+			wantErr: "runner: invalid: Setpriv.Runner: must be set",
+		},
+		{
+			name:    "reuid is a flag",
+			runner:  &Setpriv{Runner: &Local{}, Reuid: "--reset-env"},
+			wantErr: "runner: invalid: Setpriv.Reuid: must not begin with -",
+		},
+		{
+			name: "capability with space",
+			runner: &Setpriv{
+				Runner: &Local{}, BoundingSet: []string{"-all", "+a b"},
+			},
+			wantErr: "runner: invalid: Setpriv.BoundingSet[1]: " +
+				`contains illegal character ' '`,
+		},
+		{
+			name: "conflicting group options",
+			runner: &Setpriv{
+				Runner: &Local{}, ClearGroups: true, KeepGroups: true,
+			},
+			wantErr: "runner: invalid: Setpriv.ClearGroups: only one of " +
+				"ClearGroups, InitGroups, and KeepGroups may be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Singleflight is a Runner that wraps another Runner, and coalesces identical
+// concurrent commands into a single execution. Commands are identical if they
+// have the same command, arguments, environment variables, and working
+// directory given with WithDir.
+//
+// The output of the shared execution is captured, and once it completes, is
+// written to the stdout and stderr writers of each caller, in the order it was
+// written by the command. Each caller receives the same error.
+//
+// Commands with a non-nil stdin are never coalesced, as their input may
+// differ. Commands run with RunContext use the context of the caller which
+// started the shared execution, so cancelling it fails the command for all
+// callers.
+//
+// Calls to Env are passed to the underlying Runner, and also retained to
+// identify identical commands.
+type Singleflight struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	mu    sync.Mutex
+	calls map[string]*singleflightCall
+	env   []string
+}
+
+type singleflightCall struct {
+	done       chan struct{}
+	transcript *Transcript
+	err        error
+}
+
+var _ Runner = &Singleflight{}
+
+// Run calls Run on the underlying Runner, unless an identical command is
+// already running, in which case its result is shared.
+//
+// Will panic if Runner field is nil.
+func (r *Singleflight) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if stdin != nil {
+		return r.Runner.Run(stdin, stdout, stderr, command, args...)
+	}
+
+	return r.do(
+		context.Background(), stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.Run(nil, stdout, stderr, command, args...)
+		},
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, unless an identical
+// command is already running, in which case its result is shared.
+//
+// Will panic if Runner field is nil.
+func (r *Singleflight) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if stdin != nil {
+		return r.Runner.RunContext(
+			ctx, stdin, stdout, stderr, command, args...,
+		)
+	}
+
+	return r.do(
+		ctx, stdout, stderr, command, args,
+		func(stdout, stderr io.Writer) error {
+			return r.Runner.RunContext(
+				ctx, nil, stdout, stderr, command, args...,
+			)
+		},
+	)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Singleflight) Env(env ...string) {
+	r.mu.Lock()
+	r.env = copyStrings(env)
+	r.mu.Unlock()
+
+	r.Runner.Env(env...)
+}
+
+func (r *Singleflight) do(
+	ctx context.Context,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args []string,
+	run func(stdout, stderr io.Writer) error,
+) error {
+	r.mu.Lock()
+	key := r.key(ctx, command, args)
+	call, ok := r.calls[key]
+	if !ok {
+		call = &singleflightCall{
+			done:       make(chan struct{}),
+			transcript: &Transcript{},
+		}
+		if r.calls == nil {
+			r.calls = map[string]*singleflightCall{}
+		}
+		r.calls[key] = call
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		call.err = run(call.transcript.Stdout(), call.transcript.Stderr())
+
+		r.mu.Lock()
+		delete(r.calls, key)
+		r.mu.Unlock()
+
+		close(call.done)
+	} else {
+		<-call.done
+	}
+
+	err := replayChunks(call.transcript.Chunks(), stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	return call.err
+}
+
+// key returns a string identifying the command. It must be called with mu
+// held.
+func (r *Singleflight) key(
+	ctx context.Context,
+	command string,
+	args []string,
+) string {
+	return commandKey(ctx, r.env, command, args)
+}
+
+// commandKey returns a string identifying a command by its name, arguments,
+// environment variables, and working directory given with WithDir.
+func commandKey(
+	ctx context.Context,
+	env []string,
+	command string,
+	args []string,
+) string {
+	parts := make([]string, 0, len(args)+len(env)+4)
+	parts = append(parts, command)
+	parts = append(parts, args...)
+	parts = append(parts, "\x00env")
+	parts = append(parts, mergeEnv(env, callEnv(ctx))...)
+	parts = append(parts, "\x00dir", callDir(ctx))
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// blockingStub returns a Stub which writes output and returns err once
+// release is closed, counting the number of executions in count.
+func blockingStub(
+	release <-chan struct{},
+	count *atomic.Int32,
+	err error,
+) *Stub {
+	return &Stub{Fallback: func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, stderr io.Writer,
+		command string,
+		args ...string,
+	) error {
+		count.Add(1)
+		<-release
+
+		_, _ = io.WriteString(stdout, "out:"+strings.Join(args, " ")+"\n")
+		_, _ = io.WriteString(stderr, "err\n")
+		_, _ = io.WriteString(stdout, "done\n")
+
+		return err
+	}}
+}
+
+func TestSingleflight_RunContext(t *testing.T) {
+	release := make(chan struct{})
+	var count atomic.Int32
+	r := &Singleflight{
+		Runner: blockingStub(release, &count, &FakeExitError{Code: 1}),
+	}
+
+	const callers = 5
+	stdouts := make([]bytes.Buffer, callers)
+	stderrs := make([]bytes.Buffer, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = r.RunContext(
+				context.Background(), nil, &stdouts[i], &stderrs[i],
+				"zfs", "list",
+			)
+		}(i)
+	}
+
+	// Wait for all callers to be waiting on the shared execution.
+	require.Eventually(t, func() bool {
+		return count.Load() == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), count.Load())
+	for i := 0; i < callers; i++ {
+		assert.EqualError(t, errs[i], ": exit status 1")
+		assert.Equal(t, "out:list\ndone\n", stdouts[i].String())
+		assert.Equal(t, "err\n", stderrs[i].String())
+	}
+	assert.Empty(t, r.calls)
+}
+
+func TestSingleflight_Run_combinedOrder(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	var count atomic.Int32
+
+	r := &Singleflight{Runner: blockingStub(release, &count, nil)}
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Run(nil, &buf, &buf, "zfs", "list"))
+	assert.Equal(t, "out:list\nerr\ndone\n", buf.String())
+
+	require.NoError(t, r.Run(nil, nil, nil, "zfs", "list"))
+	assert.Equal(t, int32(2), count.Load())
+}
+
+func TestSingleflight_distinctCommands(t *testing.T) {
+	release := make(chan struct{})
+	var count atomic.Int32
+
+	r := &Singleflight{Runner: blockingStub(release, &count, nil)}
+
+	runs := []func() error{
+		func() error {
+			return r.Run(nil, nil, nil, "zfs", "list")
+		},
+		func() error {
+			return r.Run(nil, nil, nil, "zfs", "list", "-H")
+		},
+		func() error {
+			return r.Run(nil, nil, nil, "zpool", "list")
+		},
+		func() error {
+			return RunWith(
+				context.Background(), r,
+				[]RunOption{WithEnv("FOO=bar")}, "zfs", "list",
+			)
+		},
+		func() error {
+			return RunWith(
+				context.Background(), r,
+				[]RunOption{WithDir("/tmp")}, "zfs", "list",
+			)
+		},
+		func() error {
+			return r.Run(strings.NewReader(""), nil, nil, "zfs", "list")
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func(run func() error) {
+			defer wg.Done()
+			assert.NoError(t, run())
+		}(run)
+	}
+
+	require.Eventually(t, func() bool {
+		return count.Load() == int32(len(runs))
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestSingleflight_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Singleflight{Runner: m}
+	r.Env("FOO=bar")
+
+	assert.Equal(t, []string{"FOO=bar"}, r.env)
+	assert.NotEqual(
+		t,
+		r.key(context.Background(), "zfs", nil),
+		(&Singleflight{}).key(context.Background(), "zfs", nil),
+	)
+}
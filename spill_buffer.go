@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var ErrSpillBuffer = fmt.Errorf("%w: spill buffer", Err)
+
+// DefaultSpillMaxMemory is the default value for SpillBuffer.MaxMemory.
+const DefaultSpillMaxMemory = 8 << 20
+
+// SpillBuffer is an io.Writer for capturing command output of unpredictable
+// size. It keeps up to MaxMemory bytes in memory, and spills any further data
+// to a temporary file. Once written, the data can be read back with Reader.
+//
+// Close must be called when done, to remove any temporary file. SpillBuffer
+// is safe for concurrent use, and its zero value is ready to use.
+type SpillBuffer struct {
+	// MaxMemory is the number of bytes to keep in memory before spilling to
+	// disk. Defaults to DefaultSpillMaxMemory.
+	MaxMemory int
+
+	// Dir is the directory the temporary file is created in. Defaults to the
+	// default directory for temporary files, as returned by os.TempDir.
+	Dir string
+
+	mu   sync.Mutex
+	mem  []byte
+	file *os.File
+	size int64
+}
+
+var _ io.WriteCloser = &SpillBuffer{}
+
+// NewSpillBuffer returns a SpillBuffer which keeps up to maxMemory bytes in
+// memory.
+func NewSpillBuffer(maxMemory int) *SpillBuffer {
+	return &SpillBuffer{MaxMemory: maxMemory}
+}
+
+// Write appends p to the buffer, creating the temporary file when the data
+// first exceeds MaxMemory.
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	limit := b.MaxMemory
+	if limit <= 0 {
+		limit = DefaultSpillMaxMemory
+	}
+
+	n := 0
+	if len(b.mem) < limit {
+		n = limit - len(b.mem)
+		if n > len(p) {
+			n = len(p)
+		}
+		b.mem = append(b.mem, p[:n]...)
+		b.size += int64(n)
+	}
+
+	if n == len(p) {
+		return n, nil
+	}
+
+	if b.file == nil {
+		f, err := os.CreateTemp(b.Dir, "runner-spill-*")
+		if err != nil {
+			return n, fmt.Errorf("%w: %w", ErrSpillBuffer, err)
+		}
+		b.file = f
+	}
+
+	m, err := b.file.WriteAt(p[n:], b.size-int64(len(b.mem)))
+	b.size += int64(m)
+	if err != nil {
+		return n + m, fmt.Errorf("%w: %w", ErrSpillBuffer, err)
+	}
+
+	return len(p), nil
+}
+
+// Len returns the number of bytes written.
+func (b *SpillBuffer) Len() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+// Spilled reports if data has been spilled to a temporary file.
+func (b *SpillBuffer) Spilled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.file != nil
+}
+
+// Reader returns an io.ReadSeeker over the data written so far. Data written
+// afterwards is not included. The reader must not be used after Close has
+// been called.
+func (b *SpillBuffer) Reader() io.ReadSeeker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ra := &spillReaderAt{mem: b.mem[:len(b.mem):len(b.mem)], file: b.file}
+
+	return io.NewSectionReader(ra, 0, b.size)
+}
+
+// Close removes the temporary file, if any. The buffer must not be used after
+// Close has been called.
+func (b *SpillBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.mem = nil
+	b.size = 0
+	if b.file == nil {
+		return nil
+	}
+
+	f := b.file
+	b.file = nil
+
+	err := f.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+
+	return err
+}
+
+// spillReaderAt reads from the in-memory data of a SpillBuffer, followed by
+// its temporary file.
+type spillReaderAt struct {
+	mem  []byte
+	file *os.File
+}
+
+func (r *spillReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < int64(len(r.mem)) {
+		n = copy(p, r.mem[off:])
+		if n == len(p) {
+			return n, nil
+		}
+	}
+
+	if r.file == nil {
+		return n, io.EOF
+	}
+
+	m, err := r.file.ReadAt(p[n:], off+int64(n)-int64(len(r.mem)))
+
+	return n + m, err
+}
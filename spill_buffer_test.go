@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxMemory   int
+		writes      []string
+		wantSpilled bool
+	}{
+		{
+			name:      "empty",
+			maxMemory: 4,
+		},
+		{
+			name:      "in memory",
+			maxMemory: 10,
+			writes:    []string{"hello", "world"},
+		},
+		{
+			name:        "spilled across write",
+			maxMemory:   4,
+			writes:      []string{"hel", "lo wor", "ld"},
+			wantSpilled: true,
+		},
+		{
+			name:        "spilled on boundary",
+			maxMemory:   5,
+			writes:      []string{"hello", "world"},
+			wantSpilled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewSpillBuffer(tt.maxMemory)
+			b.Dir = t.TempDir()
+
+			for _, s := range tt.writes {
+				n, err := b.Write([]byte(s))
+				require.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+
+			want := strings.Join(tt.writes, "")
+			assert.Equal(t, int64(len(want)), b.Len())
+			assert.Equal(t, tt.wantSpilled, b.Spilled())
+
+			r := b.Reader()
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, want, string(got))
+
+			if len(want) > 3 {
+				_, err = r.Seek(3, io.SeekStart)
+				require.NoError(t, err)
+				got, err = io.ReadAll(r)
+				require.NoError(t, err)
+				assert.Equal(t, want[3:], string(got))
+			}
+
+			entries, err := os.ReadDir(b.Dir)
+			require.NoError(t, err)
+			if tt.wantSpilled {
+				assert.Len(t, entries, 1)
+			} else {
+				assert.Empty(t, entries)
+			}
+
+			require.NoError(t, b.Close())
+
+			entries, err = os.ReadDir(b.Dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestSpillBuffer_Reader_snapshot(t *testing.T) {
+	b := NewSpillBuffer(2)
+	b.Dir = t.TempDir()
+	defer b.Close()
+
+	_, err := io.WriteString(b, "abcd")
+	require.NoError(t, err)
+
+	r := b.Reader()
+
+	_, err = io.WriteString(b, "efgh")
+	require.NoError(t, err)
+
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "abcd", string(got))
+
+	got, err = io.ReadAll(b.Reader())
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh", string(got))
+}
+
+func TestSpillBuffer_createError(t *testing.T) {
+	b := NewSpillBuffer(2)
+	b.Dir = "/nonexistent/runner-test"
+
+	n, err := io.WriteString(b, "abcd")
+	assert.ErrorIs(t, err, ErrSpillBuffer)
+	assert.Equal(t, 2, n)
+	assert.NoError(t, b.Close())
+}
+
+func TestSpillBuffer_Local(t *testing.T) {
+	b := &SpillBuffer{MaxMemory: 1024, Dir: t.TempDir()}
+	defer b.Close()
+
+	err := (&Local{}).Run(nil, b, nil, "head", "-c", "100000", "/dev/zero")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100000), b.Len())
+	assert.True(t, b.Spilled())
+
+	n, err := io.Copy(io.Discard, b.Reader())
+	require.NoError(t, err)
+	assert.Equal(t, int64(100000), n)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 var (
@@ -46,10 +49,16 @@ type SSHCLI struct {
 	// will be used.
 	Login string
 
+	// Dir is the working directory on the remote host to run commands in.
+	// When set, commands are prefixed with "cd <Dir> &&", relying on the
+	// remote user's shell to change directory.
+	Dir string
+
 	// Args is a string slice of extra arguments to pass to ssh.
 	Args []string
 
-	env []string
+	env   []string
+	envMu sync.RWMutex
 }
 
 var _ Runner = &SSHCLI{}
@@ -66,7 +75,7 @@ func (rsc *SSHCLI) Run(
 	command string,
 	args ...string,
 ) error {
-	sshArgs, err := rsc.args(command, args)
+	sshArgs, err := rsc.args(context.Background(), command, args)
 	if err != nil {
 		return err
 	}
@@ -87,15 +96,21 @@ func (rsc *SSHCLI) RunContext(
 	command string,
 	args ...string,
 ) error {
-	sshArgs, err := rsc.args(command, args)
+	sshArgs, err := rsc.args(ctx, command, args)
 	if err != nil {
 		return err
 	}
 
-	return rsc.Runner.RunContext(ctx, stdin, stdout, stderr, "ssh", sshArgs...)
+	return rsc.Runner.RunContext(
+		withoutCallOptions(ctx), stdin, stdout, stderr, "ssh", sshArgs...,
+	)
 }
 
-func (rsc *SSHCLI) args(command string, args []string) ([]string, error) {
+func (rsc *SSHCLI) args(
+	ctx context.Context,
+	command string,
+	args []string,
+) ([]string, error) {
 	if rsc.Destination == "" {
 		return nil, ErrSSHCLINoDestination
 	}
@@ -116,9 +131,17 @@ func (rsc *SSHCLI) args(command string, args []string) ([]string, error) {
 	}
 	sshArgs = append(sshArgs, rsc.Destination, "--")
 
-	if len(rsc.env) > 0 {
+	dir := rsc.Dir
+	if d := callDir(ctx); d != "" {
+		dir = d
+	}
+	if dir != "" {
+		sshArgs = append(sshArgs, "cd", shellQuote(dir), "&&")
+	}
+
+	if env := DedupEnv(mergeEnv(rsc.environ(), callEnv(ctx))...); len(env) > 0 {
 		sshArgs = append(sshArgs, "env")
-		sshArgs = append(sshArgs, rsc.env...)
+		sshArgs = append(sshArgs, env...)
 	}
 	sshArgs = append(sshArgs, command)
 	sshArgs = append(sshArgs, args...)
@@ -126,8 +149,94 @@ func (rsc *SSHCLI) args(command string, args []string) ([]string, error) {
 	return sshArgs, nil
 }
 
-// Env sets the environment by calling Env on the underlying Runner. Will panic
-// if Runner field is nil on SSH instance.
+var _ Validator = &SSHCLI{}
+
+// Validate checks the SSHCLI is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner and Destination are
+// set, that values passed to ssh do not contain illegal characters, or begin
+// with "-" where they could be mistaken for flags, that a login is not given
+// both in Destination and Login, and that a port is not given both in a
+// Destination URI and Port. The underlying Runner is validated too.
+func (rsc *SSHCLI) Validate() error {
+	if rsc.Destination == "" {
+		return &ValidationError{
+			Field: "SSHCLI.Destination",
+			Msg:   "must be set",
+			Err:   ErrSSHCLINoDestination,
+		}
+	}
+
+	return firstError(
+		validateRunner("SSHCLI.Runner", rsc.Runner),
+		validateWord("SSHCLI.Destination", rsc.Destination),
+		validatePort("SSHCLI.Port", rsc.Port),
+		validateWord("SSHCLI.Login", rsc.Login),
+		validateChars("SSHCLI.IdentityFile", rsc.IdentityFile, false),
+		validateChars("SSHCLI.Dir", rsc.Dir, false),
+		validateEnv("SSHCLI.Env", rsc.environ()),
+		rsc.validateDestination(),
+		Validate(rsc.Runner),
+	)
+}
+
+// validateDestination checks the login and port given in Destination do not
+// conflict with Login and Port.
+func (rsc *SSHCLI) validateDestination() error {
+	host, isURI := strings.CutPrefix(rsc.Destination, "ssh://")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		if rsc.Login != "" {
+			return &ValidationError{
+				Field: "SSHCLI.Login",
+				Msg:   "conflicts with login given in Destination",
+			}
+		}
+		host = host[i+1:]
+	}
+
+	if _, port, err := net.SplitHostPort(host); isURI && err == nil &&
+		port != "" && rsc.Port != 0 {
+		return &ValidationError{
+			Field: "SSHCLI.Port",
+			Msg:   "conflicts with port given in Destination",
+		}
+	}
+
+	return nil
+}
+
+var _ LookPather = &SSHCLI{}
+
+// LookPath returns the path of the executable the command would be run with
+// on the remote host, as reported by "command -v" run within "sh -c", with
+// the environment set via Env.
+func (rsc *SSHCLI) LookPath(
+	ctx context.Context,
+	command string,
+) (string, error) {
+	return lookPathCommandV(
+		ctx, rsc, command,
+		"sh", "-c", shellQuote(commandVScript), "sh", shellQuote(command),
+	)
+}
+
+// Env sets the environment variables which will be provided to commands run
+// on the remote host, via the env command. Env is not called on the underlying
+// Runner, so the local ssh command itself is not affected.
 func (rsc *SSHCLI) Env(env ...string) {
-	rsc.env = env
+	rsc.envMu.Lock()
+	defer rsc.envMu.Unlock()
+
+	rsc.env = copyStrings(env)
+}
+
+func (rsc *SSHCLI) environ() []string {
+	rsc.envMu.RLock()
+	defer rsc.envMu.RUnlock()
+
+	return rsc.env
+}
+
+// shellQuote quotes s for safe use as a single word in a POSIX shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
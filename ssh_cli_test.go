@@ -19,6 +19,7 @@ func TestSSHCLI_Run(t *testing.T) {
 		Port         int
 		IdentityFile string
 		Login        string
+		Dir          string
 		Args         []string
 	}
 	type args struct {
@@ -215,6 +216,25 @@ func TestSSHCLI_Run(t *testing.T) {
 				"--", "env", "FOO=BAR", "PORT=8080", "myapp", "run", "-a",
 			},
 		},
+		{
+			name: "with duplicate Env",
+			env:  []string{"PORT=80", "FOO=BAR", "PORT=8080"},
+			fields: fields{
+				Destination: "narnia.local",
+			},
+			args: args{
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "myapp",
+				args:    []string{"run", "-a"},
+			},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"narnia.local",
+				"--", "env", "FOO=BAR", "PORT=8080", "myapp", "run", "-a",
+			},
+		},
 		{
 			name: "with Args",
 			fields: fields{
@@ -261,6 +281,45 @@ func TestSSHCLI_Run(t *testing.T) {
 				"--", "env", "FOO=BAR", "PORT=8080", "docker", "ps", "-a",
 			},
 		},
+		{
+			name: "with Dir",
+			fields: fields{
+				Destination: "narnia.local",
+				Dir:         "/opt/it's here",
+			},
+			args: args{
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"narnia.local", "--", "cd", `'/opt/it'\''s here'`, "&&",
+				"docker", "ps", "-a",
+			},
+		},
+		{
+			name: "with Dir and Env",
+			env:  []string{"FOO=BAR"},
+			fields: fields{
+				Destination: "narnia.local",
+				Dir:         "/opt",
+			},
+			args: args{
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"narnia.local", "--", "cd", "'/opt'", "&&",
+				"env", "FOO=BAR", "docker", "ps", "-a",
+			},
+		},
 		{
 			name:   "no destination",
 			fields: fields{},
@@ -311,6 +370,7 @@ func TestSSHCLI_Run(t *testing.T) {
 				Port:         tt.fields.Port,
 				IdentityFile: tt.fields.IdentityFile,
 				Login:        tt.fields.Login,
+				Dir:          tt.fields.Dir,
 				Args:         tt.fields.Args,
 			}
 
@@ -343,6 +403,7 @@ func TestSSHCLI_RunContext(t *testing.T) {
 		Port         int
 		IdentityFile string
 		Login        string
+		Dir          string
 		Args         []string
 	}
 
@@ -599,6 +660,47 @@ func TestSSHCLI_RunContext(t *testing.T) {
 				"--", "env", "FOO=BAR", "PORT=8080", "docker", "ps", "-a",
 			},
 		},
+		{
+			name: "with Dir",
+			fields: fields{
+				Destination: "narnia.local",
+				Dir:         "/opt/it's here",
+			},
+			args: args{
+				ctx:     ctx,
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"narnia.local", "--", "cd", `'/opt/it'\''s here'`, "&&",
+				"docker", "ps", "-a",
+			},
+		},
+		{
+			name: "with Dir and Env",
+			env:  []string{"FOO=BAR"},
+			fields: fields{
+				Destination: "narnia.local",
+				Dir:         "/opt",
+			},
+			args: args{
+				ctx:     ctx,
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"narnia.local", "--", "cd", "'/opt'", "&&",
+				"env", "FOO=BAR", "docker", "ps", "-a",
+			},
+		},
 		{
 			name:   "no destination",
 			fields: fields{},
@@ -652,6 +754,7 @@ func TestSSHCLI_RunContext(t *testing.T) {
 				Port:         tt.fields.Port,
 				IdentityFile: tt.fields.IdentityFile,
 				Login:        tt.fields.Login,
+				Dir:          tt.fields.Dir,
 				Args:         tt.fields.Args,
 			}
 
@@ -724,3 +827,113 @@ func TestSSHCLI_Env(t *testing.T) {
 		})
 	}
 }
+
+func TestSSHCLI_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *SSHCLI
+		env     []string
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "valid",
+			runner: &SSHCLI{Runner: &Local{}, Destination: "web1", Port: 22},
+		},
+		{
+			name: "valid URI",
+			runner: &SSHCLI{
+				Runner:      &Local{},
+				Destination: "ssh://deploy@[2001:db8::1]:2222",
+			},
+		},
+		{
+			name:    "no runner",
+			runner:  &SSHCLI{Destination: "web1"},
+			wantErr: "runner: invalid: SSHCLI.Runner: must be set",
+		},
+		{
+			name:    "no destination",
+			runner:  &SSHCLI{Runner: &Local{}},
+			wantErr: "runner: invalid: SSHCLI.Destination: must be set",
+			wantIs:  ErrSSHCLINoDestination,
+		},
+		{
+			name: "destination is a flag",
+			runner: &SSHCLI{
+				Runner:      &Local{},
+				Destination: "-oProxyCommand=sh",
+			},
+			wantErr: "runner: invalid: SSHCLI.Destination: " +
+				"must not begin with -",
+		},
+		{
+			name: "login with space",
+			runner: &SSHCLI{
+				Runner:      &Local{},
+				Destination: "web1",
+				Login:       "a b",
+			},
+			wantErr: "runner: invalid: SSHCLI.Login: " +
+				"contains illegal character ' '",
+		},
+		{
+			name:    "invalid port",
+			runner:  &SSHCLI{Runner: &Local{}, Destination: "web1", Port: -1},
+			wantErr: "runner: invalid: SSHCLI.Port: invalid port -1",
+		},
+		{
+			name: "login conflict",
+			runner: &SSHCLI{
+				Runner:      &Local{},
+				Destination: "root@web1",
+				Login:       "deploy",
+			},
+			wantErr: "runner: invalid: SSHCLI.Login: " +
+				"conflicts with login given in Destination",
+		},
+		{
+			name: "port conflict",
+			runner: &SSHCLI{
+				Runner:      &Local{},
+				Destination: "ssh://web1:2222",
+				Port:        22,
+			},
+			wantErr: "runner: invalid: SSHCLI.Port: " +
+				"conflicts with port given in Destination",
+		},
+		{
+			name:   "invalid env",
+			runner: &SSHCLI{Runner: &Local{}, Destination: "web1"},
+			env:    []string{"A B=1"},
+			wantErr: "runner: invalid: SSHCLI.Env[0]: " +
+				"contains illegal character ' '",
+		},
+		{
+			name: "invalid underlying runner",
+			runner: &SSHCLI{
+				Runner:      &Sudo{Runner: &Local{}, User: "-s"},
+				Destination: "web1",
+			},
+			wantErr: "runner: invalid: Sudo.User: must not begin with -",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.runner.Env(tt.env...)
+
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+			if tt.wantIs != nil {
+				assert.ErrorIs(t, err, tt.wantIs)
+			}
+		})
+	}
+}
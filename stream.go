@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrStream           = fmt.Errorf("%w: stream", Err)
+	ErrStreamStarted    = fmt.Errorf("%w: already started", ErrStream)
+	ErrStreamNotStarted = fmt.Errorf("%w: not started", ErrStream)
+)
+
+// Stream runs a command with a Runner, exposing its stdout and stderr as live
+// readers, rather than requiring writers to be provided up front. It is
+// modeled after exec.Cmd's StdoutPipe and StderrPipe methods.
+//
+// The pipes are synchronous, meaning the command blocks while writing output
+// until it has been read. Hence all pipes must be read until EOF, or the
+// context passed to Start must be cancelled, for the command to complete.
+type Stream struct {
+	// Runner is the Runner used to run the command. If not set, starting the
+	// stream will cause a panic.
+	Runner Runner
+
+	// Command is the command to run.
+	Command string
+
+	// Args are the arguments passed to Command.
+	Args []string
+
+	// Stdin is the optional stdin of the command.
+	Stdin io.Reader
+
+	stdout *io.PipeWriter
+	stderr *io.PipeWriter
+	done   chan struct{}
+	err    error
+}
+
+// NewStream returns a Stream which runs the given command and arguments with
+// the given Runner.
+func NewStream(r Runner, command string, args ...string) *Stream {
+	return &Stream{Runner: r, Command: command, Args: copyStrings(args)}
+}
+
+// StdoutPipe returns a reader which yields the command's stdout once the
+// stream has been started. The reader is closed with EOF once the command has
+// exited. Must be called before Start.
+func (s *Stream) StdoutPipe() (io.ReadCloser, error) {
+	if s.done != nil {
+		return nil, ErrStreamStarted
+	}
+	if s.stdout != nil {
+		return nil, fmt.Errorf("%w: StdoutPipe already called", ErrStream)
+	}
+
+	pr, pw := io.Pipe()
+	s.stdout = pw
+
+	return pr, nil
+}
+
+// StderrPipe returns a reader which yields the command's stderr once the
+// stream has been started. The reader is closed with EOF once the command has
+// exited. Must be called before Start.
+func (s *Stream) StderrPipe() (io.ReadCloser, error) {
+	if s.done != nil {
+		return nil, ErrStreamStarted
+	}
+	if s.stderr != nil {
+		return nil, fmt.Errorf("%w: StderrPipe already called", ErrStream)
+	}
+
+	pr, pw := io.Pipe()
+	s.stderr = pw
+
+	return pr, nil
+}
+
+// Start runs the command in the background via RunContext on the Runner. The
+// output of the command is discarded unless StdoutPipe or StderrPipe were
+// called before Start.
+func (s *Stream) Start(ctx context.Context) error {
+	if s.done != nil {
+		return ErrStreamStarted
+	}
+	s.done = make(chan struct{})
+
+	// Avoid passing typed nil pointers as io.Writer values.
+	var stdout, stderr io.Writer
+	if s.stdout != nil {
+		stdout = s.stdout
+	}
+	if s.stderr != nil {
+		stderr = s.stderr
+	}
+
+	go func() {
+		defer close(s.done)
+
+		s.err = s.Runner.RunContext(
+			ctx, s.Stdin, stdout, stderr, s.Command, s.Args...,
+		)
+
+		if s.stdout != nil {
+			s.stdout.Close()
+		}
+		if s.stderr != nil {
+			s.stderr.Close()
+		}
+	}()
+
+	return nil
+}
+
+// Wait blocks until the command has exited, and returns the error returned
+// by the Runner.
+func (s *Stream) Wait() error {
+	if s.done == nil {
+		return ErrStreamNotStarted
+	}
+	<-s.done
+
+	return s.err
+}
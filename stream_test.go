@@ -0,0 +1,134 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStream(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	s := NewStream(New(), "sh", "-c", `echo first; read -r x; echo "$x"`)
+	s.Stdin = stdinR
+
+	stdout, err := s.StdoutPipe()
+	require.NoError(t, err)
+
+	err = s.Start(context.Background())
+	require.NoError(t, err)
+
+	// The first line is readable while the command is still running, as it
+	// is waiting on stdin.
+	scanner := bufio.NewScanner(stdout)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "first", scanner.Text())
+
+	_, err = io.WriteString(stdinW, "second\n")
+	require.NoError(t, err)
+	require.NoError(t, stdinW.Close())
+
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "second", scanner.Text())
+	assert.False(t, scanner.Scan())
+
+	assert.NoError(t, s.Wait())
+}
+
+func TestNewStream_copiesArgs(t *testing.T) {
+	args := []string{"-c", "echo hello"}
+	s := NewStream(New(), "sh", args...)
+	args[1] = "echo modified"
+
+	assert.Equal(t, []string{"-c", "echo hello"}, s.Args)
+}
+
+func TestStream_stdoutAndStderr(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, gomock.Any(), gomock.Any(),
+		"myapp", []string{"-v"},
+	).DoAndReturn(func(
+		_ context.Context,
+		_ io.Reader,
+		stdout, stderr io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		_, _ = io.WriteString(stdout, "out\n")
+		_, _ = io.WriteString(stderr, "err\n")
+
+		return errors.New("exit status 1")
+	})
+
+	s := NewStream(r, "myapp", "-v")
+	stdout, err := s.StdoutPipe()
+	require.NoError(t, err)
+	stderr, err := s.StderrPipe()
+	require.NoError(t, err)
+
+	require.NoError(t, s.Start(ctx))
+
+	// Both pipes must be read concurrently, as writes block until read.
+	errOut := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(stderr)
+		errOut <- b
+	}()
+
+	out, err := io.ReadAll(stdout)
+	require.NoError(t, err)
+	assert.Equal(t, "out\n", string(out))
+	assert.Equal(t, "err\n", string(<-errOut))
+
+	assert.EqualError(t, s.Wait(), "exit status 1")
+}
+
+func TestStream_noPipes(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, nil, nil, "true", []string{},
+	).Return(nil)
+
+	s := NewStream(r, "true")
+	s.Args = []string{}
+
+	require.NoError(t, s.Start(ctx))
+	assert.NoError(t, s.Wait())
+}
+
+func TestStream_errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomock.Any(), nil, gomock.Any(), nil, "true", gomock.Any(),
+	).Return(nil)
+
+	s := NewStream(r, "true")
+
+	assert.ErrorIs(t, s.Wait(), ErrStreamNotStarted)
+
+	_, err := s.StdoutPipe()
+	require.NoError(t, err)
+	_, err = s.StdoutPipe()
+	assert.ErrorIs(t, err, ErrStream)
+
+	require.NoError(t, s.Start(context.Background()))
+
+	assert.ErrorIs(t, s.Start(context.Background()), ErrStreamStarted)
+	_, err = s.StderrPipe()
+	assert.ErrorIs(t, err, ErrStreamStarted)
+
+	assert.NoError(t, s.Wait())
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+// StrictT is an interface that describes the *testing.T methods needed by the
+// Strict runner implementation.
+type StrictT interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+}
+
+// Strict is a Runner for tests which wraps another Runner, and fails the test
+// by calling Fatalf on T for any command which does not match one of its Allow
+// rules. This catches code paths which unexpectedly run commands.
+//
+// As Fatalf must be called from the goroutine running the test, commands
+// should only be run via Strict from that goroutine.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Strict struct {
+	// Runner is the underlying Runner to run allowed commands with. If not
+	// set, running allowed commands will cause a panic.
+	Runner Runner
+
+	// T is the *testing.T instance used to fail the test. If not set, running
+	// commands will cause a panic.
+	T StrictT
+
+	// Allow is a list of rules, one of which commands must match to be run.
+	// As with Policy, rules only match commands exactly as given. When empty,
+	// no commands are allowed.
+	Allow []PolicyRule
+}
+
+var _ Runner = &Strict{}
+
+// Run calls Run on the underlying Runner if the command is allowed, and fails
+// the test otherwise.
+func (r *Strict) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	r.T.Helper()
+	r.check(command, args)
+
+	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext calls RunContext on the underlying Runner if the command is
+// allowed, and fails the test otherwise.
+func (r *Strict) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	r.T.Helper()
+	r.check(command, args)
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+func (r *Strict) check(command string, args []string) {
+	r.T.Helper()
+
+	for _, rule := range r.Allow {
+		if rule.match(command, args, false) {
+			return
+		}
+	}
+
+	r.T.Fatalf(
+		"runner: unexpected command: %s", formatCmdline(command, args),
+	)
+}
+
+// Env calls Env on the underlying Runner.
+func (r *Strict) Env(env ...string) {
+	r.Runner.Env(env...)
+}
@@ -0,0 +1,136 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/romdo/gomockctx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type fakeStrictT struct {
+	Fatals []string
+}
+
+func (f *fakeStrictT) Helper() {}
+
+func (f *fakeStrictT) Fatalf(format string, args ...interface{}) {
+	f.Fatals = append(f.Fatals, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+// runStrict calls fn in a new goroutine, so calls to Fatalf on ft can exit
+// it, and reports whether fn returned normally.
+func runStrict(fn func()) bool {
+	var wg sync.WaitGroup
+	returned := false
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fn()
+		returned = true
+	}()
+	wg.Wait()
+
+	return returned
+}
+
+var strictAllow = []PolicyRule{
+	{Command: "zfs", Args: []string{"list"}},
+	{Command: "uptime"},
+}
+
+func TestStrict_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		args      []string
+		wantFatal string
+	}{
+		{name: "allowed", command: "zfs", args: []string{"list", "-H"}},
+		{
+			name:    "allowed without args",
+			command: "uptime",
+			args:    []string{},
+		},
+		{
+			name:      "not allowed",
+			command:   "zfs",
+			args:      []string{"destroy", "my pool"},
+			wantFatal: "runner: unexpected command: zfs destroy 'my pool'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			r := mock_runner.NewMockRunner(ctrl)
+			if tt.wantFatal == "" {
+				r.EXPECT().Run(nil, nil, nil, tt.command, tt.args).Return(nil)
+			}
+
+			ft := &fakeStrictT{}
+			s := &Strict{Runner: r, T: ft, Allow: strictAllow}
+
+			returned := runStrict(func() {
+				err := s.Run(nil, nil, nil, tt.command, tt.args...)
+				assert.NoError(t, err)
+			})
+
+			if tt.wantFatal != "" {
+				assert.False(t, returned)
+				assert.Equal(t, []string{tt.wantFatal}, ft.Fatals)
+			} else {
+				assert.True(t, returned)
+				assert.Empty(t, ft.Fatals)
+			}
+		})
+	}
+}
+
+func TestStrict_RunContext(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, nil, nil, "uptime", []string{"-p"},
+	).Return(nil)
+
+	ft := &fakeStrictT{}
+	s := &Strict{Runner: r, T: ft, Allow: strictAllow}
+
+	assert.True(t, runStrict(func() {
+		assert.NoError(t, s.RunContext(ctx, nil, nil, nil, "uptime", "-p"))
+	}))
+	assert.False(t, runStrict(func() {
+		_ = s.RunContext(ctx, nil, nil, nil, "reboot")
+	}))
+	assert.Equal(
+		t, []string{"runner: unexpected command: reboot"}, ft.Fatals,
+	)
+}
+
+func TestStrict_noAllow(t *testing.T) {
+	ft := &fakeStrictT{}
+	s := &Strict{Runner: &Fake{}, T: ft}
+
+	assert.False(t, runStrict(func() {
+		_ = s.Run(nil, nil, nil, "true")
+	}))
+	assert.Equal(t, []string{"runner: unexpected command: true"}, ft.Fatals)
+}
+
+func TestStrict_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"FOO=BAR", "PORT=8080"})
+
+	s := &Strict{Runner: r, T: t}
+	s.Env("FOO=BAR", "PORT=8080")
+}
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrStub          = fmt.Errorf("%w: stub", Err)
+	ErrNoStubHandler = fmt.Errorf("%w: no handler", ErrStub)
+)
+
+// StubHandler is a function which simulates running a command for a Stub
+// runner. It may read from stdin and write to stdout and stderr as the command
+// would, and returns the error the command would.
+type StubHandler func(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error
+
+// Stub is a Runner which does not run commands, but instead calls a handler
+// function for each command, allowing tests to simulate interactive behavior
+// like streaming output and consuming stdin, which canned responses can not.
+//
+// Handlers are never given nil readers or writers. A nil stdin is replaced
+// with an empty reader, and nil stdout and stderr with io.Discard.
+//
+// Calls to Env are ignored. Handlers and Fallback must not be modified while
+// commands are being run.
+type Stub struct {
+	// Handlers maps command names to the handler called to run them.
+	Handlers map[string]StubHandler
+
+	// Fallback, when set, is called for commands with no handler in Handlers.
+	// If not set, such commands return an error wrapping ErrNoStubHandler.
+	Fallback StubHandler
+}
+
+var _ Runner = &Stub{}
+
+// Run calls the handler for command.
+func (r *Stub) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext calls the handler for command.
+func (r *Stub) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	h, ok := r.Handlers[command]
+	if !ok {
+		h = r.Fallback
+	}
+	if h == nil {
+		cmdline := strings.Join(append([]string{command}, args...), " ")
+
+		return fmt.Errorf("%w: %s", ErrNoStubHandler, cmdline)
+	}
+
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	return h(ctx, stdin, stdout, stderr, command, copyStrings(args)...)
+}
+
+// Env does nothing, as commands are not run.
+func (r *Stub) Env(...string) {}
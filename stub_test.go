@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stubCat(
+	_ context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	_ io.Writer,
+	_ string,
+	_ ...string,
+) error {
+	_, err := io.Copy(stdout, stdin)
+
+	return err
+}
+
+func TestStub_Run(t *testing.T) {
+	r := &Stub{
+		Handlers: map[string]StubHandler{
+			"cat": stubCat,
+			"false": func(
+				_ context.Context, _ io.Reader, _, stderr io.Writer,
+				command string, _ ...string,
+			) error {
+				fmt.Fprintln(stderr, "failing")
+
+				return &FakeExitError{Command: command, Code: 1}
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		stdin      io.Reader
+		command    string
+		args       []string
+		wantStdout string
+		wantStderr string
+		wantErr    string
+	}{
+		{
+			name:       "cat",
+			stdin:      strings.NewReader("hello\nworld\n"),
+			command:    "cat",
+			wantStdout: "hello\nworld\n",
+		},
+		{
+			name:    "nil stdin",
+			command: "cat",
+		},
+		{
+			name:       "error",
+			command:    "false",
+			wantStderr: "failing\n",
+			wantErr:    "false: exit status 1",
+		},
+		{
+			name:    "no handler",
+			command: "ls",
+			args:    []string{"-l"},
+			wantErr: "runner: stub: no handler: ls -l",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := r.Run(tt.stdin, &stdout, &stderr, tt.command, tt.args...)
+
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStdout, stdout.String())
+			assert.Equal(t, tt.wantStderr, stderr.String())
+		})
+	}
+}
+
+func TestStub_RunContext(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	var got []string
+	r := &Stub{
+		Fallback: func(
+			ctx context.Context, _ io.Reader, stdout, _ io.Writer,
+			command string, args ...string,
+		) error {
+			got = append(
+				append(got, ctx.Value(ctxKey{}).(string), command), args...,
+			)
+
+			// Output is written to a nil stdout without panicking.
+			_, err := fmt.Fprintln(stdout, "ignored")
+
+			return err
+		},
+	}
+
+	err := r.RunContext(ctx, nil, nil, nil, "uptime", "-p")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"value", "uptime", "-p"}, got)
+}
+
+func TestStub_interactive(t *testing.T) {
+	// A handler which answers each line of input as it arrives.
+	r := &Stub{
+		Handlers: map[string]StubHandler{
+			"bc": func(
+				_ context.Context, stdin io.Reader, stdout, _ io.Writer,
+				_ string, _ ...string,
+			) error {
+				scanner := bufio.NewScanner(stdin)
+				for scanner.Scan() {
+					fmt.Fprintf(stdout, "= %s\n", scanner.Text())
+				}
+
+				return scanner.Err()
+			},
+		},
+	}
+
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Run(stdinR, stdoutW, nil, "bc")
+		stdoutW.Close()
+	}()
+
+	out := bufio.NewReader(stdoutR)
+	for _, line := range []string{"1+1", "2*3"} {
+		_, err := fmt.Fprintln(stdinW, line)
+		require.NoError(t, err)
+
+		resp, err := out.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "= "+line+"\n", resp)
+	}
+
+	require.NoError(t, stdinW.Close())
+	assert.NoError(t, <-done)
+}
@@ -3,6 +3,7 @@ package runner
 import (
 	"context"
 	"io"
+	"sync"
 )
 
 // Sudo is a Runner that wraps another Runner and runs commands via sudo.
@@ -12,7 +13,8 @@ import (
 type Sudo struct {
 	// env is a internal string slice of environment variables which are
 	// provided to the command being run in sudo.
-	env []string
+	env   []string
+	envMu sync.RWMutex
 
 	// Runner is the underlying Runner to run commands with, after wrapping them
 	// with sudo. If not set, running commands will cause a panic.
@@ -21,12 +23,34 @@ type Sudo struct {
 	// User value passed to sudo via -u flag.
 	User string
 
+	// Dir is the working directory to run commands in, passed to sudo via the
+	// -D flag. When empty, no -D flag will be used. Requires sudo 1.9.3 or
+	// later, and the sudoers policy must permit it via the CWD option.
+	Dir string
+
 	// Args is a string slice of extra arguments to pass to sudo.
 	Args []string
 }
 
 var _ Runner = &Sudo{}
 
+var _ Validator = &Sudo{}
+
+// Validate checks the Sudo is configured correctly, so misconfiguration is
+// reported before any command is run. It checks Runner is set, and that
+// values passed to sudo do not contain illegal characters, or begin with "-"
+// where they could be mistaken for flags. The underlying Runner is validated
+// too.
+func (r *Sudo) Validate() error {
+	return firstError(
+		validateRunner("Sudo.Runner", r.Runner),
+		validateWord("Sudo.User", r.User),
+		validateChars("Sudo.Dir", r.Dir, false),
+		validateEnv("Sudo.Env", r.environ()),
+		Validate(r.Runner),
+	)
+}
+
 // Run executes the command via sudo by calling Run on the underlying Runner.
 // Will panic if Runner field is nil on Sudo instance.
 func (r *Sudo) Run(
@@ -36,7 +60,7 @@ func (r *Sudo) Run(
 	command string,
 	args ...string,
 ) error {
-	sudoArgs := r.args(command, args)
+	sudoArgs := r.args(context.Background(), command, args)
 
 	return r.Runner.Run(stdin, stdout, stderr, "sudo", sudoArgs...)
 }
@@ -51,20 +75,34 @@ func (r *Sudo) RunContext(
 	command string,
 	args ...string,
 ) error {
-	sudoArgs := r.args(command, args)
+	sudoArgs := r.args(ctx, command, args)
 
-	return r.Runner.RunContext(ctx, stdin, stdout, stderr, "sudo", sudoArgs...)
+	return r.Runner.RunContext(
+		withoutCallOptions(ctx), stdin, stdout, stderr, "sudo", sudoArgs...,
+	)
 }
 
-func (r *Sudo) args(command string, args []string) []string {
+func (r *Sudo) args(
+	ctx context.Context,
+	command string,
+	args []string,
+) []string {
 	sudoArgs := []string{"-n"}
 	if r.User != "" {
 		sudoArgs = append(sudoArgs, "-u", r.User)
 	}
+
+	dir := r.Dir
+	if d := callDir(ctx); d != "" {
+		dir = d
+	}
+	if dir != "" {
+		sudoArgs = append(sudoArgs, "-D", dir)
+	}
 	sudoArgs = append(sudoArgs, r.Args...)
 
-	if len(r.env) > 0 {
-		sudoArgs = append(sudoArgs, r.env...)
+	if env := DedupEnv(mergeEnv(r.environ(), callEnv(ctx))...); len(env) > 0 {
+		sudoArgs = append(sudoArgs, env...)
 	}
 	sudoArgs = append(sudoArgs, "--", command)
 	sudoArgs = append(sudoArgs, args...)
@@ -72,8 +110,19 @@ func (r *Sudo) args(command string, args []string) []string {
 	return sudoArgs
 }
 
-// Env sets the environment by calling Env on the underlying Runner. Will panic
-// if Runner field is nil on Sudo instance.
+// Env sets the environment variables which will be provided to commands run
+// via sudo, by passing them to sudo as arguments. Env is not called on the
+// underlying Runner, so sudo itself is not affected.
 func (r *Sudo) Env(env ...string) {
-	r.env = env
+	r.envMu.Lock()
+	defer r.envMu.Unlock()
+
+	r.env = copyStrings(env)
+}
+
+func (r *Sudo) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
 }
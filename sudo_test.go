@@ -16,6 +16,7 @@ import (
 func TestSudo_Run(t *testing.T) {
 	type fields struct {
 		User string
+		Dir  string
 		Args []string
 	}
 	type args struct {
@@ -119,6 +120,21 @@ func TestSudo_Run(t *testing.T) {
 				"-n", "FOO=BAR", "PORT=8080", "--", "myapp", "run", "-a",
 			},
 		},
+		{
+			name: "with duplicate Env",
+			env:  []string{"PORT=80", "FOO=BAR", "PORT=8080"},
+			args: args{
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "myapp",
+				args:    []string{"run", "-a"},
+			},
+			wantCommand: "sudo",
+			wantArgs: []string{
+				"-n", "FOO=BAR", "PORT=8080", "--", "myapp", "run", "-a",
+			},
+		},
 		{
 			name: "with Args",
 			fields: fields{
@@ -157,6 +173,25 @@ func TestSudo_Run(t *testing.T) {
 				"FOO=BAR", "PORT=8080", "--", "docker", "ps", "-a",
 			},
 		},
+		{
+			name: "with Dir",
+			fields: fields{
+				User: "barfoo",
+				Dir:  "/opt/thing",
+			},
+			args: args{
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "sudo",
+			wantArgs: []string{
+				"-n", "-u", "barfoo", "-D", "/opt/thing",
+				"--", "docker", "ps", "-a",
+			},
+		},
 		{
 			name:   "error",
 			fields: fields{},
@@ -188,6 +223,7 @@ func TestSudo_Run(t *testing.T) {
 			s := &Sudo{
 				Runner: r,
 				User:   tt.fields.User,
+				Dir:    tt.fields.Dir,
 				Args:   tt.fields.Args,
 			}
 
@@ -217,6 +253,7 @@ func TestSudo_RunContext(t *testing.T) {
 
 	type fields struct {
 		User string
+		Dir  string
 		Args []string
 	}
 	type args struct {
@@ -367,6 +404,26 @@ func TestSudo_RunContext(t *testing.T) {
 				"FOO=BAR", "PORT=8080", "--", "docker", "ps", "-a",
 			},
 		},
+		{
+			name: "with Dir",
+			fields: fields{
+				User: "barfoo",
+				Dir:  "/opt/thing",
+			},
+			args: args{
+				ctx:     ctx,
+				stdin:   nil,
+				stdout:  &bytes.Buffer{},
+				stderr:  &bytes.Buffer{},
+				command: "docker",
+				args:    []string{"ps", "-a"},
+			},
+			wantCommand: "sudo",
+			wantArgs: []string{
+				"-n", "-u", "barfoo", "-D", "/opt/thing",
+				"--", "docker", "ps", "-a",
+			},
+		},
 		{
 			name:   "error",
 			fields: fields{},
@@ -400,6 +457,7 @@ func TestSudo_RunContext(t *testing.T) {
 			s := &Sudo{
 				Runner: r,
 				User:   tt.fields.User,
+				Dir:    tt.fields.Dir,
 				Args:   tt.fields.Args,
 			}
 
@@ -472,3 +530,76 @@ func TestSudo_Env(t *testing.T) {
 		})
 	}
 }
+
+func TestSudo_Env_copy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Run(
+		nil, nil, nil, "sudo", "-n", "foo=bar", "--", "whoami",
+	).Return(nil)
+
+	env := []string{"foo=bar"}
+	s := &Sudo{Runner: r}
+	s.Env(env...)
+	env[0] = "foo=modified"
+
+	err := s.Run(nil, nil, nil, "whoami")
+	assert.NoError(t, err)
+}
+
+func TestSudo_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		runner  *Sudo
+		env     []string
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			runner: &Sudo{Runner: &Local{}, User: "deploy", Dir: "/srv/my app"},
+			env:    []string{"A=1"},
+		},
+		{
+			name:    "no runner",
+			runner:  &Sudo{},
+			wantErr: "runner: invalid: Sudo.Runner: must be set",
+		},
+		{
+			name:    "user is a flag",
+			runner:  &Sudo{Runner: &Local{}, User: "-s"},
+			wantErr: "runner: invalid: Sudo.User: must not begin with -",
+		},
+		{
+			name:   "dir with newline",
+			runner: &Sudo{Runner: &Local{}, Dir: "/srv\n"},
+			wantErr: "runner: invalid: Sudo.Dir: " +
+				`contains illegal character '\n'`,
+		},
+		{
+			name:    "env is a flag",
+			runner:  &Sudo{Runner: &Local{}},
+			env:     []string{"-E=1"},
+			wantErr: "runner: invalid: Sudo.Env[0]: must not begin with -",
+		},
+		{
+			name:    "invalid underlying runner",
+			runner:  &Sudo{Runner: &SSHCLI{Runner: &Local{}}},
+			wantErr: "runner: invalid: SSHCLI.Destination: must be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.runner.Env(tt.env...)
+
+			err := tt.runner.Validate()
+
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
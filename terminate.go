@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// terminator gracefully terminates a command when its context is done, by
+// first sending it a termination signal, and then killing it if it has not
+// exited by the end of the grace period. When the grace period is zero, the
+// command is never killed.
+type terminator struct {
+	cmd         *exec.Cmd
+	signal      os.Signal
+	gracePeriod time.Duration
+	group       bool
+
+	mu    sync.Mutex
+	timer *time.Timer
+	done  bool
+}
+
+// cancel is used as the Cancel function of cmd.
+func (t *terminator) cancel() error {
+	err := t.send(t.signal)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.done && t.gracePeriod > 0 {
+		t.timer = time.AfterFunc(t.gracePeriod, t.kill)
+	}
+
+	return nil
+}
+
+func (t *terminator) kill() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.done {
+		_ = t.send(syscall.SIGKILL)
+	}
+}
+
+// stop must be called as soon as cmd.Wait returns, to prevent it being killed
+// after the fact, when its PID may have been reused. It may be called more
+// than once.
+func (t *terminator) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.done = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+}
+
+func (t *terminator) send(sig os.Signal) error {
+	if t.group {
+		return signalProcessGroup(t.cmd.Process, sig)
+	}
+
+	return t.cmd.Process.Signal(sig)
+}
@@ -2,8 +2,11 @@ package runner
 
 import (
 	"context"
-	"encoding/json"
 	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
 )
 
 // TestingT is a interface that describes the *testing.T methods needed by the
@@ -13,7 +16,9 @@ type TestingT interface {
 }
 
 // Testing is a Runner that wraps another Runner, and logs all executed commands
-// and their arguments to a *testing.T instance.
+// and their arguments to a *testing.T instance. Executed commands are also
+// recorded, and can be inspected with Calls, or asserted on with AssertRan and
+// AssertNotRan.
 //
 // Both Runner and T must be non-nil, or running commands will cause a panic.
 type Testing struct {
@@ -27,12 +32,63 @@ type Testing struct {
 
 	// LogEnv indicates if calls to Env() should be logged.
 	LogEnv bool
+
+	// RedactKeys is a list of environment variable keys whose values are
+	// logged as "***". Keys may be glob patterns as supported by path.Match,
+	// like "*_TOKEN", and are matched case-insensitively.
+	//
+	// Redaction applies to logged env vars, and to command arguments of the
+	// form "key=value", which is how wrapper runners like Sudo pass env vars.
+	RedactKeys []string
+
+	// LogOutput indicates if the stdout and stderr output of commands should
+	// be logged once they complete. Output is still written to the given
+	// stdout and stderr writers.
+	//
+	// To capture output, nil stdout and stderr writers are not passed to the
+	// underlying Runner as is. With Local, this means the stderr output of
+	// failed commands is not included in the error message.
+	LogOutput bool
+
+	// MaxLogOutput limits logged output to the last MaxLogOutput bytes of each
+	// of stdout and stderr when LogOutput is true. When zero, all output is
+	// logged.
+	MaxLogOutput int
+
+	// Formatter formats events to be logged. Events for which it returns an
+	// empty string are not logged, allowing it to filter them. If not set,
+	// DefaultTestingFormatter is used.
+	Formatter TestingFormatter
+
+	mu    sync.Mutex
+	env   []string
+	calls []TestingCall
+}
+
+// TestingCall is a command run via a Testing runner.
+type TestingCall struct {
+	// Command is the command which was run.
+	Command string
+
+	// Args are the arguments the command was run with.
+	Args []string
+
+	// Env is the environment variables set via Env when the command was run,
+	// followed by any given with WithEnv. Values are not redacted.
+	Env []string
+
+	// Err is the error returned by the underlying Runner.
+	Err error
+
+	// Duration is how long the command took to run.
+	Duration time.Duration
 }
 
 var _ Runner = &Testing{}
 
 // Run executes the command with the underlying Runner, and logs command and
-// arguments to TestingT.
+// arguments to TestingT. Once the command has completed, its exit status,
+// duration, and any error are also logged.
 func (r *Testing) Run(
 	stdin io.Reader,
 	stdout io.Writer,
@@ -40,17 +96,29 @@ func (r *Testing) Run(
 	command string,
 	args ...string,
 ) error {
-	jsonArgs, _ := json.Marshal(args)
-	r.TestingT.Logf(
-		"runner.Run: command=%s args=%s",
-		command, string(jsonArgs),
+	r.log(TestingEvent{
+		Type:    TestingStart,
+		Method:  "runner.Run",
+		Command: command,
+		Args:    RedactEnv(args, r.RedactKeys),
+	})
+
+	stdout, stderr, output := r.captureOutput(stdout, stderr)
+
+	start := time.Now()
+	err := r.Runner.Run(stdin, stdout, stderr, command, args...)
+	r.logOutput("runner.Run", command, args, output)
+	r.done(
+		context.Background(), "runner.Run", command, args, err,
+		time.Since(start),
 	)
 
-	return r.Runner.Run(stdin, stdout, stderr, command, args...)
+	return err
 }
 
 // RunContext executes the command with the underlying Runner, and logs command
-// and arguments to TestingT.
+// and arguments to TestingT. Once the command has completed, its exit status,
+// duration, and any error are also logged.
 func (r *Testing) RunContext(
 	ctx context.Context,
 	stdin io.Reader,
@@ -59,22 +127,273 @@ func (r *Testing) RunContext(
 	command string,
 	args ...string,
 ) error {
-	jsonArgs, _ := json.Marshal(args)
-	r.TestingT.Logf(
-		"runner.RunContext: command=%s args=%s",
-		command, string(jsonArgs),
-	)
+	r.log(TestingEvent{
+		Type:    TestingStart,
+		Method:  "runner.RunContext",
+		Command: command,
+		Args:    RedactEnv(args, r.RedactKeys),
+	})
+
+	stdout, stderr, output := r.captureOutput(stdout, stderr)
 
-	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	start := time.Now()
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	r.logOutput("runner.RunContext", command, args, output)
+	r.done(ctx, "runner.RunContext", command, args, err, time.Since(start))
+
+	return err
 }
 
 // Env sets the environment variables for the underlying Runner, and if LogEnv
 // is true it logs the given environment variables to TestingT.
 func (r *Testing) Env(vars ...string) {
 	if r.LogEnv {
-		jsonVars, _ := json.Marshal(vars)
-		r.TestingT.Logf("runner.Env: vars=%s", string(jsonVars))
+		r.log(TestingEvent{
+			Type:   TestingEnv,
+			Method: "runner.Env",
+			Env:    RedactEnv(vars, r.RedactKeys),
+		})
 	}
 
+	r.mu.Lock()
+	r.env = copyStrings(vars)
+	r.mu.Unlock()
+
 	r.Runner.Env(vars...)
 }
+
+// log formats the given event with Formatter, and logs it to TestingT.
+func (r *Testing) log(e TestingEvent) {
+	format := r.Formatter
+	if format == nil {
+		format = DefaultTestingFormatter
+	}
+
+	if msg := format(e); msg != "" {
+		r.TestingT.Logf("%s", msg)
+	}
+}
+
+// testingOutput holds the captured stdout and stderr output of a command.
+type testingOutput struct {
+	stdout *outputCapture
+	stderr *outputCapture
+}
+
+// captureOutput returns stdout and stderr writers which also capture output
+// for logging, if LogOutput is true.
+func (r *Testing) captureOutput(
+	stdout io.Writer,
+	stderr io.Writer,
+) (io.Writer, io.Writer, *testingOutput) {
+	if !r.LogOutput {
+		return stdout, stderr, nil
+	}
+
+	stdout, stderr = syncIfSame(stdout, stderr)
+
+	output := &testingOutput{
+		stdout: &outputCapture{max: r.MaxLogOutput},
+		stderr: &outputCapture{max: r.MaxLogOutput},
+	}
+
+	return teeWriter(stdout, output.stdout), teeWriter(stderr, output.stderr),
+		output
+}
+
+func (r *Testing) logOutput(
+	method string,
+	command string,
+	args []string,
+	output *testingOutput,
+) {
+	if output == nil {
+		return
+	}
+
+	for _, o := range []struct {
+		name    string
+		capture *outputCapture
+	}{
+		{name: "stdout", capture: output.stdout},
+		{name: "stderr", capture: output.stderr},
+	} {
+		if o.capture.total == 0 {
+			continue
+		}
+
+		r.log(TestingEvent{
+			Type:        TestingOutput,
+			Method:      method,
+			Command:     command,
+			Args:        RedactEnv(args, r.RedactKeys),
+			Stream:      o.name,
+			Output:      o.capture.Bytes(),
+			OutputTotal: o.capture.total,
+		})
+	}
+}
+
+// outputCapture is an io.Writer which retains all data written to it, or only
+// the last max bytes if max is greater than zero.
+type outputCapture struct {
+	max   int
+	buf   []byte
+	total int
+}
+
+func (c *outputCapture) Write(p []byte) (int, error) {
+	c.total += len(p)
+	c.buf = append(c.buf, p...)
+	if c.max > 0 && len(c.buf) > c.max {
+		c.buf = append(c.buf[:0], c.buf[len(c.buf)-c.max:]...)
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns the retained data.
+func (c *outputCapture) Bytes() []byte {
+	return c.buf
+}
+
+// done logs the result of a completed command, and records it.
+func (r *Testing) done(
+	ctx context.Context,
+	method string,
+	command string,
+	args []string,
+	err error,
+	duration time.Duration,
+) {
+	r.log(TestingEvent{
+		Type:     TestingDone,
+		Method:   method,
+		Command:  command,
+		Args:     RedactEnv(args, r.RedactKeys),
+		ExitCode: ExitCode(err),
+		Err:      err,
+		Duration: duration,
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, TestingCall{
+		Command:  command,
+		Args:     copyStrings(args),
+		Env:      mergeEnv(copyStrings(r.env), callEnv(ctx)),
+		Err:      err,
+		Duration: duration,
+	})
+}
+
+// Calls returns the commands which have been run, in order.
+func (r *Testing) Calls() []TestingCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]TestingCall, len(r.calls))
+	copy(calls, r.calls)
+
+	return calls
+}
+
+// Ran reports if command has been run with exactly the given arguments.
+func (r *Testing) Ran(command string, args ...string) bool {
+	for _, call := range r.Calls() {
+		if call.Command == command && equalStrings(call.Args, args) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AssertRan reports a failure via t if command has not been run with exactly
+// the given arguments, and returns whether it has.
+func (r *Testing) AssertRan(t ExpectT, command string, args ...string) bool {
+	t.Helper()
+
+	if r.Ran(command, args...) {
+		return true
+	}
+
+	t.Errorf(
+		"runner: command not run: %s\n\tran: %s",
+		formatCmdline(command, args), r.formatCalls(),
+	)
+
+	return false
+}
+
+// AssertNotRan reports a failure via t if command has been run with exactly
+// the given arguments, and returns whether it has not.
+func (r *Testing) AssertNotRan(
+	t ExpectT,
+	command string,
+	args ...string,
+) bool {
+	t.Helper()
+
+	if !r.Ran(command, args...) {
+		return true
+	}
+
+	t.Errorf("runner: command was run: %s", formatCmdline(command, args))
+
+	return false
+}
+
+func (r *Testing) formatCalls() string {
+	calls := r.Calls()
+	if len(calls) == 0 {
+		return "<none>"
+	}
+
+	lines := make([]string, 0, len(calls))
+	for _, call := range calls {
+		lines = append(lines, formatCmdline(call.Command, call.Args))
+	}
+
+	return strings.Join(lines, "\n\t     ")
+}
+
+// redacted is the value logged in place of redacted env var values.
+const redacted = "***"
+
+// RedactEnv returns a copy of env, with the value of any "key=value" entries
+// whose key matches one of the glob patterns replaced with "***". Entries
+// which are not of the form "key=value" are left as is.
+//
+// Patterns are matched case-insensitively, as with Testing.RedactKeys. It can
+// be used to redact command arguments as well as env vars, as wrapper runners
+// like Sudo pass env vars as arguments.
+func RedactEnv(env []string, patterns []string) []string {
+	if len(patterns) == 0 || len(env) == 0 {
+		return env
+	}
+
+	out := make([]string, len(env))
+	for i, entry := range env {
+		out[i] = entry
+
+		key, _, ok := strings.Cut(entry, "=")
+		if ok && key != "" && matchKey(key, patterns) {
+			out[i] = key + "=" + redacted
+		}
+	}
+
+	return out
+}
+
+func matchKey(key string, patterns []string) bool {
+	key = strings.ToUpper(key)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(pattern), key); ok {
+			return true
+		}
+	}
+
+	return false
+}
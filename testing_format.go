@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TestingEventType is the type of a TestingEvent.
+type TestingEventType int
+
+const (
+	// TestingStart is logged before a command is run.
+	TestingStart TestingEventType = iota
+
+	// TestingOutput is logged after a command has completed, once for each of
+	// stdout and stderr, when LogOutput is enabled and the command wrote to
+	// them.
+	TestingOutput
+
+	// TestingDone is logged after a command has completed.
+	TestingDone
+
+	// TestingEnv is logged when Env is called, if LogEnv is enabled.
+	TestingEnv
+)
+
+// TestingEvent is an event logged by a Testing runner. Args and Env have
+// already been redacted according to RedactKeys.
+type TestingEvent struct {
+	// Type is the type of event.
+	Type TestingEventType
+
+	// Method is the name of the method, like "runner.Run".
+	Method string
+
+	// Command is the command being run. Not set for TestingEnv events.
+	Command string
+
+	// Args are the arguments the command is being run with. Not set for
+	// TestingEnv events.
+	Args []string
+
+	// Env is the environment variables given to Env. Only set for TestingEnv
+	// events.
+	Env []string
+
+	// Stream is "stdout" or "stderr". Only set for TestingOutput events.
+	Stream string
+
+	// Output is the captured output, which may be truncated to the last
+	// MaxLogOutput bytes. Only set for TestingOutput events.
+	Output []byte
+
+	// OutputTotal is the total number of bytes of output written, before any
+	// truncation. Only set for TestingOutput events.
+	OutputTotal int
+
+	// ExitCode is the exit code of the command, or -1 if it failed without
+	// one. Only set for TestingDone events.
+	ExitCode int
+
+	// Err is the error returned by the underlying Runner. Only set for
+	// TestingDone events.
+	Err error
+
+	// Duration is how long the command took to run. Only set for TestingDone
+	// events.
+	Duration time.Duration
+}
+
+// TestingFormatter formats a TestingEvent as a log message. An empty string
+// indicates the event should not be logged.
+type TestingFormatter func(e TestingEvent) string
+
+// DefaultTestingFormatter is the TestingFormatter used by Testing when none is
+// set. It formats events with the command, and JSON encoded arguments, like:
+//
+//	runner.Run: command=zfs args=["list","-H"]
+//	runner.Run: command=zfs exit=0 duration=1.2ms
+func DefaultTestingFormatter(e TestingEvent) string {
+	switch e.Type {
+	case TestingStart:
+		jsonArgs, _ := json.Marshal(e.Args)
+
+		return fmt.Sprintf(
+			"%s: command=%s args=%s", e.Method, e.Command, string(jsonArgs),
+		)
+	case TestingOutput:
+		return fmt.Sprintf(
+			"%s: command=%s %s%s:\n%s",
+			e.Method, e.Command, e.Stream, truncatedNote(e),
+			strings.TrimRight(string(e.Output), "\n"),
+		)
+	case TestingDone:
+		if e.Err != nil {
+			return fmt.Sprintf(
+				"%s: command=%s exit=%d duration=%s error=%q",
+				e.Method, e.Command, e.ExitCode, e.Duration, e.Err.Error(),
+			)
+		}
+
+		return fmt.Sprintf(
+			"%s: command=%s exit=0 duration=%s",
+			e.Method, e.Command, e.Duration,
+		)
+	case TestingEnv:
+		jsonVars, _ := json.Marshal(e.Env)
+
+		return fmt.Sprintf("%s: vars=%s", e.Method, string(jsonVars))
+	}
+
+	return ""
+}
+
+// ShellTestingFormatter is a TestingFormatter which renders commands as shell
+// command lines, like:
+//
+//	$ zfs list -H
+//	[exit 0, 1.2ms]
+func ShellTestingFormatter(e TestingEvent) string {
+	switch e.Type {
+	case TestingStart:
+		return "$ " + formatCmdline(e.Command, e.Args)
+	case TestingOutput:
+		return fmt.Sprintf(
+			"[%s%s]\n%s",
+			e.Stream, truncatedNote(e),
+			strings.TrimRight(string(e.Output), "\n"),
+		)
+	case TestingDone:
+		if e.Err != nil {
+			return fmt.Sprintf(
+				"[exit %d, %s: %s]", e.ExitCode, e.Duration, e.Err.Error(),
+			)
+		}
+
+		return fmt.Sprintf("[exit 0, %s]", e.Duration)
+	case TestingEnv:
+		return "$ export " + quoteWords(e.Env)
+	}
+
+	return ""
+}
+
+// KeyValueTestingFormatter is a TestingFormatter which renders events as
+// single lines of logfmt style key=value pairs, like:
+//
+//	event=start method=runner.Run command=zfs args="list -H"
+//	event=done method=runner.Run command=zfs exit=0 duration=1.2ms
+//
+// Output is quoted, so it does not span multiple lines.
+func KeyValueTestingFormatter(e TestingEvent) string {
+	var kvs []string
+	add := func(key, value string) {
+		if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		kvs = append(kvs, key+"="+value)
+	}
+
+	switch e.Type {
+	case TestingStart:
+		add("event", "start")
+		add("method", e.Method)
+		add("command", e.Command)
+		add("args", quoteWords(e.Args))
+	case TestingOutput:
+		add("event", "output")
+		add("method", e.Method)
+		add("command", e.Command)
+		add("stream", e.Stream)
+		add("bytes", fmt.Sprint(e.OutputTotal))
+		add("output", string(e.Output))
+	case TestingDone:
+		add("event", "done")
+		add("method", e.Method)
+		add("command", e.Command)
+		add("exit", fmt.Sprint(e.ExitCode))
+		add("duration", e.Duration.String())
+		if e.Err != nil {
+			add("error", e.Err.Error())
+		}
+	case TestingEnv:
+		add("event", "env")
+		add("method", e.Method)
+		add("vars", strings.Join(e.Env, " "))
+	default:
+		return ""
+	}
+
+	return strings.Join(kvs, " ")
+}
+
+// truncatedNote returns a note describing how much of the output of a
+// TestingOutput event is included, if it was truncated.
+func truncatedNote(e TestingEvent) string {
+	if len(e.Output) >= e.OutputTotal {
+		return ""
+	}
+
+	return fmt.Sprintf(" (last %d of %d bytes)", len(e.Output), e.OutputTotal)
+}
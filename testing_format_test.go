@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testingFormatEvents = []TestingEvent{
+	{
+		Type:    TestingStart,
+		Method:  "runner.Run",
+		Command: "zfs",
+		Args:    []string{"list", "-o", "name,used", "my pool"},
+	},
+	{
+		Type:        TestingOutput,
+		Method:      "runner.Run",
+		Command:     "zfs",
+		Stream:      "stdout",
+		Output:      []byte("tank 1G\n"),
+		OutputTotal: 8,
+	},
+	{
+		Type:        TestingOutput,
+		Method:      "runner.Run",
+		Command:     "zfs",
+		Stream:      "stderr",
+		Output:      []byte("warning\n"),
+		OutputTotal: 20,
+	},
+	{
+		Type:     TestingDone,
+		Method:   "runner.Run",
+		Command:  "zfs",
+		Duration: 1500 * time.Microsecond,
+	},
+	{
+		Type:     TestingDone,
+		Method:   "runner.RunContext",
+		Command:  "zfs",
+		ExitCode: 1,
+		Err:      errors.New("zfs: exit status 1"),
+		Duration: 2 * time.Second,
+	},
+	{
+		Type:   TestingEnv,
+		Method: "runner.Env",
+		Env:    []string{"FOO=bar", "MSG=hello world"},
+	},
+	{
+		Type: TestingEventType(99),
+	},
+}
+
+func TestDefaultTestingFormatter(t *testing.T) {
+	want := []string{
+		`runner.Run: command=zfs args=["list","-o","name,used","my pool"]`,
+		"runner.Run: command=zfs stdout:\ntank 1G",
+		"runner.Run: command=zfs stderr (last 8 of 20 bytes):\nwarning",
+		"runner.Run: command=zfs exit=0 duration=1.5ms",
+		"runner.RunContext: command=zfs exit=1 duration=2s " +
+			`error="zfs: exit status 1"`,
+		`runner.Env: vars=["FOO=bar","MSG=hello world"]`,
+		"",
+	}
+
+	for i, e := range testingFormatEvents {
+		assert.Equal(t, want[i], DefaultTestingFormatter(e))
+	}
+}
+
+func TestShellTestingFormatter(t *testing.T) {
+	want := []string{
+		"$ zfs list -o name,used 'my pool'",
+		"[stdout]\ntank 1G",
+		"[stderr (last 8 of 20 bytes)]\nwarning",
+		"[exit 0, 1.5ms]",
+		"[exit 1, 2s: zfs: exit status 1]",
+		"$ export FOO=bar 'MSG=hello world'",
+		"",
+	}
+
+	for i, e := range testingFormatEvents {
+		assert.Equal(t, want[i], ShellTestingFormatter(e))
+	}
+}
+
+func TestKeyValueTestingFormatter(t *testing.T) {
+	want := []string{
+		`event=start method=runner.Run command=zfs ` +
+			`args="list -o name,used 'my pool'"`,
+		`event=output method=runner.Run command=zfs stream=stdout ` +
+			`bytes=8 output="tank 1G\n"`,
+		`event=output method=runner.Run command=zfs stream=stderr ` +
+			`bytes=20 output="warning\n"`,
+		"event=done method=runner.Run command=zfs exit=0 duration=1.5ms",
+		"event=done method=runner.RunContext command=zfs exit=1 " +
+			`duration=2s error="zfs: exit status 1"`,
+		`event=env method=runner.Env vars="FOO=bar MSG=hello world"`,
+		"",
+	}
+
+	for i, e := range testingFormatEvents {
+		assert.Equal(t, want[i], KeyValueTestingFormatter(e))
+	}
+}
+
+func TestTesting_Formatter(t *testing.T) {
+	ft := &fakeTestingT{}
+	tr := &Testing{
+		Runner:     &Local{},
+		TestingT:   ft,
+		LogEnv:     true,
+		RedactKeys: []string{"TOKEN"},
+		Formatter: func(e TestingEvent) string {
+			// Only log commands, not their results.
+			if e.Type == TestingDone {
+				return ""
+			}
+
+			return ShellTestingFormatter(e)
+		},
+	}
+
+	tr.Env("TOKEN=secret")
+	err := tr.Run(nil, nil, nil, "env", "TOKEN=secret", "true")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"$ export 'TOKEN=***'",
+		"$ env 'TOKEN=***' true",
+	}, ft.Messages)
+}
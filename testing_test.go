@@ -6,11 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"testing"
+	"time"
 
 	mock_runner "github.com/krystal/go-runner/mock"
 	"github.com/romdo/gomockctx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -18,12 +21,18 @@ type fakeTestingT struct {
 	Messages []string
 }
 
+// durationRE matches logged durations, which are replaced with "<d>" in
+// Messages, so they can be compared.
+var durationRE = regexp.MustCompile(`duration=\S+`)
+
 func (f *fakeTestingT) Logf(format string, args ...interface{}) {
 	if f == nil {
 		return
 	}
 
-	f.Messages = append(f.Messages, fmt.Sprintf(format, args...))
+	msg := fmt.Sprintf(format, args...)
+	msg = durationRE.ReplaceAllString(msg, "duration=<d>")
+	f.Messages = append(f.Messages, msg)
 }
 
 func TestTesting_Run(t *testing.T) {
@@ -70,6 +79,7 @@ func TestTesting_Run(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.Run: command=echo args=["-n","hello world"]`,
+				`runner.Run: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -86,6 +96,7 @@ func TestTesting_Run(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.Run: command=echo args=["hi","john"]`,
+				`runner.Run: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -102,6 +113,7 @@ func TestTesting_Run(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.Run: command=echo args=["hi","jane"]`,
+				`runner.Run: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -118,6 +130,7 @@ func TestTesting_Run(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.Run: command=ps args=["-a","-ux"]`,
+				`runner.Run: command=ps exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -136,6 +149,8 @@ func TestTesting_Run(t *testing.T) {
 			wantErr: "exit status 1",
 			wantLog: []string{
 				`runner.Run: command=false args=[]`,
+				`runner.Run: command=false exit=-1 duration=<d> ` +
+					`error="exit status 1"`,
 			},
 		},
 	}
@@ -228,6 +243,7 @@ func TestTesting_RunContext(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.RunContext: command=echo args=["-n","hello world"]`,
+				`runner.RunContext: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -245,6 +261,7 @@ func TestTesting_RunContext(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.RunContext: command=echo args=["hi","john"]`,
+				`runner.RunContext: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -262,6 +279,7 @@ func TestTesting_RunContext(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.RunContext: command=echo args=["hi","jane"]`,
+				`runner.RunContext: command=echo exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -279,6 +297,7 @@ func TestTesting_RunContext(t *testing.T) {
 			},
 			wantLog: []string{
 				`runner.RunContext: command=ps args=["-a","-ux"]`,
+				`runner.RunContext: command=ps exit=0 duration=<d>`,
 			},
 		},
 		{
@@ -298,6 +317,8 @@ func TestTesting_RunContext(t *testing.T) {
 			wantErr: "exit status 1",
 			wantLog: []string{
 				`runner.RunContext: command=false args=[]`,
+				`runner.RunContext: command=false exit=-1 duration=<d> ` +
+					`error="exit status 1"`,
 			},
 		},
 	}
@@ -482,3 +503,265 @@ func TestTesting_Env(t *testing.T) {
 		})
 	}
 }
+
+func TestTesting_RedactKeys(t *testing.T) {
+	ctx := gomockctx.New(context.Background())
+	ctrl := gomock.NewController(t)
+	r := mock_runner.NewMockRunner(ctrl)
+	r.EXPECT().Env([]string{"API_KEY=12345", "HOME=/root"})
+	r.EXPECT().Run(
+		nil, nil, nil, "sudo", []string{"-n", "API_KEY=12345", "--", "ls"},
+	)
+	r.EXPECT().RunContext(
+		gomockctx.Eq(ctx), nil, nil, nil,
+		"myapp", []string{"--db-password=hunter2", "db_password"},
+	)
+
+	ft := &fakeTestingT{}
+	tr := &Testing{
+		Runner:     r,
+		TestingT:   ft,
+		LogEnv:     true,
+		RedactKeys: []string{"API_KEY", "*password"},
+	}
+
+	tr.Env("API_KEY=12345", "HOME=/root")
+	err := tr.Run(nil, nil, nil, "sudo", "-n", "API_KEY=12345", "--", "ls")
+	assert.NoError(t, err)
+	err = tr.RunContext(
+		ctx, nil, nil, nil, "myapp", "--db-password=hunter2", "db_password",
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`runner.Env: vars=["API_KEY=***","HOME=/root"]`,
+		`runner.Run: command=sudo args=["-n","API_KEY=***","--","ls"]`,
+		`runner.Run: command=sudo exit=0 duration=<d>`,
+		`runner.RunContext: command=myapp ` +
+			`args=["--db-password=***","db_password"]`,
+		`runner.RunContext: command=myapp exit=0 duration=<d>`,
+	}, ft.Messages)
+}
+
+func TestRedactEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      []string
+		patterns []string
+		want     []string
+	}{
+		{
+			name: "no patterns",
+			env:  []string{"API_KEY=12345"},
+			want: []string{"API_KEY=12345"},
+		},
+		{
+			name:     "exact key",
+			env:      []string{"API_KEY=12345", "API_KEYS=a,b", "FOO=bar"},
+			patterns: []string{"API_KEY"},
+			want:     []string{"API_KEY=***", "API_KEYS=a,b", "FOO=bar"},
+		},
+		{
+			name:     "glob pattern",
+			env:      []string{"GH_TOKEN=abc", "TOKEN=def", "FOO=bar"},
+			patterns: []string{"*_TOKEN"},
+			want:     []string{"GH_TOKEN=***", "TOKEN=def", "FOO=bar"},
+		},
+		{
+			name:     "case-insensitive",
+			env:      []string{"secret=abc", "Secret=def"},
+			patterns: []string{"SECRET"},
+			want:     []string{"secret=***", "Secret=***"},
+		},
+		{
+			name:     "empty value",
+			env:      []string{"SECRET="},
+			patterns: []string{"SECRET"},
+			want:     []string{"SECRET=***"},
+		},
+		{
+			name:     "not key=value",
+			env:      []string{"SECRET", "=SECRET"},
+			patterns: []string{"SECRET"},
+			want:     []string{"SECRET", "=SECRET"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := append([]string(nil), tt.env...)
+
+			got := RedactEnv(env, tt.patterns)
+
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.env, env)
+		})
+	}
+}
+
+func TestTesting_Calls(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{})
+	f.Respond("false", nil, FakeResponse{Err: errBoom})
+
+	tr := &Testing{Runner: f, TestingT: &fakeTestingT{}}
+	tr.Env("FOO=bar")
+
+	args := []string{"list", "-H"}
+	require.NoError(t, tr.Run(nil, nil, nil, "zfs", args...))
+	args[0] = "changed"
+
+	ctx := withCallEnv(context.Background(), []string{"TZ=UTC"})
+	err := tr.RunContext(ctx, nil, nil, nil, "false")
+	assert.ErrorIs(t, err, errBoom)
+
+	calls := tr.Calls()
+	for i := range calls {
+		assert.Greater(t, calls[i].Duration, time.Duration(0))
+		calls[i].Duration = 0
+	}
+
+	assert.Equal(t, []TestingCall{
+		{
+			Command: "zfs",
+			Args:    []string{"list", "-H"},
+			Env:     []string{"FOO=bar"},
+		},
+		{
+			Command: "false",
+			Env:     []string{"FOO=bar", "TZ=UTC"},
+			Err:     errBoom,
+		},
+	}, calls)
+}
+
+func TestTesting_AssertRan(t *testing.T) {
+	f := &Fake{}
+	f.Respond("*", nil, FakeResponse{})
+
+	tr := &Testing{Runner: f, TestingT: &fakeTestingT{}}
+
+	ft := &fakeExpectT{}
+	assert.False(t, tr.AssertRan(ft, "zfs", "list"))
+	assert.True(t, tr.AssertNotRan(ft, "zfs", "list"))
+	assert.Equal(t, []string{
+		"runner: command not run: zfs list\n\tran: <none>",
+	}, ft.Errors)
+
+	require.NoError(t, tr.Run(nil, nil, nil, "zfs", "list"))
+	require.NoError(t, tr.Run(nil, nil, nil, "zpool", "status", "my pool"))
+
+	ft = &fakeExpectT{}
+	assert.True(t, tr.AssertRan(ft, "zfs", "list"))
+	assert.True(t, tr.AssertRan(ft, "zpool", "status", "my pool"))
+	assert.False(t, tr.AssertRan(ft, "zfs", "list", "-H"))
+	assert.False(t, tr.AssertNotRan(ft, "zfs", "list"))
+	assert.Equal(t, []string{
+		"runner: command not run: zfs list -H\n" +
+			"\tran: zfs list\n" +
+			"\t     zpool status 'my pool'",
+		"runner: command was run: zfs list",
+	}, ft.Errors)
+
+	tr.AssertRan(t, "zfs", "list")
+	tr.AssertNotRan(t, "zfs", "destroy")
+}
+
+func TestTesting_LogOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		max        int
+		nilWriters bool
+		script     string
+		wantStdout string
+		wantLog    []string
+	}{
+		{
+			name:       "stdout and stderr",
+			script:     "echo hello; echo world; echo oops >&2",
+			wantStdout: "hello\nworld\n",
+			wantLog: []string{
+				"runner.Run: command=sh stdout:\nhello\nworld",
+				"runner.Run: command=sh stderr:\noops",
+			},
+		},
+		{
+			name:       "nil writers",
+			nilWriters: true,
+			script:     "echo hello",
+			wantLog: []string{
+				"runner.Run: command=sh stdout:\nhello",
+			},
+		},
+		{
+			name:    "no output",
+			script:  "true",
+			wantLog: []string{},
+		},
+		{
+			name:       "truncated",
+			max:        6,
+			script:     "echo hello; echo world",
+			wantStdout: "hello\nworld\n",
+			wantLog: []string{
+				"runner.Run: command=sh stdout (last 6 of 12 bytes):\nworld",
+			},
+		},
+		{
+			name:       "not truncated",
+			max:        12,
+			script:     "echo hello; echo world",
+			wantStdout: "hello\nworld\n",
+			wantLog: []string{
+				"runner.Run: command=sh stdout:\nhello\nworld",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeTestingT{}
+			tr := &Testing{
+				Runner:       &Local{},
+				TestingT:     ft,
+				LogOutput:    true,
+				MaxLogOutput: tt.max,
+			}
+
+			var stdout bytes.Buffer
+			var err error
+			if tt.nilWriters {
+				err = tr.Run(nil, nil, nil, "sh", "-c", tt.script)
+			} else {
+				err = tr.Run(nil, &stdout, io.Discard, "sh", "-c", tt.script)
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStdout, stdout.String())
+
+			require.Len(t, ft.Messages, len(tt.wantLog)+2)
+			assert.Equal(t, tt.wantLog, ft.Messages[1:len(ft.Messages)-1])
+		})
+	}
+}
+
+func TestTesting_LogOutput_sameWriter(t *testing.T) {
+	ft := &fakeTestingT{}
+	tr := &Testing{Runner: &Local{}, TestingT: ft, LogOutput: true}
+
+	var buf bytes.Buffer
+	err := tr.RunContext(
+		context.Background(), nil, &buf, &buf,
+		"sh", "-c", "echo out; sleep 0.1; echo err >&2",
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "out\nerr\n", buf.String())
+	assert.Equal(t, []string{
+		`runner.RunContext: command=sh ` +
+			`args=["-c","echo out; sleep 0.1; echo err \u003e\u00262"]`,
+		"runner.RunContext: command=sh stdout:\nout",
+		"runner.RunContext: command=sh stderr:\nerr",
+		"runner.RunContext: command=sh exit=0 duration=<d>",
+	}, ft.Messages)
+}
@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// TimeoutRule sets the timeout applied by a Timeout runner to commands
+// matching its PolicyRule.
+type TimeoutRule struct {
+	PolicyRule
+
+	// Timeout is the maximum time matching commands may run for. When zero,
+	// matching commands are run without a timeout being added.
+	Timeout time.Duration
+
+	// Override causes Timeout to replace any deadline of the caller's
+	// context, rather than only bounding it. This allows commands known to
+	// take a long time, like "zpool scrub", to outlive the deadlines of
+	// generic callers. Cancellation of the caller's context is still
+	// honoured.
+	Override bool
+}
+
+// Timeout is a Runner that wraps another Runner, and applies timeouts to
+// commands based on their name and arguments, allowing timeouts to be tuned
+// in one central table rather than at each call site.
+//
+// Commands are run with RunContext on the underlying Runner, with a context
+// which times out after the timeout of the first matching rule, or Default
+// when no rule matches. Unless the rule sets Override, the timeout only
+// bounds the caller's context, so an earlier deadline still applies.
+//
+// Calls to Env are passed directly to the underlying Runner.
+type Timeout struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner Runner
+
+	// Rules sets the timeout of specific commands. The first matching rule is
+	// used.
+	Rules []TimeoutRule
+
+	// Default is the timeout of commands which do not match any of the Rules.
+	// When zero, such commands are run without a timeout being added.
+	Default time.Duration
+}
+
+var _ Runner = &Timeout{}
+
+// Run calls RunContext with a background context.
+//
+// Will panic if Runner field is nil.
+func (r *Timeout) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	if r.rule(command, args).Timeout <= 0 {
+		return r.Runner.Run(stdin, stdout, stderr, command, args...)
+	}
+
+	return r.RunContext(
+		context.Background(), stdin, stdout, stderr, command, args...,
+	)
+}
+
+// RunContext calls RunContext on the underlying Runner, with the timeout of
+// the command applied to ctx.
+//
+// Will panic if Runner field is nil.
+func (r *Timeout) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	rule := r.rule(command, args)
+	if rule.Timeout > 0 {
+		var cancel context.CancelFunc
+		if rule.Override {
+			ctx, cancel = withoutDeadline(ctx)
+			defer cancel()
+		}
+
+		ctx, cancel = context.WithTimeout(ctx, rule.Timeout)
+		defer cancel()
+	}
+
+	return r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Env sets the environment variables for the underlying Runner.
+func (r *Timeout) Env(env ...string) {
+	r.Runner.Env(env...)
+}
+
+func (r *Timeout) rule(command string, args []string) TimeoutRule {
+	for _, rule := range r.Rules {
+		if rule.Match(command, args) {
+			return rule
+		}
+	}
+
+	return TimeoutRule{Timeout: r.Default}
+}
+
+// withoutDeadline returns a context which carries the values of parent, and
+// is canceled when parent is canceled, but not when its deadline is exceeded.
+func withoutDeadline(
+	parent context.Context,
+) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(valuesContext{parent})
+
+	go func() {
+		select {
+		case <-parent.Done():
+			if errors.Is(parent.Err(), context.Canceled) {
+				cancel()
+			}
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// valuesContext is a context which carries the values of the wrapped context,
+// but not its deadline or cancellation.
+type valuesContext struct {
+	context.Context //nolint:containedctx
+}
+
+func (valuesContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (valuesContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (valuesContext) Err() error {
+	return nil
+}
@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type ctxKey struct{}
+
+// deadlineStub returns a Stub which records the remaining time until the
+// deadline of the context each command is run with, or -1 if it has none.
+func deadlineStub(got *time.Duration) *Stub {
+	return &Stub{Fallback: func(
+		ctx context.Context,
+		_ io.Reader,
+		_, _ io.Writer,
+		_ string,
+		_ ...string,
+	) error {
+		*got = -1
+		if deadline, ok := ctx.Deadline(); ok {
+			*got = time.Until(deadline)
+		}
+
+		return ctx.Err()
+	}}
+}
+
+func TestTimeout_RunContext(t *testing.T) {
+	r := &Timeout{
+		Rules: []TimeoutRule{
+			{
+				PolicyRule: PolicyRule{
+					Command: "zpool", Args: []string{"scrub"},
+				},
+				Timeout:  4 * time.Hour,
+				Override: true,
+			},
+			{
+				PolicyRule: PolicyRule{Command: "systemctl"},
+				Timeout:    30 * time.Second,
+			},
+			{PolicyRule: PolicyRule{Command: "tail"}},
+		},
+		Default: time.Minute,
+	}
+
+	tests := []struct {
+		name       string
+		ctxTimeout time.Duration
+		command    string
+		args       []string
+		want       time.Duration
+	}{
+		{
+			name:    "matching rule",
+			command: "systemctl",
+			args:    []string{"restart", "nginx"},
+			want:    30 * time.Second,
+		},
+		{
+			name:       "caller deadline is earlier",
+			ctxTimeout: 10 * time.Second,
+			command:    "systemctl",
+			want:       10 * time.Second,
+		},
+		{
+			name:       "caller deadline is later",
+			ctxTimeout: time.Hour,
+			command:    "systemctl",
+			want:       30 * time.Second,
+		},
+		{
+			name:       "override",
+			ctxTimeout: 10 * time.Second,
+			command:    "zpool",
+			args:       []string{"scrub", "tank"},
+			want:       4 * time.Hour,
+		},
+		{
+			name:    "default",
+			command: "zpool",
+			args:    []string{"status"},
+			want:    time.Minute,
+		},
+		{
+			name:    "rule without timeout",
+			command: "tail",
+			want:    -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got time.Duration
+			r.Runner = deadlineStub(&got)
+
+			ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			err := r.RunContext(ctx, nil, nil, nil, tt.command, tt.args...)
+			require.NoError(t, err)
+
+			if tt.want < 0 {
+				assert.Equal(t, tt.want, got)
+			} else {
+				assert.InDelta(t, tt.want, got, float64(time.Second))
+			}
+		})
+	}
+}
+
+func TestTimeout_RunContext_override(t *testing.T) {
+	r := &Timeout{
+		Rules: []TimeoutRule{
+			{
+				PolicyRule: PolicyRule{Command: "sleep"},
+				Timeout:    time.Minute,
+				Override:   true,
+			},
+		},
+	}
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 20*time.Millisecond,
+		)
+		defer cancel()
+		ctx = context.WithValue(ctx, ctxKey{}, "v")
+
+		r.Runner = &Stub{Fallback: func(
+			rctx context.Context,
+			_ io.Reader,
+			_, _ io.Writer,
+			_ string,
+			_ ...string,
+		) error {
+			assert.Equal(t, "v", rctx.Value(ctxKey{}))
+
+			select {
+			case <-rctx.Done():
+				return rctx.Err()
+			case <-time.After(100 * time.Millisecond):
+				return nil
+			}
+		}}
+
+		err := r.RunContext(ctx, nil, nil, nil, "sleep")
+		assert.NoError(t, err)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		r.Runner = &Stub{Fallback: func(
+			rctx context.Context,
+			_ io.Reader,
+			_, _ io.Writer,
+			_ string,
+			_ ...string,
+		) error {
+			select {
+			case <-rctx.Done():
+				return rctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		}}
+
+		err := r.RunContext(ctx, nil, nil, nil, "sleep")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTimeout_Run(t *testing.T) {
+	var got time.Duration
+	r := &Timeout{Runner: deadlineStub(&got), Default: time.Minute}
+
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+	assert.InDelta(t, time.Minute, got, float64(time.Second))
+
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Run(nil, nil, nil, "true", []string{}).Return(nil)
+
+	r = &Timeout{Runner: m}
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+}
+
+func TestTimeout_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar"})
+
+	r := &Timeout{Runner: m}
+	r.Env("FOO=bar")
+}
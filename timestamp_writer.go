@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultTimestampLayout is the default value for TimestampWriter.Layout.
+const DefaultTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// TimestampWriter is an io.Writer which prefixes each line written to it with
+// the time its first byte was written, before forwarding it to the underlying
+// writer. For example:
+//
+//	2024-03-01T12:00:00.000Z +1.250s Installing packages...
+//
+// Output is forwarded as it is written, so partial lines, like progress
+// output, are not held back. TimestampWriter is safe for concurrent use,
+// though concurrent writers may interleave partial lines.
+type TimestampWriter struct {
+	// Layout is the time layout used to format timestamps, as supported by
+	// time.Time.Format. Defaults to DefaultTimestampLayout.
+	Layout string
+
+	// UTC indicates if timestamps should be formatted in UTC rather than
+	// local time.
+	UTC bool
+
+	// Delta indicates if the time elapsed since the TimestampWriter was
+	// created should follow the timestamp, like "+1.250s". It is measured
+	// with the monotonic clock, so is not affected by changes to the wall
+	// clock.
+	Delta bool
+
+	w   io.Writer
+	now func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	midLine bool
+	buf     bytes.Buffer
+}
+
+var _ io.Writer = &TimestampWriter{}
+
+// NewTimestampWriter returns a TimestampWriter which writes to w.
+func NewTimestampWriter(w io.Writer) *TimestampWriter {
+	tw := &TimestampWriter{w: w, now: time.Now}
+	tw.start = tw.now()
+
+	return tw
+}
+
+// Write prefixes any lines started in p with a timestamp, and writes them to
+// the underlying writer with a single call to its Write method.
+func (tw *TimestampWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.buf.Reset()
+	for rest := p; len(rest) > 0; {
+		if !tw.midLine {
+			tw.writePrefix()
+		}
+
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			tw.buf.Write(rest)
+			tw.midLine = true
+
+			break
+		}
+
+		tw.buf.Write(rest[:i+1])
+		rest = rest[i+1:]
+		tw.midLine = false
+	}
+
+	_, err := tw.w.Write(tw.buf.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (tw *TimestampWriter) writePrefix() {
+	now := tw.now()
+
+	t := now
+	if tw.UTC {
+		t = t.UTC()
+	}
+
+	layout := tw.Layout
+	if layout == "" {
+		layout = DefaultTimestampLayout
+	}
+
+	tw.buf.WriteString(t.Format(layout))
+	if tw.Delta {
+		fmt.Fprintf(&tw.buf, " +%.3fs", now.Sub(tw.start).Seconds())
+	}
+	tw.buf.WriteByte(' ')
+}
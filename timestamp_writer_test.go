@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampWriter(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		layout string
+		delta  bool
+		writes []string
+		want   string
+	}{
+		{
+			name:   "lines",
+			writes: []string{"hello\nworld\n"},
+			want: "2024-03-01T12:00:01.000Z hello\n" +
+				"2024-03-01T12:00:01.000Z world\n",
+		},
+		{
+			name:   "partial lines",
+			writes: []string{"hel", "lo\nwor", "ld", "\n", "\n", "end"},
+			want: "2024-03-01T12:00:01.000Z hello\n" +
+				"2024-03-01T12:00:02.000Z world\n" +
+				"2024-03-01T12:00:05.000Z \n" +
+				"2024-03-01T12:00:06.000Z end",
+		},
+		{
+			name:   "empty write",
+			writes: []string{"", "a\n", ""},
+			want:   "2024-03-01T12:00:02.000Z a\n",
+		},
+		{
+			name:   "delta",
+			layout: time.Kitchen,
+			delta:  true,
+			writes: []string{"one\n", "two\n"},
+			want:   "12:00PM +1.000s one\n12:00PM +2.000s two\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := NewTimestampWriter(&buf)
+			tw.Layout = tt.layout
+			tw.Delta = tt.delta
+			tw.UTC = true
+
+			now := start
+			tw.start = start
+			tw.now = func() time.Time { return now }
+
+			for _, s := range tt.writes {
+				now = now.Add(time.Second)
+
+				n, err := tw.Write([]byte(s))
+				assert.NoError(t, err)
+				assert.Equal(t, len(s), n)
+			}
+
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTimestampWriter_error(t *testing.T) {
+	errBoom := errors.New("boom")
+	tw := NewTimestampWriter(&errWriter{err: errBoom})
+
+	n, err := tw.Write([]byte("hello\n"))
+	assert.ErrorIs(t, err, errBoom)
+	assert.Zero(t, n)
+}
+
+func TestTimestampWriter_Local(t *testing.T) {
+	var buf bytes.Buffer
+	tw := NewTimestampWriter(&buf)
+	tw.Layout = "2006"
+
+	err := (&Local{}).Run(nil, tw, nil, "printf", "a\\nb\\n")
+	assert.NoError(t, err)
+
+	year := time.Now().Format("2006")
+	assert.Equal(t, year+" a\n"+year+" b\n", buf.String())
+}
@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// TranscriptChunk is a chunk of output recorded by a Transcript.
+type TranscriptChunk struct {
+	// Stream is the stream the output was written to, "stdout" or "stderr".
+	Stream string
+
+	// Data is the output.
+	Data []byte
+
+	// Time is the time the first byte of the chunk was written.
+	Time time.Time
+}
+
+// Transcript records the stdout and stderr output of a command into a single
+// ordered list of chunks, tagged with the stream they were written to. Unlike
+// capturing each stream to a separate buffer, this preserves the order in
+// which output was written across streams.
+//
+// Consecutive writes to the same stream are merged into one chunk. As Local
+// reads stdout and stderr from separate pipes, the recorded order reflects
+// when output was read, and output written by a command in quick succession
+// to both streams may appear out of order.
+//
+// Transcript is safe for concurrent use, and its zero value is ready to use.
+type Transcript struct {
+	mu     sync.Mutex
+	chunks []TranscriptChunk
+}
+
+// CaptureTranscript runs the given command via RunContext on r, and returns a
+// Transcript of its output.
+func CaptureTranscript(
+	ctx context.Context,
+	r Runner,
+	command string,
+	args ...string,
+) (*Transcript, error) {
+	t := &Transcript{}
+	err := r.RunContext(ctx, nil, t.Stdout(), t.Stderr(), command, args...)
+
+	return t, err
+}
+
+// Stdout returns an io.Writer which records output to the "stdout" stream.
+func (t *Transcript) Stdout() io.Writer {
+	return &transcriptWriter{t: t, stream: "stdout"}
+}
+
+// Stderr returns an io.Writer which records output to the "stderr" stream.
+func (t *Transcript) Stderr() io.Writer {
+	return &transcriptWriter{t: t, stream: "stderr"}
+}
+
+// Chunks returns the recorded chunks, in the order they were written.
+func (t *Transcript) Chunks() []TranscriptChunk {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	chunks := make([]TranscriptChunk, len(t.chunks))
+	for i, c := range t.chunks {
+		c.Data = append([]byte(nil), c.Data...)
+		chunks[i] = c
+	}
+
+	return chunks
+}
+
+// Bytes returns the combined output of all streams, in the order it was
+// written.
+func (t *Transcript) Bytes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b []byte
+	for _, c := range t.chunks {
+		b = append(b, c.Data...)
+	}
+
+	return b
+}
+
+// String returns the combined output of all streams as a string.
+func (t *Transcript) String() string {
+	return string(t.Bytes())
+}
+
+// StreamBytes returns the output written to stream, "stdout" or "stderr".
+func (t *Transcript) StreamBytes(stream string) []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b []byte
+	for _, c := range t.chunks {
+		if c.Stream == stream {
+			b = append(b, c.Data...)
+		}
+	}
+
+	return b
+}
+
+// replayChunks writes each chunk in order to stdout or stderr, according to
+// its stream, skipping those for a nil writer, and returns the first error.
+func replayChunks(chunks []TranscriptChunk, stdout, stderr io.Writer) error {
+	for _, chunk := range chunks {
+		w := stdout
+		if chunk.Stream == "stderr" {
+			w = stderr
+		}
+		if w == nil {
+			continue
+		}
+
+		_, err := w.Write(chunk.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Transcript) write(stream string, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n := len(t.chunks); n > 0 && t.chunks[n-1].Stream == stream {
+		t.chunks[n-1].Data = append(t.chunks[n-1].Data, p...)
+
+		return
+	}
+
+	t.chunks = append(t.chunks, TranscriptChunk{
+		Stream: stream,
+		Data:   append([]byte(nil), p...),
+		Time:   time.Now(),
+	})
+}
+
+type transcriptWriter struct {
+	t      *Transcript
+	stream string
+}
+
+func (w *transcriptWriter) Write(p []byte) (int, error) {
+	w.t.write(w.stream, p)
+
+	return len(p), nil
+}
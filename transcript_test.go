@@ -0,0 +1,78 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	tr := &Transcript{}
+	stdout, stderr := tr.Stdout(), tr.Stderr()
+
+	before := time.Now()
+	for _, w := range []struct {
+		w io.Writer
+		s string
+	}{
+		{stdout, "Connecting..."},
+		{stdout, "\n"},
+		{stderr, "warning: slow\n"},
+		{stdout, ""},
+		{stderr, "error: "},
+		{stderr, "refused\n"},
+		{stdout, "Retrying\n"},
+	} {
+		_, err := io.WriteString(w.w, w.s)
+		require.NoError(t, err)
+	}
+
+	chunks := tr.Chunks()
+	require.Len(t, chunks, 3)
+	for i := range chunks {
+		assert.False(t, chunks[i].Time.Before(before))
+		chunks[i].Time = time.Time{}
+	}
+	assert.Equal(t, []TranscriptChunk{
+		{Stream: "stdout", Data: []byte("Connecting...\n")},
+		{Stream: "stderr", Data: []byte("warning: slow\nerror: refused\n")},
+		{Stream: "stdout", Data: []byte("Retrying\n")},
+	}, chunks)
+
+	assert.Equal(t,
+		"Connecting...\nwarning: slow\nerror: refused\nRetrying\n",
+		tr.String(),
+	)
+	assert.Equal(t,
+		"Connecting...\nRetrying\n", string(tr.StreamBytes("stdout")),
+	)
+	assert.Equal(t,
+		"warning: slow\nerror: refused\n", string(tr.StreamBytes("stderr")),
+	)
+
+	// Chunks returns copies.
+	chunks[0].Data[0] = 'X'
+	assert.Equal(t, byte('C'), tr.Bytes()[0])
+}
+
+func TestCaptureTranscript(t *testing.T) {
+	tr, err := CaptureTranscript(
+		context.Background(), &Local{}, "sh", "-c",
+		"echo one; sleep 0.1; echo two >&2; sleep 0.1; echo three; exit 3",
+	)
+	assert.EqualError(t, err, "sh: exit status 3")
+
+	chunks := tr.Chunks()
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "stdout", chunks[0].Stream)
+	assert.Equal(t, "one\n", string(chunks[0].Data))
+	assert.Equal(t, "stderr", chunks[1].Stream)
+	assert.Equal(t, "two\n", string(chunks[1].Data))
+	assert.Equal(t, "stdout", chunks[2].Stream)
+	assert.Equal(t, "three\n", string(chunks[2].Data))
+	assert.Equal(t, "one\ntwo\nthree\n", tr.String())
+}
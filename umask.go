@@ -0,0 +1,7 @@
+package runner
+
+import "fmt"
+
+var ErrUmaskUnsupported = fmt.Errorf(
+	"%w: umask: not supported on this platform", Err,
+)
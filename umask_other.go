@@ -0,0 +1,12 @@
+//go:build !unix
+
+package runner
+
+import (
+	"os"
+	"os/exec"
+)
+
+func applyUmask(*exec.Cmd, os.FileMode) error {
+	return ErrUmaskUnsupported
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// applyUmask rewrites cmd to be executed via a shell, which sets its umask to
+// mask before executing the original command. This avoids changing the umask
+// of the current process, which is shared by all goroutines.
+func applyUmask(cmd *exec.Cmd, mask os.FileMode) error {
+	script := fmt.Sprintf(`umask %04o && exec "$@"`, mask.Perm())
+
+	args := make([]string, 0, len(cmd.Args)+4)
+	args = append(args, "sh", "-c", script, "sh", cmd.Path)
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+
+	return nil
+}
@@ -0,0 +1,71 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_umask(t *testing.T) {
+	tests := []struct {
+		name     string
+		umask    os.FileMode
+		wantMode os.FileMode
+	}{
+		{name: "0077", umask: 0o077, wantMode: 0o600},
+		{name: "0027", umask: 0o027, wantMode: 0o640},
+		{name: "0000", umask: 0o000, wantMode: 0o666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "file")
+			umask := tt.umask
+			r := &Local{Umask: &umask}
+
+			before := syscall.Umask(0o022)
+			syscall.Umask(before)
+
+			var stdout bytes.Buffer
+			err := r.Run(
+				nil, &stdout, nil, "sh", "-c", `umask; touch "$0"`, file,
+			)
+			require.NoError(t, err)
+
+			after := syscall.Umask(0o022)
+			syscall.Umask(after)
+
+			assert.Equal(t, tt.name+"\n", stdout.String())
+			fi, err := os.Stat(file)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMode, fi.Mode().Perm())
+			assert.Equal(t, before, after, "umask of current process changed")
+		})
+	}
+}
+
+func TestLocal_Run_umaskErrors(t *testing.T) {
+	umask := os.FileMode(0o077)
+	r := &Local{Umask: &umask}
+
+	err := r.Run(nil, nil, nil, "sh", "-c", "exit 3")
+
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, "sh", exitErr.Command)
+	assert.Equal(t, []string{"-c", "exit 3"}, exitErr.Args)
+	assert.Equal(t, 3, exitErr.ExitCode())
+
+	err = r.Run(nil, nil, nil, "/nonexistent/runner-test-command")
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+
+	r.Dir = t.TempDir()
+	err = r.Run(nil, nil, nil, "./runner-test-command")
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+}
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+var (
+	ErrURL            = fmt.Errorf("%w: url", Err)
+	ErrURLInvalid     = fmt.Errorf("%w: invalid", ErrURL)
+	ErrURLUnsupported = fmt.Errorf("%w: unsupported scheme", ErrURL)
+)
+
+// FromURL returns a Runner configured by a target URL, like those given by
+// users on the command line. The supported schemes are:
+//
+//	local://                                  runs commands locally, via New
+//	ssh://[user@]host[:port][/dir]            runs commands via SSHCLI
+//	docker://[user@]container[/dir]           runs commands via Docker
+//
+// For ssh, the "identity" query parameter sets the identity file, like
+// "ssh://deploy@web1?identity=/keys/deploy". The path, when given, is the
+// working directory commands are run in, like "docker://app/srv/app".
+//
+// For all schemes, the "sudo" query parameter wraps the runner with Sudo, so
+// commands run as the given user, or root when it has no value, like
+// "ssh://web1?sudo" or "local://?sudo=postgres".
+//
+// Unknown query parameters are rejected, and the returned runner is checked
+// with Validate, so mistakes in the URL are reported early, rather than when a
+// command is run.
+func FromURL(rawURL string) (Runner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrURLInvalid, err)
+	}
+
+	if _, ok := u.User.Password(); ok {
+		return nil, fmt.Errorf(
+			"%w: passwords are not supported", ErrURLInvalid,
+		)
+	}
+
+	query := u.Query()
+	sudoUser, sudo := query.Get("sudo"), query.Has("sudo")
+	query.Del("sudo")
+
+	var r Runner
+	switch u.Scheme {
+	case "local":
+		if u.Host != "" || u.User != nil || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf(
+				"%w: local takes no host or path: %s", ErrURLInvalid, rawURL,
+			)
+		}
+		r = New()
+	case "ssh":
+		r, err = sshFromURL(u, query)
+	case "docker":
+		r, err = dockerFromURL(u)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrURLUnsupported, u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range query {
+		if key != "identity" || u.Scheme != "ssh" {
+			return nil, fmt.Errorf(
+				"%w: unknown query parameter %q", ErrURLInvalid, key,
+			)
+		}
+	}
+
+	if sudo {
+		r = &Sudo{Runner: r, User: sudoUser}
+	}
+
+	if err := Validate(r); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrURLInvalid, err)
+	}
+
+	return r, nil
+}
+
+func sshFromURL(u *url.URL, query url.Values) (Runner, error) {
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("%w: ssh requires a host", ErrURLInvalid)
+	}
+
+	r := &SSHCLI{
+		Runner:       New(),
+		Destination:  u.Hostname(),
+		Login:        u.User.Username(),
+		IdentityFile: query.Get("identity"),
+		Dir:          urlDir(u),
+	}
+
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("%w: invalid port %q", ErrURLInvalid, p)
+		}
+		r.Port = port
+	}
+
+	return r, nil
+}
+
+func dockerFromURL(u *url.URL) (Runner, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf(
+			"%w: docker requires a container", ErrURLInvalid,
+		)
+	}
+
+	return &Docker{
+		Runner:    New(),
+		Container: u.Host,
+		User:      u.User.Username(),
+		Dir:       urlDir(u),
+	}, nil
+}
+
+// urlDir returns the path of u as a working directory, or an empty string if
+// it has no path.
+func urlDir(u *url.URL) string {
+	if u.Path == "/" {
+		return ""
+	}
+
+	return u.Path
+}
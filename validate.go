@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalid is matched by errors returned by the Validate methods of
+// runners, when their configuration is invalid.
+var ErrInvalid = fmt.Errorf("%w: invalid", Err)
+
+// Validator is implemented by runners which can check their configuration
+// before any command is run, like SSHCLI and Sudo. Wrapper runners should
+// implement it to report missing required fields, conflicting options, and
+// values which would be misinterpreted by the command they wrap.
+type Validator interface {
+	Validate() error
+}
+
+// Validate validates r if it implements Validator. Otherwise, when r is a
+// pointer to a struct with a Runner field, like most wrapper runners, it
+// checks the field is set and validates the runner it holds, so layers
+// beneath runners which do not implement Validator are still validated.
+func Validate(r Runner) error {
+	if v, ok := r.(Validator); ok {
+		return v.Validate()
+	}
+
+	underlying, name, ok := wrappedRunner(r)
+	if !ok {
+		return nil
+	}
+	if err := validateRunner(name+".Runner", underlying); err != nil {
+		return err
+	}
+
+	return Validate(underlying)
+}
+
+var runnerType = reflect.TypeOf((*Runner)(nil)).Elem()
+
+// wrappedRunner returns the value of the Runner field of r, and the name of
+// its type, if r is a pointer to a struct with such a field.
+func wrappedRunner(r Runner) (Runner, string, bool) {
+	v := reflect.ValueOf(r)
+	if v.Kind() != reflect.Pointer || v.IsNil() ||
+		v.Elem().Kind() != reflect.Struct {
+		return nil, "", false
+	}
+
+	f := v.Elem().FieldByName("Runner")
+	if !f.IsValid() || f.Type() != runnerType {
+		return nil, "", false
+	}
+	underlying, _ := f.Interface().(Runner)
+
+	return underlying, v.Elem().Type().Name(), true
+}
+
+// ValidationError describes an invalid field of a runner. It matches
+// ErrInvalid with errors.Is, and Err when set.
+type ValidationError struct {
+	// Field is the name of the invalid field, like "SSHCLI.Destination".
+	Field string
+
+	// Msg describes the problem with the field.
+	Msg string
+
+	// Err is a more specific error matched by errors.Is, like
+	// ErrSSHCLINoDestination, or nil.
+	Err error
+}
+
+var _ error = &ValidationError{}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrInvalid.Error(), e.Field, e.Msg)
+}
+
+// Unwrap returns ErrInvalid, and Err when set.
+func (e *ValidationError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrInvalid}
+	}
+
+	return []error{ErrInvalid, e.Err}
+}
+
+// validateRunner checks the underlying Runner of a wrapper is set.
+func validateRunner(field string, r Runner) error {
+	if r == nil {
+		return &ValidationError{Field: field, Msg: "must be set"}
+	}
+
+	return nil
+}
+
+// validateWord checks value can be safely passed as a single argument to a
+// wrapping command, which may be interpreted by a remote shell. It must not
+// contain whitespace or control characters, and must not begin with "-", so
+// it is not mistaken for a flag.
+func validateWord(field string, value string) error {
+	if strings.HasPrefix(value, "-") {
+		return &ValidationError{Field: field, Msg: "must not begin with -"}
+	}
+
+	return validateChars(field, value, true)
+}
+
+// validateChars checks value contains no control characters, and when
+// noSpace is true, no whitespace.
+func validateChars(field string, value string, noSpace bool) error {
+	for _, c := range value {
+		if unicode.IsControl(c) || (noSpace && unicode.IsSpace(c)) {
+			return &ValidationError{
+				Field: field,
+				Msg:   fmt.Sprintf("contains illegal character %q", c),
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateEnv checks each entry of env is of the form "key=value", with a key
+// which contains no whitespace or control characters, and does not begin with
+// "-".
+func validateEnv(field string, env []string) error {
+	for i, kv := range env {
+		f := fmt.Sprintf("%s[%d]", field, i)
+		k, _, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return &ValidationError{
+				Field: f,
+				Msg:   fmt.Sprintf("%q is not of the form key=value", kv),
+			}
+		}
+		if err := validateWord(f, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validatePort checks port is a valid TCP port, or zero.
+func validatePort(field string, port int) error {
+	if port < 0 || port > 65535 {
+		return &ValidationError{
+			Field: field,
+			Msg:   fmt.Sprintf("invalid port %d", port),
+		}
+	}
+
+	return nil
+}
+
+// firstError returns the first non-nil error.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
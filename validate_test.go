@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&Local{}))
+	assert.NoError(t, Validate(&Sudo{Runner: &Local{}}))
+
+	err := Validate(&Sudo{})
+	assert.EqualError(t, err, "runner: invalid: Sudo.Runner: must be set")
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	var vErr *ValidationError
+	require.ErrorAs(t, err, &vErr)
+	assert.Equal(t, "Sudo.Runner", vErr.Field)
+}
+
+func TestValidate_nonValidator(t *testing.T) {
+	assert.NoError(t, Validate(&Retry{Runner: &Sudo{Runner: &Local{}}}))
+	assert.NoError(t, Validate(&Fake{}))
+
+	assert.EqualError(
+		t, Validate(&Retry{}), "runner: invalid: Retry.Runner: must be set",
+	)
+	assert.EqualError(
+		t, Validate(&Timeout{Runner: &Retry{Runner: &Sudo{}}}),
+		"runner: invalid: Sudo.Runner: must be set",
+	)
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{
+		Field: "SSHCLI.Destination",
+		Msg:   "must be set",
+		Err:   ErrSSHCLINoDestination,
+	}
+
+	assert.EqualError(
+		t, err, "runner: invalid: SSHCLI.Destination: must be set",
+	)
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorIs(t, err, ErrSSHCLINoDestination)
+}
+
+func TestValidateHelpers(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr string
+	}{
+		{
+			name: "word",
+			err:  validateWord("F", "deploy"),
+		},
+		{
+			name: "empty word",
+			err:  validateWord("F", ""),
+		},
+		{
+			name:    "word with flag",
+			err:     validateWord("F", "-oProxyCommand=sh"),
+			wantErr: "runner: invalid: F: must not begin with -",
+		},
+		{
+			name:    "word with space",
+			err:     validateWord("F", "web1 rm"),
+			wantErr: `runner: invalid: F: contains illegal character ' '`,
+		},
+		{
+			name:    "word with newline",
+			err:     validateWord("F", "web1\nrm"),
+			wantErr: `runner: invalid: F: contains illegal character '\n'`,
+		},
+		{
+			name: "chars with space",
+			err:  validateChars("F", "/srv/my app", false),
+		},
+		{
+			name:    "chars with control character",
+			err:     validateChars("F", "/srv/app\x00", false),
+			wantErr: `runner: invalid: F: contains illegal character '\x00'`,
+		},
+		{
+			name: "env",
+			err:  validateEnv("F", []string{"A=1", "B=two words", "C="}),
+		},
+		{
+			name:    "env without value",
+			err:     validateEnv("F", []string{"A=1", "B"}),
+			wantErr: `runner: invalid: F[1]: "B" is not of the form key=value`,
+		},
+		{
+			name:    "env without key",
+			err:     validateEnv("F", []string{"=1"}),
+			wantErr: `runner: invalid: F[0]: "=1" is not of the form key=value`,
+		},
+		{
+			name:    "env key with flag",
+			err:     validateEnv("F", []string{"-u=root"}),
+			wantErr: "runner: invalid: F[0]: must not begin with -",
+		},
+		{
+			name: "port",
+			err:  validatePort("F", 22),
+		},
+		{
+			name:    "invalid port",
+			err:     validatePort("F", 65536),
+			wantErr: "runner: invalid: F: invalid port 65536",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantErr == "" {
+				assert.NoError(t, tt.err)
+
+				return
+			}
+
+			assert.EqualError(t, tt.err, tt.wantErr)
+			assert.ErrorIs(t, tt.err, ErrInvalid)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrWatchdog            = fmt.Errorf("%w: watchdog", Err)
+	ErrWatchdogUnsupported = fmt.Errorf(
+		"%w: not supported on this platform", ErrWatchdog,
+	)
+)
+
+// DefaultWatchdogInterval is the default value for Watchdog.Interval.
+const DefaultWatchdogInterval = time.Second
+
+// Resources monitored by a Watchdog, as reported by WatchdogError.Resource.
+const (
+	WatchdogRSS = "rss"
+	WatchdogCPU = "cpu"
+)
+
+// Watchdog describes resource usage thresholds for commands executed by
+// Local. The resource usage of each command's process is sampled
+// periodically, and it is killed if any threshold is exceeded, causing a
+// *WatchdogError to be returned.
+//
+// Unlike Cgroup and RLimits, which are enforced by the kernel, the watchdog
+// only notices usage exceeding thresholds when it samples it, and only
+// monitors the process started, not its child processes. When Local has
+// KillProcessGroup set, the whole process group is killed. The watchdog is
+// only supported on Linux, where usage is read from /proc.
+type Watchdog struct {
+	// MaxRSS is the maximum resident set size of the process in bytes. When
+	// 0, memory usage is not limited.
+	MaxRSS int64
+
+	// MaxCPU is the maximum CPU time, user and system combined, the process
+	// may use. When 0, CPU time is not limited.
+	MaxCPU time.Duration
+
+	// Interval is how often resource usage is sampled. Defaults to
+	// DefaultWatchdogInterval.
+	Interval time.Duration
+}
+
+// WatchdogError is returned by Local when a command is killed by its
+// Watchdog. It matches ErrWatchdog with errors.Is, and the error returned by
+// the killed command with errors.Is and errors.As.
+type WatchdogError struct {
+	// Resource is the resource which exceeded its threshold, WatchdogRSS or
+	// WatchdogCPU.
+	Resource string
+
+	// Limit is the threshold which was exceeded, in bytes for WatchdogRSS,
+	// and nanoseconds for WatchdogCPU.
+	Limit int64
+
+	// Usage is the sampled usage which exceeded Limit, in the same unit.
+	Usage int64
+
+	// Err is the error returned by the killed command.
+	Err error
+}
+
+var _ error = &WatchdogError{}
+
+func (e *WatchdogError) Error() string {
+	usage, limit := fmt.Sprint(e.Usage), fmt.Sprint(e.Limit)
+	if e.Resource == WatchdogCPU {
+		usage = time.Duration(e.Usage).String()
+		limit = time.Duration(e.Limit).String()
+	}
+
+	msg := fmt.Sprintf(
+		"%s: %s usage %s exceeded limit %s",
+		ErrWatchdog.Error(), e.Resource, usage, limit,
+	)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns ErrWatchdog, and the error returned by the killed command.
+func (e *WatchdogError) Unwrap() []error {
+	return []error{ErrWatchdog, e.Err}
+}
+
+// watch samples the resource usage of p until the returned function is
+// called, killing p, or its process group when group is true, if a threshold
+// is exceeded. The returned function must be called once p has exited, and
+// returns a *WatchdogError if p was killed.
+func (w *Watchdog) watch(p *os.Process, group bool) func() *WatchdogError {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchdogInterval
+	}
+
+	var result *WatchdogError
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			// Sampling fails once the process has exited, which Wait will
+			// report shortly.
+			rss, cpu, err := processUsage(p.Pid)
+			if err != nil {
+				continue
+			}
+
+			switch {
+			case w.MaxRSS > 0 && rss > w.MaxRSS:
+				result = &WatchdogError{
+					Resource: WatchdogRSS, Limit: w.MaxRSS, Usage: rss,
+				}
+			case w.MaxCPU > 0 && cpu > w.MaxCPU:
+				result = &WatchdogError{
+					Resource: WatchdogCPU,
+					Limit:    int64(w.MaxCPU),
+					Usage:    int64(cpu),
+				}
+			default:
+				continue
+			}
+
+			if group {
+				_ = signalProcessGroup(p, syscall.SIGKILL)
+			} else {
+				_ = p.Kill()
+			}
+
+			return
+		}
+	}()
+
+	return func() *WatchdogError {
+		close(done)
+		<-stopped
+
+		return result
+	}
+}
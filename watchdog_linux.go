@@ -0,0 +1,55 @@
+//go:build linux
+
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// clockTicks is the unit of CPU times in /proc/<pid>/stat. It is 100 on all
+// Linux architectures, regardless of the kernel's internal tick rate.
+const clockTicks = 100
+
+func checkWatchdogSupported() error {
+	return nil
+}
+
+// processUsage returns the resident set size in bytes, and the combined user
+// and system CPU time, of the process with the given pid.
+func processUsage(pid int) (int64, time.Duration, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The command name in field 2 may contain spaces, so fields are counted
+	// from the closing parenthesis which ends it.
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, 0, fmt.Errorf("%w: invalid stat for pid %d", ErrWatchdog, pid)
+	}
+	fields := bytes.Fields(b[i+1:])
+
+	// Fields following the command name start at field 3 (state), so field
+	// n is at index n-3.
+	const utime, stime, rss = 14 - 3, 15 - 3, 24 - 3
+	if len(fields) <= rss {
+		return 0, 0, fmt.Errorf("%w: invalid stat for pid %d", ErrWatchdog, pid)
+	}
+
+	values := make([]int64, 3)
+	for j, n := range []int{utime, stime, rss} {
+		values[j], err = strconv.ParseInt(string(fields[n]), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %w", ErrWatchdog, err)
+		}
+	}
+
+	cpu := time.Duration(values[0]+values[1]) * time.Second / clockTicks
+
+	return values[2] * int64(os.Getpagesize()), cpu, nil
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package runner
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Run_watchdog(t *testing.T) {
+	tests := []struct {
+		name         string
+		watchdog     *Watchdog
+		args         []string
+		wantResource string
+	}{
+		{
+			name: "rss exceeded",
+			watchdog: &Watchdog{
+				MaxRSS:   32 << 20,
+				Interval: 10 * time.Millisecond,
+			},
+			// tail buffers its input in memory until it sees a newline,
+			// which /dev/zero never provides.
+			args:         []string{"tail", "/dev/zero"},
+			wantResource: WatchdogRSS,
+		},
+		{
+			name: "cpu exceeded",
+			watchdog: &Watchdog{
+				MaxCPU:   100 * time.Millisecond,
+				Interval: 10 * time.Millisecond,
+			},
+			args:         []string{"sh", "-c", "while :; do :; done"},
+			wantResource: WatchdogCPU,
+		},
+		{
+			name: "within thresholds",
+			watchdog: &Watchdog{
+				MaxRSS:   1 << 30,
+				MaxCPU:   time.Minute,
+				Interval: 10 * time.Millisecond,
+			},
+			args: []string{"sleep", "0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Local{Watchdog: tt.watchdog}
+
+			start := time.Now()
+			err := r.Run(nil, nil, nil, tt.args[0], tt.args[1:]...)
+
+			if tt.wantResource == "" {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var wdErr *WatchdogError
+			require.ErrorAs(t, err, &wdErr)
+			assert.ErrorIs(t, err, ErrWatchdog)
+			assert.Equal(t, tt.wantResource, wdErr.Resource)
+			assert.Greater(t, wdErr.Usage, wdErr.Limit)
+			assert.Equal(t, -1, ExitCode(err))
+
+			var exitErr *ExitError
+			assert.ErrorAs(t, err, &exitErr)
+			assert.Less(t, time.Since(start), 10*time.Second)
+		})
+	}
+}
+
+func TestProcessUsage(t *testing.T) {
+	rss, cpu, err := processUsage(os.Getpid())
+	require.NoError(t, err)
+
+	assert.Greater(t, rss, int64(0))
+	assert.GreaterOrEqual(t, cpu, time.Duration(0))
+
+	_, _, err = processUsage(-1)
+	assert.Error(t, err)
+}
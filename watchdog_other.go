@@ -0,0 +1,13 @@
+//go:build !linux
+
+package runner
+
+import "time"
+
+func checkWatchdogSupported() error {
+	return ErrWatchdogUnsupported
+}
+
+func processUsage(int) (int64, time.Duration, error) {
+	return 0, 0, ErrWatchdogUnsupported
+}
@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdogError(t *testing.T) {
+	exitErr := &FakeExitError{Command: "plugin", Code: 137}
+
+	tests := []struct {
+		name string
+		err  *WatchdogError
+		want string
+	}{
+		{
+			name: "rss",
+			err: &WatchdogError{
+				Resource: WatchdogRSS,
+				Limit:    1024,
+				Usage:    4096,
+				Err:      exitErr,
+			},
+			want: "runner: watchdog: rss usage 4096 exceeded limit 1024: " +
+				"plugin: exit status 137",
+		},
+		{
+			name: "cpu",
+			err: &WatchdogError{
+				Resource: WatchdogCPU,
+				Limit:    int64(time.Second),
+				Usage:    int64(1500 * time.Millisecond),
+			},
+			want: "runner: watchdog: cpu usage 1.5s exceeded limit 1s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.err, tt.want)
+			assert.ErrorIs(t, tt.err, ErrWatchdog)
+			if tt.err.Err != nil {
+				var fe *FakeExitError
+				assert.True(t, errors.As(tt.err, &fe))
+				assert.Equal(t, 137, ExitCode(tt.err))
+			}
+		})
+	}
+}
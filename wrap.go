@@ -0,0 +1,122 @@
+package runner
+
+import (
+	"context"
+	"io"
+)
+
+// Stack is a Runner made up of layers of runners, each wrapping the one
+// beneath it, as built by Wrap. Commands are run via the outermost layer.
+//
+// Calling Env on a Stack sets the environment of the commands run through it,
+// and of nothing else. It is only called on the outermost layer, where each
+// kind of runner provides the variables to the command it runs:
+//
+//   - Local sets them as the environment of the command's process.
+//   - Sudo, SSHCLI, Docker, Jexec, and Bwrap pass them as arguments, to
+//     sudo, the env command on the remote host, docker exec, the env command
+//     in the jail, and bwrap, respectively. The wrapping command itself, like
+//     sudo or ssh, runs with the environment of the layer beneath it, which
+//     is unaffected.
+//   - Other runners, like Retry or Timeout, which do not change the command,
+//     call Env on the runner they wrap.
+//
+// To configure the environment of an intermediate command, like ssh, call Env
+// on that layer before building the stack, or via Layers.
+//
+// Environment variables and working directories given for a single command
+// with RunWith follow the same rule, and are applied by the outermost layer
+// which changes the command.
+type Stack struct {
+	layers []Runner
+}
+
+var _ Runner = &Stack{}
+
+// Wrap builds a Stack by wrapping base with each of the given middlewares,
+// in the same order as Chain, so the first middleware is the outermost layer.
+// While Chain returns only the outermost runner, Wrap returns a Stack which
+// also keeps each layer beneath it. For example, this runs commands as root on
+// a remote host, connecting to it as the local deploy user:
+//
+//	r := runner.Wrap(
+//		runner.New(),
+//		func(r runner.Runner) runner.Runner {
+//			return &runner.Sudo{Runner: r}
+//		},
+//		func(r runner.Runner) runner.Runner {
+//			return &runner.SSHCLI{Runner: r, Destination: "web1"}
+//		},
+//		func(r runner.Runner) runner.Runner {
+//			return &runner.Sudo{Runner: r, User: "deploy"}
+//		},
+//	)
+//
+// Nil middlewares, and middlewares which return nil, are skipped.
+func Wrap(base Runner, middlewares ...Middleware) *Stack {
+	s := &Stack{layers: []Runner{base}}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] == nil {
+			continue
+		}
+		if r := middlewares[i](s.Outer()); r != nil {
+			s.layers = append(s.layers, r)
+		}
+	}
+
+	return s
+}
+
+// Layers returns the runners of the stack, starting with the base, and ending
+// with the outermost layer.
+func (s *Stack) Layers() []Runner {
+	return append([]Runner(nil), s.layers...)
+}
+
+// Outer returns the outermost layer of the stack.
+func (s *Stack) Outer() Runner {
+	return s.layers[len(s.layers)-1]
+}
+
+var _ Validator = &Stack{}
+
+// Validate validates each layer of the stack which implements Validator,
+// starting with the base, and returns the first error.
+func (s *Stack) Validate() error {
+	for _, r := range s.layers {
+		if err := Validate(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run runs the command by calling Run on the outermost layer.
+func (s *Stack) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return s.Outer().Run(stdin, stdout, stderr, command, args...)
+}
+
+// RunContext runs the command by calling RunContext on the outermost layer.
+func (s *Stack) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	return s.Outer().RunContext(ctx, stdin, stdout, stderr, command, args...)
+}
+
+// Env sets the environment variables of commands run through the stack, by
+// calling Env on the outermost layer.
+func (s *Stack) Env(env ...string) {
+	s.Outer().Env(env...)
+}
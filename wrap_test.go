@@ -0,0 +1,165 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrap_Env(t *testing.T) {
+	sudo := func(user string) Middleware {
+		return func(r Runner) Runner { return &Sudo{Runner: r, User: user} }
+	}
+	ssh := func(r Runner) Runner {
+		return &SSHCLI{Runner: r, Destination: "web1"}
+	}
+	docker := func(r Runner) Runner {
+		return &Docker{Runner: r, Container: "app"}
+	}
+	retry := func(r Runner) Runner { return &Retry{Runner: r} }
+
+	tests := []struct {
+		name        string
+		middlewares []Middleware
+		opts        []RunOption
+		wantCommand string
+		wantArgs    []string
+		wantEnv     []string
+	}{
+		{
+			name:        "base only",
+			wantCommand: "env",
+			wantEnv:     []string{"A=1"},
+		},
+		{
+			name:        "sudo",
+			middlewares: []Middleware{sudo("deploy")},
+			wantCommand: "sudo",
+			wantArgs:    []string{"-n", "-u", "deploy", "A=1", "--", "env"},
+		},
+		{
+			name:        "sudo then ssh",
+			middlewares: []Middleware{ssh, sudo("deploy")},
+			wantCommand: "sudo",
+			wantArgs: []string{
+				"-n", "-u", "deploy", "--",
+				"ssh", "web1", "--", "env", "A=1", "env",
+			},
+		},
+		{
+			name:        "ssh then sudo",
+			middlewares: []Middleware{sudo(""), ssh},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"web1", "--", "sudo", "-n", "A=1", "--", "env",
+			},
+		},
+		{
+			name:        "ssh then docker",
+			middlewares: []Middleware{docker, ssh},
+			wantCommand: "ssh",
+			wantArgs: []string{
+				"web1", "--", "docker", "exec", "-e", "A=1", "app", "env",
+			},
+		},
+		{
+			name:        "pass-through outermost layer",
+			middlewares: []Middleware{retry, ssh},
+			wantCommand: "ssh",
+			wantArgs:    []string{"web1", "--", "env", "A=1", "env"},
+		},
+		{
+			name:        "call options",
+			middlewares: []Middleware{ssh, sudo("deploy")},
+			opts:        []RunOption{WithEnv("B=2"), WithDir("/srv")},
+			wantCommand: "sudo",
+			wantArgs: []string{
+				"-n", "-u", "deploy", "--",
+				"ssh", "web1", "--", "cd", "'/srv'", "&&",
+				"env", "A=1", "B=2", "env",
+			},
+		},
+		{
+			name: "nil middlewares skipped",
+			middlewares: []Middleware{
+				nil,
+				func(Runner) Runner { return nil },
+				sudo("deploy"),
+			},
+			wantCommand: "sudo",
+			wantArgs:    []string{"-n", "-u", "deploy", "A=1", "--", "env"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &Fake{}
+			f.Respond(tt.wantCommand, nil, FakeResponse{})
+
+			s := Wrap(f, tt.middlewares...)
+			s.Env("A=1")
+
+			err := RunWith(context.Background(), s, tt.opts, "env")
+			require.NoError(t, err)
+
+			calls := f.Calls()
+			require.Len(t, calls, 1)
+			assert.Equal(t, tt.wantCommand, calls[0].Command)
+			assert.Equal(t, tt.wantArgs, calls[0].Args)
+			assert.Equal(t, tt.wantEnv, calls[0].Env)
+		})
+	}
+}
+
+func TestWrap_Layers(t *testing.T) {
+	base := &Fake{}
+	s := Wrap(base, func(r Runner) Runner { return &Sudo{Runner: r} })
+
+	layers := s.Layers()
+	require.Len(t, layers, 2)
+	assert.Same(t, base, layers[0])
+	assert.Same(t, base, layers[1].(*Sudo).Runner)
+	assert.Same(t, layers[1], s.Outer())
+
+	assert.Same(t, base, Wrap(base).Outer())
+}
+
+func TestStack_Validate(t *testing.T) {
+	s := Wrap(
+		&Local{},
+		func(r Runner) Runner { return &Sudo{Runner: r} },
+		func(r Runner) Runner { return &Retry{Runner: r} },
+	)
+	assert.NoError(t, s.Validate())
+
+	s = Wrap(
+		&Local{},
+		func(r Runner) Runner { return &SSHCLI{Runner: r} },
+		func(r Runner) Runner { return &Retry{Runner: r} },
+	)
+	err := s.Validate()
+	assert.EqualError(
+		t, err, "runner: invalid: SSHCLI.Destination: must be set",
+	)
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestWrap_matchesChain(t *testing.T) {
+	sudo := func(r Runner) Runner { return &Sudo{Runner: r} }
+	ssh := func(r Runner) Runner {
+		return &SSHCLI{Runner: r, Destination: "web1"}
+	}
+
+	wrapped := &Fake{}
+	wrapped.Respond("ssh", nil, FakeResponse{})
+	err := Wrap(wrapped, sudo, ssh).Run(nil, nil, nil, "id")
+	require.NoError(t, err)
+
+	chained := &Fake{}
+	chained.Respond("ssh", nil, FakeResponse{})
+	err = Chain(chained, sudo, ssh).Run(nil, nil, nil, "id")
+	require.NoError(t, err)
+
+	assert.Equal(t, chained.Calls(), wrapped.Calls())
+}
@@ -0,0 +1,22 @@
+module github.com/krystal/go-runner/zaprunner
+
+go 1.20
+
+require (
+	github.com/krystal/go-runner v0.2.1-0.20261015174412-190d082f010b
+	github.com/stretchr/testify v1.8.4
+	go.uber.org/mock v0.3.0
+	go.uber.org/zap v1.27.0
+)
+
+require (
+	github.com/creack/pty v1.1.21 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The root module is replaced for development within this repository only.
+// The version required above must provide everything this module uses.
+replace github.com/krystal/go-runner => ../
@@ -0,0 +1,181 @@
+// Package zaprunner provides a runner.Runner which logs commands to a
+// *zap.Logger, for services which standardize on zap.
+package zaprunner
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	runner "github.com/krystal/go-runner"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Log is a runner.Runner which wraps another Runner, and logs each command
+// run to a *zap.Logger once it has completed, with the command, arguments,
+// env vars set via Env, exit code, duration, and any error as structured
+// fields. The values of "key=value" arguments and env vars whose keys match
+// RedactKeys are redacted.
+type Log struct {
+	// Runner is the underlying Runner to run commands with. If not set,
+	// running commands will cause a panic.
+	Runner runner.Runner
+
+	// Logger is the logger commands are logged to. If not set, commands are
+	// not logged.
+	Logger *zap.Logger
+
+	// ContextLogger, when set, returns the logger to log commands run with
+	// RunContext to, allowing per-request loggers carrying fields like request
+	// IDs to be used. Logger is used if it returns nil. FromContext can be
+	// used with loggers added to contexts via NewContext.
+	ContextLogger func(ctx context.Context) *zap.Logger
+
+	// Level is the level successful commands are logged at. Defaults to
+	// zapcore.InfoLevel.
+	Level zapcore.Level
+
+	// FailureLevel, when set, is the level failed commands are logged at.
+	// Defaults to zapcore.ErrorLevel.
+	FailureLevel *zapcore.Level
+
+	// RedactKeys is a list of keys whose values are redacted in "key=value"
+	// arguments and env vars, as supported by runner.RedactEnv.
+	RedactKeys []string
+
+	// Message is the message commands are logged with. Defaults to
+	// DefaultMessage.
+	Message string
+
+	env   []string
+	envMu sync.RWMutex
+}
+
+// DefaultMessage is the message commands are logged with when Log.Message is
+// not set.
+const DefaultMessage = "runner: command completed"
+
+var _ runner.Runner = &Log{}
+
+// Run executes the command with the underlying Runner, and logs it.
+func (r *Log) Run(
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.Run(stdin, stdout, stderr, command, args...)
+	r.log(r.Logger, command, args, err, time.Since(start))
+
+	return err
+}
+
+// RunContext executes the command with the underlying Runner, and logs it.
+func (r *Log) RunContext(
+	ctx context.Context,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+	command string,
+	args ...string,
+) error {
+	start := time.Now()
+	err := r.Runner.RunContext(ctx, stdin, stdout, stderr, command, args...)
+	r.log(r.logger(ctx), command, args, err, time.Since(start))
+
+	return err
+}
+
+// Env sets the environment variables for the underlying Runner. They are
+// logged with each command, with the values of keys matching RedactKeys
+// redacted.
+func (r *Log) Env(env ...string) {
+	r.envMu.Lock()
+	r.env = append([]string(nil), env...)
+	r.envMu.Unlock()
+
+	r.Runner.Env(env...)
+}
+
+func (r *Log) environ() []string {
+	r.envMu.RLock()
+	defer r.envMu.RUnlock()
+
+	return r.env
+}
+
+func (r *Log) logger(ctx context.Context) *zap.Logger {
+	if r.ContextLogger != nil {
+		if logger := r.ContextLogger(ctx); logger != nil {
+			return logger
+		}
+	}
+
+	return r.Logger
+}
+
+func (r *Log) log(
+	logger *zap.Logger,
+	command string,
+	args []string,
+	err error,
+	duration time.Duration,
+) {
+	if logger == nil {
+		return
+	}
+
+	level := r.Level
+	if err != nil {
+		level = zapcore.ErrorLevel
+		if r.FailureLevel != nil {
+			level = *r.FailureLevel
+		}
+	}
+
+	msg := r.Message
+	if msg == "" {
+		msg = DefaultMessage
+	}
+
+	ce := logger.Check(level, msg)
+	if ce == nil {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("command", command),
+		zap.Strings("args", runner.RedactEnv(args, r.RedactKeys)),
+		zap.Int("exit_code", runner.ExitCode(err)),
+		zap.Duration("duration", duration),
+	}
+	if env := r.environ(); len(env) > 0 {
+		fields = append(fields,
+			zap.Strings("env", runner.RedactEnv(env, r.RedactKeys)),
+		)
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	ce.Write(fields...)
+}
+
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying logger, which is returned by
+// FromContext.
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx via NewContext, or nil.
+func FromContext(ctx context.Context) *zap.Logger {
+	logger, _ := ctx.Value(loggerKey{}).(*zap.Logger)
+
+	return logger
+}
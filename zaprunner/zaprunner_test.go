@@ -0,0 +1,227 @@
+package zaprunner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	runner "github.com/krystal/go-runner"
+	mock_runner "github.com/krystal/go-runner/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLog_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+	warn := zapcore.WarnLevel
+	info := zapcore.InfoLevel
+
+	tests := []struct {
+		name       string
+		log        *Log
+		resp       runner.FakeResponse
+		command    string
+		args       []string
+		wantErr    error
+		wantLevel  zapcore.Level
+		wantMsg    string
+		wantFields map[string]interface{}
+		wantNoLog  bool
+	}{
+		{
+			name:      "success",
+			command:   "zfs",
+			args:      []string{"list", "-H"},
+			wantLevel: zapcore.InfoLevel,
+			wantMsg:   "runner: command completed",
+			wantFields: map[string]interface{}{
+				"command":   "zfs",
+				"args":      []interface{}{"list", "-H"},
+				"exit_code": int64(0),
+			},
+		},
+		{
+			name: "exit code",
+			log: &Log{
+				Level:        zapcore.DebugLevel,
+				FailureLevel: &warn,
+				Message:      "exec",
+			},
+			resp:      runner.FakeResponse{ExitCode: 2},
+			command:   "false",
+			wantErr:   &runner.FakeExitError{Command: "false", Code: 2},
+			wantLevel: zapcore.WarnLevel,
+			wantMsg:   "exec",
+			wantFields: map[string]interface{}{
+				"command":   "false",
+				"args":      []interface{}{},
+				"exit_code": int64(2),
+				"error":     "false: exit status 2",
+			},
+		},
+		{
+			name:      "error",
+			resp:      runner.FakeResponse{Err: errBoom},
+			command:   "rm",
+			wantErr:   errBoom,
+			wantLevel: zapcore.ErrorLevel,
+			wantMsg:   "runner: command completed",
+			wantFields: map[string]interface{}{
+				"command":   "rm",
+				"args":      []interface{}{},
+				"exit_code": int64(-1),
+				"error":     "boom",
+			},
+		},
+		{
+			name:      "failure level",
+			log:       &Log{FailureLevel: &info},
+			resp:      runner.FakeResponse{ExitCode: 1},
+			command:   "false",
+			wantErr:   &runner.FakeExitError{Command: "false", Code: 1},
+			wantLevel: zapcore.InfoLevel,
+			wantMsg:   "runner: command completed",
+			wantFields: map[string]interface{}{
+				"command":   "false",
+				"args":      []interface{}{},
+				"exit_code": int64(1),
+				"error":     "false: exit status 1",
+			},
+		},
+		{
+			name:      "redacted args",
+			log:       &Log{RedactKeys: []string{"*_token"}},
+			command:   "env",
+			args:      []string{"API_TOKEN=secret", "FOO=bar", "true"},
+			wantLevel: zapcore.InfoLevel,
+			wantMsg:   "runner: command completed",
+			wantFields: map[string]interface{}{
+				"command": "env",
+				"args": []interface{}{
+					"API_TOKEN=***", "FOO=bar", "true",
+				},
+				"exit_code": int64(0),
+			},
+		},
+		{
+			name:      "below logger level",
+			log:       &Log{Level: zapcore.DebugLevel},
+			command:   "true",
+			wantNoLog: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+
+			f := &runner.Fake{}
+			f.Respond("*", nil, tt.resp)
+
+			r := tt.log
+			if r == nil {
+				r = &Log{}
+			}
+			r.Runner = f
+			r.Logger = zap.New(core)
+
+			err := r.Run(nil, nil, nil, tt.command, tt.args...)
+			assert.Equal(t, tt.wantErr, err)
+
+			r.Logger = zap.New(core)
+			err = r.RunContext(
+				context.Background(), nil, nil, nil, tt.command, tt.args...,
+			)
+			assert.Equal(t, tt.wantErr, err)
+
+			if tt.wantNoLog {
+				assert.Zero(t, logs.Len())
+
+				return
+			}
+
+			entries := logs.AllUntimed()
+			require.Len(t, entries, 2)
+			for _, e := range entries {
+				assert.Equal(t, tt.wantLevel, e.Level)
+				assert.Equal(t, tt.wantMsg, e.Message)
+
+				fields := e.ContextMap()
+				assert.IsType(t, time.Duration(0), fields["duration"])
+				delete(fields, "duration")
+				assert.Equal(t, tt.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestLog_nilLogger(t *testing.T) {
+	f := &runner.Fake{}
+	f.Respond("true", nil, runner.FakeResponse{})
+
+	r := &Log{Runner: f}
+
+	assert.NoError(t, r.Run(nil, nil, nil, "true"))
+	assert.Len(t, f.Calls(), 1)
+}
+
+func TestLog_Env(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mock_runner.NewMockRunner(ctrl)
+	m.EXPECT().Env([]string{"FOO=bar", "DB_PASSWORD=secret"})
+	m.EXPECT().Run(nil, nil, nil, "true")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	r := &Log{
+		Runner:     m,
+		Logger:     zap.New(core),
+		RedactKeys: []string{"*_PASSWORD"},
+	}
+	r.Env("FOO=bar", "DB_PASSWORD=secret")
+	assert.Zero(t, logs.Len())
+
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 1)
+	assert.Equal(t,
+		[]interface{}{"FOO=bar", "DB_PASSWORD=***"},
+		entries[0].ContextMap()["env"],
+	)
+}
+
+func TestLog_ContextLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	base := zap.New(core).With(zap.String("logger", "base"))
+	reqLogger := zap.New(core).With(zap.String("request_id", "abc123"))
+
+	f := &runner.Fake{}
+	f.Respond("true", nil, runner.FakeResponse{})
+
+	r := &Log{Runner: f, Logger: base, ContextLogger: FromContext}
+
+	ctx := NewContext(context.Background(), reqLogger)
+	require.NoError(t, r.RunContext(ctx, nil, nil, nil, "true"))
+	err := r.RunContext(context.Background(), nil, nil, nil, "true")
+	require.NoError(t, err)
+	require.NoError(t, r.Run(nil, nil, nil, "true"))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "abc123", entries[0].ContextMap()["request_id"])
+	assert.Equal(t, "base", entries[1].ContextMap()["logger"])
+	assert.Equal(t, "base", entries[2].ContextMap()["logger"])
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	logger := zap.NewNop()
+	ctx := NewContext(context.Background(), logger)
+	assert.Same(t, logger, FromContext(ctx))
+}